@@ -37,12 +37,17 @@ class chunkedBuffer {
   // Get the number of key/value pairs written to this chunkedBuffer.
   int Count() const { return count_; }
 
+  // Get the number of key and value bytes written to this chunkedBuffer,
+  // excluding the encoded sizes.
+  int64_t NumBytes() const { return bytes_; }
+
  private:
   void put(const char* data, int len, int next_size_hint);
 
  private:
   std::vector<DBSlice> bufs_;
   int64_t count_;
+  int64_t bytes_;
   char* buf_ptr_;
 };
 
@@ -48,12 +48,14 @@ static const int kMaxItersBeforeSeek = 10;
 template <bool reverse> class mvccScanner {
  public:
   mvccScanner(DBIterator* iter, DBSlice start, DBSlice end, DBTimestamp timestamp, int64_t max_keys,
-              DBTxn txn, bool inconsistent, bool tombstones, bool ignore_sequence)
+              int64_t target_bytes, DBTxn txn, bool inconsistent, bool tombstones,
+              bool ignore_sequence, bool keys_only)
       : iter_(iter),
         iter_rep_(iter->rep.get()),
         start_key_(ToSlice(start)),
         end_key_(ToSlice(end)),
         max_keys_(max_keys),
+        target_bytes_(target_bytes),
         timestamp_(timestamp),
         txn_id_(ToSlice(txn.id)),
         txn_epoch_(txn.epoch),
@@ -62,6 +64,7 @@ template <bool reverse> class mvccScanner {
         inconsistent_(inconsistent),
         tombstones_(tombstones),
         ignore_sequence_(ignore_sequence),
+        keys_only_(keys_only),
         check_uncertainty_(timestamp < txn.max_timestamp),
         kvs_(new chunkedBuffer),
         intents_(new rocksdb::WriteBatch),
@@ -132,7 +135,7 @@ template <bool reverse> class mvccScanner {
     while (getAndAdvance()) {
     }
 
-    if (kvs_->Count() == max_keys_ && advanceKey()) {
+    if (limitReached() && advanceKey()) {
       if (reverse) {
         // It is possible for cur_key_ to be pointing into mvccScanner.saved_buf_
         // instead of iter_rep_'s underlying storage if iterating in reverse (see
@@ -187,7 +190,7 @@ template <bool reverse> class mvccScanner {
     const auto intent = *(up - 1);
     rocksdb::Slice value = intent.value();
     if (value.size() > 0 || tombstones_) {
-      kvs_->Put(cur_raw_key_, value);
+      putResult(value);
     }
     return true;
   }
@@ -277,7 +280,7 @@ template <bool reverse> class mvccScanner {
       // historical timestamp < the intent timestamp. However, we
       // return the intent separately; the caller may want to resolve
       // it.
-      if (kvs_->Count() == max_keys_) {
+      if (limitReached()) {
         // We've already retrieved the desired number of keys and now
         // we're adding the resume key. We don't want to add the
         // intent here as the intents should only correspond to KVs
@@ -470,14 +473,28 @@ template <bool reverse> class mvccScanner {
     // Don't include deleted versions (value.size() == 0), unless we've been
     // instructed to include tombstones in the results.
     if (value.size() > 0 || tombstones_) {
-      kvs_->Put(cur_raw_key_, value);
-      if (kvs_->Count() == max_keys_) {
+      putResult(value);
+      if (limitReached()) {
         return false;
       }
     }
     return advanceKey();
   }
 
+  // putResult adds the current key and the given value to the results. If
+  // keys_only_ is set, the value is omitted and its bytes are never copied.
+  void putResult(const rocksdb::Slice& value) {
+    kvs_->Put(cur_raw_key_, keys_only_ ? rocksdb::Slice() : value);
+  }
+
+  // limitReached returns true if the scan has retrieved max_keys_ keys or, if
+  // target_bytes_ is set, at least target_bytes_ bytes of keys and values. The
+  // key/value pair that crosses target_bytes_ is included in the results so
+  // that a scan always makes progress.
+  bool limitReached() const {
+    return kvs_->Count() == max_keys_ || (target_bytes_ > 0 && kvs_->NumBytes() >= target_bytes_);
+  }
+
   // seekVersion advances the iterator to point to an MVCC version for
   // the specified key that is earlier than <ts_wall_time,
   // ts_logical>. Returns false if the iterator is exhausted or an
@@ -653,6 +670,7 @@ template <bool reverse> class mvccScanner {
   const rocksdb::Slice start_key_;
   const rocksdb::Slice end_key_;
   const int64_t max_keys_;
+  const int64_t target_bytes_;
   const DBTimestamp timestamp_;
   const rocksdb::Slice txn_id_;
   const uint32_t txn_epoch_;
@@ -661,6 +679,7 @@ template <bool reverse> class mvccScanner {
   const bool inconsistent_;
   const bool tombstones_;
   const bool ignore_sequence_;
+  const bool keys_only_;
   const bool check_uncertainty_;
   DBScanResults results_;
   std::unique_ptr<chunkedBuffer> kvs_;
@@ -32,6 +32,12 @@ var (
 	// This is also used by testing to simplify fake configs.
 	ZoneConfigHook zoneConfigHook
 
+	// ZoneSourceHook is a function used to look up the ID of the object whose
+	// zone config applies to the given table or database ID, i.e. the object
+	// the zone config returned by ZoneConfigHook was inherited from. If unset,
+	// zone configs are assumed to be the object's own.
+	ZoneSourceHook func(sysCfg *SystemConfig, objectID uint32) (uint32, error)
+
 	// testingLargestIDHook is a function used to bypass GetLargestObjectID
 	// in tests.
 	testingLargestIDHook func(uint32) uint32
@@ -41,9 +47,29 @@ var (
 	SplitAtIDHook func(uint32, *SystemConfig) bool
 )
 
+// The sources a zone config can be inherited from, as returned by
+// GetZoneConfigAndSourceForKey.
+const (
+	// ZoneSourceDefault is the default zone (.default).
+	ZoneSourceDefault = "default"
+	// ZoneSourceDatabase is the zone of a database, including the system
+	// database.
+	ZoneSourceDatabase = "database"
+	// ZoneSourceTable is the zone of a table.
+	ZoneSourceTable = "table"
+	// ZoneSourceSubzone is the zone of an index or partition.
+	ZoneSourceSubzone = "subzone"
+	// ZoneSourceNamedRange is the zone of a named system range, such as
+	// .meta or .liveness.
+	ZoneSourceNamedRange = "range"
+)
+
 type zoneEntry struct {
 	zone        *ZoneConfig
 	placeholder *ZoneConfig
+	// zoneID is the ID of the object whose zone config zone was inherited
+	// from.
+	zoneID uint32
 
 	// combined merges the zone and placeholder configs into a combined config.
 	// If both have subzone information, the placeholder information is preferred.
@@ -251,6 +277,17 @@ func (s *SystemConfig) GetLargestObjectID(maxID uint32) (uint32, error) {
 // or database, specified by key.id). It is the caller's
 // responsibility to ensure that the range does not need to be split.
 func (s *SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (*ZoneConfig, error) {
+	zone, _, err := s.GetZoneConfigAndSourceForKey(key)
+	return zone, err
+}
+
+// GetZoneConfigAndSourceForKey is like GetZoneConfigForKey, but additionally
+// returns which kind of zone the zone config was taken from (one of the
+// ZoneSource constants). Note that a zone may itself inherit some of its
+// fields from its parents.
+func (s *SystemConfig) GetZoneConfigAndSourceForKey(
+	key roachpb.RKey,
+) (*ZoneConfig, string, error) {
 	objectID, keySuffix, ok := DecodeObjectID(key)
 	if !ok {
 		// Not in the structured data namespace.
@@ -304,13 +341,20 @@ func (s *SystemConfig) getZoneEntry(id uint32) (zoneEntry, error) {
 	}
 	testingLock.Lock()
 	hook := ZoneConfigHook
+	sourceHook := ZoneSourceHook
 	testingLock.Unlock()
 	zone, placeholder, cache, err := hook(s, id)
 	if err != nil {
 		return zoneEntry{}, err
 	}
 	if zone != nil {
-		entry := zoneEntry{zone: zone, placeholder: placeholder, combined: zone}
+		zoneID := id
+		if sourceHook != nil {
+			if zoneID, err = sourceHook(s, id); err != nil {
+				return zoneEntry{}, err
+			}
+		}
+		entry := zoneEntry{zone: zone, placeholder: placeholder, zoneID: zoneID, combined: zone}
 		if placeholder != nil {
 			// Merge placeholder with zone by copying over subzone information.
 			// Placeholders should only define the Subzones and SubzoneSpans fields.
@@ -330,10 +374,12 @@ func (s *SystemConfig) getZoneEntry(id uint32) (zoneEntry, error) {
 	return zoneEntry{}, nil
 }
 
-func (s *SystemConfig) getZoneConfigForKey(id uint32, keySuffix []byte) (*ZoneConfig, error) {
+func (s *SystemConfig) getZoneConfigForKey(
+	id uint32, keySuffix []byte,
+) (*ZoneConfig, string, error) {
 	entry, err := s.getZoneEntry(id)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if entry.zone != nil {
 		if entry.placeholder != nil {
@@ -342,18 +388,37 @@ func (s *SystemConfig) getZoneConfigForKey(id uint32, keySuffix []byte) (*ZoneCo
 					subzone.Config.InheritFromParent(&indexSubzone.Config)
 				}
 				subzone.Config.InheritFromParent(entry.zone)
-				return &subzone.Config, nil
+				return &subzone.Config, ZoneSourceSubzone, nil
 			}
 		} else if subzone := entry.zone.GetSubzoneForKeySuffix(keySuffix); subzone != nil {
 			if indexSubzone := entry.zone.GetSubzone(subzone.IndexID, ""); indexSubzone != nil {
 				subzone.Config.InheritFromParent(&indexSubzone.Config)
 			}
 			subzone.Config.InheritFromParent(entry.zone)
-			return &subzone.Config, nil
+			return &subzone.Config, ZoneSourceSubzone, nil
+		}
+		return entry.zone, zoneSource(id, entry.zoneID), nil
+	}
+	return s.DefaultZoneConfig, ZoneSourceDefault, nil
+}
+
+// zoneSource returns the kind of zone that the zone config for the object
+// with the given ID was inherited from, given the ID of that zone.
+func zoneSource(objectID, zoneID uint32) string {
+	switch {
+	case zoneID == keys.RootNamespaceID:
+		return ZoneSourceDefault
+	case zoneID == keys.SystemDatabaseID || zoneID != objectID:
+		// Tables which don't have a zone of their own inherit from their
+		// database. All system tables use the system database's zone.
+		return ZoneSourceDatabase
+	}
+	for _, id := range keys.PseudoTableIDs {
+		if zoneID == id {
+			return ZoneSourceNamedRange
 		}
-		return entry.zone, nil
 	}
-	return s.DefaultZoneConfig, nil
+	return ZoneSourceTable
 }
 
 var staticSplits = []roachpb.RKey{
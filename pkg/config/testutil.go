@@ -22,6 +22,10 @@ var (
 	testingHasHook      bool
 	testingPreviousHook zoneConfigHook
 	testingLock         syncutil.Mutex
+
+	// testingPreviousSourceHook is the ZoneSourceHook, which is unset while
+	// the testing hook is in place.
+	testingPreviousSourceHook func(*SystemConfig, uint32) (uint32, error)
 )
 
 // TestingSetupZoneConfigHook initializes the zone config hook
@@ -39,6 +43,8 @@ func TestingSetupZoneConfigHook(stopper *stop.Stopper) {
 	testingZoneConfig = make(zoneConfigMap)
 	testingPreviousHook = ZoneConfigHook
 	ZoneConfigHook = testingZoneConfigHook
+	testingPreviousSourceHook = ZoneSourceHook
+	ZoneSourceHook = nil
 	testingLargestIDHook = func(maxID uint32) (max uint32) {
 		testingLock.Lock()
 		defer testingLock.Unlock()
@@ -64,6 +70,7 @@ func testingResetZoneConfigHook() {
 	}
 	testingHasHook = false
 	ZoneConfigHook = testingPreviousHook
+	ZoneSourceHook = testingPreviousSourceHook
 	testingLargestIDHook = nil
 }
 
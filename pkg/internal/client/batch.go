@@ -728,3 +728,24 @@ func (b *Batch) addSSTable(
 	b.appendReqs(req)
 	b.initResult(1, 0, notRaw, nil)
 }
+
+// addSSTables is only exported on DB.
+func (b *Batch) addSSTables(
+	files []roachpb.AddSSTableRequest_File, disallowShadowing bool, stats *enginepb.MVCCStats,
+) {
+	if len(files) == 0 {
+		b.initResult(0, 0, notRaw, errors.New("no files to add"))
+		return
+	}
+	req := &roachpb.AddSSTableRequest{
+		RequestHeader: roachpb.RequestHeader{
+			Key:    files[0].Span.Key,
+			EndKey: files[len(files)-1].Span.EndKey,
+		},
+		Files:             files,
+		DisallowShadowing: disallowShadowing,
+		MVCCStats:         stats,
+	}
+	b.appendReqs(req)
+	b.initResult(1, 0, notRaw, nil)
+}
@@ -16,6 +16,7 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -563,6 +564,102 @@ func (db *DB) AdminChangeReplicas(
 	return &desc, nil
 }
 
+// AmbiguousResultPolicy determines how AdminChangeReplicasWithPolicy handles an
+// AmbiguousResultError, after which it is unknown whether the replication
+// change committed.
+type AmbiguousResultPolicy int
+
+const (
+	// AmbiguousResultFailFast returns the AmbiguousResultError to the caller.
+	AmbiguousResultFailFast AmbiguousResultPolicy = iota
+	// AmbiguousResultRetryIdempotent retries the change against the same
+	// expected descriptor. If the ambiguous attempt did commit, the retry fails
+	// with a descriptor mismatch which the caller has to interpret.
+	AmbiguousResultRetryIdempotent
+	// AmbiguousResultVerifyDescriptor re-reads the range descriptor to find out
+	// whether the change took effect. If it did, the updated descriptor is
+	// returned; if the descriptor is unchanged, the change is retried.
+	// Otherwise, the descriptor was changed concurrently and the
+	// AmbiguousResultError is returned.
+	AmbiguousResultVerifyDescriptor
+)
+
+// maxAmbiguousResultRetries bounds the number of times
+// AdminChangeReplicasWithPolicy retries after an AmbiguousResultError.
+const maxAmbiguousResultRetries = 5
+
+// AdminChangeReplicasWithPolicy is like AdminChangeReplicas, but resolves
+// AmbiguousResultErrors according to the supplied policy.
+func (db *DB) AdminChangeReplicasWithPolicy(
+	ctx context.Context,
+	key interface{},
+	expDesc roachpb.RangeDescriptor,
+	chgs []roachpb.ReplicationChange,
+	policy AmbiguousResultPolicy,
+) (*roachpb.RangeDescriptor, error) {
+	retryOpts := base.DefaultRetryOptions()
+	retryOpts.MaxRetries = maxAmbiguousResultRetries
+	var err error
+	for r := retry.StartWithCtx(ctx, retryOpts); r.Next(); {
+		var desc *roachpb.RangeDescriptor
+		desc, err = db.AdminChangeReplicas(ctx, key, expDesc, chgs)
+		if _, ok := errors.Cause(err).(*roachpb.AmbiguousResultError); !ok {
+			return desc, err
+		}
+		switch policy {
+		case AmbiguousResultFailFast:
+			return nil, err
+		case AmbiguousResultRetryIdempotent:
+			log.VEventf(ctx, 2, "retrying replication change after %v", err)
+		case AmbiguousResultVerifyDescriptor:
+			var curDesc roachpb.RangeDescriptor
+			if verr := db.GetProto(
+				ctx, keys.RangeDescriptorKey(expDesc.StartKey), &curDesc,
+			); verr != nil {
+				return nil, errors.Wrapf(err, "unable to verify replication change: %v", verr)
+			}
+			if curDesc.Equal(&expDesc) {
+				log.VEventf(ctx, 2, "replication change did not apply, retrying after %v", err)
+				continue
+			}
+			if replicationChangesApplied(&curDesc, chgs) {
+				return &curDesc, nil
+			}
+			return nil, errors.Wrapf(err, "range descriptor changed concurrently to %s", &curDesc)
+		default:
+			return nil, errors.Errorf("unknown ambiguous result policy %d", policy)
+		}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return nil, err
+}
+
+// replicationChangesApplied returns whether desc reflects all of the supplied
+// replication changes.
+func replicationChangesApplied(
+	desc *roachpb.RangeDescriptor, chgs []roachpb.ReplicationChange,
+) bool {
+	for _, chg := range chgs {
+		repl, ok := desc.GetReplicaDescriptor(chg.Target.StoreID)
+		present := ok && repl.NodeID == chg.Target.NodeID
+		switch chg.ChangeType {
+		case roachpb.ADD_REPLICA:
+			if !present {
+				return false
+			}
+		case roachpb.REMOVE_REPLICA:
+			if ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // AdminRelocateRange relocates the replicas for a range onto the specified
 // list of stores.
 func (db *DB) AdminRelocateRange(
@@ -596,6 +693,21 @@ func (db *DB) AddSSTable(
 	return getOneErr(db.Run(ctx, b), b)
 }
 
+// AddSSTables is like AddSSTable, but atomically links multiple files, which
+// must be sorted by span and not overlap, using a single request. This is
+// considerably cheaper than adding many small files individually. If stats
+// are given, they must cover all of the files.
+func (db *DB) AddSSTables(
+	ctx context.Context,
+	files []roachpb.AddSSTableRequest_File,
+	disallowShadowing bool,
+	stats *enginepb.MVCCStats,
+) error {
+	b := &Batch{}
+	b.addSSTables(files, disallowShadowing, stats)
+	return getOneErr(db.Run(ctx, b), b)
+}
+
 // sendAndFill is a helper which sends the given batch and fills its results,
 // returning the appropriate error which is either from the first failing call,
 // or an "internal" error.
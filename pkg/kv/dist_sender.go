@@ -554,7 +554,7 @@ func (ds *DistSender) initAndVerifyBatch(
 		return roachpb.NewErrorf("empty batch")
 	}
 
-	if ba.MaxSpanRequestKeys != 0 {
+	if ba.MaxSpanRequestKeys != 0 || ba.MaxSpanRequestBytes != 0 || ba.MaxIntentRows != 0 {
 		// Verify that the batch contains only specific range requests or the
 		// Begin/EndTransactionRequest. Verify that a batch with a ReverseScan
 		// only contains ReverseScan range requests.
@@ -696,10 +696,11 @@ func (ds *DistSender) Send(
 		splitET = true
 	}
 	parts := splitBatchAndCheckForRefreshSpans(ba, splitET)
-	if len(parts) > 1 && ba.MaxSpanRequestKeys != 0 {
+	if len(parts) > 1 &&
+		(ba.MaxSpanRequestKeys != 0 || ba.MaxSpanRequestBytes != 0 || ba.MaxIntentRows != 0) {
 		// We already verified above that the batch contains only scan requests of the same type.
 		// Such a batch should never need splitting.
-		panic("batch with MaxSpanRequestKeys needs splitting")
+		panic("batch with MaxSpanRequestKeys, MaxSpanRequestBytes or MaxIntentRows needs splitting")
 	}
 
 	var pErr *roachpb.Error
@@ -1211,7 +1212,8 @@ func (ds *DistSender) divideAndSendBatchToRanges(
 	// accumulated so far.
 	var numResults int64
 	stopAtRangeBoundary := ba.Header.ScanOptions != nil && ba.Header.ScanOptions.StopAtRangeBoundary
-	canParallelize := (ba.Header.MaxSpanRequestKeys == 0) && !stopAtRangeBoundary
+	canParallelize := ba.Header.MaxSpanRequestKeys == 0 && ba.Header.MaxSpanRequestBytes == 0 &&
+		ba.Header.MaxIntentRows == 0 && !stopAtRangeBoundary
 
 	for ; ri.Valid(); ri.Seek(ctx, seekKey, scanDir) {
 		responseCh := make(chan response, 1)
@@ -1266,12 +1268,25 @@ func (ds *DistSender) divideAndSendBatchToRanges(
 				ba.UpdateTxn(resp.reply.Txn)
 			}
 
-			mightStopEarly := ba.MaxSpanRequestKeys > 0 || stopAtRangeBoundary
+			mightStopEarly := ba.MaxSpanRequestKeys > 0 || ba.MaxSpanRequestBytes > 0 ||
+				ba.MaxIntentRows > 0 || stopAtRangeBoundary
 			// Check whether we've received enough responses to exit query loop.
 			if mightStopEarly {
-				var replyResults int64
+				var replyResults, replyBytes, replyIntentRows int64
+				var byteLimitHit, intentLimitHit bool
 				for _, r := range resp.reply.Responses {
-					replyResults += r.GetInner().Header().NumKeys
+					inner := r.GetInner()
+					h := inner.Header()
+					replyResults += h.NumKeys
+					replyBytes += h.NumBytes
+					byteLimitHit = byteLimitHit || h.ResumeReason == roachpb.RESUME_BYTE_LIMIT
+					intentLimitHit = intentLimitHit || h.ResumeReason == roachpb.RESUME_INTENT_LIMIT
+					switch t := inner.(type) {
+					case *roachpb.ScanResponse:
+						replyIntentRows += int64(len(t.IntentRows))
+					case *roachpb.ReverseScanResponse:
+						replyIntentRows += int64(len(t.IntentRows))
+					}
 				}
 				// Do accounting for results. It's important that we update
 				// MaxSpanRequestKeys and ScanOptions.MinResults, as ba might be
@@ -1290,6 +1305,26 @@ func (ds *DistSender) divideAndSendBatchToRanges(
 						return
 					}
 				}
+				if ba.MaxSpanRequestBytes > 0 {
+					// Results are never split within a key/value pair, so the range may
+					// have returned more than the remaining budget.
+					ba.MaxSpanRequestBytes -= replyBytes
+					// Exiting; any missing responses will be filled in via defer().
+					if ba.MaxSpanRequestBytes <= 0 || byteLimitHit {
+						couldHaveSkippedResponses = true
+						resumeReason = roachpb.RESUME_BYTE_LIMIT
+						return
+					}
+				}
+				if ba.MaxIntentRows > 0 {
+					ba.MaxIntentRows -= replyIntentRows
+					// Exiting; any missing responses will be filled in via defer().
+					if ba.MaxIntentRows <= 0 || intentLimitHit {
+						couldHaveSkippedResponses = true
+						resumeReason = roachpb.RESUME_INTENT_LIMIT
+						return
+					}
+				}
 				var minResultsSatisfied bool
 				if !stopAtRangeBoundary {
 					minResultsSatisfied = true
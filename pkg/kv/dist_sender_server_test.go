@@ -13,6 +13,7 @@ package kv_test
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
@@ -861,6 +862,182 @@ func TestMultiRangeEmptyAfterTruncate(t *testing.T) {
 	}
 }
 
+// TestMultiRangeBoundedBytesScan verifies that MaxSpanRequestBytes is
+// accounted for across ranges by the DistSender: ranges which return no
+// results don't consume the budget, the scan stops in the range in which the
+// budget is exhausted, and resuming from the returned resume spans reads every
+// key exactly once.
+func TestMultiRangeBoundedBytesScan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _ := startNoSplitMergeServer(t)
+	ctx := context.TODO()
+	defer s.Stopper().Stop(ctx)
+
+	db := s.DB()
+	if err := setupMultipleRanges(ctx, db, "a", "b", "c", "d", "e", "f"); err != nil {
+		t.Fatal(err)
+	}
+	// The range [e,f) is left empty.
+	keys := []string{"a1", "a2", "a3", "b1", "b2", "c1", "c2", "d1", "f1", "f2", "f3"}
+	for _, key := range keys {
+		if err := db.Put(ctx, key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rowSize := func(kv client.KeyValue) int64 {
+		return int64(len(kv.Key) + len(kv.Value.RawBytes))
+	}
+
+	for _, reverse := range []bool{false, true} {
+		expKeys := append([]string(nil), keys...)
+		if reverse {
+			for i, j := 0, len(expKeys)-1; i < j; i, j = i+1, j-1 {
+				expKeys[i], expKeys[j] = expKeys[j], expKeys[i]
+			}
+		}
+		for _, maxBytes := range []int64{1, 20, 50, 1000} {
+			t.Run(fmt.Sprintf("reverse=%t,bytes=%d", reverse, maxBytes), func(t *testing.T) {
+				var results []string
+				span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("g")}
+				for batches := 0; ; batches++ {
+					if batches > len(keys) {
+						t.Fatalf("scan did not make progress; read %v", results)
+					}
+					b := &client.Batch{}
+					b.Header.MaxSpanRequestBytes = maxBytes
+					if !reverse {
+						b.Scan(span.Key, span.EndKey)
+					} else {
+						b.ReverseScan(span.Key, span.EndKey)
+					}
+					if err := db.Run(ctx, b); err != nil {
+						t.Fatal(err)
+					}
+					res := b.Results[0]
+					if len(res.Rows) == 0 {
+						t.Fatalf("batch %d returned no rows", batches)
+					}
+					// Every row but the last one must have fit into the budget.
+					var size int64
+					for _, row := range res.Rows[:len(res.Rows)-1] {
+						size += rowSize(row)
+					}
+					if size >= maxBytes {
+						t.Fatalf("batch %d returned %d bytes before its last row, exceeding %d",
+							batches, size, maxBytes)
+					}
+					for _, row := range res.Rows {
+						results = append(results, string(row.Key))
+					}
+					if res.ResumeSpan == nil {
+						break
+					}
+					if res.ResumeReason != roachpb.RESUME_BYTE_LIMIT {
+						t.Fatalf("expected resume reason %s, found %s",
+							roachpb.RESUME_BYTE_LIMIT, res.ResumeReason)
+					}
+					span = *res.ResumeSpan
+				}
+				if !reflect.DeepEqual(results, expKeys) {
+					t.Fatalf("expected %v, found %v", expKeys, results)
+				}
+			})
+		}
+	}
+
+	// A budget of a single byte returns exactly one row per batch, even when
+	// the row is found ranges away from where the scan starts.
+	b := &client.Batch{}
+	b.Header.MaxSpanRequestBytes = 1
+	b.Scan("d2", "g")
+	if err := db.Run(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if res := b.Results[0]; len(res.Rows) != 1 || string(res.Rows[0].Key) != "f1" ||
+		res.ResumeSpan == nil || !res.ResumeSpan.Key.Equal(roachpb.Key("f2")) {
+		t.Fatalf("expected a single row f1 and a resume span starting at f2, found %+v", res)
+	}
+}
+
+// TestMultiRangeBoundedIntentRowsScan verifies that MaxIntentRows is
+// accounted for across ranges by the DistSender for READ_UNCOMMITTED scans,
+// and that resuming from the returned resume spans reads every committed
+// value and intent exactly once.
+func TestMultiRangeBoundedIntentRowsScan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _ := startNoSplitMergeServer(t)
+	ctx := context.TODO()
+	defer s.Stopper().Stop(ctx)
+
+	db := s.DB()
+	if err := setupMultipleRanges(ctx, db, "b", "c", "d"); err != nil {
+		t.Fatal(err)
+	}
+	committedKeys := []string{"a1", "b1", "c1", "d1"}
+	for _, key := range committedKeys {
+		if err := db.Put(ctx, key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Leave intents behind from a transaction that remains open.
+	intentKeys := []string{"a2", "b2", "b3", "c2", "d2"}
+	txn := client.NewTxn(ctx, db, s.NodeID(), client.RootTxn)
+	defer func() { _ = txn.Rollback(ctx) }()
+	for _, key := range intentKeys {
+		if err := txn.Put(ctx, key, "intent"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const maxIntentRows = 2
+	var rows, intentRows []string
+	span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("e")}
+	for batches := 0; ; batches++ {
+		if batches > len(intentKeys) {
+			t.Fatalf("scan did not make progress; read %v and intents %v", rows, intentRows)
+		}
+		reply, pErr := client.SendWrappedWith(ctx, s.DistSender(), roachpb.Header{
+			ReadConsistency: roachpb.READ_UNCOMMITTED,
+			MaxIntentRows:   maxIntentRows,
+		}, roachpb.NewScan(span.Key, span.EndKey))
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		scanReply := reply.(*roachpb.ScanResponse)
+		if len(scanReply.IntentRows) > maxIntentRows {
+			t.Fatalf("batch %d returned %d intent rows, exceeding %d",
+				batches, len(scanReply.IntentRows), maxIntentRows)
+		}
+		for _, row := range scanReply.Rows {
+			rows = append(rows, string(row.Key))
+		}
+		for _, row := range scanReply.IntentRows {
+			intentRows = append(intentRows, string(row.Key))
+		}
+		if batches == 0 {
+			// The first range contributes one intent row, which leaves room for
+			// a single one from the second range.
+			if exp := []string{"a2", "b2"}; !reflect.DeepEqual(intentRows, exp) {
+				t.Fatalf("expected intent rows %v in first batch, found %v", exp, intentRows)
+			}
+		}
+		rh := scanReply.Header()
+		if rh.ResumeSpan == nil {
+			break
+		}
+		if rh.ResumeReason != roachpb.RESUME_INTENT_LIMIT {
+			t.Fatalf("expected resume reason %s, found %s", roachpb.RESUME_INTENT_LIMIT, rh.ResumeReason)
+		}
+		span = *rh.ResumeSpan
+	}
+	if !reflect.DeepEqual(rows, committedKeys) {
+		t.Errorf("expected rows %v, found %v", committedKeys, rows)
+	}
+	if !reflect.DeepEqual(intentRows, intentKeys) {
+		t.Errorf("expected intent rows %v, found %v", intentKeys, intentRows)
+	}
+}
+
 // TestMultiRequestBatchWithFwdAndReverseRequests are disallowed.
 func TestMultiRequestBatchWithFwdAndReverseRequests(t *testing.T) {
 	defer leaktest.AfterTest(t)()
@@ -238,6 +238,8 @@ func (rh *ResponseHeader) combine(otherRH ResponseHeader) error {
 	rh.ResumeSpan = otherRH.ResumeSpan
 	rh.ResumeReason = otherRH.ResumeReason
 	rh.NumKeys += otherRH.NumKeys
+	rh.NumMutations += otherRH.NumMutations
+	rh.NumBytes += otherRH.NumBytes
 	rh.RangeInfos = append(rh.RangeInfos, otherRH.RangeInfos...)
 	return nil
 }
@@ -400,6 +402,10 @@ func (h *BatchResponse_Header) combine(o BatchResponse_Header) error {
 	}
 	h.Now.Forward(o.Now)
 	h.CollectedSpans = append(h.CollectedSpans, o.CollectedSpans...)
+	if h.ServingReplica == nil {
+		h.ServingReplica = o.ServingReplica
+	}
+	h.ReadTimestampClamped = h.ReadTimestampClamped || o.ReadTimestampClamped
 	return nil
 }
 
@@ -1038,6 +1044,13 @@ func (drr *DeleteRangeRequest) flags() int {
 	// while this special case in flags() fixes all current issues succinctly.
 	// This workaround does not preclude us from creating a separate
 	// "DeleteInlineRange" command at a later date.
+	//
+	// A dry run only reads the keys it would delete, so it is flagged like a
+	// Scan. It doesn't take write latches, isn't proposed to Raft and isn't
+	// tracked as a write by the TxnCoordSender.
+	if drr.DryRun {
+		return isRead | isTxn | isRange | updatesReadTSCache | needsRefresh
+	}
 	if drr.Inline {
 		return isWrite | isRange | isAlone
 	}
@@ -72,6 +72,8 @@ func (ru ErrorDetail) GetInner() error {
 		return t.RangefeedRetry
 	case *ErrorDetail_IndeterminateCommit:
 		return t.IndeterminateCommit
+	case *ErrorDetail_TooManyIntents:
+		return t.TooManyIntents
 	default:
 		return nil
 	}
@@ -331,6 +333,8 @@ func (ru *ErrorDetail) SetInner(r error) bool {
 		union = &ErrorDetail_RangefeedRetry{t}
 	case *IndeterminateCommitError:
 		union = &ErrorDetail_IndeterminateCommit{t}
+	case *TooManyIntentsError:
+		union = &ErrorDetail_TooManyIntents{t}
 	default:
 		return false
 	}
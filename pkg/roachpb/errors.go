@@ -883,3 +883,23 @@ func (e *IndeterminateCommitError) message(pErr *Error) string {
 }
 
 var _ ErrorDetailInterface = &IndeterminateCommitError{}
+
+// NewTooManyIntentsError initializes a new TooManyIntentsError.
+func NewTooManyIntentsError(intentCount, maxIntents int64) *TooManyIntentsError {
+	return &TooManyIntentsError{IntentCount: intentCount, MaxIntents: maxIntents}
+}
+
+func (e *TooManyIntentsError) Error() string {
+	return e.message(nil)
+}
+
+func (e *TooManyIntentsError) message(pErr *Error) string {
+	s := fmt.Sprintf("write would leave %d intents on range, exceeding limit of %d",
+		e.IntentCount, e.MaxIntents)
+	if pErr.GetTxn() == nil {
+		return s
+	}
+	return fmt.Sprintf("txn %s %s", pErr.GetTxn(), s)
+}
+
+var _ ErrorDetailInterface = &TooManyIntentsError{}
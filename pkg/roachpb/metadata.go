@@ -236,6 +236,32 @@ func (r *RangeDescriptor) Validate() error {
 	return nil
 }
 
+// ValidateRangeDescriptor checks that a range descriptor is internally
+// consistent. In addition to the checks performed by Validate, it requires
+// that the descriptor spans a non-empty key range, has at least one voter,
+// and that no store holds more than one of its replicas. It is intended for
+// tools which construct or migrate descriptors.
+func ValidateRangeDescriptor(desc *RangeDescriptor) error {
+	if !desc.StartKey.Less(desc.EndKey) {
+		return errors.Errorf("StartKey %s must be less than EndKey %s", desc.StartKey, desc.EndKey)
+	}
+	if err := desc.Validate(); err != nil {
+		return err
+	}
+	if len(desc.Replicas().Voters()) == 0 {
+		return errors.Errorf("descriptor must contain at least one voter")
+	}
+	stores := map[StoreID]ReplicaID{}
+	for _, rep := range desc.Replicas().All() {
+		if other, ok := stores[rep.StoreID]; ok {
+			return errors.Errorf("store s%d holds both replica %d and replica %d",
+				rep.StoreID, other, rep.ReplicaID)
+		}
+		stores[rep.StoreID] = rep.ReplicaID
+	}
+	return nil
+}
+
 func (r *RangeDescriptor) String() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "r%d:", r.RangeID)
@@ -88,6 +88,72 @@ func TestRangeDescriptorMissingReplica(t *testing.T) {
 	}
 }
 
+func TestValidateRangeDescriptor(t *testing.T) {
+	makeDesc := func() RangeDescriptor {
+		return RangeDescriptor{
+			RangeID:  1,
+			StartKey: RKey("a"),
+			EndKey:   RKey("z"),
+			InternalReplicas: []ReplicaDescriptor{
+				{NodeID: 1, StoreID: 1, ReplicaID: 1},
+				{NodeID: 2, StoreID: 2, ReplicaID: 2},
+				{NodeID: 3, StoreID: 3, ReplicaID: 3, Type: ReplicaTypeLearner()},
+			},
+			NextReplicaID: 4,
+		}
+	}
+	valid := makeDesc()
+	if err := ValidateRangeDescriptor(&valid); err != nil {
+		t.Fatalf("unexpected error validating %s: %v", &valid, err)
+	}
+
+	testCases := []struct {
+		name   string
+		mutate func(*RangeDescriptor)
+		expErr string
+	}{
+		{
+			name:   "empty span",
+			mutate: func(d *RangeDescriptor) { d.EndKey = d.StartKey },
+			expErr: "must be less than EndKey",
+		},
+		{
+			name:   "inverted span",
+			mutate: func(d *RangeDescriptor) { d.StartKey, d.EndKey = d.EndKey, d.StartKey },
+			expErr: "must be less than EndKey",
+		},
+		{
+			name:   "duplicate replica ID",
+			mutate: func(d *RangeDescriptor) { d.InternalReplicas[1].ReplicaID = 1 },
+			expErr: "ReplicaID 1 was reused",
+		},
+		{
+			name:   "stale NextReplicaID",
+			mutate: func(d *RangeDescriptor) { d.NextReplicaID = 3 },
+			expErr: "ReplicaID 3 must be less than NextReplicaID 3",
+		},
+		{
+			name:   "no voters",
+			mutate: func(d *RangeDescriptor) { d.InternalReplicas = d.InternalReplicas[2:] },
+			expErr: "at least one voter",
+		},
+		{
+			name:   "store with two replicas",
+			mutate: func(d *RangeDescriptor) { d.InternalReplicas[2].StoreID = 1 },
+			expErr: "store s1 holds both replica 1 and replica 3",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			desc := makeDesc()
+			tc.mutate(&desc)
+			if err := ValidateRangeDescriptor(&desc); err == nil || !strings.Contains(err.Error(), tc.expErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.expErr, err)
+			}
+		})
+	}
+}
+
 // TestLocalityConversions verifies that setting the value from the CLI short
 // hand format works correctly.
 func TestLocalityConversions(t *testing.T) {
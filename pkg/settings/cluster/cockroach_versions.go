@@ -43,6 +43,10 @@ const (
 	VersionLearnerReplicas
 	VersionTopLevelForeignKeys
 	VersionAtomicChangeReplicasTrigger
+	VersionClearStatsEstimates
+	VersionAddSSTableProgressMarker
+	VersionAddSSTableFiles
+	VersionAddSSTableWriteAtRequestTimestamp
 
 	// Add new versions here (step one of two).
 
@@ -513,6 +517,35 @@ var versionsSingleton = keyedVersions([]keyedVersion{
 		Key:     VersionAtomicChangeReplicasTrigger,
 		Version: roachpb.Version{Major: 19, Minor: 1, Unstable: 8},
 	},
+	{
+		// VersionClearStatsEstimates enables RecomputeStats requests to clear
+		// the ContainsEstimates flag on all replicas via
+		// ReplicatedEvalResult.ClearStatsEstimates, which older replicas would
+		// ignore.
+		Key:     VersionClearStatsEstimates,
+		Version: roachpb.Version{Major: 19, Minor: 1, Unstable: 9},
+	},
+	{
+		// VersionAddSSTableProgressMarker enables the progress marker fields of
+		// AddSSTableRequest, which nodes running older versions ignore.
+		Key:     VersionAddSSTableProgressMarker,
+		Version: roachpb.Version{Major: 19, Minor: 1, Unstable: 10},
+	},
+	{
+		// VersionAddSSTableFiles enables the files field of AddSSTableRequest,
+		// whose files are carried to the replicas in
+		// ReplicatedEvalResult.AddSSTableFiles, which older replicas would
+		// ignore.
+		Key:     VersionAddSSTableFiles,
+		Version: roachpb.Version{Major: 19, Minor: 1, Unstable: 11},
+	},
+	{
+		// VersionAddSSTableWriteAtRequestTimestamp enables the
+		// write_at_request_timestamp field of AddSSTableRequest, which nodes
+		// running older versions ignore.
+		Key:     VersionAddSSTableWriteAtRequestTimestamp,
+		Version: roachpb.Version{Major: 19, Minor: 1, Unstable: 12},
+	},
 
 	// Add new versions here (step two of two).
 
@@ -20,11 +20,15 @@ func _() {
 	_ = x[VersionLearnerReplicas-9]
 	_ = x[VersionTopLevelForeignKeys-10]
 	_ = x[VersionAtomicChangeReplicasTrigger-11]
+	_ = x[VersionClearStatsEstimates-12]
+	_ = x[VersionAddSSTableProgressMarker-13]
+	_ = x[VersionAddSSTableFiles-14]
+	_ = x[VersionAddSSTableWriteAtRequestTimestamp-15]
 }
 
-const _VersionKey_name = "Version2_1VersionUnreplicatedRaftTruncatedStateVersionSideloadedStorageNoReplicaIDVersion19_1VersionStart19_2VersionQueryTxnTimestampVersionStickyBitVersionParallelCommitsVersionGenerationComparableVersionLearnerReplicasVersionTopLevelForeignKeysVersionAtomicChangeReplicasTrigger"
+const _VersionKey_name = "Version2_1VersionUnreplicatedRaftTruncatedStateVersionSideloadedStorageNoReplicaIDVersion19_1VersionStart19_2VersionQueryTxnTimestampVersionStickyBitVersionParallelCommitsVersionGenerationComparableVersionLearnerReplicasVersionTopLevelForeignKeysVersionAtomicChangeReplicasTriggerVersionClearStatsEstimatesVersionAddSSTableProgressMarkerVersionAddSSTableFilesVersionAddSSTableWriteAtRequestTimestamp"
 
-var _VersionKey_index = [...]uint16{0, 10, 47, 82, 93, 109, 133, 149, 171, 198, 220, 246, 280}
+var _VersionKey_index = [...]uint16{0, 10, 47, 82, 93, 109, 133, 149, 171, 198, 220, 246, 280, 306, 337, 359, 399}
 
 func (i VersionKey) String() string {
 	if i < 0 || i >= VersionKey(len(_VersionKey_index)-1) {
@@ -31,6 +31,7 @@ func init() {
 	// TODO(marc): we use a hook to avoid a dependency on the sql package. We
 	// should probably move keys/protos elsewhere.
 	config.ZoneConfigHook = ZoneConfigHook
+	config.ZoneSourceHook = ZoneSourceHook
 }
 
 var errNoZoneConfigApplies = errors.New("no zone config applies")
@@ -168,6 +169,20 @@ func ZoneConfigHook(
 	return zone, placeholder, true, nil
 }
 
+// ZoneSourceHook returns the ID of the object whose zone config applies to the
+// object with id, using the cached system config. This is the object that the
+// zone config returned by ZoneConfigHook was inherited from.
+func ZoneSourceHook(cfg *config.SystemConfig, id uint32) (uint32, error) {
+	getKey := func(key roachpb.Key) (*roachpb.Value, error) {
+		return cfg.GetValue(key), nil
+	}
+	zoneID, _, _, _, err := getZoneConfig(id, getKey, false /* getInheritedDefault */)
+	if err == errNoZoneConfigApplies {
+		return keys.RootNamespaceID, nil
+	}
+	return zoneID, err
+}
+
 // GetZoneConfigInTxn looks up the zone and subzone for the specified
 // object ID, index, and partition.
 func GetZoneConfigInTxn(
@@ -77,22 +77,38 @@ func rangeAddressing(b *client.Batch, desc *roachpb.RangeDescriptor, action meta
 		return errors.Errorf("meta1 addressing records cannot be split: %+v", desc)
 	}
 
-	// Note that both cases 2 and 3 are handled by keys.RangeMetaKey.
-	//
-	// 2. the case of the range ending with a meta2 prefix. This means
-	// the range is full of meta2. We must update the relevant meta1
-	// entry pointing to the end of this range.
-	//
-	// 3. the range ends with a normal user key, so we must update the
-	// relevant meta2 entry pointing to the end of this range.
-	action(b, keys.RangeMetaKey(desc.EndKey).AsRawKey(), desc)
+	meta1Key, meta2Key := rangeAddressingKeys(desc)
+	if meta2Key != nil {
+		action(b, meta2Key, desc)
+	}
+	if meta1Key != nil {
+		action(b, meta1Key, desc)
+	}
+	return nil
+}
 
+// rangeAddressingKeys returns the meta1 and meta2 keys under which the
+// addressing records for the range specified by desc are stored, following
+// the rules described on rangeAddressing. A nil key indicates that the range
+// has no addressing record at that level. desc must not start or end in meta1.
+func rangeAddressingKeys(desc *roachpb.RangeDescriptor) (meta1Key, meta2Key roachpb.Key) {
+	// Note that both cases 2 and 3 are handled by keys.RangeMetaKey.
+	metaKey := keys.RangeMetaKey(desc.EndKey).AsRawKey()
+	if bytes.HasPrefix(metaKey, keys.Meta1Prefix) {
+		// 2. the case of the range ending with a meta2 prefix. This means
+		// the range is full of meta2. The relevant meta1 entry points to
+		// the end of this range.
+		return metaKey, nil
+	}
+	// 3. the range ends with a normal user key, so the relevant meta2
+	// entry points to the end of this range.
+	meta2Key = metaKey
 	if bytes.Compare(desc.StartKey, keys.MetaMax) < 0 &&
 		bytes.Compare(desc.EndKey, keys.MetaMax) >= 0 {
-		// 3a. the range spans meta2 and user keys, update the meta1
+		// 3a. the range spans meta2 and user keys, so there is also a meta1
 		// entry for KeyMax. We do this to prevent the 3 levels of
 		// descriptor indirection described in #18998.
-		action(b, keys.Meta1KeyMax, desc)
+		meta1Key = keys.Meta1KeyMax
 	}
-	return nil
+	return meta1Key, meta2Key
 }
@@ -257,3 +257,40 @@ func TestUpdateRangeAddressingSplitMeta1(t *testing.T) {
 		t.Error("expected failure trying to update addressing records for meta1 split")
 	}
 }
+
+// TestReplicaMetaDescriptorKeys verifies the meta1 and meta2 keys reported
+// for ranges ending in meta2 and in the user keyspace.
+func TestReplicaMetaDescriptorKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	metaM := keys.RangeMetaKey(roachpb.RKey("m"))
+	metaZ := keys.RangeMetaKey(roachpb.RKey("z"))
+	testCases := []struct {
+		start, end         roachpb.RKey
+		expMeta1, expMeta2 roachpb.Key
+	}{
+		// Ranges ending in meta2 are addressed by meta1 only.
+		{roachpb.RKeyMin, metaM, meta1Key(roachpb.RKey("m")), nil},
+		{metaM, metaZ, meta1Key(roachpb.RKey("z")), nil},
+		// Ranges spanning meta2 and user keys are also addressed by
+		// meta1(KeyMax).
+		{roachpb.RKeyMin, roachpb.RKey("a"), keys.Meta1KeyMax, meta2Key(roachpb.RKey("a"))},
+		{metaM, roachpb.RKey("a"), keys.Meta1KeyMax, meta2Key(roachpb.RKey("a"))},
+		{roachpb.RKeyMin, roachpb.RKeyMax, keys.Meta1KeyMax, meta2Key(roachpb.RKeyMax)},
+		// Ranges in the user keyspace are addressed by meta2 only.
+		{roachpb.RKey("a"), roachpb.RKey("z"), nil, meta2Key(roachpb.RKey("z"))},
+		{roachpb.RKey("a"), roachpb.RKeyMax, nil, meta2Key(roachpb.RKeyMax)},
+	}
+	for i, test := range testCases {
+		var repl Replica
+		repl.mu.state.Desc = &roachpb.RangeDescriptor{
+			RangeID: roachpb.RangeID(i + 1), StartKey: test.start, EndKey: test.end,
+		}
+		meta1, meta2 := repl.MetaDescriptorKeys()
+		if !meta1.Equal(test.expMeta1) || (meta1 == nil) != (test.expMeta1 == nil) {
+			t.Errorf("%d: expected meta1 key %s, got %s", i, test.expMeta1, meta1)
+		}
+		if !meta2.Equal(test.expMeta2) || (meta2 == nil) != (test.expMeta2 == nil) {
+			t.Errorf("%d: expected meta2 key %s, got %s", i, test.expMeta2, meta2)
+		}
+	}
+}
@@ -12,26 +12,53 @@ package batcheval
 
 import (
 	"context"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/spanset"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagepb"
 	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/pkg/errors"
 )
 
+// AddSSTableSkipChecksumVerification allows AddSSTable requests to opt out
+// of the verification of their key/value checksums. It is a safeguard which
+// lets operators disable the opt-out should a producer turn out to be less
+// trustworthy than assumed.
+var AddSSTableSkipChecksumVerification = settings.RegisterBoolSetting(
+	"kv.bulk_io_write.addsstable_skip_checksum_verification.enabled",
+	"if set, AddSSTable requests may skip verifying the checksums of the values they ingest",
+	false,
+)
+
 func init() {
-	RegisterCommand(roachpb.AddSSTable, DefaultDeclareKeys, EvalAddSSTable)
+	RegisterCommand(roachpb.AddSSTable, declareKeysAddSSTable, EvalAddSSTable)
+}
+
+func declareKeysAddSSTable(
+	desc *roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *spanset.SpanSet,
+) {
+	DefaultDeclareKeys(desc, header, req, spans)
+	args := req.(*roachpb.AddSSTableRequest)
+	if args.ProgressMarkerKey != nil {
+		spans.Add(spanset.SpanReadWrite, roachpb.Span{Key: args.ProgressMarkerKey})
+	}
 }
 
 // EvalAddSSTable evaluates an AddSSTable command.
 func EvalAddSSTable(
-	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, _ roachpb.Response,
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
 ) (result.Result, error) {
 	args := cArgs.Args.(*roachpb.AddSSTableRequest)
 	h := cArgs.Header
@@ -43,55 +70,109 @@ func EvalAddSSTable(
 	// defer tracing.FinishSpan(span)
 	log.Eventf(ctx, "evaluating AddSSTable [%s,%s)", mvccStartKey.Key, mvccEndKey.Key)
 
-	// IMPORT INTO should not proceed if any KVs from the SST shadow existing data
-	// entries - #38044.
-	if args.DisallowShadowing {
-		if err := checkForKeyCollisions(ctx, batch, mvccStartKey, mvccEndKey, args.Data); err != nil {
-			return result.Result{}, errors.Wrap(err, "checking for key collisions")
+	if args.ProgressMarkerKey != nil {
+		if !cArgs.EvalCtx.ClusterSettings().Version.IsActive(cluster.VersionAddSSTableProgressMarker) {
+			return result.Result{}, errors.New(
+				"AddSSTable progress markers require all nodes to be upgraded")
+		}
+		if !cArgs.EvalCtx.ContainsKey(args.ProgressMarkerKey) {
+			return result.Result{}, errors.Errorf("progress marker key %s outside of range %s",
+				args.ProgressMarkerKey, cArgs.EvalCtx.Desc())
 		}
 	}
 
-	// Verify that the keys in the sstable are within the range specified by the
-	// request header, and if the request did not include pre-computed stats,
-	// compute the expected MVCC stats delta of ingesting the SST.
-	dataIter, err := engine.NewMemSSTIterator(args.Data, true)
-	if err != nil {
-		return result.Result{}, err
+	if args.WriteAtRequestTimestamp {
+		if !cArgs.EvalCtx.ClusterSettings().Version.IsActive(cluster.VersionAddSSTableWriteAtRequestTimestamp) {
+			return result.Result{}, errors.New(
+				"AddSSTable requests writing at the request timestamp require all nodes to be upgraded")
+		}
+		// The request timestamp is pushed above any conflicting reads and the
+		// closed timestamp before evaluation. A transaction's timestamp can't be
+		// pushed that way without it having to refresh, which the SSTable
+		// doesn't support.
+		if h.Txn != nil {
+			return result.Result{}, errors.New(
+				"AddSSTable requests writing at the request timestamp cannot be transactional")
+		}
 	}
-	defer dataIter.Close()
 
-	// Check that the first key is in the expected range.
-	dataIter.Seek(engine.MVCCKey{Key: keys.MinKey})
-	ok, err := dataIter.Valid()
-	if err != nil {
-		return result.Result{}, err
-	} else if ok {
-		if unsafeKey := dataIter.UnsafeKey(); unsafeKey.Less(mvccStartKey) {
-			return result.Result{}, errors.Errorf("first key %s not in request range [%s,%s)",
-				unsafeKey.Key, mvccStartKey.Key, mvccEndKey.Key)
-		}
+	// Trusted producers may ask for the per-entry checksums to not be verified.
+	// This only skips the (expensive) value checksums: the checksums of the
+	// SSTables' blocks are still verified below, so SSTables which were
+	// corrupted after being produced are rejected before they are ingested.
+	verify := !args.DisableChecksumVerification ||
+		!AddSSTableSkipChecksumVerification.Get(&cArgs.EvalCtx.ClusterSettings().SV)
+	if !verify {
+		log.VEventf(ctx, 2, "skipping checksum verification for SSTable [%s,%s)",
+			mvccStartKey.Key, mvccEndKey.Key)
 	}
 
-	var stats enginepb.MVCCStats
-	if args.MVCCStats != nil {
-		stats = *args.MVCCStats
+	// Each file is evaluated on its own and carried to the replicas separately,
+	// which ingest them one after the other below Raft.
+	files := args.Files
+	if len(files) > 0 {
+		if !cArgs.EvalCtx.ClusterSettings().Version.IsActive(cluster.VersionAddSSTableFiles) {
+			return result.Result{}, errors.New(
+				"AddSSTable files require all nodes to be upgraded")
+		}
+		if len(args.Data) > 0 {
+			return result.Result{}, errors.New("cannot ingest both data and files")
+		}
+		reqSpan := args.Span()
+		for i := range files {
+			if len(files[i].Span.EndKey) == 0 || !reqSpan.Contains(files[i].Span) {
+				return result.Result{}, errors.Errorf("file span %s not in request range %s",
+					files[i].Span, reqSpan)
+			}
+			if i > 0 && files[i].Span.Key.Compare(files[i-1].Span.EndKey) < 0 {
+				return result.Result{}, errors.Errorf("file span %s overlaps preceding file span %s",
+					files[i].Span, files[i-1].Span)
+			}
+		}
 	} else {
-		log.VEventf(ctx, 2, "computing MVCCStats for SSTable [%s,%s)", mvccStartKey.Key, mvccEndKey.Key)
+		files = []roachpb.AddSSTableRequest_File{{Span: args.Span(), Data: args.Data}}
+	}
 
-		computed, err := engine.ComputeStatsGo(dataIter, mvccStartKey, mvccEndKey, h.Timestamp.WallTime)
+	// Rewriting the timestamps of the keys invalidates any stats computed by the
+	// caller, so they are recomputed from the rewritten SSTables below.
+	computeStats := args.MVCCStats == nil || args.WriteAtRequestTimestamp
+	var collisions *engine.KeyCollisions
+	if args.DisallowShadowing && args.ReportShadowedKeys {
+		collisions = &engine.KeyCollisions{MaxSample: keyCollisionSampleSize}
+	}
+	var hasher hash.Hash
+	if args.ComputeContentHash {
+		hasher = sha512.New()
+	}
+	var stats enginepb.MVCCStats
+	ssts := make([]storagepb.ReplicatedEvalResult_AddSSTable, len(files))
+	for i := range files {
+		data, fileStats, err := evalSSTable(
+			ctx, batch, args, h.Timestamp, files[i], computeStats, collisions, hasher, verify,
+		)
 		if err != nil {
-			return result.Result{}, errors.Wrap(err, "computing SSTable MVCC stats")
+			return result.Result{}, err
 		}
-		stats = computed
+		stats.Add(fileStats)
+		ssts[i] = storagepb.ReplicatedEvalResult_AddSSTable{Data: data, CRC32: util.CRC32(data)}
 	}
-
-	dataIter.Seek(mvccEndKey)
-	ok, err = dataIter.Valid()
-	if err != nil {
-		return result.Result{}, err
-	} else if ok {
-		return result.Result{}, errors.Errorf("last key %s not in request range [%s,%s)",
-			dataIter.UnsafeKey(), mvccStartKey.Key, mvccEndKey.Key)
+	if collisions != nil {
+		reply := resp.(*roachpb.AddSSTableResponse)
+		reply.LiveKeyCollisions = collisions.Live
+		reply.TombstoneKeyCollisions = collisions.Tombstone
+		reply.LiveKeyCollisionSample = collisions.LiveSample
+		reply.TombstoneKeyCollisionSample = collisions.TombstoneSample
+		if reply.LiveKeyCollisions > 0 || reply.TombstoneKeyCollisions > 0 {
+			log.VEventf(ctx, 2, "not ingesting SSTable: %d live and %d tombstone key collisions",
+				reply.LiveKeyCollisions, reply.TombstoneKeyCollisions)
+			return result.Result{}, nil
+		}
+	}
+	if !computeStats {
+		stats = *args.MVCCStats
+	}
+	if hasher != nil {
+		resp.(*roachpb.AddSSTableResponse).ContentHash = hasher.Sum(nil)
 	}
 
 	// The above MVCCStats represents what is in this new SST.
@@ -138,22 +219,289 @@ func EvalAddSSTable(
 	stats.ContainsEstimates = true
 	ms.Add(stats)
 
-	return result.Result{
-		Replicated: storagepb.ReplicatedEvalResult{
-			AddSSTable: &storagepb.ReplicatedEvalResult_AddSSTable{
-				Data:  args.Data,
-				CRC32: util.CRC32(args.Data),
-			},
-		},
-	}, nil
+	// The progress marker is written to the batch, which is applied in the
+	// same Raft command as the ingestion of the SSTable. Like any other
+	// non-transactional write, it is written at the batch timestamp, which was
+	// forwarded past the timestamp cache and the closed timestamp for the
+	// marker key (see Replica.applyTimestampCache).
+	if args.ProgressMarkerKey != nil {
+		var value roachpb.Value
+		if args.ProgressMarkerValue != nil {
+			value = *args.ProgressMarkerValue
+		}
+		if err := engine.MVCCPut(
+			ctx, batch, ms, args.ProgressMarkerKey, h.Timestamp, value, nil, /* txn */
+		); err != nil {
+			return result.Result{}, errors.Wrap(err, "writing progress marker")
+		}
+	}
+
+	var res result.Result
+	if len(args.Files) > 0 {
+		res.Replicated.AddSSTableFiles = ssts
+	} else {
+		res.Replicated.AddSSTable = &ssts[0]
+	}
+	return res, nil
+}
+
+// evalSSTable evaluates a single SSTable of an AddSSTable request and returns
+// the SSTable to ingest along with its MVCCStats, which are only computed if
+// computeStats is set. The keys of the SSTable must be contained in the span
+// of the file. If collisions is non-nil, keys colliding with existing data are
+// accumulated in it rather than returned as an error. If hasher is non-nil,
+// the contents of the SSTable are added to it.
+func evalSSTable(
+	ctx context.Context,
+	batch engine.ReadWriter,
+	args *roachpb.AddSSTableRequest,
+	ts hlc.Timestamp,
+	file roachpb.AddSSTableRequest_File,
+	computeStats bool,
+	collisions *engine.KeyCollisions,
+	hasher hash.Hash,
+	verify bool,
+) ([]byte, enginepb.MVCCStats, error) {
+	data := file.Data
+	mvccStartKey, mvccEndKey := engine.MVCCKey{Key: file.Span.Key}, engine.MVCCKey{Key: file.Span.EndKey}
+	if args.WriteAtRequestTimestamp {
+		var err error
+		data, err = rewriteSSTTimestamps(batch, data, mvccStartKey, mvccEndKey, ts, verify)
+		if err != nil {
+			return nil, enginepb.MVCCStats{}, err
+		}
+	}
+
+	// IMPORT INTO should not proceed if any KVs from the SST shadow existing data
+	// entries - #38044.
+	if args.DisallowShadowing {
+		if err := checkForKeyCollisions(
+			ctx, batch, mvccStartKey, mvccEndKey, data, collisions,
+		); err != nil {
+			return nil, enginepb.MVCCStats{}, errors.Wrap(err, "checking for key collisions")
+		}
+	}
+
+	// Verify that the keys in the sstable are within the span of the file, and
+	// if the request did not include pre-computed stats, compute the expected
+	// MVCC stats delta of ingesting the SST.
+	dataIter, err := newSSTIterator(data, verify)
+	if err != nil {
+		return nil, enginepb.MVCCStats{}, err
+	}
+	defer dataIter.Close()
+
+	// Check that the first key is in the expected range.
+	dataIter.Seek(engine.MVCCKey{Key: keys.MinKey})
+	ok, err := dataIter.Valid()
+	if err != nil {
+		return nil, enginepb.MVCCStats{}, err
+	} else if ok {
+		if unsafeKey := dataIter.UnsafeKey(); unsafeKey.Less(mvccStartKey) {
+			return nil, enginepb.MVCCStats{}, errors.Errorf("first key %s not in %s [%s,%s)",
+				unsafeKey.Key, spanDescription(args), mvccStartKey.Key, mvccEndKey.Key)
+		}
+	}
+
+	var stats enginepb.MVCCStats
+	if computeStats {
+		log.VEventf(ctx, 2, "computing MVCCStats for SSTable [%s,%s)", mvccStartKey.Key, mvccEndKey.Key)
+
+		stats, err = engine.ComputeStatsGo(dataIter, mvccStartKey, mvccEndKey, ts.WallTime)
+		if err != nil {
+			return nil, enginepb.MVCCStats{}, errors.Wrap(err, "computing SSTable MVCC stats")
+		}
+	} else if !verify {
+		// Without the value checksums, reading all of the SSTable's blocks is
+		// what verifies it, which computing the stats would otherwise have done.
+		for dataIter.Seek(mvccStartKey); ; dataIter.Next() {
+			if ok, err := dataIter.Valid(); err != nil {
+				return nil, enginepb.MVCCStats{}, errors.Wrap(err, "verifying SSTable")
+			} else if !ok {
+				break
+			}
+		}
+	}
+
+	dataIter.Seek(mvccEndKey)
+	ok, err = dataIter.Valid()
+	if err != nil {
+		return nil, enginepb.MVCCStats{}, err
+	} else if ok {
+		return nil, enginepb.MVCCStats{}, errors.Errorf("last key %s not in %s [%s,%s)",
+			dataIter.UnsafeKey(), spanDescription(args), mvccStartKey.Key, mvccEndKey.Key)
+	}
+
+	if hasher != nil {
+		if err := hashSSTContents(hasher, dataIter, mvccStartKey, mvccEndKey); err != nil {
+			return nil, enginepb.MVCCStats{}, errors.Wrap(err, "computing SSTable content hash")
+		}
+	}
+	return data, stats, nil
+}
+
+// newSSTIterator returns an iterator over the given SSTable which verifies the
+// checksums of its values if verify is set, and those of its blocks otherwise.
+func newSSTIterator(data []byte, verify bool) (engine.SimpleIterator, error) {
+	if verify {
+		return engine.NewMemSSTIterator(data, true /* verify */)
+	}
+	return engine.NewMemSSTIteratorVerifyingBlocks(data)
+}
+
+// spanDescription describes the span an SSTable of the given request must be
+// contained in for use in error messages.
+func spanDescription(args *roachpb.AddSSTableRequest) string {
+	if len(args.Files) > 0 {
+		return "file span"
+	}
+	return "request range"
+}
+
+// rewriteSSTTimestamps returns a copy of the given SSTable in which the
+// timestamps of all keys in [start, end) are replaced with ts. Since all
+// versions of a key collapse into one, only the newest version of each key is
+// retained, preserving whether it is live or deleted. Inline values and
+// intents in the SSTable can't be rewritten and result in an error, as does an
+// intent on any of the keys in the existing data.
+func rewriteSSTTimestamps(
+	reader engine.Reader, data []byte, start, end engine.MVCCKey, ts hlc.Timestamp, verify bool,
+) ([]byte, error) {
+	sstIter, err := newSSTIterator(data, verify)
+	if err != nil {
+		return nil, err
+	}
+	defer sstIter.Close()
+	existingIter := reader.NewIterator(engine.IterOptions{UpperBound: end.Key})
+	defer existingIter.Close()
+
+	sst, err := engine.MakeRocksDBSstFileWriter()
+	if err != nil {
+		return nil, err
+	}
+	defer sst.Close()
+
+	var intents []roachpb.Intent
+	var prevKey roachpb.Key
+	for sstIter.Seek(start); ; sstIter.Next() {
+		if ok, err := sstIter.Valid(); err != nil {
+			return nil, err
+		} else if !ok || !sstIter.UnsafeKey().Less(end) {
+			break
+		}
+		sstKey := sstIter.UnsafeKey()
+		if !sstKey.IsValue() {
+			return nil, errors.Errorf("cannot rewrite timestamp of inline value or intent at key %s",
+				sstKey.Key)
+		}
+		if prevKey != nil && prevKey.Equal(sstKey.Key) {
+			// Only the newest version of each key is retained.
+			continue
+		}
+		prevKey = append(prevKey[:0], sstKey.Key...)
+
+		existingIter.Seek(engine.MakeMVCCMetadataKey(sstKey.Key))
+		if ok, err := existingIter.Valid(); err != nil {
+			return nil, err
+		} else if ok && existingIter.UnsafeKey().Key.Equal(sstKey.Key) && !existingIter.UnsafeKey().IsValue() {
+			var meta enginepb.MVCCMetadata
+			if err := protoutil.Unmarshal(existingIter.UnsafeValue(), &meta); err != nil {
+				return nil, errors.Wrap(err, "failed to parse meta")
+			}
+			if meta.Txn != nil {
+				intents = append(intents, roachpb.Intent{
+					Span:   roachpb.Span{Key: append(roachpb.Key(nil), prevKey...)},
+					Status: roachpb.PENDING,
+					Txn:    *meta.Txn,
+				})
+			}
+		}
+		if len(intents) > 0 {
+			// Keep collecting intents, but don't bother building the SSTable.
+			continue
+		}
+		if err := sst.Put(engine.MVCCKey{Key: prevKey, Timestamp: ts}, sstIter.UnsafeValue()); err != nil {
+			return nil, err
+		}
+	}
+	if len(intents) > 0 {
+		return nil, &roachpb.WriteIntentError{Intents: intents}
+	}
+	return sst.Finish()
 }
 
+// hashSSTContents adds the key/value pairs in [start, end) to the given hash.
+// Each key and value is prefixed with its length, so the hash depends only on
+// the logical contents of the SSTable and not on its physical layout.
+func hashSSTContents(hasher hash.Hash, iter engine.SimpleIterator, start, end engine.MVCCKey) error {
+	var intBuf [8]byte
+	write := func(b []byte) error {
+		binary.LittleEndian.PutUint64(intBuf[:], uint64(len(b)))
+		if _, err := hasher.Write(intBuf[:]); err != nil {
+			return err
+		}
+		_, err := hasher.Write(b)
+		return err
+	}
+	for iter.Seek(start); ; iter.Next() {
+		if ok, err := iter.Valid(); err != nil {
+			return err
+		} else if !ok || !iter.UnsafeKey().Less(end) {
+			break
+		}
+		if err := write(engine.EncodeKey(iter.UnsafeKey())); err != nil {
+			return err
+		}
+		if err := write(iter.UnsafeValue()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySSTableOrdering checks that the keys in the SSTable are strictly
+// increasing and that all of them fall within span, returning an error
+// describing the first violation. It is much cheaper than evaluating an
+// AddSSTable request and can be used to reject malformed SSTables early.
+func VerifySSTableOrdering(data []byte, span roachpb.Span) error {
+	iter, err := engine.NewMemSSTIterator(data, false /* verify */)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	var prev engine.MVCCKey
+	first := true
+	for iter.Seek(engine.MVCCKey{Key: keys.MinKey}); ; iter.Next() {
+		if ok, err := iter.Valid(); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		key := iter.UnsafeKey()
+		if !first && !prev.Less(key) {
+			return errors.Errorf("key %s is not ordered after preceding key %s", key, prev)
+		}
+		if !span.ContainsKey(key.Key) {
+			return errors.Errorf("key %s not in span %s", key, span)
+		}
+		prev.Key = append(prev.Key[:0], key.Key...)
+		prev.Timestamp = key.Timestamp
+		first = false
+	}
+	return nil
+}
+
+// checkForKeyCollisions checks whether any of the keys in the SST collide with
+// existing data. If collisions is nil, an error is returned on the first
+// colliding key. Otherwise, the colliding keys are accumulated in collisions.
 func checkForKeyCollisions(
 	ctx context.Context,
 	batch engine.ReadWriter,
 	mvccStartKey engine.MVCCKey,
 	mvccEndKey engine.MVCCKey,
 	data []byte,
+	collisions *engine.KeyCollisions,
 ) error {
 	// We could get a spansetBatch so fetch the underlying rocksDBBatchEngine as
 	// we need access to the underlying C.DBIterator later, and the
@@ -190,6 +538,10 @@ func checkForKeyCollisions(
 		return errors.Wrap(err, "checking for key collisions")
 	}
 
-	checkErr := engine.CheckForKeyCollisions(existingDataIter, sstIterator)
+	checkErr := engine.CheckForKeyCollisions(existingDataIter, sstIterator, collisions)
 	return checkErr
 }
+
+// keyCollisionSampleSize is the maximum number of colliding keys of each kind
+// returned in an AddSSTableResponse.
+const keyCollisionSampleSize = 10
@@ -0,0 +1,132 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package batcheval
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestEvalAddSSTableFiles verifies that the files of an AddSSTable request are
+// carried to the replicas individually rather than combined into a single
+// SSTable, that their stats add up to those of a single SSTable with the same
+// contents, and that they are rejected until all nodes have been upgraded.
+func TestEvalAddSSTableFiles(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+
+	makeSST := func(keys ...string) []byte {
+		t.Helper()
+		sst, err := engine.MakeRocksDBSstFileWriter()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		defer sst.Close()
+		for _, k := range keys {
+			key := engine.MVCCKey{Key: roachpb.Key(k), Timestamp: hlc.Timestamp{WallTime: 2}}
+			if err := sst.Put(key, roachpb.MakeValueFromString(k).RawBytes); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		data, err := sst.Finish()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return data
+	}
+	mkFile := func(start, end, key string) roachpb.AddSSTableRequest_File {
+		return roachpb.AddSSTableRequest_File{
+			Span: roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)},
+			Data: makeSST(key),
+		}
+	}
+	files := []roachpb.AddSSTableRequest_File{
+		mkFile("ba", "bb", "ba1"),
+		mkFile("bb", "bc", "bb1"),
+		mkFile("bc", "bd", "bc1"),
+	}
+
+	testutils.RunTrueAndFalse(t, "versionActive", func(t *testing.T, versionActive bool) {
+		v := cluster.VersionByKey(cluster.VersionAddSSTableFiles)
+		if !versionActive {
+			v = cluster.VersionByKey(cluster.VersionAddSSTableFiles - 1)
+		}
+		evalCtx := &mockEvalCtx{clusterSettings: cluster.MakeTestingClusterSettingsWithVersion(v, v)}
+		eval := func(args *roachpb.AddSSTableRequest) (enginepb.MVCCStats, *roachpb.AddSSTableResponse, error) {
+			args.RequestHeader = roachpb.RequestHeader{Key: roachpb.Key("b"), EndKey: roachpb.Key("c")}
+			cArgs := CommandArgs{
+				EvalCtx: evalCtx,
+				Header:  roachpb.Header{Timestamp: hlc.Timestamp{WallTime: 7}},
+				Args:    args,
+				Stats:   &enginepb.MVCCStats{},
+			}
+			resp := &roachpb.AddSSTableResponse{}
+			res, err := EvalAddSSTable(ctx, e, cArgs, resp)
+			if err != nil {
+				return enginepb.MVCCStats{}, nil, err
+			}
+			if len(args.Files) == 0 {
+				return *cArgs.Stats, resp, nil
+			}
+			if res.Replicated.AddSSTable != nil {
+				t.Fatalf("expected files not to be combined, got %+v", res.Replicated.AddSSTable)
+			}
+			if len(res.Replicated.AddSSTableFiles) != len(args.Files) {
+				t.Fatalf("expected %d files, got %d", len(args.Files), len(res.Replicated.AddSSTableFiles))
+			}
+			for i, sst := range res.Replicated.AddSSTableFiles {
+				if !bytes.Equal(sst.Data, args.Files[i].Data) {
+					t.Fatalf("expected file %d to be ingested as is", i)
+				}
+				if sst.CRC32 != util.CRC32(sst.Data) {
+					t.Fatalf("expected checksum %x for file %d, got %x", util.CRC32(sst.Data), i, sst.CRC32)
+				}
+			}
+			return *cArgs.Stats, resp, nil
+		}
+
+		filesStats, filesResp, err := eval(&roachpb.AddSSTableRequest{Files: files, ComputeContentHash: true})
+		if !versionActive {
+			if !testutils.IsError(err, "require all nodes to be upgraded") {
+				t.Fatalf("expected files to be rejected, got %+v", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		stats, resp, err := eval(&roachpb.AddSSTableRequest{
+			Data: makeSST("ba1", "bb1", "bc1"), ComputeContentHash: true,
+		})
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if filesStats != stats {
+			t.Fatalf("expected stats %+v, got %+v", stats, filesStats)
+		}
+		if !bytes.Equal(filesResp.ContentHash, resp.ContentHash) {
+			t.Fatalf("expected content hash %x, got %x", resp.ContentHash, filesResp.ContentHash)
+		}
+	})
+}
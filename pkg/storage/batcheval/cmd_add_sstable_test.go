@@ -13,25 +13,36 @@ package batcheval_test
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval"
+	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/golang/leveldb/db"
+	"github.com/golang/leveldb/table"
 	"github.com/kr/pretty"
 )
 
@@ -228,6 +239,231 @@ func runTestDBAddSSTable(ctx context.Context, t *testing.T, db *client.DB, store
 	}
 }
 
+// TestDBAddSSTableProgressMarker verifies that the progress marker of an
+// AddSSTable request is part of the same Raft command as the ingestion of the
+// SSTable: when the command fails to apply, neither the SSTable nor the marker
+// is written, and when it applies, both are.
+func TestDBAddSSTableProgressMarker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// While set, the apply filter rejects AddSSTable commands, so that their
+	// application fails as a whole.
+	var rejectApply int32
+	s, _, db := serverutils.StartServer(t, base.TestServerArgs{
+		Insecure: true,
+		Knobs: base.TestingKnobs{
+			Store: &storage.StoreTestingKnobs{
+				TestingApplyFilter: func(args storagebase.ApplyFilterArgs) (int, *roachpb.Error) {
+					if args.AddSSTable != nil && atomic.LoadInt32(&rejectApply) == 1 {
+						return 0, roachpb.NewErrorf("rejecting application of AddSSTable")
+					}
+					return 0, nil
+				},
+			},
+		},
+	})
+	ctx := context.Background()
+	defer s.Stopper().Stop(ctx)
+
+	key := engine.MVCCKey{Key: []byte("bb"), Timestamp: hlc.Timestamp{WallTime: 2}}
+	data, err := singleKVSSTable(key, roachpb.MakeValueFromString("1").RawBytes)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	markerKey := roachpb.Key("progress")
+	addSSTable := func(markerKey roachpb.Key) error {
+		marker := roachpb.MakeValueFromString("done")
+		var b client.Batch
+		b.AddRawRequest(&roachpb.AddSSTableRequest{
+			RequestHeader:       roachpb.RequestHeader{Key: roachpb.Key("b"), EndKey: roachpb.Key("c")},
+			Data:                data,
+			ProgressMarkerKey:   markerKey,
+			ProgressMarkerValue: &marker,
+		})
+		return db.Run(ctx, &b)
+	}
+	checkPresent := func(exp bool) {
+		t.Helper()
+		for _, k := range []roachpb.Key{key.Key, markerKey} {
+			if r, err := db.Get(ctx, k); err != nil {
+				t.Fatalf("%+v", err)
+			} else if r.Exists() != exp {
+				t.Fatalf("expected key %s present=%t, found %v", k, exp, r.Value)
+			}
+		}
+	}
+
+	atomic.StoreInt32(&rejectApply, 1)
+	if err := addSSTable(markerKey); !testutils.IsError(err, "rejecting application of AddSSTable") {
+		t.Fatalf("expected rejected application, got %+v", err)
+	}
+	checkPresent(false)
+
+	atomic.StoreInt32(&rejectApply, 0)
+	if err := addSSTable(markerKey); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	checkPresent(true)
+	if r, err := db.Get(ctx, markerKey); err != nil {
+		t.Fatalf("%+v", err)
+	} else if v, err := r.Value.GetBytes(); err != nil {
+		t.Fatalf("%+v", err)
+	} else if string(v) != "done" {
+		t.Fatalf("expected progress marker %q, got %q", "done", v)
+	}
+
+	// A progress marker outside of the range is rejected.
+	if err := addSSTable(keys.SystemConfigSpan.Key); !testutils.IsError(err, "outside of range") {
+		t.Fatalf("expected error for progress marker outside of range, got %+v", err)
+	}
+}
+
+// TestDBAddSSTableDisableChecksumVerification verifies that AddSSTable only
+// skips verifying value checksums when allowed by the cluster setting, and
+// that it still rejects SSTables whose blocks are corrupt when it does.
+func TestDBAddSSTableDisableChecksumVerification(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, db := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	ctx := context.Background()
+	defer s.Stopper().Stop(ctx)
+
+	key := engine.MVCCKey{Key: []byte("bb"), Timestamp: hlc.Timestamp{WallTime: 1}}
+	value := roachpb.MakeValueFromString("1")
+	value.InitChecksum([]byte("foo"))
+	data, err := singleKVSSTable(key, value.RawBytes)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	addSSTable := func(data []byte) error {
+		var b client.Batch
+		b.AddRawRequest(&roachpb.AddSSTableRequest{
+			RequestHeader:               roachpb.RequestHeader{Key: roachpb.Key("b"), EndKey: roachpb.Key("c")},
+			Data:                        data,
+			DisableChecksumVerification: true,
+		})
+		return db.Run(ctx, &b)
+	}
+
+	// The request can't opt out of verification unless the setting allows it.
+	if err := addSSTable(data); !testutils.IsError(err, "invalid checksum") {
+		t.Fatalf("expected 'invalid checksum' error got: %+v", err)
+	}
+
+	batcheval.AddSSTableSkipChecksumVerification.Override(&s.ClusterSettings().SV, true)
+	if err := addSSTable(data); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// The corrupt value is ingested, but reading it fails.
+	if _, err := db.Get(ctx, key.Key); !testutils.IsError(err, "invalid checksum") {
+		t.Fatalf("expected 'invalid checksum' error got: %+v", err)
+	}
+	// An SSTable which can't be decoded is still rejected.
+	if err := addSSTable(data[:len(data)/2]); err == nil {
+		t.Fatal("expected error ingesting truncated SSTable")
+	}
+	// So is an SSTable whose blocks were corrupted, even if the values still
+	// decode and their checksums aren't verified.
+	corrupt := append([]byte(nil), data...)
+	valueIdx := bytes.LastIndex(corrupt, value.RawBytes)
+	if valueIdx < 0 {
+		t.Fatal("value not found in SSTable")
+	}
+	corrupt[valueIdx+len(value.RawBytes)-1] ^= 0xff
+	if err := addSSTable(corrupt); !testutils.IsError(err, "checksum mismatch") {
+		t.Fatalf("expected 'checksum mismatch' error got: %+v", err)
+	}
+}
+
+// TestDBAddSSTables verifies that multiple SSTables can be ingested by a
+// single AddSSTable command, and that the spans of the files are validated.
+func TestDBAddSSTables(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var applied int32
+	s, _, db := serverutils.StartServer(t, base.TestServerArgs{
+		Insecure: true,
+		Knobs: base.TestingKnobs{
+			Store: &storage.StoreTestingKnobs{
+				TestingApplyFilter: func(args storagebase.ApplyFilterArgs) (int, *roachpb.Error) {
+					if len(args.AddSSTableFiles) > 0 {
+						atomic.AddInt32(&applied, 1)
+					}
+					return 0, nil
+				},
+			},
+		},
+	})
+	ctx := context.Background()
+	defer s.Stopper().Stop(ctx)
+
+	mkFile := func(start, end, key string) roachpb.AddSSTableRequest_File {
+		t.Helper()
+		data, err := singleKVSSTable(
+			engine.MVCCKey{Key: roachpb.Key(key), Timestamp: hlc.Timestamp{WallTime: 2}},
+			roachpb.MakeValueFromString(key).RawBytes,
+		)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return roachpb.AddSSTableRequest_File{
+			Span: roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)},
+			Data: data,
+		}
+	}
+
+	files := []roachpb.AddSSTableRequest_File{
+		mkFile("ba", "bb", "ba1"),
+		mkFile("bb", "bc", "bb1"),
+		mkFile("bc", "bd", "bc1"),
+	}
+	if err := db.AddSSTables(ctx, files, false /* disallowShadowing */, nil /* stats */); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if n := atomic.LoadInt32(&applied); n != 1 {
+		t.Fatalf("expected files to be ingested by 1 command, got %d", n)
+	}
+	for _, k := range []string{"ba1", "bb1", "bc1"} {
+		if r, err := db.Get(ctx, k); err != nil {
+			t.Fatalf("%+v", err)
+		} else if v, err := r.Value.GetBytes(); err != nil {
+			t.Fatalf("%+v", err)
+		} else if string(v) != k {
+			t.Fatalf("expected %s to be %q, got %q", k, k, v)
+		}
+	}
+
+	addSSTables := func(span roachpb.Span, files ...roachpb.AddSSTableRequest_File) error {
+		var b client.Batch
+		b.AddRawRequest(&roachpb.AddSSTableRequest{
+			RequestHeader: roachpb.RequestHeader{Key: span.Key, EndKey: span.EndKey},
+			Files:         files,
+		})
+		return db.Run(ctx, &b)
+	}
+	reqSpan := roachpb.Span{Key: roachpb.Key("c"), EndKey: roachpb.Key("d")}
+	for _, tc := range []struct {
+		files  []roachpb.AddSSTableRequest_File
+		expErr string
+	}{
+		{
+			files:  []roachpb.AddSSTableRequest_File{mkFile("ca", "cb", "ca1"), mkFile("cb", "e", "cb1")},
+			expErr: "not in request range",
+		},
+		{
+			files:  []roachpb.AddSSTableRequest_File{mkFile("ca", "cc", "ca1"), mkFile("cb", "cd", "cb1")},
+			expErr: "overlaps preceding file span",
+		},
+		{
+			files:  []roachpb.AddSSTableRequest_File{mkFile("ca", "cb", "ca1"), mkFile("cb", "cc", "cc1")},
+			expErr: "not in file span",
+		},
+	} {
+		if err := addSSTables(reqSpan, tc.files...); !testutils.IsError(err, tc.expErr) {
+			t.Errorf("expected error %q, got %+v", tc.expErr, err)
+		}
+	}
+}
+
 type strKv struct {
 	k  string
 	ts int64
@@ -839,3 +1075,385 @@ func TestAddSSTableDisallowShadowing(t *testing.T) {
 		}
 	}
 }
+
+// TestAddSSTableReportShadowedKeys verifies that AddSSTable reports, rather
+// than fails on, key collisions when requested, and that it distinguishes
+// tombstones shadowing live keys from live keys shadowing live keys.
+func TestAddSSTableReportShadowedKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+
+	for _, kv := range mvccKVsFromStrs([]strKv{
+		{"a", 2, "aa"},
+		{"b", 1, "bb"},
+		{"b", 6, ""},
+		{"g", 5, "gg"},
+		{"y", 5, "yyy"},
+		{"z", 2, "zz"},
+	}) {
+		if err := e.Put(kv.Key, kv.Value); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	getSSTBytes := func(sstKVs []engine.MVCCKeyValue) []byte {
+		sst, err := engine.MakeRocksDBSstFileWriter()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		defer sst.Close()
+		for _, kv := range sstKVs {
+			if err := sst.Put(kv.Key, kv.Value); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		sstBytes, err := sst.Finish()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return sstBytes
+	}
+	evalAddSSTable := func(sstKVs []strKv) (result.Result, roachpb.AddSSTableResponse) {
+		cArgs := batcheval.CommandArgs{
+			Header: roachpb.Header{
+				Timestamp: hlc.Timestamp{WallTime: 7},
+			},
+			Args: &roachpb.AddSSTableRequest{
+				RequestHeader:      roachpb.RequestHeader{Key: roachpb.Key("a"), EndKey: roachpb.Key("zz")},
+				Data:               getSSTBytes(mvccKVsFromStrs(sstKVs)),
+				DisallowShadowing:  true,
+				ReportShadowedKeys: true,
+			},
+			Stats: &enginepb.MVCCStats{},
+		}
+		var resp roachpb.AddSSTableResponse
+		res, err := batcheval.EvalAddSSTable(ctx, e, cArgs, &resp)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return res, resp
+	}
+
+	res, resp := evalAddSSTable([]strKv{
+		{"a", 7, ""},    // tombstone over a live key
+		{"b", 7, "b"},   // live key over a tombstone - not a collision
+		{"g", 4, "ggg"}, // live key over a live key
+		{"y", 5, "yyy"}, // identical to the existing version - not a collision
+		{"z", 3, "zzz"}, // live key over a live key
+	})
+	if res.Replicated.AddSSTable != nil {
+		t.Fatal("expected SSTable with collisions not to be ingested")
+	}
+	if resp.LiveKeyCollisions != 2 || resp.TombstoneKeyCollisions != 1 {
+		t.Fatalf("expected 2 live and 1 tombstone collisions, got %d and %d",
+			resp.LiveKeyCollisions, resp.TombstoneKeyCollisions)
+	}
+	if exp := []roachpb.Key{roachpb.Key("g"), roachpb.Key("z")}; !reflect.DeepEqual(exp, resp.LiveKeyCollisionSample) {
+		t.Errorf("expected live collision sample %v, got %v", exp, resp.LiveKeyCollisionSample)
+	}
+	if exp := []roachpb.Key{roachpb.Key("a")}; !reflect.DeepEqual(exp, resp.TombstoneKeyCollisionSample) {
+		t.Errorf("expected tombstone collision sample %v, got %v", exp, resp.TombstoneKeyCollisionSample)
+	}
+
+	// Without any collisions, the SSTable is ingested.
+	res, resp = evalAddSSTable([]strKv{
+		{"b", 7, "b"},
+		{"c", 7, "c"},
+	})
+	if res.Replicated.AddSSTable == nil {
+		t.Fatal("expected SSTable without collisions to be ingested")
+	}
+	if resp.LiveKeyCollisions != 0 || resp.TombstoneKeyCollisions != 0 {
+		t.Fatalf("expected no collisions, got %d live and %d tombstone",
+			resp.LiveKeyCollisions, resp.TombstoneKeyCollisions)
+	}
+}
+
+func TestAddSSTableContentHash(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+
+	mkSST := func(kvs []engine.MVCCKeyValue) []byte {
+		sst, err := engine.MakeRocksDBSstFileWriter()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		defer sst.Close()
+		for _, kv := range kvs {
+			if err := sst.Put(kv.Key, kv.Value); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		sstBytes, err := sst.Finish()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return sstBytes
+	}
+
+	contentHash := func(sstBytes []byte) []byte {
+		cArgs := batcheval.CommandArgs{
+			Header: roachpb.Header{
+				Timestamp: hlc.Timestamp{WallTime: 7},
+			},
+			Args: &roachpb.AddSSTableRequest{
+				RequestHeader:      roachpb.RequestHeader{Key: keys.MinKey, EndKey: keys.MaxKey},
+				Data:               sstBytes,
+				ComputeContentHash: true,
+			},
+			Stats: &enginepb.MVCCStats{},
+		}
+		var resp roachpb.AddSSTableResponse
+		if _, err := batcheval.EvalAddSSTable(ctx, e, cArgs, &resp); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if len(resp.ContentHash) == 0 {
+			t.Fatal("expected a content hash")
+		}
+		return resp.ContentHash
+	}
+
+	strKVs := []strKv{
+		{"a", 2, "aa"},
+		{"b", 1, "bb"},
+		{"b", 6, ""},
+		{"c", 4, "cccc"},
+	}
+	sstKVs := mvccKVsFromStrs(strKVs)
+	hash := contentHash(mkSST(sstKVs))
+
+	// An unmodified SSTable hashes to the same value, even if it is rebuilt.
+	if h := contentHash(mkSST(mvccKVsFromStrs(strKVs))); !bytes.Equal(hash, h) {
+		t.Fatalf("expected stable hash %x, got %x", hash, h)
+	}
+
+	// Flipping a single byte in one of the values changes the hash.
+	flippedKVs := mvccKVsFromStrs(strKVs)
+	flippedKVs[len(flippedKVs)-1].Value[len(flippedKVs[len(flippedKVs)-1].Value)-1] ^= 0xff
+	if h := contentHash(mkSST(flippedKVs)); bytes.Equal(hash, h) {
+		t.Fatalf("expected hash of modified SSTable to differ from %x", hash)
+	}
+
+	// No hash is computed unless requested.
+	cArgs := batcheval.CommandArgs{
+		Header: roachpb.Header{
+			Timestamp: hlc.Timestamp{WallTime: 7},
+		},
+		Args: &roachpb.AddSSTableRequest{
+			RequestHeader: roachpb.RequestHeader{Key: keys.MinKey, EndKey: keys.MaxKey},
+			Data:          mkSST(sstKVs),
+		},
+		Stats: &enginepb.MVCCStats{},
+	}
+	var resp roachpb.AddSSTableResponse
+	if _, err := batcheval.EvalAddSSTable(ctx, e, cArgs, &resp); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if resp.ContentHash != nil {
+		t.Fatalf("expected no content hash, got %x", resp.ContentHash)
+	}
+}
+
+// TestAddSSTableWriteAtRequestTimestamp verifies that AddSSTable can rewrite
+// the timestamps of the keys in an SSTable to the request timestamp.
+func TestAddSSTableWriteAtRequestTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+
+	// Write an intent on "i".
+	txn := roachpb.MakeTransaction(
+		"test", nil /* baseKey */, roachpb.NormalUserPriority, hlc.Timestamp{WallTime: 1},
+		base.DefaultMaxClockOffset.Nanoseconds(),
+	)
+	if err := engine.MVCCPut(
+		ctx, e, nil, roachpb.Key("i"), txn.Timestamp, roachpb.MakeValueFromString("i"), &txn,
+	); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	mkSST := func(kvs []engine.MVCCKeyValue) []byte {
+		sst, err := engine.MakeRocksDBSstFileWriter()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		defer sst.Close()
+		for _, kv := range kvs {
+			if err := sst.Put(kv.Key, kv.Value); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		sstBytes, err := sst.Finish()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return sstBytes
+	}
+	ts := hlc.Timestamp{WallTime: 10}
+	evalCtx := batcheval.MakeTestingEvalContext(cluster.MakeTestingClusterSettings())
+	h := roachpb.Header{Timestamp: ts}
+	addSSTable := func(sstBytes []byte, ms *enginepb.MVCCStats) (result.Result, error) {
+		cArgs := batcheval.CommandArgs{
+			EvalCtx: evalCtx,
+			Header:  h,
+			Args: &roachpb.AddSSTableRequest{
+				RequestHeader:           roachpb.RequestHeader{Key: keys.MinKey, EndKey: keys.MaxKey},
+				Data:                    sstBytes,
+				MVCCStats:               ms,
+				WriteAtRequestTimestamp: true,
+			},
+			Stats: &enginepb.MVCCStats{},
+		}
+		res, err := batcheval.EvalAddSSTable(ctx, e, cArgs, &roachpb.AddSSTableResponse{})
+		if err == nil && cArgs.Stats.KeyCount != 2 {
+			t.Errorf("expected stats of rewritten SSTable, got %+v", cArgs.Stats)
+		}
+		return res, err
+	}
+
+	// Only the newest version of each key is retained, at the request
+	// timestamp, and a deleted key stays deleted. Stats provided by the caller
+	// are ignored.
+	res, err := addSSTable(mkSST(mvccKVsFromStrs([]strKv{
+		{"a", 2, "aa"},
+		{"a", 4, ""},
+		{"c", 3, "ccc"},
+		{"c", 1, "c"},
+	})), &enginepb.MVCCStats{KeyCount: 100})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	iter, err := engine.NewMemSSTIterator(res.Replicated.AddSSTable.Data, true /* verify */)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer iter.Close()
+	var kvs []engine.MVCCKeyValue
+	for iter.Seek(engine.NilKey); ; iter.Next() {
+		if ok, err := iter.Valid(); err != nil {
+			t.Fatalf("%+v", err)
+		} else if !ok {
+			break
+		}
+		key := iter.UnsafeKey()
+		kvs = append(kvs, engine.MVCCKeyValue{
+			Key:   engine.MVCCKey{Key: append(roachpb.Key(nil), key.Key...), Timestamp: key.Timestamp},
+			Value: append([]byte(nil), iter.UnsafeValue()...),
+		})
+	}
+	expected := mvccKVsFromStrs([]strKv{{"a", 10, ""}, {"c", 10, "ccc"}})
+	if !reflect.DeepEqual(kvs, expected) {
+		t.Fatalf("expected %v, got %v", expected, kvs)
+	}
+
+	// Keys with existing intents are rejected.
+	_, err = addSSTable(mkSST(mvccKVsFromStrs([]strKv{{"i", 2, "ii"}})), nil)
+	if _, ok := err.(*roachpb.WriteIntentError); !ok {
+		t.Fatalf("expected WriteIntentError, got %+v", err)
+	}
+
+	// Inline values can't be rewritten.
+	_, err = addSSTable(mkSST(mvccKVsFromStrs([]strKv{{"j", 0, "j"}})), nil)
+	if !testutils.IsError(err, "cannot rewrite timestamp") {
+		t.Fatalf("expected error rewriting inline value, got %+v", err)
+	}
+
+	// Transactional requests are rejected.
+	h.Txn = &txn
+	_, err = addSSTable(mkSST(mvccKVsFromStrs([]strKv{{"k", 2, "kk"}})), nil)
+	if !testutils.IsError(err, "cannot be transactional") {
+		t.Fatalf("expected transactional request to be rejected, got %+v", err)
+	}
+	h.Txn = nil
+
+	// The request is rejected until all nodes have been upgraded.
+	v := cluster.VersionByKey(cluster.VersionAddSSTableWriteAtRequestTimestamp - 1)
+	evalCtx = batcheval.MakeTestingEvalContext(cluster.MakeTestingClusterSettingsWithVersion(v, v))
+	_, err = addSSTable(mkSST(mvccKVsFromStrs([]strKv{{"k", 2, "kk"}})), nil)
+	if !testutils.IsError(err, "require all nodes to be upgraded") {
+		t.Fatalf("expected request to be rejected, got %+v", err)
+	}
+}
+
+// unorderedComparer is a db.Comparer which considers every key to sort after
+// its predecessor, which allows writing SSTables with keys in arbitrary order.
+type unorderedComparer struct{}
+
+func (unorderedComparer) Compare(a, b []byte) int                 { return -1 }
+func (unorderedComparer) Name() string                            { return "unordered" }
+func (unorderedComparer) AppendSeparator(dst, a, b []byte) []byte { return append(dst, a...) }
+
+func TestVerifySSTableOrdering(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, dirCleanupFn := testutils.TempDir(t)
+	defer dirCleanupFn()
+
+	// mkSST writes the keys in the given order, bypassing the ordering checks
+	// of the RocksDB SSTable writer.
+	mkSST := func(name string, kvs []strKv) []byte {
+		path := filepath.Join(dir, name)
+		f, err := db.DefaultFileSystem.Create(path)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		w := table.NewWriter(f, &db.Options{Comparer: unorderedComparer{}})
+		for _, kv := range kvs {
+			// Append the RocksDB InternalKey trailer expected by the SSTable
+			// iterator.
+			var trailer [8]byte
+			binary.LittleEndian.PutUint64(trailer[:], uint64(engine.BatchTypeValue))
+			key := engine.MVCCKey{Key: roachpb.Key(kv.k), Timestamp: hlc.Timestamp{WallTime: kv.ts}}
+			value := roachpb.MakeValueFromString(kv.v).RawBytes
+			if err := w.Set(append(engine.EncodeKey(key), trailer[:]...), value, nil); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return data
+	}
+
+	span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("d")}
+	testCases := []struct {
+		name   string
+		kvs    []strKv
+		expErr string
+	}{
+		{"ordered", []strKv{{"a", 2, "aa"}, {"b", 3, "bb"}, {"b", 1, "bb"}, {"c", 4, "cc"}}, ""},
+		{"out-of-order keys", []strKv{{"a", 2, "aa"}, {"c", 4, "cc"}, {"b", 1, "bb"}},
+			`key "b"/0.000000001,0 is not ordered after preceding key "c"/0.000000004,0`},
+		{"out-of-order timestamps", []strKv{{"a", 2, "aa"}, {"b", 1, "bb"}, {"b", 3, "bb"}},
+			`key "b"/0.000000003,0 is not ordered after preceding key "b"/0.000000001,0`},
+		{"duplicate keys", []strKv{{"a", 2, "aa"}, {"a", 2, "aa"}},
+			`key "a"/0.000000002,0 is not ordered after preceding key "a"/0.000000002,0`},
+		{"key outside span", []strKv{{"a", 2, "aa"}, {"d", 1, "dd"}},
+			`key "d"/0.000000001,0 not in span`},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := mkSST(fmt.Sprintf("%d.sst", i), tc.kvs)
+			err := batcheval.VerifySSTableOrdering(data, span)
+			if tc.expErr == "" {
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+			} else if !testutils.IsError(err, regexp.QuoteMeta(tc.expErr)) {
+				t.Fatalf("expected %q, got %v", tc.expErr, err)
+			}
+		})
+	}
+}
@@ -32,6 +32,10 @@ func DeleteRange(
 	h := cArgs.Header
 	reply := resp.(*roachpb.DeleteRangeResponse)
 
+	if args.DryRun {
+		return deleteRangeDryRun(ctx, batch, cArgs, args, reply)
+	}
+
 	var timestamp hlc.Timestamp
 	if !args.Inline {
 		timestamp = h.Timestamp
@@ -49,3 +53,36 @@ func DeleteRange(
 	}
 	return result.Result{}, err
 }
+
+// deleteRangeDryRun counts the keys and bytes that the DeleteRange request
+// would delete, without deleting them. A dry run is a read-only request (see
+// DeleteRangeRequest.flags), so it scans the live keys at the request's
+// timestamp like a Scan would.
+func deleteRangeDryRun(
+	ctx context.Context,
+	batch engine.Reader,
+	cArgs CommandArgs,
+	args *roachpb.DeleteRangeRequest,
+	reply *roachpb.DeleteRangeResponse,
+) (result.Result, error) {
+	h := cArgs.Header
+	kvs, resumeSpan, _, err := engine.MVCCScan(
+		ctx, batch, args.Key, args.EndKey, cArgs.MaxKeys, h.Timestamp,
+		engine.MVCCScanOptions{IgnoreSequence: shouldIgnoreSequenceNums(), Txn: h.Txn},
+	)
+	if err != nil {
+		return result.Result{}, err
+	}
+	if args.ReturnKeys {
+		for _, kv := range kvs {
+			reply.Keys = append(reply.Keys, kv.Key)
+		}
+	}
+	reply.NumKeys = int64(len(kvs))
+	reply.NumBytes = rowsNumBytes(kvs)
+	if resumeSpan != nil {
+		reply.ResumeSpan = resumeSpan
+		reply.ResumeReason = roachpb.RESUME_KEY_LIMIT
+	}
+	return result.Result{}, nil
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
@@ -48,6 +49,25 @@ func declareKeysRecomputeStats(
 	rdKey := keys.RangeDescriptorKey(desc.StartKey)
 	spans.Add(spanset.SpanReadOnly, roachpb.Span{Key: rdKey})
 	spans.Add(spanset.SpanReadWrite, roachpb.Span{Key: keys.TransactionKey(rdKey, uuid.Nil)})
+
+	// Clearing the ContainsEstimates flag is only correct if no command which
+	// contributes estimated stats is in flight, so in that case we block all
+	// other commands on the range by declaring write access to all of its data.
+	if req.(*roachpb.RecomputeStatsRequest).ClearEstimates {
+		spans.Add(spanset.SpanReadWrite, roachpb.Span{
+			Key:    desc.StartKey.AsRawKey(),
+			EndKey: desc.EndKey.AsRawKey(),
+		})
+		spans.Add(spanset.SpanReadWrite, roachpb.Span{
+			Key:    keys.MakeRangeKeyPrefix(desc.StartKey),
+			EndKey: keys.MakeRangeKeyPrefix(desc.EndKey).PrefixEnd(),
+		})
+		rangeIDPrefix := keys.MakeRangeIDReplicatedPrefix(header.RangeID)
+		spans.Add(spanset.SpanReadWrite, roachpb.Span{
+			Key:    rangeIDPrefix,
+			EndKey: rangeIDPrefix.PrefixEnd(),
+		})
+	}
 }
 
 // RecomputeStats recomputes the MVCCStats stored for this range and adjust them accordingly,
@@ -61,6 +81,11 @@ func RecomputeStats(
 		return result.Result{}, errors.New("descriptor mismatch; range likely merged")
 	}
 	dryRun := args.DryRun
+	// Replicas which predate VersionClearStatsEstimates would ignore the
+	// instruction to clear the ContainsEstimates flag and their stats would
+	// diverge, so fall back to merely correcting the stats until then.
+	clearEstimates := args.ClearEstimates &&
+		cArgs.EvalCtx.ClusterSettings().Version.IsActive(cluster.VersionClearStatsEstimates)
 
 	args = nil // avoid accidental use below
 
@@ -92,14 +117,16 @@ func RecomputeStats(
 	delta := actualMS
 	delta.Subtract(currentStats)
 
+	var res result.Result
 	if !dryRun {
-		// NB: this will never clear the ContainsEstimates flag. To be able to do this,
-		// we would need to guarantee that no command that sets it is in-flight in
-		// parallel with this command. This can be achieved by blocking all of the range
-		// or by using our inside knowledge that dictates that ranges which contain no
-		// timeseries writes never have the flag reset, or by making ContainsEstimates
-		// a counter (and ensuring that we're the only one subtracting at any given
-		// time).
+		// NB: unless clearEstimates is set, this will never clear the
+		// ContainsEstimates flag. To be able to do this, we need to guarantee
+		// that no command that sets it is in-flight in parallel with this
+		// command, which clearEstimates achieves by blocking all of the range.
+		// Alternatives would be using our inside knowledge that dictates that
+		// ranges which contain no timeseries writes never have the flag reset,
+		// or making ContainsEstimates a counter (and ensuring that we're the
+		// only one subtracting at any given time).
 		//
 		// TODO(tschottdorf): do we not want to run at all if we have estimates in
 		// this range? I think we want to as this would give us much more realistic
@@ -107,8 +134,9 @@ func RecomputeStats(
 		// wildly overcounting) and this is paced by the consistency checker, but it
 		// means some extra engine churn.
 		cArgs.Stats.Add(delta)
+		res.Replicated.ClearStatsEstimates = clearEstimates
 	}
 
 	resp.(*roachpb.RecomputeStatsResponse).AddedDelta = enginepb.MVCCStatsDelta(delta)
-	return result.Result{}, nil
+	return res, nil
 }
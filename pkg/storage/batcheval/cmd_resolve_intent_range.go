@@ -31,6 +31,10 @@ func declareKeysResolveIntentRange(
 
 // ResolveIntentRange resolves write intents in the specified
 // key range according to the status of the transaction which created it.
+// Only intents belonging to args.IntentTxn are resolved; intents written by
+// other transactions within the span are left untouched. This makes it
+// suitable for cleaning up all of a single transaction's intents in a span
+// with one command.
 func ResolveIntentRange(
 	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
 ) (result.Result, error) {
@@ -220,3 +220,77 @@ func TestDeclareKeysResolveIntent(t *testing.T) {
 		}
 	})
 }
+
+// TestResolveIntentRangeOnlyResolvesTargetTxn verifies that ResolveIntentRange
+// only resolves the intents of the transaction it names, even when another
+// transaction has intents interleaved within the same span.
+func TestResolveIntentRangeOnlyResolvesTargetTxn(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	eng := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer eng.Close()
+
+	ts := hlc.Timestamp{WallTime: 1}
+	txn1 := roachpb.MakeTransaction("txn1", roachpb.Key("a"), roachpb.NormalUserPriority, ts, 0)
+	txn2 := roachpb.MakeTransaction("txn2", roachpb.Key("b"), roachpb.NormalUserPriority, ts, 0)
+	txn1Keys := []roachpb.Key{roachpb.Key("a"), roachpb.Key("c")}
+	txn2Keys := []roachpb.Key{roachpb.Key("b"), roachpb.Key("d")}
+	for _, k := range txn1Keys {
+		if err := engine.MVCCPut(ctx, eng, nil, k, ts, roachpb.MakeValueFromString("txn1"), &txn1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, k := range txn2Keys {
+		if err := engine.MVCCPut(ctx, eng, nil, k, ts, roachpb.MakeValueFromString("txn2"), &txn2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	desc := roachpb.RangeDescriptor{
+		RangeID:  99,
+		StartKey: roachpb.RKey("a"),
+		EndKey:   roachpb.RKey("z"),
+	}
+	rir := roachpb.ResolveIntentRangeRequest{
+		IntentTxn: txn1.TxnMeta,
+		Status:    roachpb.COMMITTED,
+	}
+	rir.Key = roachpb.Key("a")
+	rir.EndKey = roachpb.Key("e")
+
+	var h roachpb.Header
+	h.RangeID = desc.RangeID
+	cArgs := CommandArgs{Header: h, Args: &rir}
+	cArgs.EvalCtx = &mockEvalCtx{desc: &desc, abortSpan: abortspan.New(desc.RangeID)}
+
+	batch := eng.NewBatch()
+	defer batch.Close()
+	var resp roachpb.ResolveIntentRangeResponse
+	if _, err := ResolveIntentRange(ctx, batch, cArgs, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Commit(false /* sync */); err != nil {
+		t.Fatal(err)
+	}
+	if resp.NumKeys != int64(len(txn1Keys)) {
+		t.Errorf("expected %d keys to be resolved, got %d", len(txn1Keys), resp.NumKeys)
+	}
+
+	checkIntent := func(k roachpb.Key, expIntent bool) {
+		t.Helper()
+		_, intent, err := engine.MVCCGet(ctx, eng, k, ts, engine.MVCCGetOptions{Inconsistent: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hasIntent := intent != nil; hasIntent != expIntent {
+			t.Errorf("%s: expected intent %t, found %t", k, expIntent, hasIntent)
+		}
+	}
+	for _, k := range txn1Keys {
+		checkIntent(k, false)
+	}
+	for _, k := range txn2Keys {
+		checkIntent(k, true)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 )
 
 func init() {
@@ -49,11 +50,13 @@ func ReverseScan(
 				IgnoreSequence: shouldIgnoreSequenceNums(),
 				Txn:            h.Txn,
 				Reverse:        true,
+				TargetBytes:    cArgs.TargetBytes,
 			})
 		if err != nil {
 			return result.Result{}, err
 		}
 		reply.NumKeys = numKvs
+		reply.NumBytes = enginepb.ScanDataNumBytes(kvData, numKvs)
 		reply.BatchResponses = [][]byte{kvData}
 	case roachpb.KEY_VALUES:
 		var rows []roachpb.KeyValue
@@ -63,11 +66,13 @@ func ReverseScan(
 				IgnoreSequence: shouldIgnoreSequenceNums(),
 				Txn:            h.Txn,
 				Reverse:        true,
+				TargetBytes:    cArgs.TargetBytes,
 			})
 		if err != nil {
 			return result.Result{}, err
 		}
 		reply.NumKeys = int64(len(rows))
+		reply.NumBytes = rowsNumBytes(rows)
 		reply.Rows = rows
 	default:
 		panic(fmt.Sprintf("Unknown scanFormat %d", args.ScanFormat))
@@ -76,10 +81,17 @@ func ReverseScan(
 	if resumeSpan != nil {
 		reply.ResumeSpan = resumeSpan
 		reply.ResumeReason = roachpb.RESUME_KEY_LIMIT
+		if cArgs.TargetBytes > 0 && reply.NumKeys < cArgs.MaxKeys {
+			// The scan stopped short of the key limit, so it hit the byte limit.
+			reply.ResumeReason = roachpb.RESUME_BYTE_LIMIT
+		}
 	}
 
 	if h.ReadConsistency == roachpb.READ_UNCOMMITTED {
-		reply.IntentRows, err = CollectIntentRows(ctx, batch, cArgs, intents)
+		reply.IntentRows, err = collectLimitedIntentRows(
+			ctx, batch, h.MaxIntentRows, true /* reverse */, args.Span(), &reply.ResponseHeader,
+			&reply.Rows, &reply.BatchResponses, &intents,
+		)
 	}
 	return result.FromIntents(intents, args), err
 }
@@ -17,6 +17,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/pkg/errors"
 )
 
 func init() {
@@ -26,7 +28,8 @@ func init() {
 // Scan scans the key range specified by start key through end key
 // in ascending order up to some maximum number of results. maxKeys
 // stores the number of scan results remaining for this batch
-// (MaxInt64 for no limit).
+// (MaxInt64 for no limit). If KeysOnly is set, the values are not read
+// from the engine and the returned rows are left empty.
 func Scan(
 	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
 ) (result.Result, error) {
@@ -40,6 +43,10 @@ func Scan(
 
 	switch args.ScanFormat {
 	case roachpb.BATCH_RESPONSE:
+		if args.KeysOnly {
+			return result.Result{}, errors.Errorf(
+				"keys_only is not supported with the %s scan format", args.ScanFormat)
+		}
 		var kvData []byte
 		var numKvs int64
 		kvData, numKvs, resumeSpan, intents, err = engine.MVCCScanToBytes(
@@ -48,11 +55,13 @@ func Scan(
 				Inconsistent:   h.ReadConsistency != roachpb.CONSISTENT,
 				IgnoreSequence: shouldIgnoreSequenceNums(),
 				Txn:            h.Txn,
+				TargetBytes:    cArgs.TargetBytes,
 			})
 		if err != nil {
 			return result.Result{}, err
 		}
 		reply.NumKeys = numKvs
+		reply.NumBytes = enginepb.ScanDataNumBytes(kvData, numKvs)
 		reply.BatchResponses = [][]byte{kvData}
 	case roachpb.KEY_VALUES:
 		var rows []roachpb.KeyValue
@@ -61,11 +70,14 @@ func Scan(
 				Inconsistent:   h.ReadConsistency != roachpb.CONSISTENT,
 				IgnoreSequence: shouldIgnoreSequenceNums(),
 				Txn:            h.Txn,
+				TargetBytes:    cArgs.TargetBytes,
+				KeysOnly:       args.KeysOnly,
 			})
 		if err != nil {
 			return result.Result{}, err
 		}
 		reply.NumKeys = int64(len(rows))
+		reply.NumBytes = rowsNumBytes(rows)
 		reply.Rows = rows
 	default:
 		panic(fmt.Sprintf("Unknown scanFormat %d", args.ScanFormat))
@@ -74,10 +86,34 @@ func Scan(
 	if resumeSpan != nil {
 		reply.ResumeSpan = resumeSpan
 		reply.ResumeReason = roachpb.RESUME_KEY_LIMIT
+		if cArgs.TargetBytes > 0 && reply.NumKeys < cArgs.MaxKeys {
+			// The scan stopped short of the key limit, so it hit the byte limit.
+			reply.ResumeReason = roachpb.RESUME_BYTE_LIMIT
+		}
 	}
 
 	if h.ReadConsistency == roachpb.READ_UNCOMMITTED {
-		reply.IntentRows, err = CollectIntentRows(ctx, batch, cArgs, intents)
+		reply.IntentRows, err = collectLimitedIntentRows(
+			ctx, batch, h.MaxIntentRows, false /* reverse */, args.Span(), &reply.ResponseHeader,
+			&reply.Rows, &reply.BatchResponses, &intents,
+		)
+		if args.KeysOnly {
+			for i := range reply.IntentRows {
+				reply.IntentRows[i].Value.RawBytes = nil
+			}
+		}
 	}
 	return result.FromIntents(intents, args), err
 }
+
+// rowsNumBytes returns the number of bytes the given rows count towards
+// ResponseHeader.NumBytes: the sizes of their MVCC-encoded keys and of their
+// values, which is what the storage engine counts against TargetBytes.
+func rowsNumBytes(rows []roachpb.KeyValue) int64 {
+	var n int64
+	for i := range rows {
+		key := engine.MVCCKey{Key: rows[i].Key, Timestamp: rows[i].Value.Timestamp}
+		n += int64(engine.EncodedKeySize(key) + len(rows[i].Value.RawBytes))
+	}
+	return n
+}
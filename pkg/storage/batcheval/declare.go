@@ -62,6 +62,12 @@ type CommandArgs struct {
 	// NumKeys and ResumeSpan in their responses.
 	MaxKeys int64
 
+	// If TargetBytes is non-zero, span requests should stop once the results
+	// they return reach that many bytes, without splitting a key/value pair.
+	// Commands using this feature should also set NumBytes in their responses
+	// and report RESUME_BYTE_LIMIT if they stopped because of it.
+	TargetBytes int64
+
 	// *Stats should be mutated to reflect any writes made by the command.
 	Stats *enginepb.MVCCStats
 }
@@ -0,0 +1,24 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// This file includes test-only helper methods added to types in
+// package batcheval. These methods are only linked in to tests in this
+// directory (but may be used from tests in both package batcheval and
+// package batcheval_test).
+
+package batcheval
+
+import "github.com/cockroachdb/cockroach/pkg/settings/cluster"
+
+// MakeTestingEvalContext returns an EvalContext which only provides the given
+// cluster settings, for tests which evaluate commands directly.
+func MakeTestingEvalContext(st *cluster.Settings) EvalContext {
+	return &mockEvalCtx{clusterSettings: st}
+}
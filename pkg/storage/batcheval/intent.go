@@ -15,6 +15,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 )
 
 // CollectIntentRows collects the key-value pairs for each intent provided. It
@@ -26,12 +27,28 @@ import (
 // is ok for now.
 func CollectIntentRows(
 	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, intents []roachpb.Intent,
+) ([]roachpb.KeyValue, error) {
+	return collectIntentRows(ctx, batch, intents, 0 /* limit */)
+}
+
+// collectIntentRows implements CollectIntentRows. If limit is positive, it
+// stops once it has collected that many rows, so that the values of the
+// remaining intents are never read.
+func collectIntentRows(
+	ctx context.Context, batch engine.ReadWriter, intents []roachpb.Intent, limit int64,
 ) ([]roachpb.KeyValue, error) {
 	if len(intents) == 0 {
 		return nil, nil
 	}
-	res := make([]roachpb.KeyValue, 0, len(intents))
+	n := int64(len(intents))
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	res := make([]roachpb.KeyValue, 0, n)
 	for _, intent := range intents {
+		if limit > 0 && int64(len(res)) >= limit {
+			break
+		}
 		val, _, err := engine.MVCCGetAsTxn(
 			ctx, batch, intent.Key, intent.Txn.Timestamp, intent.Txn,
 		)
@@ -49,3 +66,103 @@ func CollectIntentRows(
 	}
 	return res, nil
 }
+
+// collectLimitedIntentRows collects the intent rows of a READ_UNCOMMITTED scan
+// and enforces the MaxIntentRows limit on its results using limitIntentRows.
+// Only the values of the intents up to the first one exceeding the limit,
+// which determines where the scan is cut short, are read.
+func collectLimitedIntentRows(
+	ctx context.Context,
+	batch engine.ReadWriter,
+	maxIntentRows int64,
+	reverse bool,
+	span roachpb.Span,
+	header *roachpb.ResponseHeader,
+	rows *[]roachpb.KeyValue,
+	batchResponses *[][]byte,
+	intents *[]roachpb.Intent,
+) ([]roachpb.KeyValue, error) {
+	var limit int64
+	if maxIntentRows > 0 {
+		limit = maxIntentRows + 1
+	}
+	intentRows, err := collectIntentRows(ctx, batch, *intents, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = limitIntentRows(maxIntentRows, reverse, span, header, rows, batchResponses, &intentRows, intents)
+	return intentRows, err
+}
+
+// limitIntentRows enforces the MaxIntentRows limit on the results of a
+// READ_UNCOMMITTED scan over span. If intentRows holds more than maxIntentRows
+// rows, the scan is cut short at the key of the first intent row that doesn't
+// fit: that key and all keys following it in scan order are dropped from the
+// rows, batch responses, intent rows and intents, and the header is updated
+// with the remaining key count and size and a resume span starting at that key.
+func limitIntentRows(
+	maxIntentRows int64,
+	reverse bool,
+	span roachpb.Span,
+	header *roachpb.ResponseHeader,
+	rows *[]roachpb.KeyValue,
+	batchResponses *[][]byte,
+	intentRows *[]roachpb.KeyValue,
+	intents *[]roachpb.Intent,
+) error {
+	if maxIntentRows <= 0 || int64(len(*intentRows)) <= maxIntentRows {
+		return nil
+	}
+	cutoff := (*intentRows)[maxIntentRows].Key
+	// before returns whether the key precedes the cutoff in scan order.
+	before := func(key roachpb.Key) bool {
+		if reverse {
+			return cutoff.Compare(key) < 0
+		}
+		return key.Compare(cutoff) < 0
+	}
+
+	*intentRows = (*intentRows)[:maxIntentRows]
+	var n int
+	for n < len(*intents) && before((*intents)[n].Key) {
+		n++
+	}
+	*intents = (*intents)[:n]
+
+	header.NumKeys, header.NumBytes = 0, 0
+	n = 0
+	for n < len(*rows) && before((*rows)[n].Key) {
+		n++
+	}
+	if *rows != nil {
+		*rows = (*rows)[:n]
+		header.NumKeys = int64(n)
+		header.NumBytes = rowsNumBytes(*rows)
+	}
+	for i, data := range *batchResponses {
+		repr := data
+		var numKVs int64
+		for len(repr) > 0 {
+			key, _, rest, err := engine.MVCCScanDecodeKeyValue(repr)
+			if err != nil {
+				return err
+			}
+			if !before(key.Key) {
+				break
+			}
+			numKVs++
+			repr = rest
+		}
+		(*batchResponses)[i] = data[:len(data)-len(repr)]
+		header.NumKeys += numKVs
+		header.NumBytes += enginepb.ScanDataNumBytes((*batchResponses)[i], numKVs)
+	}
+
+	if reverse {
+		header.ResumeSpan = &roachpb.Span{Key: span.Key, EndKey: cutoff.Next()}
+	} else {
+		header.ResumeSpan = &roachpb.Span{Key: cutoff, EndKey: span.EndKey}
+	}
+	header.ResumeReason = roachpb.RESUME_INTENT_LIMIT
+	return nil
+}
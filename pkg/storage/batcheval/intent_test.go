@@ -0,0 +1,59 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package batcheval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestCollectIntentRowsLimit verifies that collectIntentRows stops reading
+// intent values once it has collected the requested number of rows.
+func TestCollectIntentRowsLimit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	db := engine.NewInMem(roachpb.Attributes{}, 10<<20)
+	defer db.Close()
+
+	ts := hlc.Timestamp{WallTime: 1}
+	txn := roachpb.MakeTransaction("test", roachpb.Key("a"), 0, ts, 0)
+	other := roachpb.MakeTransaction("other", roachpb.Key("a"), 0, ts, 0)
+	var intents []roachpb.Intent
+	for _, k := range []string{"a", "b", "c"} {
+		key := roachpb.Key(k)
+		if err := engine.MVCCPut(
+			ctx, db, nil, key, ts, roachpb.MakeValueFromString(k), &txn,
+		); err != nil {
+			t.Fatal(err)
+		}
+		intents = append(intents, roachpb.Intent{Span: roachpb.Span{Key: key}, Txn: txn.TxnMeta})
+	}
+	// Attribute the last intent to another transaction, which makes reading
+	// its value fail.
+	intents[2].Txn = other.TxnMeta
+
+	if _, err := collectIntentRows(ctx, db, intents, 0 /* limit */); err == nil {
+		t.Fatal("expected reading the last intent to fail")
+	}
+	rows, err := collectIntentRows(ctx, db, intents, 2 /* limit */)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || !rows[0].Key.Equal(roachpb.Key("a")) || !rows[1].Key.Equal(roachpb.Key("b")) {
+		t.Fatalf("expected intent rows for a and b, found %+v", rows)
+	}
+}
@@ -280,6 +280,13 @@ func (p *Result) MergeAndDestroy(q Result) error {
 	}
 	q.Replicated.AddSSTable = nil
 
+	if p.Replicated.AddSSTableFiles == nil {
+		p.Replicated.AddSSTableFiles = q.Replicated.AddSSTableFiles
+	} else if q.Replicated.AddSSTableFiles != nil {
+		return errors.New("conflicting AddSSTableFiles")
+	}
+	q.Replicated.AddSSTableFiles = nil
+
 	if q.Replicated.SuggestedCompactions != nil {
 		if p.Replicated.SuggestedCompactions == nil {
 			p.Replicated.SuggestedCompactions = q.Replicated.SuggestedCompactions
@@ -296,6 +303,9 @@ func (p *Result) MergeAndDestroy(q Result) error {
 	}
 	q.Replicated.PrevLeaseProposal = nil
 
+	p.Replicated.ClearStatsEstimates = p.Replicated.ClearStatsEstimates || q.Replicated.ClearStatsEstimates
+	q.Replicated.ClearStatsEstimates = false
+
 	if q.Local.Intents != nil {
 		if p.Local.Intents == nil {
 			p.Local.Intents = q.Local.Intents
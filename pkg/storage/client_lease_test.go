@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/gossip"
@@ -22,10 +23,14 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagepb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"go.etcd.io/etcd/raft"
 )
 
 // TestStoreRangeLease verifies that regular ranges (not some special ones at
@@ -146,6 +151,97 @@ func TestStoreRangeLeaseSwitcheroo(t *testing.T) {
 	}
 }
 
+// TestStoreLeaselessRanges verifies that ranges whose lease expired are
+// reported by Store.LeaselessRanges until the lease is reacquired.
+func TestStoreLeaselessRanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableMergeQueue = true
+	// Prevent the scanner from reacquiring the expired lease.
+	sc.TestingKnobs.DisableScanner = true
+	// Epoch-based leases become valid again as soon as the node's liveness
+	// record is heartbeat, so use expiration-based leases.
+	sc.EnableEpochRangeLeases = false
+	mtc := &multiTestContext{storeConfig: &sc}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.stores[0]
+
+	splitKey := roachpb.Key("a")
+	splitArgs := adminSplitArgs(splitKey)
+	if _, pErr := client.SendWrapped(context.Background(), mtc.distSenders[0], splitArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	if _, err := mtc.dbs[0].Inc(context.TODO(), splitKey, 1); err != nil {
+		t.Fatal(err)
+	}
+	rangeID := store.LookupReplica(roachpb.RKey(splitKey)).RangeID
+
+	containsRange := func() bool {
+		for _, id := range store.LeaselessRanges() {
+			if id == rangeID {
+				return true
+			}
+		}
+		return false
+	}
+	if containsRange() {
+		t.Fatalf("expected r%d to hold a valid lease", rangeID)
+	}
+
+	mtc.advanceClock(context.TODO())
+	if !containsRange() {
+		t.Fatalf("expected r%d to be reported after its lease expired, got %v", rangeID, store.LeaselessRanges())
+	}
+
+	// Reacquire the lease.
+	if _, err := mtc.dbs[0].Inc(context.TODO(), splitKey, 1); err != nil {
+		t.Fatal(err)
+	}
+	if containsRange() {
+		t.Fatalf("expected r%d not to be reported after reacquiring its lease", rangeID)
+	}
+}
+
+// TestStoreLastAppliedProposer verifies that after a write, every replica of
+// the range reports the leaseholder as the proposer of the last applied
+// command.
+func TestStoreLastAppliedProposer(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableMergeQueue = true
+	mtc := &multiTestContext{storeConfig: &sc}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	key := roachpb.Key("a")
+	splitArgs := adminSplitArgs(key)
+	if _, pErr := client.SendWrapped(context.Background(), mtc.distSenders[0], splitArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	rangeID := mtc.stores[0].LookupReplica(roachpb.RKey(key)).RangeID
+	mtc.replicateRange(rangeID, 1, 2)
+
+	if err := mtc.dbs[0].Put(context.TODO(), key, "value"); err != nil {
+		t.Fatal(err)
+	}
+	lease, _ := mtc.stores[0].LookupReplica(roachpb.RKey(key)).GetLease()
+
+	for i, s := range mtc.stores {
+		repl, err := s.GetReplica(rangeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutils.SucceedsSoon(t, func() error {
+			proposer := repl.LastAppliedProposer()
+			if proposer.StoreID != lease.Replica.StoreID || proposer.ReplicaID != lease.Replica.ReplicaID {
+				return fmt.Errorf("s%d: expected last applied proposer %s, got %s", i+1, lease.Replica, proposer)
+			}
+			return nil
+		})
+	}
+}
+
 // TestStoreGossipSystemData verifies that the system-config and node-liveness
 // data is gossiped at startup.
 func TestStoreGossipSystemData(t *testing.T) {
@@ -214,6 +310,170 @@ func TestStoreGossipSystemData(t *testing.T) {
 	})
 }
 
+// TestStoreReGossipAll verifies that ReGossipAll restores cleared gossip
+// entries for the store descriptor, the first range descriptor and the
+// node liveness record.
+func TestStoreReGossipAll(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.stores[0]
+	g := mtc.gossips[0]
+
+	storeKey := gossip.MakeStoreKey(store.StoreID())
+	livenessKey := gossip.MakeNodeLivenessKey(store.Ident.NodeID)
+	if err := g.AddInfoProto(storeKey, &roachpb.StoreDescriptor{}, gossip.StoreTTL); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddInfoProto(gossip.KeyFirstRangeDescriptor, &roachpb.RangeDescriptor{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddInfoProto(livenessKey, &storagepb.Liveness{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.ReGossipAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var storeDesc roachpb.StoreDescriptor
+	if err := g.GetInfoProto(storeKey, &storeDesc); err != nil {
+		t.Fatal(err)
+	}
+	if storeDesc.StoreID != store.StoreID() {
+		t.Errorf("expected store descriptor for s%d to be gossiped, got %+v", store.StoreID(), storeDesc)
+	}
+	var firstRange roachpb.RangeDescriptor
+	if err := g.GetInfoProto(gossip.KeyFirstRangeDescriptor, &firstRange); err != nil {
+		t.Fatal(err)
+	}
+	if firstRange.RangeID != 1 {
+		t.Errorf("expected first range descriptor to be gossiped, got %+v", firstRange)
+	}
+	var liveness storagepb.Liveness
+	if err := g.GetInfoProto(livenessKey, &liveness); err != nil {
+		t.Fatal(err)
+	}
+	if liveness.NodeID != store.Ident.NodeID {
+		t.Errorf("expected liveness record for n%d to be gossiped, got %+v", store.Ident.NodeID, liveness)
+	}
+}
+
+// TestStoreReGossipAllUnchanged verifies that ReGossipAll gossips the system
+// config and node liveness records anew even when they are unchanged.
+func TestStoreReGossipAllUnchanged(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.stores[0]
+	g := mtc.gossips[0]
+
+	livenessKey := gossip.MakeNodeLivenessKey(store.Ident.NodeID)
+	origStamps := func() map[string]int64 {
+		stamps := make(map[string]int64)
+		for _, key := range []string{gossip.KeySystemConfig, livenessKey} {
+			if err := g.IterateInfos(key, func(k string, info gossip.Info) error {
+				if k == key {
+					stamps[key] = info.OrigStamp
+				}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return stamps
+	}
+	// Wait for the system config and the liveness record to have been gossiped
+	// through the usual paths.
+	testutils.SucceedsSoon(t, func() error {
+		if stamps := origStamps(); len(stamps) != 2 {
+			return fmt.Errorf("expected both infos to be gossiped, found %v", stamps)
+		}
+		return nil
+	})
+
+	before := origStamps()
+	if err := store.ReGossipAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	after := origStamps()
+	for key, stamp := range before {
+		if after[key] <= stamp {
+			t.Errorf("expected %s to be gossiped anew, but its stamp went from %d to %d",
+				key, stamp, after[key])
+		}
+	}
+}
+
+// TestStoreGossipPropagationLag verifies that the gossip propagation lag is
+// small for connected stores and grows once a peer stops gossiping.
+func TestStoreGossipPropagationLag(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 2)
+
+	mtc.gossipStores()
+	for i, s := range mtc.stores {
+		if lag := s.GossipPropagationLag(); lag <= 0 || lag > 10*time.Second {
+			t.Fatalf("s%d: unexpected gossip propagation lag %s", i+1, lag)
+		}
+	}
+
+	// Once the second store is cut off, its descriptor as known to the first
+	// store ages, no matter how often the first store gossips its own.
+	before := mtc.stores[0].GossipPropagationLag()
+	mtc.stopStore(1)
+	testutils.SucceedsSoon(t, func() error {
+		if err := mtc.stores[0].GossipStore(context.Background(), false /* useCached */); err != nil {
+			return err
+		}
+		if lag := mtc.stores[0].GossipPropagationLag(); lag < before+100*time.Millisecond {
+			return fmt.Errorf("expected gossip propagation lag to grow beyond %s, got %s",
+				before+100*time.Millisecond, lag)
+		}
+		return nil
+	})
+}
+
+// TestStoreDelayStoreCapacityGossip verifies that the DelayStoreCapacityGossip
+// testing knob keeps the store descriptor out of gossip until the delay has
+// elapsed, after which it is gossiped without further intervention.
+func TestStoreDelayStoreCapacityGossip(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	const delay = time.Second
+	cfg := storage.TestStoreConfig(nil)
+	cfg.TestingKnobs.DelayStoreCapacityGossip = delay
+	start := timeutil.Now()
+	store := createTestStoreWithOpts(t, testStoreOpts{cfg: &cfg}, stopper)
+	storeKey := gossip.MakeStoreKey(store.StoreID())
+
+	// Explicitly gossiping the store has no effect during the delay.
+	if err := store.GossipStore(ctx, false /* useCached */); err != nil {
+		t.Fatal(err)
+	}
+	var storeDesc roachpb.StoreDescriptor
+	err := store.Gossip().GetInfoProto(storeKey, &storeDesc)
+	if elapsed := timeutil.Since(start); err == nil && elapsed < delay {
+		t.Fatalf("store descriptor gossiped after %s despite a delay of %s", elapsed, delay)
+	}
+
+	testutils.SucceedsSoon(t, func() error {
+		if err := store.Gossip().GetInfoProto(storeKey, &storeDesc); err != nil {
+			return err
+		}
+		if storeDesc.StoreID != store.StoreID() {
+			return fmt.Errorf("unexpected store descriptor %+v", storeDesc)
+		}
+		return nil
+	})
+}
+
 // TestGossipSystemConfigOnLeaseChange verifies that the system-config gets
 // re-gossiped on lease transfer even if it hasn't changed. This helps prevent
 // situations where a previous leaseholder can restart and not receive the
@@ -255,6 +515,69 @@ func TestGossipSystemConfigOnLeaseChange(t *testing.T) {
 	})
 }
 
+// TestStoreEnforceLeasePreferences verifies that EnforceLeasePreferences
+// moves the lease off of a store that doesn't satisfy the zone's lease
+// preferences and onto one that does.
+func TestStoreEnforceLeasePreferences(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableReplicateQueue = true
+	mtc := &multiTestContext{storeConfig: &sc}
+	// Only the third store carries the attribute the lease preference asks for.
+	for i := 0; i < 3; i++ {
+		var attrs roachpb.Attributes
+		if i == 2 {
+			attrs.Attrs = []string{"preferred"}
+		}
+		eng := engine.NewInMem(attrs, 1<<20)
+		defer eng.Close()
+		mtc.engines = append(mtc.engines, eng)
+	}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	ctx := context.Background()
+	rangeID := mtc.stores[0].LookupReplica(roachpb.RKey("a")).RangeID
+	mtc.replicateRange(rangeID, 1, 2)
+	mtc.gossipStores()
+
+	repl, err := mtc.stores[0].GetReplica(rangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease, _ := repl.GetLease(); lease.Replica.StoreID != mtc.stores[0].StoreID() {
+		t.Fatalf("expected lease on s%d, found %s", mtc.stores[0].StoreID(), lease)
+	}
+
+	zone := config.DefaultZoneConfig()
+	zone.LeasePreferences = []config.LeasePreference{{
+		Constraints: []config.Constraint{{Type: config.Constraint_REQUIRED, Value: "preferred"}},
+	}}
+	testutils.SucceedsSoon(t, func() error {
+		// Reinstate the zone config on each attempt in case a system config
+		// update has overwritten it in the meantime.
+		repl.SetZoneConfig(&zone)
+		return mtc.stores[0].EnforceLeasePreferences(ctx, rangeID)
+	})
+
+	if lease, _ := repl.GetLease(); lease.Replica.StoreID != mtc.stores[2].StoreID() {
+		t.Fatalf("expected lease on preferred s%d, found %s", mtc.stores[2].StoreID(), lease)
+	}
+	// Now that the leaseholder satisfies the preferences, enforcing them again
+	// on the new leaseholder is a no-op.
+	repl2, err := mtc.stores[2].GetReplica(rangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repl2.SetZoneConfig(&zone)
+	if err := mtc.stores[2].EnforceLeasePreferences(ctx, rangeID); err != nil {
+		t.Fatal(err)
+	}
+	if lease, _ := repl2.GetLease(); lease.Replica.StoreID != mtc.stores[2].StoreID() {
+		t.Fatalf("expected lease to remain on s%d, found %s", mtc.stores[2].StoreID(), lease)
+	}
+}
+
 func TestGossipNodeLivenessOnLeaseChange(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	sc := storage.TestStoreConfig(nil)
@@ -301,3 +624,51 @@ func TestGossipNodeLivenessOnLeaseChange(t *testing.T) {
 		return nil
 	})
 }
+
+// TestStoreLeaseLeaderMismatches verifies that a range whose lease is
+// transferred away from the Raft leader is reported by both the leader's and
+// the leaseholder's store.
+func TestStoreLeaseLeaderMismatches(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableReplicateQueue = true
+	// Keep Raft leadership where it is when the lease moves.
+	sc.TestingKnobs.DisableLeaderFollowsLeaseholder = true
+	// Don't time out the Raft leader.
+	sc.RaftElectionTimeoutTicks = 1000000
+	mtc := &multiTestContext{
+		storeConfig:          &sc,
+		startWithSingleRange: true,
+	}
+	defer mtc.Stop()
+	mtc.Start(t, 2)
+
+	const rangeID = roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1)
+	repl, err := mtc.stores[0].GetReplica(rangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if status := repl.RaftStatus(); status == nil || status.RaftState != raft.StateLeader {
+			return errors.New("store 0 is not the raft leader")
+		}
+		return nil
+	})
+	for i := range mtc.stores {
+		if mismatches := mtc.stores[i].LeaseLeaderMismatches(); len(mismatches) != 0 {
+			t.Fatalf("store %d: unexpected mismatches %v", i, mismatches)
+		}
+	}
+
+	mtc.transferLease(context.Background(), rangeID, 0, 1)
+	testutils.SucceedsSoon(t, func() error {
+		for i := range mtc.stores {
+			mismatches := mtc.stores[i].LeaseLeaderMismatches()
+			if len(mismatches) != 1 || mismatches[0] != rangeID {
+				return fmt.Errorf("store %d: expected mismatch for r%d, got %v", i, rangeID, mismatches)
+			}
+		}
+		return nil
+	})
+}
@@ -42,8 +42,10 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/txnwait"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/testcluster"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -3302,6 +3304,51 @@ func TestMergeQueue(t *testing.T) {
 	})
 }
 
+// TestStoreMergeCandidates verifies that a small range whose end key is a zone
+// config boundary is reported as a merge candidate which can't be merged.
+func TestStoreMergeCandidates(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	s, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+	store, err := s.GetStores().(*storage.Stores).GetStore(s.GetFirstStoreID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := sqlutils.MakeSQLRunner(sqlDB)
+	db.Exec(t, `SET CLUSTER SETTING kv.range_merge.queue_enabled = true`)
+	db.Exec(t, `CREATE TABLE t (k INT PRIMARY KEY, v INT, INDEX idx (v))`)
+	var tableID uint32
+	db.QueryRow(t, `SELECT table_id FROM crdb_internal.tables WHERE name = 't'`).Scan(&tableID)
+	// The zone config on the secondary index requires a split at its start,
+	// which is the end key of the range containing the primary index.
+	db.Exec(t, `ALTER INDEX t@idx CONFIGURE ZONE USING gc.ttlseconds = 100`)
+	tableKey := roachpb.Key(keys.MakeTablePrefix(tableID))
+	idxKey := roachpb.Key(encoding.EncodeUvarintAscending(keys.MakeTablePrefix(tableID), 2))
+
+	testutils.SucceedsSoon(t, func() error {
+		repl := store.LookupReplica(roachpb.RKey(tableKey))
+		if desc := repl.Desc(); !desc.EndKey.Equal(idxKey) {
+			return errors.Errorf("expected range %s to end at %s", desc, idxKey)
+		}
+		for _, c := range store.MergeCandidates() {
+			if c.RangeID != repl.RangeID {
+				continue
+			}
+			if c.Size >= c.MinBytes {
+				return errors.Errorf("expected size %d below %d", c.Size, c.MinBytes)
+			}
+			if exp := "split required at end key"; c.BlockedReason != exp {
+				return errors.Errorf("expected blocked reason %q, got %q", exp, c.BlockedReason)
+			}
+			return nil
+		}
+		return errors.Errorf("r%d not reported as merge candidate", repl.RangeID)
+	})
+}
+
 func TestInvalidSubsumeRequest(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -621,6 +621,61 @@ func TestReplicateAfterTruncation(t *testing.T) {
 	})
 }
 
+// TestFollowerNeedsSnapshot verifies that the leader reports that a follower
+// whose progress precedes the truncated log can only be caught up by a
+// snapshot, while an up-to-date follower can be caught up from the log.
+func TestFollowerNeedsSnapshot(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := &multiTestContext{
+		startWithSingleRange: true,
+	}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	const rangeID = roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1, 2)
+	repl, err := mtc.stores[0].GetReplica(rangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicaIDOf := func(storeNum int) roachpb.ReplicaID {
+		rd, ok := repl.Desc().GetReplicaDescriptor(mtc.idents[storeNum].StoreID)
+		if !ok {
+			t.Fatalf("no replica on store %d", storeNum)
+		}
+		return rd.ReplicaID
+	}
+	upToDate, behind := replicaIDOf(1), replicaIDOf(2)
+
+	// Let the third replica fall behind, then truncate the log past its
+	// progress.
+	mtc.stopStore(2)
+	incArgs := incrementArgs([]byte("a"), 5)
+	for i := 0; i < 5; i++ {
+		if _, err := client.SendWrapped(context.Background(), mtc.stores[0].TestSender(), incArgs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := repl.GetLastIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncArgs := truncateLogArgs(index+1, rangeID)
+	if _, err := client.SendWrapped(context.Background(), mtc.stores[0].TestSender(), truncArgs); err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.SucceedsSoon(t, func() error {
+		if needsSnap, reason := repl.FollowerNeedsSnapshot(behind); !needsSnap {
+			return errors.Errorf("expected replica %d to need a snapshot: %s", behind, reason)
+		}
+		return nil
+	})
+	if needsSnap, reason := repl.FollowerNeedsSnapshot(upToDate); needsSnap {
+		t.Fatalf("expected replica %d not to need a snapshot: %s", upToDate, reason)
+	}
+}
+
 func TestRaftLogSizeAfterTruncation(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	mtc := &multiTestContext{
@@ -1752,6 +1807,74 @@ func TestChangeReplicasDescriptorInvariant(t *testing.T) {
 	})
 }
 
+// TestChangeReplicasAmbiguousResultPolicy verifies that an AmbiguousResultError
+// returned after a replication change committed is surfaced by the fail-fast
+// policy and resolved by the verify-via-descriptor policy.
+func TestChangeReplicasAmbiguousResultPolicy(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableReplicateQueue = true
+	var injectAmbiguous int32
+	sc.TestingKnobs.TestingResponseFilter = func(
+		ba roachpb.BatchRequest, _ *roachpb.BatchResponse,
+	) *roachpb.Error {
+		if ba.Requests[0].GetAdminChangeReplicas() == nil {
+			return nil
+		}
+		if atomic.CompareAndSwapInt32(&injectAmbiguous, 1, 0) {
+			return roachpb.NewError(roachpb.NewAmbiguousResultError("injected"))
+		}
+		return nil
+	}
+	mtc := &multiTestContext{
+		storeConfig:          &sc,
+		startWithSingleRange: true,
+	}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	key := roachpb.Key("a")
+	changeReplicas := func(
+		storeNum int, policy client.AmbiguousResultPolicy,
+	) (*roachpb.RangeDescriptor, error) {
+		var desc roachpb.RangeDescriptor
+		if err := mtc.dbs[0].GetProto(ctx, keys.RangeDescriptorKey(roachpb.RKeyMin), &desc); err != nil {
+			t.Fatal(err)
+		}
+		atomic.StoreInt32(&injectAmbiguous, 1)
+		return mtc.dbs[0].AdminChangeReplicasWithPolicy(
+			ctx, key, desc,
+			roachpb.MakeReplicationChanges(roachpb.ADD_REPLICA, roachpb.ReplicationTarget{
+				NodeID:  mtc.idents[storeNum].NodeID,
+				StoreID: mtc.idents[storeNum].StoreID,
+			}),
+			policy,
+		)
+	}
+
+	// The fail-fast policy returns the ambiguous error even though the change
+	// committed.
+	if _, err := changeReplicas(1, client.AmbiguousResultFailFast); !testutils.IsError(err, "injected") {
+		t.Fatalf("expected injected ambiguous result, got %v", err)
+	}
+
+	// The verify policy discovers that the change took effect.
+	desc, err := changeReplicas(2, client.AmbiguousResultVerifyDescriptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, ok := desc.GetReplicaDescriptor(mtc.idents[i].StoreID); !ok {
+			t.Fatalf("expected store %d in descriptor %s", mtc.idents[i].StoreID, desc)
+		}
+	}
+	if atomic.LoadInt32(&injectAmbiguous) != 0 {
+		t.Fatal("expected ambiguous result to be injected")
+	}
+}
+
 // TestProgressWithDownNode verifies that a surviving quorum can make progress
 // with a downed node.
 func TestProgressWithDownNode(t *testing.T) {
@@ -2210,6 +2333,94 @@ func TestQuotaPool(t *testing.T) {
 	}
 }
 
+// TestQuotaPoolThrottledFollower verifies that a follower slowed down with
+// throttleStore causes the leader's proposal quota to drain, and that the
+// quota is returned once the follower is unthrottled.
+func TestQuotaPoolThrottledFollower(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const quota = 10000
+	const numReplicas = 3
+	const rangeID = 1
+	ctx := context.Background()
+	sc := storage.TestStoreConfig(nil)
+	// Suppress timeout-based elections to avoid leadership changes in ways
+	// this test doesn't expect.
+	sc.RaftElectionTimeoutTicks = 100000
+	mtc := &multiTestContext{
+		storeConfig:          &sc,
+		startWithSingleRange: true,
+	}
+	mtc.Start(t, numReplicas)
+	defer mtc.Stop()
+
+	mtc.replicateRange(rangeID, 1, 2)
+
+	leaderRepl := mtc.getRaftLeader(rangeID)
+	if err := leaderRepl.InitQuotaPool(quota); err != nil {
+		t.Fatalf("failed to initialize quota pool: %v", err)
+	}
+	followerIdx := -1
+	for i, store := range mtc.stores {
+		if store.StoreID() != leaderRepl.StoreID() {
+			followerIdx = i
+			break
+		}
+	}
+	if followerIdx == -1 {
+		t.Fatal("could not find a follower store")
+	}
+
+	mtc.throttleStore(followerIdx, 100*time.Millisecond)
+
+	// Write continuously until told to stop. Writes only need a quorum, which
+	// the leader and the unthrottled follower provide, so they keep acquiring
+	// quota that the throttled follower is slow to release.
+	stopCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		value := bytes.Repeat([]byte("v"), quota/10)
+		for i := 0; ; i++ {
+			select {
+			case <-stopCh:
+				errCh <- nil
+				return
+			default:
+			}
+			var ba roachpb.BatchRequest
+			ba.Add(putArgs(roachpb.Key(fmt.Sprintf("k%d", i)), value))
+			if err := ba.SetActiveTimestamp(mtc.clock.Now); err != nil {
+				errCh <- err
+				return
+			}
+			if _, pErr := leaderRepl.Send(ctx, ba); pErr != nil {
+				errCh <- pErr.GoError()
+				return
+			}
+		}
+	}()
+
+	testutils.SucceedsSoon(t, func() error {
+		if curQuota := leaderRepl.QuotaAvailable(); curQuota > quota/4 {
+			return errors.Errorf("expected available quota to drain, got %d", curQuota)
+		}
+		return nil
+	})
+
+	close(stopCh)
+	mtc.unthrottleStore(followerIdx)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.SucceedsSoon(t, func() error {
+		if curQuota := leaderRepl.QuotaAvailable(); curQuota != quota {
+			return errors.Errorf("expected available quota %d, got %d", quota, curQuota)
+		}
+		return nil
+	})
+}
+
 // TestWedgedReplicaDetection verifies that a leader replica is able to
 // correctly detect a wedged follower replica and no longer consider it
 // as active for the purpose of proposal throttling.
@@ -3907,6 +4118,136 @@ func TestRangeQuiescence(t *testing.T) {
 	}
 }
 
+// TestReplicaRaftMessageRates verifies that an active range reports nonzero
+// Raft message rates while a quiesced range doesn't exchange any messages.
+func TestReplicaRaftMessageRates(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableScanner = true
+	sc.TestingKnobs.DisablePeriodicGossips = true
+	mtc := &multiTestContext{
+		storeConfig:          &sc,
+		startWithSingleRange: true,
+	}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	pauseNodeLivenessHeartbeats(mtc, true)
+
+	const rangeID = roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1, 2)
+
+	incArgs := incrementArgs([]byte("a"), 5)
+	if _, err := client.SendWrapped(context.Background(), mtc.stores[0].TestSender(), incArgs); err != nil {
+		t.Fatal(err)
+	}
+	mtc.waitForValues(roachpb.Key("a"), []int64{5, 5, 5})
+
+	repls := make([]*storage.Replica, len(mtc.stores))
+	for i, s := range mtc.stores {
+		var err error
+		if repls[i], err = s.GetReplica(rangeID); err != nil {
+			t.Fatal(err)
+		}
+		if sent, recv := repls[i].RaftMessageRates(); sent <= 0 || recv <= 0 {
+			t.Fatalf("s%d: expected nonzero raft message rates, got sent=%f recv=%f",
+				s.StoreID(), sent, recv)
+		}
+	}
+
+	// Once the range is quiesced, its replicas neither send nor receive any
+	// Raft messages.
+	testutils.SucceedsSoon(t, func() error {
+		for _, repl := range repls {
+			repl.ResetRaftMessageRates()
+		}
+		for _, repl := range repls {
+			if !repl.IsQuiescent() {
+				return errors.Errorf("%s not quiescent", repl)
+			}
+		}
+		for _, repl := range repls {
+			if sent, recv := repl.RaftMessageRates(); sent != 0 || recv != 0 {
+				return errors.Errorf("%s: expected zero raft message rates, got sent=%f recv=%f",
+					repl, sent, recv)
+			}
+		}
+		return nil
+	})
+}
+
+// TestReplicaFollowerProgress verifies that the Raft leader reports the
+// progress of its followers, and that the match index of a follower that was
+// down catches up with the leader's once the follower is restarted.
+func TestReplicaFollowerProgress(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	mtc := &multiTestContext{startWithSingleRange: true}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	const rangeID = roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1, 2)
+	key := roachpb.Key("a")
+	if _, err := mtc.dbs[0].Inc(ctx, key, 1); err != nil {
+		t.Fatal(err)
+	}
+	mtc.waitForValues(key, []int64{1, 1, 1})
+
+	leaderRepl := mtc.getRaftLeader(rangeID)
+	leaderIdx := int(leaderRepl.StoreID() - 1)
+	followerIdx := (leaderIdx + 1) % len(mtc.stores)
+	followerRepl, err := mtc.stores[followerIdx].GetReplica(rangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	followerID := followerRepl.ReplicaID()
+
+	// leaderMatch returns the match index of the leader itself, i.e. the index
+	// of the last entry in its log.
+	leaderMatch := func() uint64 {
+		status := leaderRepl.RaftStatus()
+		return status.Progress[uint64(leaderRepl.ReplicaID())].Match
+	}
+	progress := leaderRepl.FollowerProgress()
+	if len(progress) != 2 {
+		t.Fatalf("expected progress for 2 followers, found %+v", progress)
+	}
+	if _, ok := progress[leaderRepl.ReplicaID()]; ok {
+		t.Fatalf("expected no progress for the leader itself, found %+v", progress)
+	}
+	if followerProgress := followerRepl.FollowerProgress(); followerProgress != nil {
+		t.Fatalf("expected no progress on a follower, found %+v", followerProgress)
+	}
+
+	// Stop the follower and write to the range, so that the follower falls
+	// behind the leader.
+	mtc.stopStore(followerIdx)
+	for i := 0; i < 5; i++ {
+		if _, err := mtc.dbs[leaderIdx].Inc(ctx, key, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, match := leaderRepl.FollowerProgress()[followerID], leaderMatch(); p.Match >= match {
+		t.Fatalf("expected stopped follower to lag behind leader match index %d, found %+v", match, p)
+	}
+
+	// Once healed, the follower catches up with the leader.
+	mtc.restartStore(followerIdx)
+	testutils.SucceedsSoon(t, func() error {
+		p, match := leaderRepl.FollowerProgress()[followerID], leaderMatch()
+		if p.Match != match {
+			return errors.Errorf("expected follower match index %d, found %+v", match, p)
+		}
+		if !p.RecentActive {
+			return errors.Errorf("expected follower to be recently active, found %+v", p)
+		}
+		return nil
+	})
+}
+
 // TestInitRaftGroupOnRequest verifies that an uninitialized Raft group
 // is initialized if a request is received, even if the current range
 // lease points to a different replica.
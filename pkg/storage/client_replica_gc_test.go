@@ -183,3 +183,53 @@ func TestReplicaGCQueueDropReplicaGCOnScan(t *testing.T) {
 		return nil
 	})
 }
+
+// TestReplicaGCQueueRecordsDecisions verifies that the replica GC queue
+// records a decision when it removes a replica which is no longer part of its
+// range.
+func TestReplicaGCQueueRecordsDecisions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+	store := mtc.stores[1]
+	// Disable the replica gc queue so that the replica is only removed once
+	// we force it to process below.
+	store.SetReplicaGCQueueActive(false)
+
+	rangeID := roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1, 2)
+	mtc.unreplicateRange(rangeID, 1)
+
+	if decisions := store.RecentReplicaGCDecisions(10); len(decisions) != 0 {
+		t.Fatalf("expected no decisions while queue is disabled, got %+v", decisions)
+	}
+
+	store.SetReplicaGCQueueActive(true)
+	mtc.advanceClock(context.TODO())
+	mtc.manualClock.Increment(int64(storage.ReplicaGCQueueInactivityThreshold + 1))
+
+	testutils.SucceedsSoon(t, func() error {
+		store.MustForceReplicaGCScanAndProcess()
+		for _, d := range store.RecentReplicaGCDecisions(10) {
+			if d.RangeID != rangeID {
+				continue
+			}
+			if !d.Collected {
+				return errors.Errorf("expected r%d to be collected: %+v", rangeID, d)
+			}
+			if exp := "replica removed from range descriptor"; d.Reason != exp {
+				return errors.Errorf("expected reason %q, got %q", exp, d.Reason)
+			}
+			if d.At.IsZero() {
+				return errors.Errorf("expected decision timestamp to be set: %+v", d)
+			}
+			return nil
+		}
+		return errors.Errorf("no decision recorded for r%d", rangeID)
+	})
+	if _, err := store.GetReplica(rangeID); !testutils.IsError(err, "r[0-9]+ was not found") {
+		t.Fatalf("expected range removal: %v", err)
+	}
+}
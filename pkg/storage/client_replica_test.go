@@ -1791,6 +1791,58 @@ func TestSystemZoneConfigs(t *testing.T) {
 	log.Info(ctx, "TestSystemZoneConfig: up-replication of system ranges succeeded")
 }
 
+// TestReplicaEffectiveReplicationFactor verifies that a range which hasn't
+// been up-replicated yet reports fewer voters than its zone config asks for,
+// along with the kind of zone that config comes from.
+func TestReplicaEffectiveReplicationFactor(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	tc := testcluster.StartTestCluster(t, 3, base.TestClusterArgs{
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+
+	sqlDB := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (k INT PRIMARY KEY)`)
+	var tableID uint32
+	sqlDB.QueryRow(t, `SELECT table_id FROM crdb_internal.tables WHERE name = 't'`).Scan(&tableID)
+	tableKey := roachpb.Key(keys.MakeTablePrefix(tableID))
+	tc.SplitRangeOrFatal(t, tableKey)
+
+	s := tc.Server(0)
+	store, err := s.GetStores().(*storage.Stores).GetStore(s.GetFirstStoreID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repl := store.LookupReplica(roachpb.RKey(tableKey))
+
+	expect := func(expConfigured, expActual int32, expSource string) {
+		t.Helper()
+		testutils.SucceedsSoon(t, func() error {
+			configured, actual, source := repl.EffectiveReplicationFactor()
+			if configured != expConfigured || actual != expActual || source != expSource {
+				return errors.Errorf("expected (%d, %d, %q), got (%d, %d, %q)",
+					expConfigured, expActual, expSource, configured, actual, source)
+			}
+			return nil
+		})
+	}
+
+	expect(3, 1, config.ZoneSourceDefault)
+	sqlutils.SetZoneConfig(t, sqlDB, "DATABASE d", "num_replicas: 5")
+	expect(5, 1, config.ZoneSourceDatabase)
+	sqlutils.SetZoneConfig(t, sqlDB, "TABLE d.t", "num_replicas: 3")
+	expect(3, 1, config.ZoneSourceTable)
+
+	// Up-replicate the range one replica at a time.
+	tc.AddReplicasOrFatal(t, tableKey, tc.Target(1))
+	expect(3, 2, config.ZoneSourceTable)
+	tc.AddReplicasOrFatal(t, tableKey, tc.Target(2))
+	expect(3, 3, config.ZoneSourceTable)
+}
+
 func TestClearRange(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -2275,3 +2327,124 @@ func makeReplicationTargets(ids ...int) (targets []roachpb.ReplicationTarget) {
 	}
 	return targets
 }
+
+// TestReplicaLatencyPercentiles verifies that slow reads on one range raise
+// its read latency percentiles without affecting those of another range.
+func TestReplicaLatencyPercentiles(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const slowDelay = 50 * time.Millisecond
+	slowKey := roachpb.Key("b-slow")
+	fastKey := roachpb.Key("a-fast")
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableMergeQueue = true
+	sc.TestingKnobs.EvalKnobs.TestingEvalFilter =
+		func(filterArgs storagebase.FilterArgs) *roachpb.Error {
+			if _, ok := filterArgs.Req.(*roachpb.GetRequest); ok &&
+				filterArgs.Req.Header().Key.Equal(slowKey) {
+				time.Sleep(slowDelay)
+			}
+			return nil
+		}
+	mtc := &multiTestContext{storeConfig: &sc}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.stores[0]
+	ctx := context.Background()
+
+	// Isolate both keys on ranges of their own.
+	for _, splitKey := range []roachpb.Key{roachpb.Key("a"), roachpb.Key("b")} {
+		splitArgs := adminSplitArgs(splitKey)
+		if _, pErr := client.SendWrapped(ctx, mtc.distSenders[0], splitArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	slowRepl := store.LookupReplica(roachpb.RKey(slowKey))
+	fastRepl := store.LookupReplica(roachpb.RKey(fastKey))
+	if slowRepl.RangeID == fastRepl.RangeID {
+		t.Fatalf("expected %s and %s on different ranges", slowKey, fastKey)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := mtc.dbs[0].Get(ctx, fastKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fast := fastRepl.LatencyPercentiles()
+	if fast.ReadP50 == 0 || fast.ReadP99 >= slowDelay {
+		t.Fatalf("unexpected read latencies for fast range: %+v", fast)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := mtc.dbs[0].Get(ctx, slowKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if slow := slowRepl.LatencyPercentiles(); slow.ReadP50 < slowDelay || slow.ReadP99 < slowDelay {
+		t.Fatalf("expected read latencies of at least %s for slow range, got %+v", slowDelay, slow)
+	}
+	if a, e := fastRepl.LatencyPercentiles(), fast; a != e {
+		t.Fatalf("expected latencies of fast range to remain %+v, got %+v", e, a)
+	}
+}
+
+// TestReplicaByteThroughput verifies that a range serving reads of large
+// values reports a much higher read byte throughput than a range serving reads
+// of small values.
+func TestReplicaByteThroughput(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	largeKey := roachpb.Key("b-large")
+	smallKey := roachpb.Key("a-small")
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableMergeQueue = true
+	mtc := &multiTestContext{storeConfig: &sc}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.stores[0]
+	ctx := context.Background()
+
+	// Isolate both keys on ranges of their own.
+	for _, splitKey := range []roachpb.Key{roachpb.Key("a"), roachpb.Key("b")} {
+		splitArgs := adminSplitArgs(splitKey)
+		if _, pErr := client.SendWrapped(ctx, mtc.distSenders[0], splitArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	largeRepl := store.LookupReplica(roachpb.RKey(largeKey))
+	smallRepl := store.LookupReplica(roachpb.RKey(smallKey))
+	if largeRepl.RangeID == smallRepl.RangeID {
+		t.Fatalf("expected %s and %s on different ranges", largeKey, smallKey)
+	}
+
+	const largeSize = 1 << 20
+	if err := mtc.dbs[0].Put(ctx, largeKey, bytes.Repeat([]byte("x"), largeSize)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mtc.dbs[0].Put(ctx, smallKey, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, write := largeRepl.ByteThroughput(); write <= 0 {
+		t.Fatalf("expected positive write byte throughput for large range, got %f", write)
+	}
+
+	for i := 0; i < 10; i++ {
+		for _, key := range []roachpb.Key{largeKey, smallKey} {
+			if _, err := mtc.dbs[0].Get(ctx, key); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	largeRead, _ := largeRepl.ByteThroughput()
+	smallRead, _ := smallRepl.ByteThroughput()
+	if largeRead <= 0 || smallRead <= 0 {
+		t.Fatalf("expected positive read byte throughput, got %f (large) and %f (small)",
+			largeRead, smallRead)
+	}
+	// Both ranges served the same number of reads, so their throughput differs
+	// by roughly the ratio of the value sizes.
+	if largeRead < 100*smallRead {
+		t.Fatalf("expected read byte throughput of large range (%f) to dwarf that of small range (%f)",
+			largeRead, smallRead)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -39,6 +40,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/stateloader"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/storage/txnwait"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/testcluster"
@@ -55,6 +57,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/gogo/protobuf/proto"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
@@ -99,6 +102,59 @@ func TestStoreRangeSplitAtIllegalKeys(t *testing.T) {
 	}
 }
 
+// TestStoreRangeSplitMergeResponseDescriptors verifies that AdminSplit and
+// AdminMerge responses contain the descriptors committed by the split or
+// merge.
+func TestStoreRangeSplitMergeResponseDescriptors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+
+	cfg := storage.TestStoreConfig(nil)
+	cfg.TestingKnobs.DisableSplitQueue = true
+	cfg.TestingKnobs.DisableMergeQueue = true
+	store := createTestStoreWithConfig(t, stopper, cfg)
+	ctx := context.Background()
+
+	lookupDesc := func(key roachpb.Key) roachpb.RangeDescriptor {
+		t.Helper()
+		desc := store.LookupReplica(roachpb.RKey(key)).Desc()
+		var dbDesc roachpb.RangeDescriptor
+		if err := store.DB().GetProto(ctx, keys.RangeDescriptorKey(desc.StartKey), &dbDesc); err != nil {
+			t.Fatal(err)
+		}
+		if !dbDesc.Equal(desc) {
+			t.Fatalf("expected in-memory descriptor %+v to equal persisted %+v", desc, dbDesc)
+		}
+		return dbDesc
+	}
+
+	splitKey := roachpb.Key("m")
+	resp, pErr := client.SendWrapped(ctx, store.TestSender(), adminSplitArgs(splitKey))
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	splitResp := resp.(*roachpb.AdminSplitResponse)
+	if leftDesc := lookupDesc(roachpb.Key("a")); !leftDesc.Equal(splitResp.LeftDesc) {
+		t.Errorf("expected left descriptor %+v, got %+v", leftDesc, splitResp.LeftDesc)
+	}
+	if rightDesc := lookupDesc(splitKey); !rightDesc.Equal(splitResp.RightDesc) {
+		t.Errorf("expected right descriptor %+v, got %+v", rightDesc, splitResp.RightDesc)
+	}
+	if !splitResp.LeftDesc.EndKey.Equal(splitResp.RightDesc.StartKey) {
+		t.Errorf("expected adjacent descriptors, got %+v and %+v", splitResp.LeftDesc, splitResp.RightDesc)
+	}
+
+	resp, pErr = client.SendWrapped(ctx, store.TestSender(), adminMergeArgs(roachpb.Key("a")))
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	mergeResp := resp.(*roachpb.AdminMergeResponse)
+	if mergedDesc := lookupDesc(roachpb.Key("a")); !mergedDesc.Equal(mergeResp.MergedDesc) {
+		t.Errorf("expected merged descriptor %+v, got %+v", mergedDesc, mergeResp.MergedDesc)
+	}
+}
+
 // Verify that on a split, only the non-expired abort span records are copied
 // into the right hand side of the split.
 func TestStoreSplitAbortSpan(t *testing.T) {
@@ -405,6 +461,151 @@ func TestStoreRangeSplitIntents(t *testing.T) {
 	}
 }
 
+// TestStoreRangeSplitPendingDescriptorChange verifies that while a split txn
+// is in flight, the range reports a pending descriptor change, and that it
+// stops doing so once the split commits.
+func TestStoreRangeSplitPendingDescriptorChange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	splitKey := roachpb.Key("m")
+	inFilter := make(chan struct{})
+	unblock := make(chan struct{})
+	storeCfg := storage.TestStoreConfig(nil)
+	storeCfg.TestingKnobs.DisableSplitQueue = true
+	storeCfg.TestingKnobs.DisableMergeQueue = true
+	storeCfg.TestingKnobs.TestingRequestFilter = func(ba roachpb.BatchRequest) *roachpb.Error {
+		if et, ok := ba.GetArg(roachpb.EndTransaction); ok {
+			trigger := et.(*roachpb.EndTransactionRequest).InternalCommitTrigger
+			if trigger.GetSplitTrigger() != nil && trigger.SplitTrigger.RightDesc.StartKey.Equal(splitKey) {
+				inFilter <- struct{}{}
+				<-unblock
+			}
+		}
+		return nil
+	}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, storeCfg)
+
+	repl := store.LookupReplica(roachpb.RKey(splitKey))
+	if kind, _, ok := repl.PendingDescriptorChange(); ok {
+		t.Fatalf("unexpected pending %s before split", kind)
+	}
+
+	splitErr := make(chan error, 1)
+	go func() {
+		_, pErr := client.SendWrapped(context.Background(), store.TestSender(), adminSplitArgs(splitKey))
+		splitErr <- pErr.GoError()
+	}()
+	<-inFilter
+
+	kind, txnID, ok := repl.PendingDescriptorChange()
+	if !ok {
+		t.Fatal("expected a pending descriptor change during the split")
+	}
+	if kind != "split" {
+		t.Errorf("expected pending split, got %s", kind)
+	}
+	if txnID == (uuid.UUID{}) {
+		t.Errorf("expected pending change to report the split txn ID")
+	}
+
+	close(unblock)
+	if err := <-splitErr; err != nil {
+		t.Fatal(err)
+	}
+	if kind, _, ok := repl.PendingDescriptorChange(); ok {
+		t.Fatalf("unexpected pending %s after split", kind)
+	}
+}
+
+// TestStoreAbortPendingDescriptorChange verifies that AbortPendingDescriptorChange
+// refuses to abort a split txn that is still heartbeating, and that once the
+// txn is abandoned it aborts it and restores the original descriptor.
+func TestStoreAbortPendingDescriptorChange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	splitKey := roachpb.Key("m")
+	inFilter := make(chan struct{})
+	unblock := make(chan struct{})
+	var blockOnce sync.Once
+	var abandoned int32
+	manual := hlc.NewManualClock(123)
+	storeCfg := storage.TestStoreConfig(hlc.NewClock(manual.UnixNano, time.Nanosecond))
+	storeCfg.TestingKnobs.DisableSplitQueue = true
+	storeCfg.TestingKnobs.DisableMergeQueue = true
+	storeCfg.TestingKnobs.TestingRequestFilter = func(ba roachpb.BatchRequest) *roachpb.Error {
+		if ba.Txn == nil || ba.Txn.Name != "split" {
+			return nil
+		}
+		// Once the split txn is abandoned, its coordinator can no longer
+		// heartbeat it.
+		if _, ok := ba.GetArg(roachpb.HeartbeatTxn); ok && atomic.LoadInt32(&abandoned) == 1 {
+			return roachpb.NewErrorf("injected heartbeat failure")
+		}
+		if et, ok := ba.GetArg(roachpb.EndTransaction); ok {
+			trigger := et.(*roachpb.EndTransactionRequest).InternalCommitTrigger
+			if trigger.GetSplitTrigger() != nil && trigger.SplitTrigger.RightDesc.StartKey.Equal(splitKey) {
+				blockOnce.Do(func() {
+					inFilter <- struct{}{}
+					<-unblock
+				})
+			}
+		}
+		return nil
+	}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithOpts(t, testStoreOpts{cfg: &storeCfg}, stopper)
+
+	repl := store.LookupReplica(roachpb.RKey(splitKey))
+	origDesc := *repl.Desc()
+	if err := store.AbortPendingDescriptorChange(context.Background(), repl.RangeID); !testutils.IsError(
+		err, "no pending descriptor change",
+	) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	splitErr := make(chan error, 1)
+	go func() {
+		_, pErr := client.SendWrapped(context.Background(), store.TestSender(), adminSplitArgs(splitKey))
+		splitErr <- pErr.GoError()
+	}()
+	<-inFilter
+
+	// The split txn is still being heartbeat, so it must be left alone.
+	if err := store.AbortPendingDescriptorChange(context.Background(), repl.RangeID); !testutils.IsError(
+		err, "may still be live",
+	) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, ok := repl.PendingDescriptorChange(); !ok {
+		t.Fatal("expected the split to still be pending")
+	}
+
+	// Abandon the split txn and wait out its liveness threshold.
+	atomic.StoreInt32(&abandoned, 1)
+	manual.Increment(txnwait.TxnLivenessThreshold.Nanoseconds() + 1)
+	if err := store.AbortPendingDescriptorChange(context.Background(), repl.RangeID); err != nil {
+		t.Fatal(err)
+	}
+	if kind, _, ok := repl.PendingDescriptorChange(); ok {
+		t.Fatalf("unexpected pending %s after abort", kind)
+	}
+	var desc roachpb.RangeDescriptor
+	if err := store.DB().GetProto(
+		context.Background(), keys.RangeDescriptorKey(origDesc.StartKey), &desc,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !desc.Equal(origDesc) {
+		t.Fatalf("expected descriptor %s to be restored, got %s", &origDesc, &desc)
+	}
+
+	// Let the split proceed. Its txn was aborted, so it either fails or
+	// succeeds on a retry; either way it must not hang.
+	close(unblock)
+	<-splitErr
+}
+
 // TestStoreRangeSplitAtRangeBounds verifies that attempting to
 // split a range at its start key is a no-op and does not actually
 // perform a split (would create zero-length range!). This sort
@@ -2655,6 +2856,48 @@ func TestUnsplittableRange(t *testing.T) {
 	})
 }
 
+// TestSplitQueueTestingPurgatory verifies that a range placed into the split
+// queue's purgatory via TestingEnqueueSplitPurgatory stays there until the
+// purgatory is signaled, at which point it is retried and, since it doesn't
+// need to be split, leaves purgatory.
+func TestSplitQueueTestingPurgatory(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+
+	splitQueuePurgatoryChan := make(chan time.Time, 1)
+	cfg := storage.TestStoreConfig(nil)
+	cfg.TestingKnobs.SplitQueuePurgatoryChan = splitQueuePurgatoryChan
+	cfg.TestingKnobs.DisableMergeQueue = true
+	// Keep the scanner from queueing the range concurrently.
+	cfg.TestingKnobs.DisableScanner = true
+	store := createTestStoreWithConfig(t, stopper, cfg)
+
+	repl := store.LookupReplica(keys.MakeTablePrefix(keys.UITableID))
+	if err := store.TestingEnqueueSplitPurgatory(repl.RangeID, errors.New("injected")); err != nil {
+		t.Fatal(err)
+	}
+	if purgLen := store.SplitQueuePurgatoryLength(); purgLen != 1 {
+		t.Fatalf("expected split queue purgatory to contain 1 replica, found %d", purgLen)
+	}
+	// A range can't be placed into purgatory twice.
+	if err := store.TestingEnqueueSplitPurgatory(repl.RangeID, errors.New("injected")); !testutils.IsError(err, "already queued") {
+		t.Fatalf("expected already queued error, got %v", err)
+	}
+
+	// Signal the split queue's purgatory channel and ensure that the range is
+	// retried and removed from purgatory.
+	splitQueuePurgatoryChan <- timeutil.Now()
+	testutils.SucceedsSoon(t, func() error {
+		if purgLen := store.SplitQueuePurgatoryLength(); purgLen != 0 {
+			return errors.Errorf("expected split queue purgatory to be empty, found %d", purgLen)
+		}
+		return nil
+	})
+}
+
 // TestTxnWaitQueueDependencyCycleWithRangeSplit verifies that a range
 // split which occurs while a dependency cycle is partially underway
 // will cause the pending push txns to be retried such that they
@@ -26,6 +26,7 @@ import (
 	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -48,6 +49,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/rditer"
 	"github.com/cockroachdb/cockroach/pkg/storage/stateloader"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -277,6 +279,11 @@ type multiTestContext struct {
 	transportStopper *stop.Stopper
 	engineStoppers   []*stop.Stopper
 
+	// applyDelays holds the delay, in nanoseconds, injected before each
+	// command is applied on the corresponding store. Accessed atomically.
+	// See throttleStore.
+	applyDelays []int64
+
 	// The fields below may mutate at runtime so the pointers they contain are
 	// protected by 'mu'.
 	mu             *syncutil.RWMutex
@@ -330,6 +337,7 @@ func (m *multiTestContext) Start(t testing.TB, numStores int) {
 	m.grpcServers = make([]*grpc.Server, numStores)
 	m.gossips = make([]*gossip.Gossip, numStores)
 	m.nodeLivenesses = make([]*storage.NodeLiveness, numStores)
+	m.applyDelays = make([]int64, numStores)
 
 	if m.manualClock == nil {
 		m.manualClock = hlc.NewManualClock(123)
@@ -704,7 +712,9 @@ func (m *multiTestContext) makeStoreConfig(i int) storage.StoreConfig {
 		cfg.Clock = m.clocks[i]
 	} else {
 		cfg = storage.TestStoreConfig(m.clocks[i])
-		m.storeConfig = &cfg
+		// Don't alias cfg, which is customized for store i below.
+		sc := cfg
+		m.storeConfig = &sc
 	}
 	cfg.NodeDialer = m.nodeDialer
 	cfg.Transport = m.transport
@@ -712,6 +722,16 @@ func (m *multiTestContext) makeStoreConfig(i int) storage.StoreConfig {
 	cfg.TestingKnobs.DisableMergeQueue = true
 	cfg.TestingKnobs.DisableSplitQueue = true
 	cfg.TestingKnobs.ReplicateQueueAcceptsUnsplit = true
+	applyFilter := cfg.TestingKnobs.TestingApplyFilter
+	cfg.TestingKnobs.TestingApplyFilter = func(args storagebase.ApplyFilterArgs) (int, *roachpb.Error) {
+		if delay := atomic.LoadInt64(&m.applyDelays[i]); delay > 0 {
+			time.Sleep(time.Duration(delay))
+		}
+		if applyFilter != nil {
+			return applyFilter(args)
+		}
+		return 0, nil
+	}
 	return cfg
 }
 
@@ -1050,6 +1070,20 @@ func (m *multiTestContext) restartStore(i int) {
 	})
 }
 
+// throttleStore slows down store i by delaying the application of every
+// command on it by applyDelay. Since application happens on the store's Raft
+// processing goroutine, this makes the store's replicas lag behind as slow
+// followers would. The throttle persists across restarts until
+// unthrottleStore is called.
+func (m *multiTestContext) throttleStore(i int, applyDelay time.Duration) {
+	atomic.StoreInt64(&m.applyDelays[i], applyDelay.Nanoseconds())
+}
+
+// unthrottleStore removes the throttle installed by throttleStore.
+func (m *multiTestContext) unthrottleStore(i int) {
+	atomic.StoreInt64(&m.applyDelays[i], 0)
+}
+
 func (m *multiTestContext) Store(i int) *storage.Store {
 	m.mu.Lock()
 	defer m.mu.Unlock()
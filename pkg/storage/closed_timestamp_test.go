@@ -95,6 +95,101 @@ func TestClosedTimestampCanServe(t *testing.T) {
 	}
 }
 
+// TestClosedTimestampInfo verifies that Replica.ClosedTimestampInfo reports
+// the configured lead time and a closed timestamp trailing the current time
+// by approximately that lead time.
+func TestClosedTimestampInfo(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	if util.RaceEnabled {
+		t.Skip("skipping under race")
+	}
+
+	ctx := context.Background()
+	tc, db0, _, repls := setupTestClusterForClosedTimestampTesting(ctx, t, testingTargetDuration)
+	defer tc.Stopper().Stop(ctx)
+
+	if _, err := db0.Exec(`INSERT INTO cttest.kv VALUES(1, $1)`, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closed timestamps are advanced every closeFraction*testingTargetDuration,
+	// so allow them to trail the target by a few of those intervals in addition
+	// to the time it takes to propagate them to the followers.
+	const slack = 5 * time.Second
+	testutils.SucceedsSoon(t, func() error {
+		for _, repl := range repls {
+			closed, policy, leadTime := repl.ClosedTimestampInfo()
+			if policy != storage.ClosedTimestampPolicyLagByTargetDuration {
+				return errors.Errorf("unexpected policy %q", policy)
+			}
+			if leadTime != testingTargetDuration {
+				return errors.Errorf("expected lead time %s, got %s", testingTargetDuration, leadTime)
+			}
+			now := tc.Server(0).Clock().Now()
+			if lag := time.Duration(now.WallTime - closed.WallTime); lag < leadTime {
+				return errors.Errorf("closed timestamp %s lags %s by %s, less than lead time %s",
+					closed, now, lag, leadTime)
+			} else if lag > leadTime+slack {
+				return errors.Errorf("closed timestamp %s lags %s by %s, more than %s",
+					closed, now, lag, leadTime+slack)
+			}
+		}
+		return nil
+	})
+}
+
+// TestClosedTimestampFollowerReadReportsServingReplica verifies that a follower
+// read which requests it reports the descriptor of the follower that served it.
+func TestClosedTimestampFollowerReadReportsServingReplica(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	if util.RaceEnabled {
+		// Limiting how long transactions can run does not work
+		// well with race unless we're extremely lenient, which
+		// drives up the test duration.
+		t.Skip("skipping under race")
+	}
+
+	ctx := context.Background()
+	tc, db0, desc, repls := setupTestClusterForClosedTimestampTesting(ctx, t, testingTargetDuration)
+	defer tc.Stopper().Stop(ctx)
+
+	if _, err := db0.Exec(`INSERT INTO cttest.kv VALUES(1, $1)`, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	lh := getCurrentLeaseholder(t, tc, desc)
+	var follower *storage.Replica
+	for _, repl := range repls {
+		if repl.StoreID() != lh.StoreID {
+			follower = repl
+			break
+		}
+	}
+	expDesc, err := follower.GetReplicaDescriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+	baRead := makeReadBatchRequestForDesc(desc, ts)
+	baRead.ReturnServingReplica = true
+	testutils.SucceedsSoon(t, func() error {
+		br, pErr := follower.Send(ctx, baRead)
+		if pErr != nil {
+			return pErr.GoError()
+		}
+		if br.ServingReplica == nil {
+			return errors.New("expected serving replica to be reported")
+		}
+		if sr := br.ServingReplica; sr.StoreID != expDesc.StoreID || sr.ReplicaID != expDesc.ReplicaID {
+			return errors.Errorf("expected serving replica %s, got %s", expDesc, br.ServingReplica)
+		}
+		return nil
+	})
+}
+
 // TestClosedTimestampCanServerThroughoutLeaseTransfer verifies that lease
 // transfers does not prevent reading a value from a follower that was
 // previously readable.
@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/logtags"
@@ -43,6 +44,10 @@ type Compactor struct {
 	doneFn  doneCompactingFunc
 	ch      chan struct{}
 	Metrics Metrics
+
+	// processMu serializes the processing of suggested compactions, which
+	// happens both periodically and on demand.
+	processMu syncutil.Mutex
 }
 
 // NewCompactor returns a compactor for the specified storage engine.
@@ -143,7 +148,7 @@ func (c *Compactor) Start(ctx context.Context, stopper *stop.Stopper) {
 
 				case <-timer.C:
 					timer.Read = true
-					ok, err := c.processSuggestions(ctx)
+					ok, _, err := c.processSuggestions(ctx)
 					if err != nil {
 						log.Warningf(ctx, "failed processing suggested compactions: %+v", err)
 					}
@@ -199,14 +204,18 @@ func (aggr aggregatedCompaction) String() string {
 // exceed the absolute or fractional size thresholds. If suggested
 // compactions don't meet thresholds, they're discarded if they're
 // older than maxSuggestedCompactionRecordAge. Returns a boolean
-// indicating whether the queue was successfully processed.
-func (c *Compactor) processSuggestions(ctx context.Context) (bool, error) {
+// indicating whether the queue was successfully processed, along with
+// the number of suggested compactions which were compacted.
+func (c *Compactor) processSuggestions(ctx context.Context) (bool, int, error) {
+	c.processMu.Lock()
+	defer c.processMu.Unlock()
+
 	ctx, cleanup := tracing.EnsureContext(ctx, c.st.Tracer, "process suggested compactions")
 	defer cleanup()
 
 	suggestions, totalBytes, err := c.fetchSuggestions(ctx)
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
 	// Update at start of processing. Note that totalBytes is decremented and
@@ -214,7 +223,7 @@ func (c *Compactor) processSuggestions(ctx context.Context) (bool, error) {
 	c.Metrics.BytesQueued.Update(totalBytes)
 
 	if len(suggestions) == 0 {
-		return false, nil
+		return false, 0, nil
 	}
 
 	log.Eventf(ctx, "considering %d suggested compaction(s)", len(suggestions))
@@ -224,7 +233,7 @@ func (c *Compactor) processSuggestions(ctx context.Context) (bool, error) {
 	// and the time since the last processing.
 	capacity, err := c.capFn()
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
 	// Get information about SSTables in the underlying RocksDB instance.
@@ -254,13 +263,15 @@ func (c *Compactor) processSuggestions(ctx context.Context) (bool, error) {
 	// aggregation. Aggregates which exceed size thresholds are compacted. Small,
 	// isolated suggestions will be ignored until becoming too old, at which
 	// point they are discarded without compaction.
+	var processed int
 	aggr := initAggregatedCompaction(0, len(suggestions), suggestions[0])
 	for i, sc := range suggestions[1:] {
 		// Aggregate current suggestion with running aggregate if possible. If
 		// the current suggestion cannot be merged with the aggregate, process
 		// it if it meets compaction thresholds.
 		if done := c.aggregateCompaction(ctx, ssti, &aggr, sc); done {
-			processedBytes, err := c.processCompaction(ctx, aggr, capacity)
+			processedBytes, n, err := c.processCompaction(ctx, aggr, capacity)
+			processed += n
 			if err != nil {
 				log.Errorf(ctx, "failed processing suggested compactions %+v: %+v", aggr, err)
 			} else if err := updateBytesQueued(processedBytes); err != nil {
@@ -271,15 +282,25 @@ func (c *Compactor) processSuggestions(ctx context.Context) (bool, error) {
 		}
 	}
 	// Process remaining aggregated compaction.
-	processedBytes, err := c.processCompaction(ctx, aggr, capacity)
+	processedBytes, n, err := c.processCompaction(ctx, aggr, capacity)
+	processed += n
 	if err != nil {
-		return false, err
+		return false, processed, err
 	}
 	if err := updateBytesQueued(processedBytes); err != nil {
 		log.Errorf(ctx, "failed updating bytes queued metric %+v", err)
 	}
 
-	return true, nil
+	return true, processed, nil
+}
+
+// ProcessSuggestions synchronously processes the suggested compactions instead
+// of waiting for the periodic processing to pick them up. The same thresholds
+// apply as for the periodic processing. Returns the number of suggested
+// compactions which were compacted.
+func (c *Compactor) ProcessSuggestions(ctx context.Context) (int, error) {
+	_, processed, err := c.processSuggestions(ctx)
+	return processed, err
 }
 
 // fetchSuggestions loads the persisted suggested compactions from the store.
@@ -350,10 +371,11 @@ func (c *Compactor) fetchSuggestions(
 // the compaction or skips the compaction *and* deletes the suggested compaction
 // records if they're too old (and in particular, if the compactor is disabled,
 // deletes any suggestions handed to it). Returns the number of bytes processed
-// (either compacted or skipped and deleted due to age).
+// (either compacted or skipped and deleted due to age) and the number of
+// suggestions compacted.
 func (c *Compactor) processCompaction(
 	ctx context.Context, aggr aggregatedCompaction, capacity roachpb.StoreCapacity,
-) (int64, error) {
+) (int64, int, error) {
 	aboveSizeThresh := aggr.Bytes >= c.thresholdBytes()
 	aboveUsedFracThresh := func() bool {
 		thresh := c.thresholdBytesUsedFraction()
@@ -374,7 +396,7 @@ func (c *Compactor) processCompaction(
 
 		if err := c.eng.CompactRange(aggr.StartKey, aggr.EndKey, false /* forceBottommost */); err != nil {
 			c.Metrics.CompactionFailures.Inc(1)
-			return 0, errors.Wrapf(err, "unable to compact range %+v", aggr)
+			return 0, 0, errors.Wrapf(err, "unable to compact range %+v", aggr)
 		}
 		c.Metrics.BytesCompacted.Inc(aggr.Bytes)
 		c.Metrics.CompactionSuccesses.Inc(1)
@@ -413,9 +435,9 @@ func (c *Compactor) processCompaction(
 	delBatch.Close()
 
 	if shouldProcess {
-		return aggr.Bytes, nil
+		return aggr.Bytes, len(aggr.suggestions), nil
 	}
-	return 0, nil
+	return 0, 0, nil
 }
 
 // aggregateCompaction merges sc into aggr, to create a new suggested
@@ -461,7 +483,18 @@ func (c *Compactor) aggregateCompaction(
 // examineQueue returns the total number of bytes queued and updates the
 // BytesQueued gauge.
 func (c *Compactor) examineQueue(ctx context.Context) (int64, error) {
-	var totalBytes int64
+	_, totalBytes, err := c.Backlog(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.Metrics.BytesQueued.Update(totalBytes)
+	return totalBytes, nil
+}
+
+// Backlog returns the number of persisted suggested compactions which have
+// not been processed yet, along with the total number of bytes they are
+// expected to reclaim.
+func (c *Compactor) Backlog(ctx context.Context) (count int, totalBytes int64, _ error) {
 	if err := c.eng.Iterate(
 		engine.MVCCKey{Key: keys.LocalStoreSuggestedCompactionsMin},
 		engine.MVCCKey{Key: keys.LocalStoreSuggestedCompactionsMax},
@@ -470,14 +503,14 @@ func (c *Compactor) examineQueue(ctx context.Context) (int64, error) {
 			if err := protoutil.Unmarshal(kv.Value, &c); err != nil {
 				return false, err
 			}
+			count++
 			totalBytes += c.Bytes
 			return false, nil // continue iteration
 		},
 	); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	c.Metrics.BytesQueued.Update(totalBytes)
-	return totalBytes, nil
+	return count, totalBytes, nil
 }
 
 // Suggest writes the specified compaction to persistent storage and
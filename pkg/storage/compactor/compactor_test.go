@@ -778,3 +778,104 @@ func TestCompactorDisabled(t *testing.T) {
 		return nil
 	})
 }
+
+// TestCompactorBacklog verifies that the backlog reflects the suggested
+// compactions which have yet to be processed.
+func TestCompactorBacklog(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+
+	capacityFn := func() (roachpb.StoreCapacity, error) {
+		return roachpb.StoreCapacity{LogicalBytes: 100 * thresholdBytes.Default()}, nil
+	}
+	compactor, we, _, cleanup := testSetup(capacityFn)
+	defer cleanup()
+	// Keep the compactor from processing the suggestions on its own.
+	minInterval.Override(&compactor.st.SV, time.Hour)
+
+	checkBacklog := func(expCount int, expBytes int64) {
+		t.Helper()
+		count, bytes, err := compactor.Backlog(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != expCount || bytes != expBytes {
+			t.Fatalf("expected backlog of %d compactions (%d bytes); got %d (%d bytes)",
+				expCount, expBytes, count, bytes)
+		}
+	}
+
+	checkBacklog(0, 0)
+	for i, span := range [][2]string{{"a", "b"}, {"c", "d"}, {"e", "f"}} {
+		compactor.Suggest(ctx, storagepb.SuggestedCompaction{
+			StartKey: key(span[0]), EndKey: key(span[1]),
+			Compaction: storagepb.Compaction{
+				Bytes:            thresholdBytes.Default(),
+				SuggestedAtNanos: timeutil.Now().UnixNano(),
+			},
+		})
+		checkBacklog(i+1, int64(i+1)*thresholdBytes.Default())
+	}
+
+	if _, _, err := compactor.processSuggestions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(we.GetCompactions()) == 0 {
+		t.Fatal("expected compactions to have been processed")
+	}
+	checkBacklog(0, 0)
+}
+
+// TestCompactorProcessSuggestions verifies that processing suggested
+// compactions on demand clears the backlog.
+func TestCompactorProcessSuggestions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+
+	capacityFn := func() (roachpb.StoreCapacity, error) {
+		return roachpb.StoreCapacity{LogicalBytes: 100 * thresholdBytes.Default()}, nil
+	}
+	compactor, we, compactionCount, cleanup := testSetup(capacityFn)
+	defer cleanup()
+	// Keep the compactor from processing the suggestions on its own.
+	minInterval.Override(&compactor.st.SV, time.Hour)
+
+	for _, span := range [][2]string{{"a", "b"}, {"c", "d"}, {"e", "f"}} {
+		compactor.Suggest(ctx, storagepb.SuggestedCompaction{
+			StartKey: key(span[0]), EndKey: key(span[1]),
+			Compaction: storagepb.Compaction{
+				Bytes:            thresholdBytes.Default(),
+				SuggestedAtNanos: timeutil.Now().UnixNano(),
+			},
+		})
+	}
+	if count, _, err := compactor.Backlog(ctx); err != nil {
+		t.Fatal(err)
+	} else if count != 3 {
+		t.Fatalf("expected 3 suggested compactions; got %d", count)
+	}
+
+	processed, err := compactor.ProcessSuggestions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 3 {
+		t.Errorf("expected 3 processed suggestions; got %d", processed)
+	}
+	if a := atomic.LoadInt32(compactionCount); a == 0 {
+		t.Errorf("expected compactions to have been processed")
+	}
+	if len(we.GetCompactions()) == 0 {
+		t.Errorf("expected compactions to have been sent to the engine")
+	}
+	if count, bytes, err := compactor.Backlog(ctx); err != nil {
+		t.Fatal(err)
+	} else if count != 0 || bytes != 0 {
+		t.Fatalf("expected empty backlog; got %d compactions (%d bytes)", count, bytes)
+	}
+
+	// Processing an empty queue is a no-op.
+	if processed, err := compactor.ProcessSuggestions(ctx); err != nil || processed != 0 {
+		t.Fatalf("expected no processed suggestions; got %d, %v", processed, err)
+	}
+}
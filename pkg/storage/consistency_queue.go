@@ -18,10 +18,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
 	"github.com/cockroachdb/cockroach/pkg/util/grpcutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/pkg/errors"
 )
 
 var consistencyCheckInterval = settings.RegisterNonNegativeDurationSetting(
@@ -30,6 +32,32 @@ var consistencyCheckInterval = settings.RegisterNonNegativeDurationSetting(
 	24*time.Hour,
 )
 
+// EstimatedStatsRecomputeEnabled controls whether the consistency queue checks
+// ranges whose MVCC stats contain estimates (as left behind by splits or
+// AddSSTable) ahead of schedule once the estimates have settled, and whether
+// the stats recomputation triggered by the check clears the estimates.
+var EstimatedStatsRecomputeEnabled = settings.RegisterBoolSetting(
+	"kv.range.estimated_stats_recompute.enabled",
+	"if set, the consistency checker recomputes the stats of ranges containing estimates once they have settled",
+	false,
+)
+
+// EstimatedStatsRecomputeDelay is how long the stats of a range containing
+// estimates must remain unchanged before the range is checked ahead of
+// schedule. The delay avoids repeatedly recomputing the stats of a range under
+// active ingestion.
+var EstimatedStatsRecomputeDelay = settings.RegisterValidatedDurationSetting(
+	"kv.range.estimated_stats_recompute.delay",
+	"how long the stats of a range containing estimates must remain unchanged before they are recomputed",
+	10*time.Minute,
+	func(v time.Duration) error {
+		if v <= 0 {
+			return errors.Errorf("cannot set kv.range.estimated_stats_recompute.delay to a non-positive duration: %s", v)
+		}
+		return nil
+	},
+)
+
 var testingAggressiveConsistencyChecks = envutil.EnvOrDefaultBool("COCKROACH_CONSISTENCY_AGGRESSIVE", false)
 
 type consistencyQueue struct {
@@ -77,7 +105,11 @@ func (q *consistencyQueue) shouldQueue(
 			return false, 0
 		}
 		if shouldQ, priority = shouldQueueAgain(now, lpTS, interval); !shouldQ {
-			return false, 0
+			// Ranges whose stats contain settled estimates are checked ahead of
+			// schedule, as the check recomputes their stats.
+			if !hasSettledStatsEstimates(repl, now) {
+				return false, 0
+			}
 		}
 	}
 	// Check if all replicas are live. Some tests run without a NodeLiveness configured.
@@ -94,6 +126,24 @@ func (q *consistencyQueue) shouldQueue(
 	return true, priority
 }
 
+// hasSettledStatsEstimates returns whether the replica's stats contain
+// estimates which haven't changed for EstimatedStatsRecomputeDelay. It always
+// returns false unless EstimatedStatsRecomputeEnabled is set and the cluster
+// version allows the recomputation to clear the estimates.
+func hasSettledStatsEstimates(repl *Replica, now hlc.Timestamp) bool {
+	st := repl.store.ClusterSettings()
+	if !EstimatedStatsRecomputeEnabled.Get(&st.SV) ||
+		!st.Version.IsActive(cluster.VersionClearStatsEstimates) {
+		return false
+	}
+	ms := repl.GetMVCCStats()
+	if !ms.ContainsEstimates {
+		return false
+	}
+	delay := EstimatedStatsRecomputeDelay.Get(&st.SV)
+	return now.WallTime-ms.LastUpdateNanos >= delay.Nanoseconds()
+}
+
 // process() is called on every range for which this node is a lease holder.
 func (q *consistencyQueue) process(
 	ctx context.Context, repl *Replica, _ *config.SystemConfig,
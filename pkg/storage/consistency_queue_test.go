@@ -67,6 +67,74 @@ func TestConsistencyQueueRequiresLive(t *testing.T) {
 	}
 }
 
+// TestConsistencyQueueRecomputesEstimatedStats verifies that the consistency
+// queue checks a range whose stats contain estimates ahead of schedule once the
+// estimates have settled, and that the check clears the estimates, but only if
+// enabled.
+func TestConsistencyQueueRecomputesEstimatedStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	const delay = time.Minute
+
+	tc := testcluster.StartTestCluster(t, 1, base.TestClusterArgs{
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+	ts := tc.Servers[0]
+	st := ts.ClusterSettings()
+	storage.EstimatedStatsRecomputeDelay.Override(&st.SV, delay)
+	store, err := ts.Stores().GetStore(ts.GetFirstStoreID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := roachpb.Key("a")
+	if _, _, err := tc.SplitRange(key); err != nil {
+		t.Fatal(err)
+	}
+	repl := store.LookupReplica(roachpb.RKey(key))
+	data, _ := storage.MakeSSTable("b", "1", hlc.Timestamp{WallTime: 1})
+	if err := ts.DB().AddSSTable(
+		ctx, "b", "c", data, false /* disallowShadowing */, nil, /* stats */
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !repl.GetMVCCStats().ContainsEstimates {
+		t.Fatal("expected AddSSTable to leave estimates in the stats")
+	}
+
+	// The range hasn't been checked yet, so it is queued. While disabled, the
+	// check corrects the stats but leaves the estimates in place.
+	if err := store.ForceConsistencyQueueProcess(); err != nil {
+		t.Fatal(err)
+	}
+	if !repl.GetMVCCStats().ContainsEstimates {
+		t.Fatal("expected estimates to be left in place while disabled")
+	}
+
+	// Now that the range was checked, it is only queued again once its
+	// estimates have settled.
+	storage.EstimatedStatsRecomputeEnabled.Override(&st.SV, true)
+	sysCfg := config.NewSystemConfig(config.DefaultZoneConfigRef())
+	now := ts.Clock().Now()
+	if shouldQ, _ := store.ConsistencyQueueShouldQueue(ctx, now, repl, sysCfg); shouldQ {
+		t.Fatal("expected range with unsettled estimates not to be queued")
+	}
+	settled := now.Add(delay.Nanoseconds(), 0)
+	if shouldQ, _ := store.ConsistencyQueueShouldQueue(ctx, settled, repl, sysCfg); !shouldQ {
+		t.Fatal("expected range with settled estimates to be queued")
+	}
+
+	// Changes to the delay take effect on the next scan.
+	storage.EstimatedStatsRecomputeDelay.Override(&st.SV, time.Nanosecond)
+	if err := store.ForceConsistencyQueueProcess(); err != nil {
+		t.Fatal(err)
+	}
+	if repl.GetMVCCStats().ContainsEstimates {
+		t.Fatal("expected estimates to be cleared")
+	}
+}
+
 // TestCheckConsistencyMultiStore creates a node with three stores
 // with three way replication. A value is added to the node, and a
 // consistency check is run.
@@ -317,26 +317,35 @@ func EncodeKey(key MVCCKey) []byte {
 	return EncodeKeyToBuf(nil, key)
 }
 
+const (
+	timestampSentinelLen      = 1
+	walltimeEncodedLen        = 8
+	logicalEncodedLen         = 4
+	timestampEncodedLengthLen = 1
+)
+
+// EncodedKeySize returns the size of the RocksDB representation of an
+// engine.MVCC key, i.e. len(EncodeKey(key)), without encoding it.
+func EncodedKeySize(key MVCCKey) int {
+	return len(key.Key) + encodedTimestampSize(key.Timestamp) + timestampEncodedLengthLen
+}
+
+func encodedTimestampSize(ts hlc.Timestamp) int {
+	if ts == (hlc.Timestamp{}) {
+		return 0
+	}
+	if ts.Logical != 0 {
+		return timestampSentinelLen + walltimeEncodedLen + logicalEncodedLen
+	}
+	return timestampSentinelLen + walltimeEncodedLen
+}
+
 // EncodeKeyToBuf encodes an engine.MVCC key into the RocksDB representation.
 // This encoding must match with the encoding in engine/db.cc:EncodeKey().
 func EncodeKeyToBuf(buf []byte, key MVCCKey) []byte {
 	// TODO(dan): Unify this with (*RocksDBBatchBuilder).encodeKey.
 
-	const (
-		timestampSentinelLen      = 1
-		walltimeEncodedLen        = 8
-		logicalEncodedLen         = 4
-		timestampEncodedLengthLen = 1
-	)
-
-	timestampLength := 0
-	if key.Timestamp != (hlc.Timestamp{}) {
-		timestampLength = timestampSentinelLen + walltimeEncodedLen
-		if key.Timestamp.Logical != 0 {
-			timestampLength += logicalEncodedLen
-		}
-	}
-
+	timestampLength := encodedTimestampSize(key.Timestamp)
 	sz := len(key.Key) + timestampLength + timestampEncodedLengthLen
 	if cap(buf) < sz {
 		buf = make([]byte, sz)
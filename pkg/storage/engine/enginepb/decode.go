@@ -94,6 +94,13 @@ func ScanDecodeKeyValue(
 	return key, ts, value, repr, err
 }
 
+// ScanDataNumBytes returns the number of key and value bytes in a binary stream
+// of numKVs key/value pairs, such as in an MVCCScan "batch", excluding the
+// length prefix of each pair. Keys are counted in their encoded MVCC form.
+func ScanDataNumBytes(repr []byte, numKVs int64) int64 {
+	return int64(len(repr)) - numKVs*kvLenSize
+}
+
 // ScanDecodeKeyValueNoTS decodes a key/value pair from a binary stream, such as
 // in an MVCCScan "batch" (this is not the RocksDB batch repr format), returning
 // the key/value and the suffix of data remaining in the batch.
@@ -2109,7 +2109,9 @@ func mvccScanToKvs(
 			return nil, nil, nil, err
 		}
 		kvs[i].Key = k.Key
-		kvs[i].Value.RawBytes = rawBytes
+		if !opts.KeysOnly {
+			kvs[i].Value.RawBytes = rawBytes
+		}
 		kvs[i].Value.Timestamp = k.Timestamp
 	}
 	return kvs, resumeSpan, intents, err
@@ -2162,6 +2164,15 @@ type MVCCScanOptions struct {
 	IgnoreSequence bool
 	Reverse        bool
 	Txn            *roachpb.Transaction
+	// TargetBytes, if positive, stops the scan once the keys and values it
+	// has accumulated reach the given number of bytes, returning a resume span
+	// as if the max had been hit. The scan stops at a key boundary, so the
+	// key/value pair which reaches the target is included in the results.
+	TargetBytes int64
+	// KeysOnly, if set, omits the values from the results. The values are
+	// never copied out of the engine, and the returned rows have nil
+	// RawBytes.
+	KeysOnly bool
 }
 
 // MVCCScan scans the key range [key, endKey) in the provided engine up to some
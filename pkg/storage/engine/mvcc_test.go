@@ -316,6 +316,11 @@ func TestMVCCKeys(t *testing.T) {
 	if !reflect.DeepEqual(sortKeys, keys) {
 		t.Errorf("expected keys to sort in order %s, but got %s", keys, sortKeys)
 	}
+	for _, key := range keys {
+		if a, e := EncodedKeySize(key), len(EncodeKey(key)); a != e {
+			t.Errorf("%s: expected encoded size %d, got %d", key, e, a)
+		}
+	}
 }
 
 func TestMVCCEmptyKey(t *testing.T) {
@@ -1594,6 +1599,78 @@ func TestMVCCScan(t *testing.T) {
 	}
 }
 
+// TestMVCCScanKeysOnly verifies that a KeysOnly scan returns the same keys as
+// a regular scan, including from intent history and in reverse, but no values.
+func TestMVCCScanKeysOnly(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	engine := createTestEngine()
+	defer engine.Close()
+
+	if err := MVCCPut(ctx, engine, nil, testKey1, hlc.Timestamp{WallTime: 1}, value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey2, hlc.Timestamp{WallTime: 1}, value2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCDelete(ctx, engine, nil, testKey2, hlc.Timestamp{WallTime: 2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey3, hlc.Timestamp{WallTime: 1}, value3, nil); err != nil {
+		t.Fatal(err)
+	}
+	ts := hlc.Timestamp{WallTime: 3}
+	txn := *txn1
+	txn.Timestamp, txn.OrigTimestamp = ts, ts
+	txn.Sequence++
+	if err := MVCCPut(ctx, engine, nil, testKey4, ts, value4, &txn); err != nil {
+		t.Fatal(err)
+	}
+	// Overwrite the intent, which moves the previous value into its history.
+	txn.Sequence++
+	if err := MVCCPut(ctx, engine, nil, testKey4, ts, value1, &txn); err != nil {
+		t.Fatal(err)
+	}
+	// Read at the first sequence number, which is served from the intent
+	// history.
+	readTxn := txn
+	readTxn.Sequence--
+
+	for _, reverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reverse=%t", reverse), func(t *testing.T) {
+			opts := MVCCScanOptions{Reverse: reverse, Txn: &readTxn}
+			kvs, _, _, err := MVCCScan(ctx, engine, testKey1, testKey5, math.MaxInt64, ts, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.KeysOnly = true
+			keysOnly, _, _, err := MVCCScan(ctx, engine, testKey1, testKey5, math.MaxInt64, ts, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(kvs) != 3 {
+				t.Fatalf("expected 3 rows, found %d", len(kvs))
+			}
+			if len(keysOnly) != len(kvs) {
+				t.Fatalf("expected %d rows, found %d", len(kvs), len(keysOnly))
+			}
+			for i := range kvs {
+				if !kvs[i].Key.Equal(keysOnly[i].Key) {
+					t.Errorf("%d: expected key %s, found %s", i, kvs[i].Key, keysOnly[i].Key)
+				}
+				if len(kvs[i].Value.RawBytes) == 0 {
+					t.Errorf("%d: expected a value for %s", i, kvs[i].Key)
+				}
+				if keysOnly[i].Value.RawBytes != nil {
+					t.Errorf("%d: expected no value for %s, found %x", i, keysOnly[i].Key,
+						keysOnly[i].Value.RawBytes)
+				}
+			}
+		})
+	}
+}
+
 func TestMVCCScanMaxNum(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
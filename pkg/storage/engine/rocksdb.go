@@ -2431,10 +2431,10 @@ func (r *rocksDBIterator) MVCCScan(
 	r.clearState()
 	state := C.MVCCScan(
 		r.iter, goToCSlice(start), goToCSlice(end),
-		goToCTimestamp(timestamp), C.int64_t(max),
+		goToCTimestamp(timestamp), C.int64_t(max), C.int64_t(opts.TargetBytes),
 		goToCTxn(opts.Txn), C.bool(opts.Inconsistent),
 		C.bool(opts.Reverse), C.bool(opts.Tombstones),
-		C.bool(opts.IgnoreSequence),
+		C.bool(opts.IgnoreSequence), C.bool(opts.KeysOnly),
 	)
 
 	if err := statusToError(state.status); err != nil {
@@ -2897,29 +2897,79 @@ func (fr *RocksDBSstFileReader) Close() {
 	fr.rocksDB.RocksDB = nil
 }
 
-// CheckForKeyCollisions indicates if the two iterators collide on any keys.
-func CheckForKeyCollisions(existingIter Iterator, sstIter Iterator) error {
+// KeyCollisions accumulates the colliding keys found by
+// CheckForKeyCollisions. A key whose newest version in the SST is a deletion
+// tombstone is counted separately from one with a live value.
+type KeyCollisions struct {
+	// MaxSample is the maximum number of colliding keys of each kind which are
+	// retained in LiveSample and TombstoneSample.
+	MaxSample int
+
+	Live, Tombstone             int64
+	LiveSample, TombstoneSample []roachpb.Key
+}
+
+func (c *KeyCollisions) add(key roachpb.Key, tombstone bool) {
+	if tombstone {
+		c.Tombstone++
+		if len(c.TombstoneSample) < c.MaxSample {
+			c.TombstoneSample = append(c.TombstoneSample, key)
+		}
+	} else {
+		c.Live++
+		if len(c.LiveSample) < c.MaxSample {
+			c.LiveSample = append(c.LiveSample, key)
+		}
+	}
+}
+
+// CheckForKeyCollisions indicates if the two iterators collide on any keys. If
+// collisions is nil, an error is returned for the first colliding key.
+// Otherwise, all colliding keys are accumulated in collisions and only intents
+// and inline values in the existing data result in an error.
+func CheckForKeyCollisions(
+	existingIter Iterator, sstIter Iterator, collisions *KeyCollisions,
+) error {
 	existingIterGetter := existingIter.(dbIteratorGetter)
 	sstableIterGetter := sstIter.(dbIteratorGetter)
-	var intentErr C.DBString
-
-	state := C.DBCheckForKeyCollisions(existingIterGetter.getIter(), sstableIterGetter.getIter(), &intentErr)
+	for {
+		var intentErr C.DBString
+		state := C.DBCheckForKeyCollisions(existingIterGetter.getIter(), sstableIterGetter.getIter(), &intentErr)
 
-	err := statusToError(state.status)
-	if err != nil {
-		if err.Error() == "WriteIntentError" {
+		err := statusToError(state.status)
+		if err == nil {
+			return nil
+		}
+		switch err.Error() {
+		case "WriteIntentError":
 			var e roachpb.WriteIntentError
 			if err := protoutil.Unmarshal(cStringToGoBytes(intentErr), &e); err != nil {
 				return errors.Wrap(err, "failed to decode write intent error")
 			}
 			return &e
-		} else if err.Error() == "InlineError" {
+		case "InlineError":
 			return errors.Errorf("inline values are unsupported when checking for key collisions")
+		case "key collision":
+		default:
+			return err
+		}
+		key := cToGoKey(state.key)
+		if collisions == nil {
+			return errors.Wrap(&RocksDBError{msg: key.String()}, "ingested key collides with an existing one")
 		}
-		err = errors.Wrap(&RocksDBError{msg: cToGoKey(state.key).String()}, "ingested key collides with an existing one")
-	}
 
-	return err
+		// The C++ iterators were moved without updating the Go iterators, so
+		// reposition both at the colliding key before skipping past all of its
+		// versions. The SST's newest version of the key decides its kind.
+		sstIter.Seek(MakeMVCCMetadataKey(key.Key))
+		if ok, err := sstIter.Valid(); !ok {
+			return err
+		}
+		collisions.add(key.Key, len(sstIter.UnsafeValue()) == 0)
+		sstIter.NextKey()
+		existingIter.Seek(MakeMVCCMetadataKey(key.Key))
+		existingIter.NextKey()
+	}
 }
 
 // RocksDBSstFileWriter creates a file suitable for importing with
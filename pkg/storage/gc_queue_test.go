@@ -960,6 +960,59 @@ func TestGCQueueLastProcessedTimestamps(t *testing.T) {
 	})
 }
 
+func TestStoreForceGCQueueProcess(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc.Start(t, stopper)
+
+	tc.manualClock.Increment(48 * 60 * 60 * 1E9) // 2d past the epoch
+	now := tc.Clock().Now().WallTime
+	ts1 := makeTS(now-2*24*60*60*1E9+1, 0) // 2d old
+	ts2 := makeTS(now-26*60*60*1E9, 0)     // 26h old, i.e. past the GC TTL
+
+	// Write a value and delete it, both before the GC TTL. All of the key's
+	// versions are collectable.
+	key := roachpb.Key("a")
+	pArgs := putArgs(key, []byte("value"))
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts1}, &pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	dArgs := deleteArgs(key)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts2}, &dArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	delta, err := tc.store.ForceGCQueueProcess(ctx, tc.repl.RangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta.KeyCount != -1 || delta.ValCount != -2 {
+		t.Errorf("expected one key with two versions to be collected, got stats delta %+v", delta)
+	}
+	if delta.GCBytes() >= 0 {
+		t.Errorf("expected GCBytes to decrease, got stats delta %+v", delta)
+	}
+
+	kvs, err := engine.Scan(tc.store.Engine(), engine.MakeMVCCMetadataKey(key),
+		engine.MakeMVCCMetadataKey(key.Next()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 0 {
+		t.Errorf("expected all versions of %s to be collected, found %v", key, kvs)
+	}
+
+	tc.store.cfg.TestingKnobs.DisableGCQueue = true
+	if _, err := tc.store.ForceGCQueueProcess(ctx, tc.repl.RangeID); !testutils.IsError(
+		err, "GC queue disabled via testing knob",
+	) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // TestGCQueueChunkRequests verifies that many intents are chunked
 // into separate batches. This is verified both for many different
 // keys and also for many different versions of keys.
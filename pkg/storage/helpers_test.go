@@ -455,6 +455,12 @@ func (r *Replica) IsQuiescent() bool {
 	return r.mu.quiescent
 }
 
+// ResetRaftMessageRates resets the statistics reported by RaftMessageRates.
+func (r *Replica) ResetRaftMessageRates() {
+	r.raftMsgSentStats.resetRequestCounts()
+	r.raftMsgRecvStats.resetRequestCounts()
+}
+
 func (r *Replica) IsTxnWaitQueueEnabled() bool {
 	return r.txnWaitQueue.IsEnabled()
 }
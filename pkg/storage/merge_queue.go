@@ -140,28 +140,48 @@ func (mq *mergeQueue) mergesDisabledForRange(desc *roachpb.RangeDescriptor) bool
 	return err == nil
 }
 
-func (mq *mergeQueue) shouldQueue(
-	ctx context.Context, now hlc.Timestamp, repl *Replica, sysCfg *config.SystemConfig,
-) (shouldQ bool, priority float64) {
+// Reasons returned by mergeBlockedReason.
+const (
+	mergeBlockedQueueDisabled = "merge queue disabled"
+	mergeBlockedLastRange     = "no right-hand neighbor"
+	mergeBlockedSplitRequired = "split required at end key"
+	mergeBlockedTableDisabled = "merges disabled for table"
+)
+
+// mergeBlockedReason returns the reason for which the merge queue won't
+// attempt to merge the range with the given descriptor into its right-hand
+// neighbor regardless of the size of the range, or the empty string if there
+// is none. This only considers the left-hand range; whether the right-hand
+// neighbor is eligible is only checked when the range is processed.
+func (mq *mergeQueue) mergeBlockedReason(
+	desc *roachpb.RangeDescriptor, sysCfg *config.SystemConfig,
+) string {
 	if !mq.enabled() {
-		return false, 0
+		return mergeBlockedQueueDisabled
 	}
 
-	desc := repl.Desc()
-
 	if desc.EndKey.Equal(roachpb.RKeyMax) {
 		// The last range has no right-hand neighbor to merge with.
-		return false, 0
+		return mergeBlockedLastRange
 	}
 
 	if sysCfg.NeedsSplit(desc.StartKey, desc.EndKey.Next()) {
-		// This range would need to be split if it extended just one key further.
-		// There is thus no possible right-hand neighbor that it could be merged
-		// with.
-		return false, 0
+		// This range would need to be split if it extended just one key further,
+		// e.g. because its end key is a table or zone config boundary. There is
+		// thus no possible right-hand neighbor that it could be merged with.
+		return mergeBlockedSplitRequired
 	}
 
 	if mq.mergesDisabledForRange(desc) {
+		return mergeBlockedTableDisabled
+	}
+	return ""
+}
+
+func (mq *mergeQueue) shouldQueue(
+	ctx context.Context, now hlc.Timestamp, repl *Replica, sysCfg *config.SystemConfig,
+) (shouldQ bool, priority float64) {
+	if mq.mergeBlockedReason(repl.Desc(), sysCfg) != "" {
 		return false, 0
 	}
 
@@ -469,6 +469,30 @@ var (
 		Measurement: "Latency",
 		Unit:        metric.Unit_NANOSECONDS,
 	}
+	metaRaftApplyCommittedBatches = metric.Metadata{
+		Name:        "raft.process.applycommitted.batches",
+		Help:        "Number of batches in which committed Raft entries were applied",
+		Measurement: "Batches",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftApplyCommittedEntries = metric.Metadata{
+		Name:        "raft.process.applycommitted.entries",
+		Help:        "Number of committed Raft entries applied, including empty entries",
+		Measurement: "Log Entries",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftApplyCommittedEmptyEntries = metric.Metadata{
+		Name:        "raft.process.applycommitted.emptyentries",
+		Help:        "Number of empty committed Raft entries applied",
+		Measurement: "Log Entries",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftApplyCommittedStateAssertions = metric.Metadata{
+		Name:        "raft.process.applycommitted.stateassertions",
+		Help:        "Number of replica state assertions performed while applying committed Raft entries",
+		Measurement: "Assertions",
+		Unit:        metric.Unit_COUNT,
+	}
 
 	// Raft message metrics.
 	metaRaftRcvdProp = metric.Metadata{
@@ -925,6 +949,12 @@ var (
 		Measurement: "Writes",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaLargeSpanSetBatches = metric.Metadata{
+		Name:        "requests.large_span_set",
+		Help:        "Number of batches that declared more spans than kv.raft.command.large_span_set_threshold",
+		Measurement: "Batches",
+		Unit:        metric.Unit_COUNT,
+	}
 
 	// AddSSTable metrics.
 	metaAddSSTableProposals = metric.Metadata{
@@ -955,6 +985,20 @@ var (
 		Unit:        metric.Unit_CONST,
 	}
 
+	// RangeFeed metrics.
+	metaRangeFeedCatchUpScansActive = metric.Metadata{
+		Name:        "kv.rangefeed.catchup_scans_active",
+		Help:        "Number of RangeFeed catch-up scans in progress",
+		Measurement: "Scans",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRangeFeedCatchUpScansQueued = metric.Metadata{
+		Name:        "kv.rangefeed.catchup_scans_queued",
+		Help:        "Number of RangeFeed catch-up scans waiting for the concurrency limit",
+		Measurement: "Scans",
+		Unit:        metric.Unit_COUNT,
+	}
+
 	// Closed timestamp metrics.
 	metaClosedTimestampMaxBehindNanos = metric.Metadata{
 		Name:        "kv.closed_timestamp.max_behind_nanos",
@@ -1062,6 +1106,12 @@ type StoreMetrics struct {
 	RaftHandleReadyLatency    *metric.Histogram
 	RaftApplyCommittedLatency *metric.Histogram
 
+	// Raft entry application metrics.
+	RaftApplyCommittedBatches         *metric.Counter
+	RaftApplyCommittedEntries         *metric.Counter
+	RaftApplyCommittedEmptyEntries    *metric.Counter
+	RaftApplyCommittedStateAssertions *metric.Counter
+
 	// Raft message metrics.
 	RaftRcvdMsgProp           *metric.Counter
 	RaftRcvdMsgApp            *metric.Counter
@@ -1155,6 +1205,9 @@ type StoreMetrics struct {
 	// Backpressure counts.
 	BackpressuredOnSplitRequests *metric.Gauge
 
+	// Batches that declared an excessive number of spans.
+	LargeSpanSetBatches *metric.Counter
+
 	// AddSSTable stats: how many AddSSTable commands were proposed and how many
 	// were applied? How many applications required writing a copy?
 	AddSSTableProposals         *metric.Counter
@@ -1166,7 +1219,9 @@ type StoreMetrics struct {
 	EncryptionAlgorithm *metric.Gauge
 
 	// RangeFeed counts.
-	RangeFeedMetrics *rangefeed.Metrics
+	RangeFeedMetrics            *rangefeed.Metrics
+	RangeFeedCatchUpScansActive *metric.Gauge
+	RangeFeedCatchUpScansQueued *metric.Gauge
 
 	// Closed timestamp metrics.
 	ClosedTimestampMaxBehindNanos *metric.Gauge
@@ -1272,6 +1327,12 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		RaftHandleReadyLatency:    metric.NewLatency(metaRaftHandleReadyLatency, histogramWindow),
 		RaftApplyCommittedLatency: metric.NewLatency(metaRaftApplyCommittedLatency, histogramWindow),
 
+		// Raft entry application metrics.
+		RaftApplyCommittedBatches:         metric.NewCounter(metaRaftApplyCommittedBatches),
+		RaftApplyCommittedEntries:         metric.NewCounter(metaRaftApplyCommittedEntries),
+		RaftApplyCommittedEmptyEntries:    metric.NewCounter(metaRaftApplyCommittedEmptyEntries),
+		RaftApplyCommittedStateAssertions: metric.NewCounter(metaRaftApplyCommittedStateAssertions),
+
 		// Raft message metrics.
 		RaftRcvdMsgProp:           metric.NewCounter(metaRaftRcvdProp),
 		RaftRcvdMsgApp:            metric.NewCounter(metaRaftRcvdApp),
@@ -1363,6 +1424,8 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		// Backpressure counters.
 		BackpressuredOnSplitRequests: metric.NewGauge(metaBackpressuredOnSplitRequests),
 
+		LargeSpanSetBatches: metric.NewCounter(metaLargeSpanSetBatches),
+
 		// AddSSTable proposal + applications counters.
 		AddSSTableProposals:         metric.NewCounter(metaAddSSTableProposals),
 		AddSSTableApplications:      metric.NewCounter(metaAddSSTableApplications),
@@ -1372,7 +1435,9 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		EncryptionAlgorithm: metric.NewGauge(metaEncryptionAlgorithm),
 
 		// RangeFeed counters.
-		RangeFeedMetrics: rangefeed.NewMetrics(),
+		RangeFeedMetrics:            rangefeed.NewMetrics(),
+		RangeFeedCatchUpScansActive: metric.NewGauge(metaRangeFeedCatchUpScansActive),
+		RangeFeedCatchUpScansQueued: metric.NewGauge(metaRangeFeedCatchUpScansQueued),
 
 		// Closed timestamp metrics.
 		ClosedTimestampMaxBehindNanos: metric.NewGauge(metaClosedTimestampMaxBehindNanos),
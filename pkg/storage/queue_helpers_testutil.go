@@ -13,6 +13,8 @@ package storage
 import (
 	"context"
 
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/pkg/errors"
 )
@@ -26,6 +28,32 @@ func (bq *baseQueue) testingAdd(
 	return bq.addInternal(ctx, repl.Desc(), priority)
 }
 
+// testingPurgatoryError wraps an arbitrary error so that it can be used to
+// place a replica into purgatory.
+type testingPurgatoryError struct {
+	error
+}
+
+func (testingPurgatoryError) purgatoryErrorMarker() {}
+
+// testingAddToPurgatory places the replica into the queue's purgatory with
+// the given error, as if processing it had failed with that error.
+func (bq *baseQueue) testingAddToPurgatory(
+	ctx context.Context, repl replicaInQueue, err error,
+) error {
+	purgErr, ok := isPurgatoryError(err)
+	if !ok {
+		purgErr = testingPurgatoryError{err}
+	}
+	bq.mu.Lock()
+	defer bq.mu.Unlock()
+	if _, ok := bq.mu.replicas[repl.GetRangeID()]; ok {
+		return errors.Errorf("r%d is already queued", repl.GetRangeID())
+	}
+	bq.addToPurgatoryLocked(ctx, bq.store.stopper, repl, purgErr)
+	return nil
+}
+
 func forceScanAndProcess(s *Store, q *baseQueue) error {
 	// Check that the system config is available. It is needed by many queues. If
 	// it's not available, some queues silently fail to process any replicas,
@@ -73,6 +101,17 @@ func (s *Store) ForceSplitScanAndProcess() error {
 	return forceScanAndProcess(s, s.splitQueue.baseQueue)
 }
 
+// TestingEnqueueSplitPurgatory places the given range into the split queue's
+// purgatory with the specified error. The range is retried the next time the
+// purgatory is signaled (see StoreTestingKnobs.SplitQueuePurgatoryChan).
+func (s *Store) TestingEnqueueSplitPurgatory(rangeID roachpb.RangeID, err error) error {
+	repl, rErr := s.GetReplica(rangeID)
+	if rErr != nil {
+		return rErr
+	}
+	return s.splitQueue.testingAddToPurgatory(repl.AnnotateCtx(context.Background()), repl, err)
+}
+
 // MustForceRaftLogScanAndProcess iterates over all ranges and enqueues any that
 // need their raft logs truncated and then process each of them.
 func (s *Store) MustForceRaftLogScanAndProcess() {
@@ -99,6 +138,43 @@ func (s *Store) ForceConsistencyQueueProcess() error {
 	return forceScanAndProcess(s, s.consistencyQueue.baseQueue)
 }
 
+// ForceGCQueueProcess synchronously runs the GC queue on the replica of the
+// given range, regardless of whether the queue would consider it for
+// processing, and returns the resulting delta to the replica's MVCC stats.
+func (s *Store) ForceGCQueueProcess(
+	ctx context.Context, rangeID roachpb.RangeID,
+) (enginepb.MVCCStats, error) {
+	if s.TestingKnobs().DisableGCQueue {
+		return enginepb.MVCCStats{}, errors.Errorf(
+			"cannot process r%d: GC queue disabled via testing knob", rangeID)
+	}
+	repl, err := s.GetReplica(rangeID)
+	if err != nil {
+		return enginepb.MVCCStats{}, err
+	}
+	ctx = repl.AnnotateCtx(ctx)
+
+	sysCfg := s.Gossip().GetSystemConfig()
+	if sysCfg == nil {
+		return enginepb.MVCCStats{}, errors.Errorf("system config not available in gossip")
+	}
+	hasLease, pErr := repl.getLeaseForGossip(ctx)
+	if pErr != nil {
+		return enginepb.MVCCStats{}, pErr.GoError()
+	}
+	if !hasLease {
+		return enginepb.MVCCStats{}, errors.Errorf("%s does not have the range lease", repl)
+	}
+
+	before := repl.GetMVCCStats()
+	if err := s.gcQueue.process(ctx, repl, sysCfg); err != nil {
+		return enginepb.MVCCStats{}, err
+	}
+	delta := repl.GetMVCCStats()
+	delta.Subtract(before)
+	return delta, nil
+}
+
 // The methods below can be used to control a store's queues. Stopping a queue
 // is only meant to happen in tests.
 
@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
@@ -54,6 +55,18 @@ const (
 	raftLogQueuePendingSnapshotGracePeriod = 3 * time.Second
 )
 
+// raftLogForceTruncationSize is the Raft log size above which the log is
+// truncated up to the quorum index even if that cuts off recently active
+// followers which are stuck, trading the disk space used by the log for Raft
+// snapshots to catch them up. Followers that have caught up to the quorum
+// index or whose Match index keeps advancing are never cut off. Set to 0 to
+// disable.
+var raftLogForceTruncationSize = settings.RegisterByteSizeSetting(
+	"kv.raft_log.force_truncation_size",
+	"raft log size above which a range's log is truncated even if that requires snapshots for stuck followers, or 0 to disable",
+	0,
+)
+
 // raftLogQueue manages a queue of replicas slated to have their raft logs
 // truncated by removing unneeded entries.
 type raftLogQueue struct {
@@ -207,6 +220,13 @@ func newTruncateDecision(ctx context.Context, r *Replica) (truncateDecision, err
 	log.Eventf(ctx, "raft status after lastUpdateTimes check: %+v", raftStatus.Progress)
 	r.mu.RUnlock()
 
+	r.mu.Lock()
+	var stuckFollowers map[uint64]bool
+	r.mu.followerMatches, stuckFollowers = trackFollowerMatches(
+		r.mu.followerMatches, raftStatus.Progress, now,
+	)
+	r.mu.Unlock()
+
 	if pr, ok := raftStatus.Progress[raftStatus.Lead]; ok {
 		// TODO(tschottdorf): remove this line once we have picked up
 		// https://github.com/etcd-io/etcd/pull/10279
@@ -218,7 +238,9 @@ func newTruncateDecision(ctx context.Context, r *Replica) (truncateDecision, err
 		RaftStatus:                     *raftStatus,
 		LogSize:                        raftLogSize,
 		MaxLogSize:                     targetSize,
+		ForceTruncationSize:            raftLogForceTruncationSize.Get(&r.store.cfg.Settings.SV),
 		LogSizeTrusted:                 logSizeTrusted,
+		StuckFollowers:                 stuckFollowers,
 		FirstIndex:                     firstIndex,
 		LastIndex:                      lastIndex,
 		PendingPreemptiveSnapshotIndex: pendingSnapshotIndex,
@@ -255,6 +277,37 @@ func updateRaftProgressFromActivity(
 	}
 }
 
+// followerMatch is the Match index of a follower along with the time at which
+// the leader first observed it.
+type followerMatch struct {
+	match uint64
+	since time.Time
+}
+
+// trackFollowerMatches records the Match indexes of the followers in prs,
+// returning the updated matches along with the followers whose Match index
+// hasn't advanced in the last MaxQuotaReplicaLivenessDuration. Followers which
+// are no longer part of prs are dropped from the returned matches.
+func trackFollowerMatches(
+	prev map[roachpb.ReplicaID]followerMatch, prs map[uint64]tracker.Progress, now time.Time,
+) (map[roachpb.ReplicaID]followerMatch, map[uint64]bool) {
+	matches := make(map[roachpb.ReplicaID]followerMatch, len(prs))
+	var stuck map[uint64]bool
+	for id, pr := range prs {
+		m, ok := prev[roachpb.ReplicaID(id)]
+		if !ok || pr.Match > m.match {
+			m = followerMatch{match: pr.Match, since: now}
+		} else if now.Sub(m.since) > MaxQuotaReplicaLivenessDuration {
+			if stuck == nil {
+				stuck = make(map[uint64]bool)
+			}
+			stuck[id] = true
+		}
+		matches[roachpb.ReplicaID(id)] = m
+	}
+	return matches, stuck
+}
+
 const (
 	truncatableIndexChosenViaQuorumIndex     = "quorum"
 	truncatableIndexChosenViaFollowers       = "followers"
@@ -267,7 +320,9 @@ const (
 type truncateDecisionInput struct {
 	RaftStatus                     raft.Status
 	LogSize, MaxLogSize            int64
-	LogSizeTrusted                 bool // false when LogSize might be off
+	ForceTruncationSize            int64 // zero when disabled
+	LogSizeTrusted                 bool  // false when LogSize might be off
+	StuckFollowers                 map[uint64]bool
 	FirstIndex, LastIndex          uint64
 	PendingPreemptiveSnapshotIndex uint64
 }
@@ -276,6 +331,15 @@ func (input truncateDecisionInput) LogTooLarge() bool {
 	return input.LogSize > input.MaxLogSize
 }
 
+// ForceTruncation returns whether the log is so large that it is truncated
+// without regard for stuck followers. Since this can require Raft snapshots
+// for followers which are recently active, the log is never force truncated
+// based on a size which might be off.
+func (input truncateDecisionInput) ForceTruncation() bool {
+	return input.ForceTruncationSize > 0 && input.LogSize > input.ForceTruncationSize &&
+		input.LogSizeTrusted
+}
+
 type truncateDecision struct {
 	Input       truncateDecisionInput
 	QuorumIndex uint64 // largest index known to be present on quorum
@@ -333,6 +397,14 @@ func (td *truncateDecision) String() string {
 			humanizeutil.IBytes(td.Input.MaxLogSize),
 		)
 	}
+	if td.Input.ForceTruncation() {
+		_, _ = fmt.Fprintf(
+			&buf,
+			"; forced truncation (%s > %s)",
+			humanizeutil.IBytes(td.Input.LogSize),
+			humanizeutil.IBytes(td.Input.ForceTruncationSize),
+		)
+	}
 	if n := td.NumNewRaftSnapshots(); n > 0 {
 		_, _ = fmt.Fprintf(&buf, "; implies %d Raft snapshot%s", n, util.Pluralize(int64(n)))
 	}
@@ -403,7 +475,7 @@ func computeTruncateDecision(input truncateDecisionInput) truncateDecision {
 	// RaftStatus.Commit is updated at propose time.
 	decision.ProtectIndex(decision.QuorumIndex, truncatableIndexChosenViaQuorumIndex)
 
-	for _, progress := range input.RaftStatus.Progress {
+	for id, progress := range input.RaftStatus.Progress {
 		// Snapshots are expensive, so we try our best to avoid truncating past
 		// where a follower is.
 
@@ -428,6 +500,17 @@ func computeTruncateDecision(input truncateDecisionInput) truncateDecision {
 		// be split many times over, resulting in a flurry of snapshots with
 		// overlapping bounds that put significant stress on the Raft snapshot
 		// queue.
+		//
+		// The exception is a log which has grown past ForceTruncationSize
+		// because a follower is stuck but still responsive. In that case, we
+		// stop protecting the followers which are stuck, i.e. those which
+		// aren't being replicated to or whose Match index hasn't advanced
+		// recently, and they are caught up via a snapshot. Followers which are
+		// merely behind continue to be protected.
+		if input.ForceTruncation() &&
+			(progress.State != tracker.StateReplicate || input.StuckFollowers[id]) {
+			continue
+		}
 		if progress.RecentActive {
 			if progress.State == tracker.StateProbe {
 				decision.ProtectIndex(decision.Input.FirstIndex, truncatableIndexChosenViaProbingFollower)
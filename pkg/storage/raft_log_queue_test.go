@@ -330,6 +330,99 @@ func TestComputeTruncateDecisionProgressStatusProbe(t *testing.T) {
 	})
 }
 
+// TestComputeTruncateDecisionForceTruncation verifies that a log which has
+// grown past the force truncation size is truncated to the quorum index if
+// that only cuts off followers which are stuck, and only if the size of the
+// log is trusted.
+func TestComputeTruncateDecisionForceTruncation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const notForced = "should truncate: true [truncate 40 entries to first index 50 (chosen via: followers); log too large (4.0 KiB > 1.0 KiB)]"
+	const forced = "should truncate: true [truncate 490 entries to first index 500 (chosen via: quorum); log too large (4.0 KiB > 1.0 KiB); forced truncation (4.0 KiB > 2.0 KiB); implies 1 Raft snapshot]"
+	testCases := []struct {
+		name           string
+		forceSize      int64
+		state          tracker.StateType
+		stuck          bool
+		logSizeTrusted bool
+		exp            string
+	}{
+		{"disabled", 0, tracker.StateReplicate, true, true, notForced},
+		{"not exceeded", 8192, tracker.StateReplicate, true, true, notForced},
+		{"follower not stuck", 2048, tracker.StateReplicate, false, true, notForced},
+		{"follower stuck", 2048, tracker.StateReplicate, true, true, forced},
+		{"follower not replicating", 2048, tracker.StateSnapshot, false, true,
+			"should truncate: true [truncate 490 entries to first index 500 (chosen via: quorum); log too large (4.0 KiB > 1.0 KiB); forced truncation (4.0 KiB > 2.0 KiB)]"},
+		{"log size untrusted", 2048, tracker.StateReplicate, true, false,
+			"should truncate: true [truncate 40 entries to first index 50 (chosen via: followers); log too large (4.0 KiB > 1.0 KiB); log size untrusted]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := raft.Status{
+				Progress: make(map[uint64]tracker.Progress),
+			}
+			// The third follower is at index 50 and keeps responding.
+			for j, v := range []uint64{500, 500, 50} {
+				status.Progress[uint64(j)] = tracker.Progress{
+					Match:        v,
+					Next:         v + 1,
+					RecentActive: true,
+					State:        tracker.StateReplicate,
+				}
+			}
+			pr := status.Progress[2]
+			pr.State = tc.state
+			status.Progress[2] = pr
+			var stuck map[uint64]bool
+			if tc.stuck {
+				stuck = map[uint64]bool{2: true}
+			}
+
+			decision := computeTruncateDecision(truncateDecisionInput{
+				RaftStatus:          status,
+				LogSize:             4096,
+				MaxLogSize:          1024,
+				ForceTruncationSize: tc.forceSize,
+				FirstIndex:          10,
+				LastIndex:           600,
+				LogSizeTrusted:      tc.logSizeTrusted,
+				StuckFollowers:      stuck,
+			})
+			if s := decision.String(); s != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, s)
+			}
+		})
+	}
+}
+
+// TestTrackFollowerMatches verifies that a follower is considered stuck once
+// its Match index hasn't advanced for MaxQuotaReplicaLivenessDuration.
+func TestTrackFollowerMatches(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	now := timeutil.Unix(0, 123)
+	prs := map[uint64]tracker.Progress{1: {Match: 10}, 2: {Match: 10}}
+	matches, stuck := trackFollowerMatches(nil, prs, now)
+	assert.Empty(t, stuck)
+
+	// The first follower advances, the second one doesn't.
+	now = now.Add(MaxQuotaReplicaLivenessDuration / 2)
+	prs = map[uint64]tracker.Progress{1: {Match: 20}, 2: {Match: 10}}
+	matches, stuck = trackFollowerMatches(matches, prs, now)
+	assert.Empty(t, stuck)
+
+	now = now.Add(MaxQuotaReplicaLivenessDuration/2 + 1)
+	matches, stuck = trackFollowerMatches(matches, prs, now)
+	assert.Equal(t, map[uint64]bool{2: true}, stuck)
+
+	// Once the second follower advances, it is no longer stuck, and removed
+	// followers are forgotten.
+	prs = map[uint64]tracker.Progress{2: {Match: 11}}
+	matches, stuck = trackFollowerMatches(matches, prs, now)
+	assert.Empty(t, stuck)
+	assert.Len(t, matches, 1)
+}
+
 func TestTruncateDecisionZeroValue(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
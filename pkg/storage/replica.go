@@ -58,6 +58,7 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft"
+	"go.etcd.io/etcd/raft/tracker"
 )
 
 const (
@@ -112,6 +113,17 @@ const (
 	proposalIllegalLeaseIndex
 )
 
+func (r proposalReevaluationReason) String() string {
+	switch r {
+	case proposalNoReevaluation:
+		return "none"
+	case proposalIllegalLeaseIndex:
+		return "illegal lease index"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(r))
+	}
+}
+
 type atomicDescString struct {
 	strPtr unsafe.Pointer
 }
@@ -179,10 +191,29 @@ type Replica struct {
 	// writeStats tracks the number of keys written by applied raft commands
 	// in order to aid in replica rebalancing decisions.
 	writeStats *replicaStats
+	// raftMsgSentStats and raftMsgRecvStats track the number of Raft messages
+	// sent and received by the replica in order to spot ranges with unstable
+	// Raft groups.
+	raftMsgSentStats *replicaStats
+	raftMsgRecvStats *replicaStats
+	// readBytesStats and writeBytesStats track the number of bytes returned by
+	// read-only batches and written by applied raft commands in order to spot
+	// ranges that are hot by bandwidth rather than by request count.
+	readBytesStats  *replicaStats
+	writeBytesStats *replicaStats
+	// latencies tracks the latencies of the read-only and write batches served
+	// by the replica in order to pinpoint slow ranges.
+	latencies replicaLatencies
+	// txnIntentCounts tracks the number of intents each transaction has left
+	// on the range in order to enforce kv.transaction.max_intents_per_range.
+	txnIntentCounts txnIntentCounts
 
 	// creatingReplica is set when a replica is created as uninitialized
 	// via a raft message.
 	creatingReplica *roachpb.ReplicaDescriptor
+	// createdAt is the time at which the replica was instantiated on this
+	// store. Only set by the constructor.
+	createdAt time.Time
 
 	// Held in read mode during read-only commands. Held in exclusive mode to
 	// prevent read-only commands from executing. Acquired before the embedded
@@ -372,6 +403,12 @@ type Replica struct {
 		// live node will not lose leaseholdership.
 		lastUpdateTimes lastUpdateTimesMap
 
+		// The Match index of each follower as observed by the Raft log queue
+		// along with the time at which it was first observed. It is used to
+		// tell followers which are stuck from those which are merely behind
+		// when the Raft log is forcibly truncated.
+		followerMatches map[roachpb.ReplicaID]followerMatch
+
 		// The last seen replica descriptors from incoming Raft messages. These are
 		// stored so that the replica still knows the replica descriptors for itself
 		// and for its message recipients in the circumstances when its RangeDescriptor
@@ -438,9 +475,46 @@ type Replica struct {
 		// Counts calls to Replica.tick()
 		ticks int
 
+		// Set when the refresh of pending commands triggered by an empty Raft
+		// entry has been deferred to the next tick. Only used when the
+		// DeferRefreshReasonNewLeaderOrConfigChange testing knob is set.
+		deferredRefreshOnTick bool
+
+		// The Raft term as of the last handled Ready, and the value of ticks
+		// when the replica last observed a change in its Raft term, role or
+		// leader. See RaftTickState.
+		raftTerm                uint64
+		raftStateChangedAtTicks int
+
 		// Counts Raft messages refused due to queue congestion.
 		droppedMessages int
 
+		// Counts local proposals that were rejected below Raft for a reason
+		// that calls for them to be reproposed, keyed by that reason.
+		reproposalReasonCounts map[proposalReevaluationReason]int64
+
+		// The time at which the GC threshold was last advanced by a command
+		// applied on this replica. Zero if it hasn't been advanced since the
+		// replica was instantiated.
+		gcThresholdAdvancedAt time.Time
+
+		// The proposer of the most recently applied command that wasn't
+		// rejected. Empty entries (e.g. those proposed by a new Raft leader)
+		// don't count.
+		lastAppliedProposer roachpb.ReplicaDescriptor
+
+		// The applied index of, and the time of generating, the most recent
+		// snapshot this replica generated for sending to another replica.
+		lastSnapshotGeneratedIndex uint64
+		lastSnapshotGeneratedAt    time.Time
+		// The most recent successfully sent outgoing snapshots, oldest first.
+		// Bounded by maxRecentSnapshotTargets.
+		recentSnapshotTargets []SnapshotTarget
+
+		// The time at which the application of a snapshot started, if one is
+		// currently being applied. Zero otherwise.
+		applyingSnapshotSince time.Time
+
 		// Note that there are two replicaStateLoaders, in raftMu and mu,
 		// depending on which lock is being held.
 		stateLoader stateloader.StateLoader
@@ -463,6 +537,10 @@ type Replica struct {
 		//  synchronizes updates that are linearized and driven by the Raft log.
 		proc *rangefeed.Processor
 	}
+	// activeCatchUpScans is the number of rangefeed catch-up scans in progress
+	// on the replica, counting from the creation of the catch-up iterator. It
+	// is accessed atomically.
+	activeCatchUpScans int32
 
 	// Throttle how often we offer this Replica to the split and merge queues.
 	// We have triggers downstream of Raft that do so based on limited
@@ -529,6 +607,7 @@ func (r *Replica) Send(
 func (r *Replica) sendWithRangeID(
 	ctx context.Context, rangeID roachpb.RangeID, ba roachpb.BatchRequest,
 ) (*roachpb.BatchResponse, *roachpb.Error) {
+	start := timeutil.Now()
 	var br *roachpb.BatchResponse
 	if r.leaseholderStats != nil && ba.Header.GatewayNodeID != 0 {
 		r.leaseholderStats.record(ba.Header.GatewayNodeID)
@@ -579,6 +658,9 @@ func (r *Replica) sendWithRangeID(
 	} else {
 		log.Fatalf(ctx, "don't know how to handle command %s", ba)
 	}
+	if useRaft || isReadOnly {
+		r.latencies.record(isReadOnly, timeutil.Since(start))
+	}
 	if pErr != nil {
 		if _, ok := pErr.GetDetail().(*roachpb.RaftGroupDeletedError); ok {
 			// This error needs to be converted appropriately so that
@@ -587,6 +669,11 @@ func (r *Replica) sendWithRangeID(
 		}
 		log.Eventf(ctx, "replica.Send got error: %s", pErr)
 	} else {
+		if ba.ReturnServingReplica {
+			if repDesc, err := r.GetReplicaDescriptor(); err == nil {
+				br.ServingReplica = &repDesc
+			}
+		}
 		if filter := r.store.cfg.TestingKnobs.TestingResponseFilter; filter != nil {
 			pErr = filter(ba, br)
 		}
@@ -658,6 +745,28 @@ func (r *Replica) DescAndZone() (*roachpb.RangeDescriptor, *config.ZoneConfig) {
 	return r.mu.state.Desc, r.mu.zone
 }
 
+// EffectiveReplicationFactor returns the replication factor configured by the
+// replica's zone config, the actual number of voters in its range descriptor
+// and the kind of zone the zone config was taken from (one of the
+// config.ZoneSource constants). The two factors differ while the range is
+// being up- or down-replicated, or if it is stuck under- or over-replicated.
+// If the system config isn't available, the configured factor is taken from
+// the replica's cached zone config and the source is empty.
+func (r *Replica) EffectiveReplicationFactor() (configured, actual int32, source string) {
+	desc, zone := r.DescAndZone()
+	actual = int32(len(desc.Replicas().Voters()))
+	// Take the configured factor and its source from the same lookup, as the
+	// cached zone config may lag behind the system config.
+	if g := r.store.Gossip(); g != nil {
+		if sysCfg := g.GetSystemConfig(); sysCfg != nil {
+			if z, src, err := sysCfg.GetZoneConfigAndSourceForKey(desc.StartKey); err == nil {
+				zone, source = z, src
+			}
+		}
+	}
+	return *zone.NumReplicas, actual, source
+}
+
 // Desc returns the authoritative range descriptor, acquiring a replica lock in
 // the process.
 func (r *Replica) Desc() *roachpb.RangeDescriptor {
@@ -671,6 +780,13 @@ func (r *Replica) descRLocked() *roachpb.RangeDescriptor {
 	return r.mu.state.Desc
 }
 
+// MetaDescriptorKeys returns the meta1 and meta2 keys under which the range's
+// descriptor should be recorded, as determined by its end key. A nil key
+// indicates that the range's descriptor has no record at that meta level.
+func (r *Replica) MetaDescriptorKeys() (meta1Key, meta2Key roachpb.Key) {
+	return rangeAddressingKeys(r.Desc())
+}
+
 // NodeID returns the ID of the node this replica belongs to.
 func (r *Replica) NodeID() roachpb.NodeID {
 	return r.store.nodeDesc.NodeID
@@ -749,6 +865,28 @@ func (r *Replica) GetGCThreshold() hlc.Timestamp {
 	return *r.mu.state.GCThreshold
 }
 
+// GCThresholdHistory returns the current GC threshold along with the time at
+// which this replica last applied a command advancing it. The returned time
+// is zero if the threshold hasn't moved since the replica was instantiated. A
+// range whose threshold hasn't advanced in a long time isn't being GC'd.
+func (r *Replica) GCThresholdHistory() (current hlc.Timestamp, lastAdvanced time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return *r.mu.state.GCThreshold, r.mu.gcThresholdAdvancedAt
+}
+
+// LastAppliedProposer returns the replica that proposed the most recently
+// applied non-empty command on this replica. This is normally the
+// leaseholder at the time the command was proposed, so it can be used to see
+// which replica has been driving writes to the range. The returned descriptor
+// is empty if no such command has been applied since the replica was
+// instantiated.
+func (r *Replica) LastAppliedProposer() roachpb.ReplicaDescriptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mu.lastAppliedProposer
+}
+
 // maxReplicaIDOfAny returns the maximum ReplicaID of any replica, including
 // voters and learners.
 func maxReplicaIDOfAny(desc *roachpb.RangeDescriptor) roachpb.ReplicaID {
@@ -772,6 +910,132 @@ func (r *Replica) LastReplicaAdded() (roachpb.ReplicaID, time.Time) {
 	return r.mu.lastReplicaAdded, r.mu.lastReplicaAddedTime
 }
 
+// LastSnapshotGeneratedIndex returns the applied index at which this replica
+// last generated an outgoing snapshot, and when it did so. ok is false if the
+// replica hasn't generated an outgoing snapshot since it was instantiated.
+// Repeatedly generating snapshots at similar indexes hints at snapshots that
+// fail to be sent.
+func (r *Replica) LastSnapshotGeneratedIndex() (index uint64, at time.Time, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mu.lastSnapshotGeneratedIndex, r.mu.lastSnapshotGeneratedAt,
+		!r.mu.lastSnapshotGeneratedAt.IsZero()
+}
+
+// maxRecentSnapshotTargets bounds the number of outgoing snapshots remembered
+// by each replica for RecentSnapshotTargets.
+const maxRecentSnapshotTargets = 10
+
+// SnapshotTarget describes an outgoing snapshot sent by a replica.
+type SnapshotTarget struct {
+	To       roachpb.ReplicaDescriptor
+	At       time.Time
+	Priority SnapshotRequest_Priority
+	Bytes    int64
+}
+
+// recordSnapshotTarget remembers a successfully sent outgoing snapshot,
+// evicting the oldest one if maxRecentSnapshotTargets are already tracked.
+func (r *Replica) recordSnapshotTarget(target SnapshotTarget) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.mu.recentSnapshotTargets) >= maxRecentSnapshotTargets {
+		copy(r.mu.recentSnapshotTargets, r.mu.recentSnapshotTargets[1:])
+		r.mu.recentSnapshotTargets = r.mu.recentSnapshotTargets[:maxRecentSnapshotTargets-1]
+	}
+	r.mu.recentSnapshotTargets = append(r.mu.recentSnapshotTargets, target)
+}
+
+// RecentSnapshotTargets returns up to n of the snapshots most recently sent by
+// this replica, newest first. Only the last maxRecentSnapshotTargets snapshots
+// are remembered, and only since the replica was instantiated.
+func (r *Replica) RecentSnapshotTargets(n int) []SnapshotTarget {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	targets := r.mu.recentSnapshotTargets
+	if n <= 0 {
+		return nil
+	}
+	if n > len(targets) {
+		n = len(targets)
+	}
+	res := make([]SnapshotTarget, 0, n)
+	for i := len(targets) - 1; i >= len(targets)-n; i-- {
+		res = append(res, targets[i])
+	}
+	return res
+}
+
+// IsApplyingSnapshot returns whether the replica is currently applying a
+// snapshot and, if so, the time at which the application started. A
+// long-running snapshot application stalls all reads and writes on the range.
+func (r *Replica) IsApplyingSnapshot() (applying bool, since time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.mu.applyingSnapshotSince.IsZero(), r.mu.applyingSnapshotSince
+}
+
+// PendingDescriptorChange returns whether the range descriptor is covered by
+// the intent of a transaction that has not yet committed or aborted and, if
+// so, the ID of that transaction and the kind of change it is making: "split",
+// "merge" or "change-replica". A range whose descriptor stays in this state
+// is a sign of a hung split, merge or replication change.
+//
+// The kind is inferred from the provisional descriptor: a split shrinks the
+// range, a merge either grows it (on the subsuming range) or deletes it (on
+// the subsumed range), and a replication change leaves the bounds alone.
+func (r *Replica) PendingDescriptorChange() (kind string, txnID uuid.UUID, ok bool) {
+	ctx := r.AnnotateCtx(context.Background())
+	kind, intent, err := r.pendingDescriptorChange(ctx)
+	if err != nil {
+		log.Warningf(ctx, "%+v", err)
+		return "", uuid.UUID{}, false
+	}
+	if intent == nil {
+		return "", uuid.UUID{}, false
+	}
+	return kind, intent.Txn.ID, true
+}
+
+// pendingDescriptorChange is like PendingDescriptorChange, but returns the
+// intent on the range descriptor. The returned intent is nil if there is no
+// pending change.
+func (r *Replica) pendingDescriptorChange(ctx context.Context) (string, *roachpb.Intent, error) {
+	desc := r.Desc()
+	if !desc.IsInitialized() {
+		return "", nil, nil
+	}
+	descKey := keys.RangeDescriptorKey(desc.StartKey)
+	_, intent, err := engine.MVCCGet(ctx, r.store.Engine(), descKey, hlc.MaxTimestamp,
+		engine.MVCCGetOptions{Inconsistent: true})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "unable to read range descriptor")
+	}
+	if intent == nil {
+		return "", nil, nil
+	}
+
+	provisional, _, err := engine.MVCCGetAsTxn(ctx, r.store.Engine(), descKey, intent.Txn.Timestamp, intent.Txn)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "unable to read provisional range descriptor")
+	}
+	if provisional == nil {
+		return mergeTxnName, intent, nil
+	}
+	var newDesc roachpb.RangeDescriptor
+	if err := provisional.GetProto(&newDesc); err != nil {
+		return "", nil, errors.Wrap(err, "unable to decode provisional range descriptor")
+	}
+	switch {
+	case newDesc.EndKey.Less(desc.EndKey):
+		return splitTxnName, intent, nil
+	case desc.EndKey.Less(newDesc.EndKey):
+		return mergeTxnName, intent, nil
+	default:
+		return replicaChangeTxnName, intent, nil
+	}
+}
+
 // GetReplicaDescriptor returns the replica for this range from the range
 // descriptor. Returns a *RangeNotFoundError if the replica is not found.
 // No other errors are returned.
@@ -817,6 +1081,189 @@ func (r *Replica) GetMVCCStats() enginepb.MVCCStats {
 	return *r.mu.state.Stats
 }
 
+// RecomputeAndPersistStats recomputes the range's MVCC stats from its data and
+// replaces the persisted stats with the result through Raft, clearing the
+// ContainsEstimates flag on all replicas. This is useful to finalize the stats
+// after a bulk ingestion via AddSSTable, which only estimates them. All other
+// commands on the range are blocked while the stats are recomputed. The
+// returned stats are those of this replica after the recomputation applied,
+// which are only guaranteed to be up to date on the leaseholder. Until the
+// cluster version VersionClearStatsEstimates is active, the stats are corrected
+// but the ContainsEstimates flag is left set.
+func (r *Replica) RecomputeAndPersistStats(ctx context.Context) (enginepb.MVCCStats, error) {
+	var b client.Batch
+	b.AddRawRequest(&roachpb.RecomputeStatsRequest{
+		RequestHeader:  roachpb.RequestHeader{Key: r.Desc().StartKey.AsRawKey()},
+		ClearEstimates: true,
+	})
+	if err := r.store.DB().Run(ctx, &b); err != nil {
+		return enginepb.MVCCStats{}, err
+	}
+	return r.GetMVCCStats(), nil
+}
+
+// LeaseIndexStatus returns the replica's lease applied index along with the
+// last maximum lease index assigned to a proposal. On the leaseholder, the
+// difference between the two is the number of commands that have been
+// sequenced for proposal but not yet applied; a large gap suggests that
+// application is lagging behind proposal.
+func (r *Replica) LeaseIndexStatus() (appliedLeaseIndex, lastAssignedMaxLeaseIndex uint64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mu.state.LeaseAppliedIndex, r.mu.proposalBuf.LastAssignedLeaseIndexRLocked()
+}
+
+// ApplyLag returns the number of log entries which the replica knows to be
+// committed but which it has not applied yet. On the leader, the committed
+// index is that acknowledged by a quorum of the Raft group. A lag which keeps
+// growing indicates that the replica's application of commands can't keep up
+// (for instance due to a slow disk), as opposed to a lag in replication. An
+// error is returned if the replica's Raft group has not been initialized.
+func (r *Replica) ApplyLag() (uint64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status := r.raftStatusRLocked()
+	if status == nil {
+		return 0, errors.Errorf("%s: raft group not initialized", r)
+	}
+	if applied := r.mu.state.RaftAppliedIndex; status.Commit > applied {
+		return status.Commit - applied, nil
+	}
+	return 0, nil
+}
+
+// maxHostedTransactions bounds the number of transaction records returned by
+// Replica.HostedTransactions.
+const maxHostedTransactions = 10000
+
+// HostedTxn describes a transaction record stored on a range, as returned by
+// Replica.HostedTransactions.
+type HostedTxn struct {
+	ID            uuid.UUID
+	Status        roachpb.TransactionStatus
+	LastHeartbeat hlc.Timestamp
+}
+
+// HostedTransactions returns the transaction records anchored on this range,
+// in key order. This helps to identify transactions which have been STAGING
+// (i.e. in an indeterminate commit state) or PENDING for a long time. To bound
+// the cost of the scan, at most maxHostedTransactions records are returned.
+func (r *Replica) HostedTransactions(ctx context.Context) ([]HostedTxn, error) {
+	desc := r.Desc()
+	startKey := keys.MakeRangeKeyPrefix(desc.StartKey)
+	endKey := keys.MakeRangeKeyPrefix(desc.EndKey)
+	var txns []HostedTxn
+	_, err := engine.MVCCIterate(ctx, r.store.Engine(), startKey, endKey, hlc.Timestamp{},
+		engine.MVCCScanOptions{}, func(kv roachpb.KeyValue) (bool, error) {
+			_, suffix, _, err := keys.DecodeRangeKey(kv.Key)
+			if err != nil {
+				return false, err
+			}
+			if !suffix.Equal(keys.LocalTransactionSuffix.AsRawKey()) {
+				return false, nil
+			}
+			var txn roachpb.Transaction
+			if err := kv.Value.GetProto(&txn); err != nil {
+				return false, err
+			}
+			txns = append(txns, HostedTxn{
+				ID:            txn.ID,
+				Status:        txn.Status,
+				LastHeartbeat: txn.LastHeartbeat,
+			})
+			return len(txns) >= maxHostedTransactions, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+// KeyIntentStatus returns whether the given key currently has a write intent
+// and, if so, the ID of the transaction holding it and the intent's timestamp.
+// Only the key's MVCC metadata is read, which makes this a more precise and
+// cheaper tool for debugging contention on a specific key than a scan.
+func (r *Replica) KeyIntentStatus(
+	ctx context.Context, key roachpb.Key,
+) (hasIntent bool, txnID uuid.UUID, ts hlc.Timestamp, err error) {
+	if !r.ContainsKey(key) {
+		return false, uuid.UUID{}, hlc.Timestamp{}, errors.Errorf("key %s not contained in %s", key, r)
+	}
+	var meta enginepb.MVCCMetadata
+	ok, _, _, err := r.store.Engine().GetProto(engine.MakeMVCCMetadataKey(key), &meta)
+	if err != nil || !ok || meta.Txn == nil {
+		return false, uuid.UUID{}, hlc.Timestamp{}, err
+	}
+	return true, meta.Txn.ID, hlc.Timestamp(meta.Timestamp), nil
+}
+
+// GCBacklogEstimate estimates the number of key versions and bytes below the
+// replica's GC threshold, i.e. garbage that may be collected but hasn't been
+// removed by the GC queue yet. A backlog that keeps growing indicates that the
+// GC queue isn't keeping up.
+//
+// The estimate is derived from the MVCC stats alone. GCBytesAge accumulates
+// the age of all non-live bytes; the share of it that had already accrued at
+// the GC threshold is used as the fraction of the non-live data which is
+// garbage. This is a rough approximation (which tends to underestimate) and
+// is less reliable for ranges with ContainsEstimates set.
+func (r *Replica) GCBacklogEstimate(
+	ctx context.Context,
+) (garbageKeys int64, garbageBytes int64, err error) {
+	r.mu.RLock()
+	ms := *r.mu.state.Stats
+	threshold := *r.mu.state.GCThreshold
+	r.mu.RUnlock()
+
+	if (threshold == hlc.Timestamp{}) {
+		return 0, 0, nil
+	}
+	now := r.store.Clock().Now().WallTime
+	if now < threshold.WallTime {
+		now = threshold.WallTime
+	}
+	ms.Forward(now)
+
+	gcBytes := ms.GCBytes()
+	if gcBytes <= 0 || ms.GCBytesAge <= 0 {
+		return 0, 0, nil
+	}
+	// Remove the age accrued since the threshold, leaving that accrued by the
+	// non-live bytes up to the threshold. Use the same granularity as AgeTo.
+	sinceThreshold := now/1e9 - threshold.WallTime/1e9
+	ageAtThreshold := ms.GCBytesAge - gcBytes*sinceThreshold
+	if ageAtThreshold <= 0 {
+		return 0, 0, nil
+	}
+	frac := float64(ageAtThreshold) / float64(ms.GCBytesAge)
+	garbageKeys = int64(frac * float64(ms.ValCount-ms.LiveCount))
+	garbageBytes = int64(frac * float64(gcBytes))
+	log.VEventf(ctx, 2, "estimated GC backlog of %d versions (%d bytes)", garbageKeys, garbageBytes)
+	return garbageKeys, garbageBytes, nil
+}
+
+// EstimatedTimeToGC estimates how long the GC queue will take to remove the
+// replica's current GC backlog (see GCBacklogEstimate). The processing rate
+// is the number of keys the store's GC queue has removed per unit of time
+// spent processing replicas. This is coarse: it doesn't account for the time
+// until the GC queue gets around to the replica, and the rate is averaged
+// over all replicas and the lifetime of the store. An error is returned if
+// there is a backlog but the GC queue hasn't removed any keys yet.
+func (r *Replica) EstimatedTimeToGC(ctx context.Context) (time.Duration, error) {
+	garbageKeys, _, err := r.GCBacklogEstimate(ctx)
+	if err != nil || garbageKeys == 0 {
+		return 0, err
+	}
+	keysAffected := r.store.metrics.GCNumKeysAffected.Count()
+	processingNanos := r.store.metrics.GCQueueProcessingNanos.Count()
+	if keysAffected == 0 || processingNanos == 0 {
+		return 0, errors.Errorf("no GC queue processing rate observed for a backlog of %d keys",
+			garbageKeys)
+	}
+	nanosPerKey := float64(processingNanos) / float64(keysAffected)
+	return time.Duration(float64(garbageKeys) * nanosPerKey), nil
+}
+
 // GetSplitQPS returns the Replica's queries/s request rate.
 //
 // NOTE: This should only be used for load based splitting, only
@@ -900,6 +1347,95 @@ func (r *Replica) raftStatusRLocked() *raft.Status {
 	return nil
 }
 
+// LearnerStatus returns whether this replica is a learner, along with the
+// index of the last entry in its log and the commit index that the Raft
+// leader has communicated to it. The leader tracks the former as the
+// replica's match index; a learner whose match index doesn't advance is
+// stuck waiting for a snapshot or log entries and won't be promoted to a
+// voter.
+func (r *Replica) LearnerStatus() (isLearner bool, matchIndex, leaderCommitIndex uint64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if repDesc, ok := r.mu.state.Desc.GetReplicaDescriptorByID(r.mu.replicaID); ok {
+		isLearner = repDesc.GetType() == roachpb.ReplicaType_LEARNER
+	}
+	if status := r.raftStatusRLocked(); status != nil {
+		leaderCommitIndex = status.Commit
+	}
+	return isLearner, r.mu.lastIndex, leaderCommitIndex
+}
+
+// ReplicaProgress describes the Raft leader's view of a follower's progress.
+type ReplicaProgress struct {
+	Match, Next  uint64
+	State        string
+	RecentActive bool
+}
+
+// FollowerProgress returns the Raft progress of each follower as tracked by
+// this replica, which must be the Raft leader. Returns nil if the replica is
+// not the leader. Comparing the followers' match indexes helps pinpoint which
+// follower is lagging.
+func (r *Replica) FollowerProgress() map[roachpb.ReplicaID]ReplicaProgress {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rg := r.mu.internalRaftGroup
+	if rg == nil || rg.BasicStatus().RaftState != raft.StateLeader {
+		return nil
+	}
+	progress := make(map[roachpb.ReplicaID]ReplicaProgress)
+	rg.WithProgress(func(id uint64, _ raft.ProgressType, pr tracker.Progress) {
+		if roachpb.ReplicaID(id) == r.mu.replicaID {
+			return
+		}
+		progress[roachpb.ReplicaID(id)] = ReplicaProgress{
+			Match:        pr.Match,
+			Next:         pr.Next,
+			State:        pr.State.String(),
+			RecentActive: pr.RecentActive,
+		}
+	})
+	return progress
+}
+
+// HeldLatch describes a latch currently held on a Replica.
+type HeldLatch struct {
+	Span    roachpb.Span
+	Access  string
+	HeldFor time.Duration
+}
+
+// HeldLatches returns a snapshot of the latches currently held on the range.
+// Combined with the pending proposals, this can help reveal which requests
+// are blocking which others.
+func (r *Replica) HeldLatches() []HeldLatch {
+	now := timeutil.Now()
+	infos := r.latchMgr.HeldLatches()
+	latches := make([]HeldLatch, len(infos))
+	for i, info := range infos {
+		latches[i] = HeldLatch{
+			Span:    info.Span,
+			Access:  info.Access.String(),
+			HeldFor: now.Sub(info.AcquiredAt),
+		}
+	}
+	return latches
+}
+
+// ReproposalReasonCounts returns the number of local proposals on this replica
+// that were rejected below Raft for a reason that calls for them to be
+// reproposed, keyed by that reason. This helps tell lease index contention
+// apart from other causes of reproposals.
+func (r *Replica) ReproposalReasonCounts() map[string]int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[string]int64, len(r.mu.reproposalReasonCounts))
+	for reason, n := range r.mu.reproposalReasonCounts {
+		counts[reason.String()] = n
+	}
+	return counts
+}
+
 // State returns a copy of the internal state of the Replica, along with some
 // auxiliary information.
 func (r *Replica) State() storagepb.RangeInfo {
@@ -981,7 +1517,10 @@ func (r *Replica) assertStateLocked(ctx context.Context, reader engine.Reader) {
 // requestCanProceed returns an error if a request (identified by its
 // key span and timestamp) can proceed. It may be called multiple
 // times during the processing of the request (i.e. during both
-// proposal and application for write commands).
+// proposal and application for write commands). If allowProtectedRead is
+// set, the request may proceed at or below the GC threshold if the store's
+// ProtectedTimestampProvider reports its span as protected at its timestamp;
+// it must only be set for read-only batches.
 //
 // This function is called both upstream and downstream of raft.
 // It is called upstream for read-only batches and admin batches;
@@ -992,12 +1531,15 @@ func (r *Replica) assertStateLocked(ctx context.Context, reader engine.Reader) {
 // this is OK although it can run downstream of raft, because it can
 // never change the evaluation of a batch, only allow or disallow
 // it.
-func (r *Replica) requestCanProceed(rspan roachpb.RSpan, ts hlc.Timestamp) error {
+func (r *Replica) requestCanProceed(
+	rspan roachpb.RSpan, ts hlc.Timestamp, allowProtectedRead bool,
+) error {
 	r.mu.RLock()
 	desc := r.mu.state.Desc
 	threshold := r.mu.state.GCThreshold
 	r.mu.RUnlock()
-	if !threshold.Less(ts) {
+	if !threshold.Less(ts) && !(allowProtectedRead &&
+		r.store.cfg.ProtectedTimestampProvider.IsProtected(rspan.AsRawSpanWithNoLocals(), ts)) {
 		return &roachpb.BatchTimestampBeforeGCError{
 			Timestamp: ts,
 			Threshold: *threshold,
@@ -1274,7 +1816,7 @@ func (r *Replica) executeAdminBatch(
 		return nil, roachpb.NewError(err)
 	}
 
-	if err := r.requestCanProceed(rSpan, ba.Timestamp); err != nil {
+	if err := r.requestCanProceed(rSpan, ba.Timestamp, false /* allowProtectedRead */); err != nil {
 		return nil, roachpb.NewError(err)
 	}
 
@@ -1428,6 +1970,8 @@ func (r *Replica) limitTxnMaxTimestamp(
 		if !ba.Txn.OrigTimestamp.Less(obsTS) {
 			log.Event(ctx, "read has no clock uncertainty")
 		}
+		log.VEventf(ctx, 2, "limiting uncertainty of txn %s to %s using observed timestamp from n%d",
+			ba.Txn.Short(), obsTS, ba.Replica.NodeID)
 		txnClone.MaxTimestamp.Backward(obsTS)
 		ba.Txn = txnClone
 	}
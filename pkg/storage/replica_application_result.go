@@ -19,6 +19,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/storagepb"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 // replica_application_*.go files provide concrete implementations of
@@ -71,6 +72,7 @@ func isTrivial(r *storagepb.ReplicatedEvalResult) bool {
 	whitelist.Timestamp = hlc.Timestamp{}
 	whitelist.DeprecatedDelta = nil
 	whitelist.PrevLeaseProposal = nil
+	whitelist.ClearStatsEstimates = false
 	whitelist.State = nil
 	return whitelist.Equal(storagepb.ReplicatedEvalResult{})
 }
@@ -101,6 +103,7 @@ func clearTrivialReplicatedEvalResultFields(r *storagepb.ReplicatedEvalResult) {
 		}
 	}
 	r.Delta = enginepb.MVCCStatsDelta{}
+	r.ClearStatsEstimates = false
 }
 
 // prepareLocalResult is performed after the command has been committed to the
@@ -152,6 +155,7 @@ func (r *Replica) prepareLocalResult(ctx context.Context, cmd *replicatedCmd) {
 			// new one. This is important for pipelined writes, since they don't
 			// have a client watching to retry, so a failure to eventually apply
 			// the proposal would be a user-visible error.
+			r.recordReproposalReason(cmd.proposalRetry)
 			pErr = r.tryReproposeWithNewLeaseIndex(ctx, cmd)
 			if pErr != nil {
 				cmd.response.Err = pErr
@@ -174,11 +178,23 @@ func (r *Replica) prepareLocalResult(ctx context.Context, cmd *replicatedCmd) {
 	cmd.response.EndTxns = cmd.proposal.Local.DetachEndTxns(pErr != nil)
 	if pErr == nil {
 		cmd.localResult = cmd.proposal.Local
+		r.recordTxnIntentCount(cmd.proposal.Request, cmd.replicatedResult().Delta.IntentCount)
 	} else if cmd.localResult != nil {
 		log.Fatalf(ctx, "shouldn't have a local result if command processing failed. pErr: %s", pErr)
 	}
 }
 
+// recordReproposalReason increments the count of local proposals rejected
+// below Raft for the given reason. See Replica.ReproposalReasonCounts.
+func (r *Replica) recordReproposalReason(reason proposalReevaluationReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mu.reproposalReasonCounts == nil {
+		r.mu.reproposalReasonCounts = make(map[proposalReevaluationReason]int64)
+	}
+	r.mu.reproposalReasonCounts[reason]++
+}
+
 // tryReproposeWithNewLeaseIndex is used by prepareLocalResult to repropose
 // commands that have gotten an illegal lease index error, and that we know
 // could not have applied while their lease index was valid (that is, we
@@ -279,6 +295,9 @@ func (r *Replica) handleGCThresholdResult(ctx context.Context, thresh *hlc.Times
 		return
 	}
 	r.mu.Lock()
+	if r.mu.state.GCThreshold.Less(*thresh) {
+		r.mu.gcThresholdAdvancedAt = timeutil.Now()
+	}
 	r.mu.state.GCThreshold = thresh
 	r.mu.Unlock()
 }
@@ -16,12 +16,14 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/storage/apply"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagepb"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/kr/pretty"
@@ -41,10 +43,62 @@ import (
 //
 // These allow Replica to interface with the storage/apply package.
 
+// slowCommandApplyThreshold is the duration above which the application of
+// Raft commands is logged as slow. It applies both to the application of a
+// batch of commands as a whole, measured up to and including the commit of the
+// batch, and to the staging of each individual command in the batch, which
+// covers the command's below-Raft checks, its write batch and its pre-apply
+// triggers (such as the ingestion of sideloaded SSTables). Slow application
+// usually points at a slow disk or at contention on the replica's locks. Set
+// to 0 to disable.
+var slowCommandApplyThreshold = settings.RegisterNonNegativeDurationSetting(
+	"kv.apply.slow_command_threshold",
+	"duration after which the application of a raft command is logged as slow, or 0 to disable",
+	time.Second,
+)
+
+// largeStatsDeltaThreshold is the magnitude of the MVCC stats delta, in bytes,
+// above which a single applied Raft command is logged. Such commands (e.g. a
+// DeleteRange over a large span) can cause spikes in downstream accounting.
+// Set to 0 to disable.
+var largeStatsDeltaThreshold = settings.RegisterValidatedByteSizeSetting(
+	"kv.apply.large_stats_delta_threshold",
+	"size of the MVCC stats delta above which the application of a raft command is logged, or 0 to disable",
+	256<<20,
+	func(v int64) error {
+		if v < 0 {
+			return errors.Errorf("cannot set kv.apply.large_stats_delta_threshold to a negative value: %d", v)
+		}
+		return nil
+	},
+)
+
+var largeStatsDeltaLogLimiter = log.Every(10 * time.Second)
+
+// statsDeltaBytes returns the magnitude of the given MVCC stats delta, in
+// bytes. This is the largest absolute change to the range's total, live or
+// system bytes.
+func statsDeltaBytes(delta enginepb.MVCCStats) int64 {
+	abs := func(v int64) int64 {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	n := abs(delta.Total())
+	if live := abs(delta.LiveBytes); live > n {
+		n = live
+	}
+	if sys := abs(delta.SysBytes); sys > n {
+		n = sys
+	}
+	return n
+}
+
 // applyCommittedEntriesStats returns stats about what happened during the
-// application of a set of raft entries.
-//
-// TODO(ajwerner): add metrics to go with these stats.
+// application of a set of raft entries. The stats are also added to the
+// corresponding StoreMetrics counters when they are moved out of the
+// replicaStateMachine (see moveStats).
 type applyCommittedEntriesStats struct {
 	batchesProcessed int
 	entriesProcessed int
@@ -380,7 +434,11 @@ type replicaAppBatch struct {
 	entries      int
 	emptyEntries int
 	mutations    int
+	writeBytes   int
 	start        time.Time
+	// lastCmdID is the ID of the most recently staged command, which is
+	// reported if the batch is slow to apply.
+	lastCmdID storagebase.CmdIDKey
 }
 
 // Stage implements the apply.Batch interface. The method handles the first
@@ -408,6 +466,7 @@ type replicaAppBatch struct {
 func (b *replicaAppBatch) Stage(cmdI apply.Command) (apply.CheckedCommand, error) {
 	cmd := cmdI.(*replicatedCmd)
 	ctx := cmd.ctx
+	start := timeutil.Now()
 	if cmd.ent.Index == 0 {
 		return nil, makeNonDeterministicFailure("processRaftCommand requires a non-zero index")
 	}
@@ -467,10 +526,12 @@ func (b *replicaAppBatch) Stage(cmdI apply.Command) (apply.CheckedCommand, error
 	// non-trivial ReplicatedState updates until later (without ever staging
 	// them in the batch) is sufficient.
 	b.stageTrivialReplicatedEvalResult(ctx, cmd)
+	b.lastCmdID = cmd.idKey
 	b.entries++
 	if len(cmd.ent.Data) == 0 {
 		b.emptyEntries++
 	}
+	b.maybeLogSlowCommand(ctx, cmd, timeutil.Since(start))
 
 	// The command was checked by shouldApplyCommand, so it can be returned
 	// as an apply.CheckedCommand.
@@ -504,6 +565,7 @@ func (b *replicaAppBatch) stageWriteBatch(ctx context.Context, cmd *replicatedCm
 	} else {
 		b.mutations += mutations
 	}
+	b.writeBytes += len(wb.Data)
 	if err := b.batch.ApplyBatchRepr(wb.Data, false); err != nil {
 		return wrapWithNonDeterministicFailure(err, "unable to apply WriteBatch")
 	}
@@ -533,6 +595,7 @@ func (b *replicaAppBatch) runPreApplyTriggers(ctx context.Context, cmd *replicat
 			b.r.raftMu.sideloaded,
 			cmd.ent.Term,
 			cmd.ent.Index,
+			"", /* suffix */
 			*res.AddSSTable,
 			b.r.store.limiters.BulkIOWriteRate,
 		)
@@ -542,6 +605,27 @@ func (b *replicaAppBatch) runPreApplyTriggers(ctx context.Context, cmd *replicat
 		}
 		res.AddSSTable = nil
 	}
+	// Files are ingested one after the other, just like the payload of
+	// AddSSTable. They aren't sideloaded, so each one is copied for ingestion
+	// under a path of its own.
+	for i := range res.AddSSTableFiles {
+		copied := addSSTablePreApply(
+			ctx,
+			b.r.store.cfg.Settings,
+			b.r.store.engine,
+			b.r.raftMu.sideloaded,
+			cmd.ent.Term,
+			cmd.ent.Index,
+			fmt.Sprintf(".file%d", i),
+			res.AddSSTableFiles[i],
+			b.r.store.limiters.BulkIOWriteRate,
+		)
+		b.r.store.metrics.AddSSTableApplications.Inc(1)
+		if copied {
+			b.r.store.metrics.AddSSTableApplicationCopies.Inc(1)
+		}
+	}
+	res.AddSSTableFiles = nil
 
 	if res.Split != nil {
 		// Splits require a new HardState to be written to the new RHS
@@ -592,6 +676,10 @@ func (b *replicaAppBatch) runPreApplyTriggers(ctx context.Context, cmd *replicat
 		}
 	}
 
+	if fn := b.r.store.cfg.TestingKnobs.InspectLogicalOpLog; fn != nil && cmd.raftCmd.LogicalOpLog != nil {
+		fn(cmd.idKey, cmd.raftCmd.LogicalOpLog.Ops)
+	}
+
 	// Provide the command's corresponding logical operations to the Replica's
 	// rangefeed. Only do so if the WriteBatch is non-nil, in which case the
 	// rangefeed requires there to be a corresponding logical operation log or
@@ -622,12 +710,20 @@ func (b *replicaAppBatch) stageTrivialReplicatedEvalResult(
 		b.state.LeaseAppliedIndex = leaseAppliedIndex
 	}
 	res := cmd.replicatedResult()
+	delta := res.Delta.ToStats()
+	if threshold := largeStatsDeltaThreshold.Get(&b.r.store.cfg.Settings.SV); threshold > 0 {
+		if n := statsDeltaBytes(delta); n > threshold && largeStatsDeltaLogLimiter.ShouldLog() {
+			log.Warningf(ctx, "large MVCC stats delta: r%d cmd=%x changed stats by %s (threshold %s): %+v",
+				b.r.RangeID, cmd.idKey, humanizeutil.IBytes(n), humanizeutil.IBytes(threshold), delta)
+		}
+	}
 	// Special-cased MVCC stats handling to exploit commutativity of stats delta
 	// upgrades. Thanks to commutativity, the spanlatch manager does not have to
 	// serialize on the stats key.
-	b.state.Stats.Add(res.Delta.ToStats())
-	// Exploit the fact that a split will result in a full stats
-	// recomputation to reset the ContainsEstimates flag.
+	b.state.Stats.Add(delta)
+	// Exploit the fact that a split (or a RecomputeStats request which held
+	// all of the range's latches) will result in a full stats recomputation to
+	// reset the ContainsEstimates flag.
 	//
 	// TODO(tschottdorf): We want to let the usual MVCCStats-delta
 	// machinery update our stats for the left-hand side. But there is no
@@ -636,7 +732,7 @@ func (b *replicaAppBatch) stageTrivialReplicatedEvalResult(
 	// makes this worth a separate effort (ContainsEstimates would need to
 	// have three possible values, 'UNCHANGED', 'NO', and 'YES').
 	// Until then, we're left with this rather crude hack.
-	if res.Split != nil {
+	if res.Split != nil || res.ClearStatsEstimates {
 		b.state.Stats.ContainsEstimates = false
 	}
 	if res.State != nil && res.State.UsingAppliedStateKey && !b.state.UsingAppliedStateKey {
@@ -672,6 +768,11 @@ func (b *replicaAppBatch) ApplyToStateMachine(ctx context.Context) error {
 	// up not being durably committed then the entries in this batch will be
 	// applied again upon startup.
 	const sync = false
+	if fn := r.store.TestingKnobs().SimulateEngineWriteStall; fn != nil {
+		if stall := fn(); stall > 0 {
+			time.Sleep(stall)
+		}
+	}
 	if err := b.batch.Commit(sync); err != nil {
 		return wrapWithNonDeterministicFailure(err, "unable to commit Raft entry batch")
 	}
@@ -698,6 +799,7 @@ func (b *replicaAppBatch) ApplyToStateMachine(ctx context.Context) error {
 	// Record the write activity, passing a 0 nodeID because replica.writeStats
 	// intentionally doesn't track the origin of the writes.
 	b.r.writeStats.recordCount(float64(b.mutations), 0 /* nodeID */)
+	b.r.writeBytesStats.recordCount(float64(b.writeBytes), 0 /* nodeID */)
 
 	// NB: the bootstrap store has a nil split queue.
 	// TODO(tbg): the above is probably a lie now.
@@ -716,7 +818,7 @@ func (b *replicaAppBatch) ApplyToStateMachine(ctx context.Context) error {
 		r.store.mergeQueue.MaybeAddAsync(ctx, r, r.store.Clock().Now())
 	}
 
-	b.recordStatsOnCommit()
+	b.recordStatsOnCommit(ctx)
 	return nil
 }
 
@@ -767,13 +869,29 @@ func (b *replicaAppBatch) addAppliedStateKeyToBatch(ctx context.Context) error {
 	return nil
 }
 
-func (b *replicaAppBatch) recordStatsOnCommit() {
+// maybeLogSlowCommand logs a warning if staging the command took longer than
+// kv.apply.slow_command_threshold.
+func (b *replicaAppBatch) maybeLogSlowCommand(
+	ctx context.Context, cmd *replicatedCmd, elapsed time.Duration,
+) {
+	threshold := slowCommandApplyThreshold.Get(&b.r.store.cfg.Settings.SV)
+	if threshold > 0 && elapsed > threshold {
+		log.Warningf(ctx, "slow raft command application: r%d cmd=%x took %s (threshold %s)",
+			b.r.RangeID, cmd.idKey, elapsed, threshold)
+	}
+}
+
+func (b *replicaAppBatch) recordStatsOnCommit(ctx context.Context) {
 	b.sm.stats.entriesProcessed += b.entries
 	b.sm.stats.numEmptyEntries += b.emptyEntries
 	b.sm.stats.batchesProcessed++
 
 	elapsed := timeutil.Since(b.start)
 	b.r.store.metrics.RaftCommandCommitLatency.RecordValue(elapsed.Nanoseconds())
+	if threshold := slowCommandApplyThreshold.Get(&b.r.store.cfg.Settings.SV); threshold > 0 && elapsed > threshold {
+		log.Warningf(ctx, "slow raft command application: r%d batch of %d commands ending with cmd=%x took %s (threshold %s)",
+			b.r.RangeID, b.entries, b.lastCmdID, elapsed, threshold)
+	}
 }
 
 // Close implements the apply.Batch interface.
@@ -878,6 +996,14 @@ func (sm *replicaStateMachine) ApplySideEffects(
 		return nil, wrapWithNonDeterministicFailure(err, "unable to apply conf change")
 	}
 
+	// Record the proposer of the command. Rejected commands (which include
+	// empty entries) weren't applied, so they are skipped.
+	if !cmd.Rejected() {
+		sm.r.mu.Lock()
+		sm.r.mu.lastAppliedProposer = cmd.raftCmd.ProposerReplica
+		sm.r.mu.Unlock()
+	}
+
 	// Mark the command as applied and return it as an apply.AppliedCommand.
 	if cmd.IsLocal() {
 		if !cmd.Rejected() {
@@ -1011,8 +1137,16 @@ func (sm *replicaStateMachine) maybeApplyConfChange(ctx context.Context, cmd *re
 	}
 }
 
+// moveStats returns the stats accumulated since the last call to moveStats and
+// resets them. It is called once per Raft ready cycle, at which point the stats
+// are also flushed into the store's metrics.
 func (sm *replicaStateMachine) moveStats() applyCommittedEntriesStats {
 	stats := sm.stats
 	sm.stats = applyCommittedEntriesStats{}
+	m := sm.r.store.metrics
+	m.RaftApplyCommittedBatches.Inc(int64(stats.batchesProcessed))
+	m.RaftApplyCommittedEntries.Inc(int64(stats.entriesProcessed))
+	m.RaftApplyCommittedEmptyEntries.Inc(int64(stats.numEmptyEntries))
+	m.RaftApplyCommittedStateAssertions.Inc(int64(stats.stateAssertions))
 	return stats
 }
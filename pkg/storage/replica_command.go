@@ -35,6 +35,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	crdberrors "github.com/cockroachdb/errors"
 	"github.com/cockroachdb/logtags"
 	"github.com/gogo/protobuf/proto"
@@ -238,8 +239,12 @@ func (r *Replica) adminSplitWithDescriptor(
 				// look at the root cause to sniff out the changed descriptor.
 				err = &benignError{errors.Wrap(err, msg)}
 			}
+			if err == nil {
+				reply.RightDesc = newDesc
+			}
 			return reply, err
 		}
+		reply.RightDesc = *desc
 		return reply, nil
 	}
 	log.Event(ctx, "found split key")
@@ -370,6 +375,8 @@ func (r *Replica) adminSplitWithDescriptor(
 		}
 		return reply, errors.Wrapf(err, "split at key %s failed", splitKey)
 	}
+	reply.LeftDesc = leftDesc
+	reply.RightDesc = *rightDesc
 	return reply, nil
 }
 
@@ -688,7 +695,11 @@ func (r *Replica) AdminMerge(
 			},
 		})
 		log.Event(ctx, "attempting commit")
-		return txn.Run(ctx, b)
+		if err := txn.Run(ctx, b); err != nil {
+			return err
+		}
+		reply.MergedDesc = updatedLeftDesc
+		return nil
 	}
 
 	// If the merge transaction encounters an error, we need to trigger a full
@@ -1473,6 +1484,10 @@ func (r *Replica) sendSnapshot(
 	}
 	defer snap.Close()
 	log.Event(ctx, "generated snapshot")
+	r.mu.Lock()
+	r.mu.lastSnapshotGeneratedIndex = snap.RaftSnap.Metadata.Index
+	r.mu.lastSnapshotGeneratedAt = timeutil.Now()
+	r.mu.Unlock()
 
 	sender, err := r.GetReplicaDescriptor()
 	if err != nil {
@@ -1541,7 +1556,9 @@ func (r *Replica) sendSnapshot(
 		Strategy:   SnapshotRequest_KV_BATCH,
 		Type:       snapType,
 	}
-	sent := func() {
+	var bytesSent int64
+	sent := func(n int64) {
+		bytesSent = n
 		r.store.metrics.RangeSnapshotsGenerated.Inc(1)
 	}
 	if err := r.store.cfg.Transport.SendSnapshot(
@@ -1555,6 +1572,12 @@ func (r *Replica) sendSnapshot(
 	); err != nil {
 		return &snapshotError{err}
 	}
+	r.recordSnapshotTarget(SnapshotTarget{
+		To:       recipient,
+		At:       timeutil.Now(),
+		Priority: priority,
+		Bytes:    bytesSent,
+	})
 	return nil
 }
 
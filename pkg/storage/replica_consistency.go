@@ -192,6 +192,9 @@ func (r *Replica) CheckConsistency(
 
 		req := roachpb.RecomputeStatsRequest{
 			RequestHeader: roachpb.RequestHeader{Key: startKey},
+			// The recomputed stats are exact, so the estimates can be cleared.
+			ClearEstimates: delta.ContainsEstimates &&
+				EstimatedStatsRecomputeEnabled.Get(&r.store.ClusterSettings().SV),
 		}
 
 		var b client.Batch
@@ -362,6 +365,44 @@ func (r *Replica) RunConsistencyCheck(
 	return results, nil
 }
 
+// SelfCheckStats compares the replica's in-memory MVCC stats against a
+// recomputation from a consistent snapshot of its data, without involving
+// other replicas or Raft. It returns the divergence (in-memory stats minus
+// recomputed stats) per field, which is zero when the stats are consistent.
+// ok is true if there is no divergence; note that when the stats contain
+// estimates (as indicated by the divergence's ContainsEstimates), divergence
+// is expected.
+func (r *Replica) SelfCheckStats(
+	ctx context.Context,
+) (divergence enginepb.MVCCStats, ok bool, err error) {
+	// Hold raftMu while grabbing the snapshot so that no command is applied
+	// between it and reading the in-memory stats.
+	r.raftMu.Lock()
+	snap := r.store.engine.NewSnapshot()
+	r.mu.RLock()
+	ms := *r.mu.state.Stats
+	desc := r.mu.state.Desc
+	r.mu.RUnlock()
+	r.raftMu.Unlock()
+	defer snap.Close()
+
+	actual, err := rditer.ComputeStatsForRange(desc, snap, ms.LastUpdateNanos)
+	if err != nil {
+		return enginepb.MVCCStats{}, false, err
+	}
+	divergence = ms
+	divergence.Subtract(actual)
+	divergence.LastUpdateNanos = 0
+
+	cmp := divergence
+	cmp.ContainsEstimates = false
+	ok = cmp == enginepb.MVCCStats{}
+	if !ok {
+		log.VEventf(ctx, 1, "stats diverge from recomputation: %+v", divergence)
+	}
+	return divergence, ok, nil
+}
+
 // getChecksum waits for the result of ComputeChecksum and returns it.
 // It returns false if there is no checksum being computed for the id,
 // or it has already been GCed.
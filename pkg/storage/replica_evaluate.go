@@ -155,6 +155,14 @@ func evaluateBatch(
 		// remaining keys we can touch.
 		maxKeys = baHeader.MaxSpanRequestKeys
 	}
+	// Similarly, targetBytes is the remaining byte budget for the results of
+	// span requests if the batch specifies one. Once it is exhausted, the
+	// remaining span requests are stopped by lowering maxKeys to zero.
+	targetBytes := baHeader.MaxSpanRequestBytes
+	var byteLimitReached bool
+	// The budget for intent rows returned by READ_UNCOMMITTED span requests is
+	// tracked in baHeader.MaxIntentRows, which is handed to each request.
+	var intentLimitReached bool
 
 	// Optimize any contiguous sequences of put and conditional put ops.
 	if len(baReqs) >= optimizePutThreshold && !readOnly {
@@ -239,6 +247,14 @@ func evaluateBatch(
 	var writeTooOldErr *roachpb.Error
 	mustReturnWriteTooOldErr := false
 
+	// If requested, report the number of engine mutations each request
+	// produces. This requires access to the batch's representation, which
+	// is costly, so it is only done when asked for.
+	var mutationBatch engine.Batch
+	if baHeader.ReturnMutationCounts && !readOnly {
+		mutationBatch, _ = batch.(engine.Batch)
+	}
+
 	for index, union := range baReqs {
 		// Execute the command.
 		args := union.GetInner()
@@ -259,7 +275,18 @@ func evaluateBatch(
 		// Note that responses are populated even when an error is returned.
 		// TODO(tschottdorf): Change that. IIRC there is nontrivial use of it currently.
 		reply := br.Responses[index].GetInner()
-		curResult, pErr := evaluateCommand(ctx, idKey, index, batch, rec, ms, baHeader, maxKeys, args, reply)
+		var mutationsBefore int
+		if mutationBatch != nil {
+			mutationsBefore = batchMutationCount(ctx, mutationBatch)
+		}
+		curResult, pErr := evaluateCommand(
+			ctx, idKey, index, batch, rec, ms, baHeader, maxKeys, targetBytes, args, reply,
+		)
+		if mutationBatch != nil {
+			h := reply.Header()
+			h.NumMutations = int64(batchMutationCount(ctx, mutationBatch) - mutationsBefore)
+			reply.SetHeader(h)
+		}
 
 		if err := result.MergeAndDestroy(curResult); err != nil {
 			// TODO(tschottdorf): see whether we really need to pass nontrivial
@@ -324,6 +351,34 @@ func evaluateBatch(
 			maxKeys -= retResults
 		}
 
+		if byteLimitReached {
+			if h := reply.Header(); h.ResumeSpan != nil {
+				h.ResumeReason = roachpb.RESUME_BYTE_LIMIT
+				reply.SetHeader(h)
+			}
+		} else if targetBytes > 0 {
+			h := reply.Header()
+			targetBytes -= h.NumBytes
+			if targetBytes <= 0 || h.ResumeReason == roachpb.RESUME_BYTE_LIMIT {
+				byteLimitReached = true
+				maxKeys = 0
+			}
+		}
+
+		if intentLimitReached {
+			if h := reply.Header(); h.ResumeSpan != nil {
+				h.ResumeReason = roachpb.RESUME_INTENT_LIMIT
+				reply.SetHeader(h)
+			}
+		} else if baHeader.MaxIntentRows > 0 {
+			baHeader.MaxIntentRows -= numIntentRows(reply)
+			if baHeader.MaxIntentRows <= 0 ||
+				reply.Header().ResumeReason == roachpb.RESUME_INTENT_LIMIT {
+				intentLimitReached = true
+				maxKeys = 0
+			}
+		}
+
 		// If transactional, we use ba.Txn for each individual command and
 		// accumulate updates to it.
 		// TODO(spencer,tschottdorf): need copy-on-write behavior for the
@@ -370,10 +425,32 @@ func evaluateBatch(
 	return br, result, nil
 }
 
+// numIntentRows returns the number of intent rows in the response to a
+// READ_UNCOMMITTED span request.
+func numIntentRows(reply roachpb.Response) int64 {
+	switch t := reply.(type) {
+	case *roachpb.ScanResponse:
+		return int64(len(t.IntentRows))
+	case *roachpb.ReverseScanResponse:
+		return int64(len(t.IntentRows))
+	}
+	return 0
+}
+
+// batchMutationCount returns the number of mutations in the batch.
+func batchMutationCount(ctx context.Context, b engine.Batch) int {
+	count, err := engine.RocksDBBatchCount(b.Repr())
+	if err != nil {
+		log.Fatalf(ctx, "unable to read batch count: %s", err)
+	}
+	return count
+}
+
 // evaluateCommand delegates to the eval method for the given
 // roachpb.Request. The returned Result may be partially valid
 // even if an error is returned. maxKeys is the number of scan results
-// remaining for this batch (MaxInt64 for no limit).
+// remaining for this batch (MaxInt64 for no limit) and targetBytes the
+// remaining size of the results in bytes (zero for no limit).
 func evaluateCommand(
 	ctx context.Context,
 	raftCmdID storagebase.CmdIDKey,
@@ -383,6 +460,7 @@ func evaluateCommand(
 	ms *enginepb.MVCCStats,
 	h roachpb.Header,
 	maxKeys int64,
+	targetBytes int64,
 	args roachpb.Request,
 	reply roachpb.Response,
 ) (result.Result, *roachpb.Error) {
@@ -407,11 +485,12 @@ func evaluateCommand(
 
 	if cmd, ok := batcheval.LookupCommand(args.Method()); ok {
 		cArgs := batcheval.CommandArgs{
-			EvalCtx: rec,
-			Header:  h,
-			Args:    args,
-			MaxKeys: maxKeys,
-			Stats:   ms,
+			EvalCtx:     rec,
+			Header:      h,
+			Args:        args,
+			MaxKeys:     maxKeys,
+			TargetBytes: targetBytes,
+			Stats:       ms,
 		}
 		pd, err = cmd.Eval(ctx, batch, cArgs, reply)
 	} else {
@@ -12,9 +12,11 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/storage/closedts"
 	"github.com/cockroachdb/cockroach/pkg/storage/closedts/ctpb"
 	ctstorage "github.com/cockroachdb/cockroach/pkg/storage/closedts/storage"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -112,3 +114,33 @@ func (r *Replica) maxClosed(ctx context.Context) hlc.Timestamp {
 	maxClosed.Forward(initialMaxClosed)
 	return maxClosed
 }
+
+// The closed timestamp policies reported by ClosedTimestampInfo.
+const (
+	// ClosedTimestampPolicyLagByTargetDuration is the policy in effect when
+	// closed timestamps trail the current time by the duration configured
+	// through the kv.closed_timestamp.target_duration setting.
+	ClosedTimestampPolicyLagByTargetDuration = "lag by target duration"
+	// ClosedTimestampPolicyDisabled is the policy in effect when the target
+	// duration is set to zero and no new timestamps are closed.
+	ClosedTimestampPolicyDisabled = "disabled"
+)
+
+// ClosedTimestampInfo returns the current closed timestamp of the range along
+// with the policy driving it and the lead time by which closed timestamps are
+// targeted to trail the current time. Follower reads at or below the closed
+// timestamp can be served by any replica, so the lead time bounds how stale
+// such reads on this range are expected to be.
+func (r *Replica) ClosedTimestampInfo() (
+	closed hlc.Timestamp,
+	policy string,
+	leadTime time.Duration,
+) {
+	closed = r.maxClosed(context.Background())
+	leadTime = closedts.TargetDuration.Get(&r.store.cfg.Settings.SV)
+	policy = ClosedTimestampPolicyLagByTargetDuration
+	if leadTime == 0 {
+		policy = ClosedTimestampPolicyDisabled
+	}
+	return closed, policy, leadTime
+}
@@ -19,9 +19,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft"
 )
@@ -37,6 +40,10 @@ const (
 	// candidate Raft state (which is a typical sign of having been removed
 	// from the group) will be considered for garbage collection.
 	ReplicaGCQueueCandidateTimeout = 1 * time.Second
+
+	// replicaGCDecisionLogSize is the number of recent decisions retained by
+	// the replica GC queue.
+	replicaGCDecisionLogSize = 128
 )
 
 // Priorities for the replica GC queue.
@@ -52,6 +59,17 @@ const (
 	replicaGCPriorityRemoved = 2.0
 )
 
+// ReplicaGCNewReplicaGracePeriod is the minimum age of a replica before the
+// replica GC queue will consider removing it. A replica that was just created,
+// for example by a snapshot sent during rebalancing, may not yet appear in the
+// range descriptor that the queue looks up, and removing it would only cause
+// another snapshot to be sent.
+var ReplicaGCNewReplicaGracePeriod = settings.RegisterNonNegativeDurationSetting(
+	"kv.replica_gc.new_replica_grace_period",
+	"minimum age of a replica before the replica GC queue will consider removing it (0 = no grace period)",
+	0,
+)
+
 var (
 	metaReplicaGCQueueRemoveReplicaCount = metric.Metadata{
 		Name:        "queue.replicagc.removereplica",
@@ -72,6 +90,16 @@ func makeReplicaGCQueueMetrics() ReplicaGCQueueMetrics {
 	}
 }
 
+// ReplicaGCDecision records the outcome of the replica GC queue processing
+// a replica.
+type ReplicaGCDecision struct {
+	RangeID roachpb.RangeID
+	// Collected is true if the replica was removed from the store.
+	Collected bool
+	Reason    string
+	At        time.Time
+}
+
 // replicaGCQueue manages a queue of replicas to be considered for garbage
 // collections. The GC process asynchronously removes local data for
 // ranges that have been rebalanced away from this store.
@@ -79,6 +107,14 @@ type replicaGCQueue struct {
 	*baseQueue
 	metrics ReplicaGCQueueMetrics
 	db      *client.DB
+
+	mu struct {
+		syncutil.Mutex
+		// decisions is a ring buffer of the most recent decisions; next is the
+		// index at which the next decision will be stored.
+		decisions []ReplicaGCDecision
+		next      int
+	}
 }
 
 // newReplicaGCQueue returns a new instance of replicaGCQueue.
@@ -115,6 +151,10 @@ func newReplicaGCQueue(store *Store, db *client.DB, gossip *gossip.Gossip) *repl
 func (rgcq *replicaGCQueue) shouldQueue(
 	ctx context.Context, now hlc.Timestamp, repl *Replica, _ *config.SystemConfig,
 ) (bool, float64) {
+	if rgcq.withinGracePeriod(repl) {
+		return false, 0
+	}
+
 	lastCheck, err := repl.GetLastReplicaGCTimestamp(ctx)
 	if err != nil {
 		log.Errorf(ctx, "could not read last replica GC timestamp: %+v", err)
@@ -187,6 +227,14 @@ func replicaGCShouldQueueImpl(
 func (rgcq *replicaGCQueue) process(
 	ctx context.Context, repl *Replica, _ *config.SystemConfig,
 ) error {
+	// Replicas may also be added to the queue directly, bypassing shouldQueue,
+	// so check the grace period again.
+	if rgcq.withinGracePeriod(repl) {
+		log.VEventf(ctx, 1, "not gc'able, replica is within the new replica grace period")
+		rgcq.recordDecision(repl.RangeID, false /* collected */, "replica is within the new replica grace period")
+		return nil
+	}
+
 	// Note that the Replicas field of desc is probably out of date, so
 	// we should only use `desc` for its static fields like RangeID and
 	// StartKey (and avoid rng.GetReplica() for the same reason).
@@ -230,6 +278,7 @@ func (rgcq *replicaGCQueue) process(
 		if err := repl.setLastReplicaGCTimestamp(ctx, repl.store.Clock().Now()); err != nil {
 			return err
 		}
+		rgcq.recordDecision(desc.RangeID, false /* collected */, "replica is still in range descriptor")
 	} else if desc.RangeID == replyDesc.RangeID {
 		// We are no longer a member of this range, but the range still exists.
 		// Clean up our local data.
@@ -239,7 +288,9 @@ func (rgcq *replicaGCQueue) process(
 		ticks := repl.mu.ticks
 		repl.mu.RUnlock()
 
+		reason := "replica removed from range descriptor"
 		if replicaID == 0 {
+			reason = "preemptive snapshot not in range descriptor"
 			// This is a preemptive replica. GC'ing a preemptive replica is a
 			// good idea if and only if the up-replication that it was a part of
 			// did *NOT* commit. If it *did* commit and we're removing the
@@ -295,6 +346,7 @@ func (rgcq *replicaGCQueue) process(
 		}); err != nil {
 			return err
 		}
+		rgcq.recordDecision(desc.RangeID, true /* collected */, reason)
 	} else {
 		// This case is tricky. This range has been merged away, so it is likely
 		// that we can GC this replica, but we need to be careful. If this store has
@@ -324,6 +376,7 @@ func (rgcq *replicaGCQueue) process(
 				// Chances are that the left replica needs to be GC'd. Since we don't
 				// have definitive proof, queue it with a low priority.
 				rgcq.AddAsync(ctx, leftRepl, replicaGCPriorityDefault)
+				rgcq.recordDecision(desc.RangeID, false /* collected */, "left neighbor not up-to-date")
 				return nil
 			}
 		}
@@ -341,10 +394,52 @@ func (rgcq *replicaGCQueue) process(
 		}); err != nil {
 			return err
 		}
+		rgcq.recordDecision(desc.RangeID, true /* collected */, "range was merged away")
 	}
 	return nil
 }
 
+// withinGracePeriod returns whether the replica was created too recently to
+// be considered for GC, per ReplicaGCNewReplicaGracePeriod.
+func (rgcq *replicaGCQueue) withinGracePeriod(repl *Replica) bool {
+	gracePeriod := ReplicaGCNewReplicaGracePeriod.Get(&repl.store.ClusterSettings().SV)
+	return gracePeriod > 0 && timeutil.Since(repl.createdAt) < gracePeriod
+}
+
+// recordDecision adds a decision to the queue's log of recent decisions,
+// evicting the oldest one if the log is full.
+func (rgcq *replicaGCQueue) recordDecision(rangeID roachpb.RangeID, collected bool, reason string) {
+	d := ReplicaGCDecision{
+		RangeID:   rangeID,
+		Collected: collected,
+		Reason:    reason,
+		At:        timeutil.Now(),
+	}
+	rgcq.mu.Lock()
+	defer rgcq.mu.Unlock()
+	if len(rgcq.mu.decisions) < replicaGCDecisionLogSize {
+		rgcq.mu.decisions = append(rgcq.mu.decisions, d)
+	} else {
+		rgcq.mu.decisions[rgcq.mu.next] = d
+	}
+	rgcq.mu.next = (rgcq.mu.next + 1) % replicaGCDecisionLogSize
+}
+
+// recentDecisions returns up to n of the most recent decisions, newest first.
+func (rgcq *replicaGCQueue) recentDecisions(n int) []ReplicaGCDecision {
+	rgcq.mu.Lock()
+	defer rgcq.mu.Unlock()
+	if n > len(rgcq.mu.decisions) {
+		n = len(rgcq.mu.decisions)
+	}
+	decisions := make([]ReplicaGCDecision, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := (rgcq.mu.next - i + replicaGCDecisionLogSize) % replicaGCDecisionLogSize
+		decisions = append(decisions, rgcq.mu.decisions[idx])
+	}
+	return decisions
+}
+
 func (*replicaGCQueue) timer(_ time.Duration) time.Duration {
 	return replicaGCQueueTimerDuration
 }
@@ -11,11 +11,13 @@
 package storage
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
 )
 
 func TestReplicaGCShouldQueue(t *testing.T) {
@@ -67,3 +69,45 @@ func TestReplicaGCShouldQueue(t *testing.T) {
 		}
 	}
 }
+
+// TestReplicaGCNewReplicaGracePeriod verifies that the replica GC queue leaves
+// replicas younger than kv.replica_gc.new_replica_grace_period alone, even if
+// they don't appear in their range descriptor.
+func TestReplicaGCNewReplicaGracePeriod(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t, testStoreOpts{}, stopper)
+
+	// An uninitialized replica isn't a member of its (empty) descriptor, so it
+	// looks like it has been removed from the range.
+	const rangeID = 77
+	repl, created, err := store.getOrCreateReplica(ctx, rangeID, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatal("no replica created")
+	}
+	repl.raftMu.Unlock()
+
+	sv := &store.ClusterSettings().SV
+	ReplicaGCNewReplicaGracePeriod.Override(sv, time.Hour)
+	if shouldQ, _ := store.replicaGCQueue.shouldQueue(ctx, store.Clock().Now(), repl, nil); shouldQ {
+		t.Fatal("expected replica within the grace period not to be queued")
+	}
+	if err := store.replicaGCQueue.process(ctx, repl, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetReplica(rangeID); err != nil {
+		t.Fatalf("expected replica within the grace period not to be removed: %s", err)
+	}
+
+	ReplicaGCNewReplicaGracePeriod.Override(sv, 0)
+	shouldQ, priority := store.replicaGCQueue.shouldQueue(ctx, store.Clock().Now(), repl, nil)
+	if !shouldQ || priority != replicaGCPriorityRemoved {
+		t.Fatalf("expected replica to be queued with priority %f, got %t/%f",
+			replicaGCPriorityRemoved, shouldQ, priority)
+	}
+}
@@ -73,6 +73,12 @@ func (r *Replica) shouldGossip() bool {
 // TODO(nvanbenschoten,bdarnell): even though this is best effort, we
 // should log louder when we continually fail to gossip system config.
 func (r *Replica) MaybeGossipSystemConfig(ctx context.Context) error {
+	return r.maybeGossipSystemConfig(ctx, false /* force */)
+}
+
+// maybeGossipSystemConfig implements MaybeGossipSystemConfig. If force is
+// set, the system config is gossiped even if it is unchanged.
+func (r *Replica) maybeGossipSystemConfig(ctx context.Context, force bool) error {
 	if r.store.Gossip() == nil {
 		log.VEventf(ctx, 2, "not gossiping system config because gossip isn't initialized")
 		return nil
@@ -101,8 +107,8 @@ func (r *Replica) MaybeGossipSystemConfig(ctx context.Context) error {
 		return errors.Wrap(err, "could not load SystemConfig span")
 	}
 
-	if gossipedCfg := r.store.Gossip().GetSystemConfig(); gossipedCfg != nil && gossipedCfg.Equal(loadedCfg) &&
-		r.store.Gossip().InfoOriginatedHere(gossip.KeySystemConfig) {
+	if gossipedCfg := r.store.Gossip().GetSystemConfig(); !force && gossipedCfg != nil &&
+		gossipedCfg.Equal(loadedCfg) && r.store.Gossip().InfoOriginatedHere(gossip.KeySystemConfig) {
 		log.VEventf(ctx, 2, "not gossiping unchanged system config")
 		return nil
 	}
@@ -121,6 +127,14 @@ func (r *Replica) MaybeGossipSystemConfig(ctx context.Context) error {
 // against what's already in gossip and only gossips records which
 // are out of date.
 func (r *Replica) MaybeGossipNodeLiveness(ctx context.Context, span roachpb.Span) error {
+	return r.maybeGossipNodeLiveness(ctx, span, false /* force */)
+}
+
+// maybeGossipNodeLiveness implements MaybeGossipNodeLiveness. If force is
+// set, all records are gossiped, including those which are unchanged.
+func (r *Replica) maybeGossipNodeLiveness(
+	ctx context.Context, span roachpb.Span, force bool,
+) error {
 	if r.store.Gossip() == nil || !r.IsInitialized() {
 		return nil
 	}
@@ -151,7 +165,7 @@ func (r *Replica) MaybeGossipNodeLiveness(ctx context.Context, span roachpb.Span
 		}
 		key := gossip.MakeNodeLivenessKey(kvLiveness.NodeID)
 		// Look up liveness from gossip; skip gossiping anew if unchanged.
-		if err := r.store.Gossip().GetInfoProto(key, &gossipLiveness); err == nil {
+		if err := r.store.Gossip().GetInfoProto(key, &gossipLiveness); !force && err == nil {
 			if gossipLiveness == kvLiveness && r.store.Gossip().InfoOriginatedHere(key) {
 				continue
 			}
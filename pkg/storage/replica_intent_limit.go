@@ -0,0 +1,119 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package storage
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+// maxIntentsPerRange is the number of unresolved intents a single transaction
+// may leave on a range before its writes to the range are rejected with a
+// TooManyIntentsError. This protects ranges from runaway transactions whose
+// intents would otherwise slow down every reader and writer of the range. Set
+// to 0 to disable.
+var maxIntentsPerRange = settings.RegisterNonNegativeIntSetting(
+	"kv.transaction.max_intents_per_range",
+	"maximum number of unresolved intents a single transaction may hold on a range, or 0 to disable",
+	0,
+)
+
+// txnIntentCounts tracks the number of intents that each transaction has
+// written to the range, as observed when applying its writes. The counts are
+// only maintained on the leaseholder, which proposed the writes, and are
+// cleared when the lease changes hands, so they are a best-effort
+// approximation. A transaction's count is dropped once a request resolving
+// its intents with a final status or ending the transaction is applied.
+type txnIntentCounts struct {
+	syncutil.Mutex
+	m map[uuid.UUID]int64
+}
+
+// checkTxnIntentLimit returns a TooManyIntentsError if the intents written by
+// the evaluated batch would push the batch's transaction over the
+// kv.transaction.max_intents_per_range limit. The intents only count against
+// the transaction once the batch applies (see recordTxnIntentCount).
+func (r *Replica) checkTxnIntentLimit(
+	ba *roachpb.BatchRequest, ms enginepb.MVCCStats,
+) *roachpb.Error {
+	limit := maxIntentsPerRange.Get(&r.store.cfg.Settings.SV)
+	if limit == 0 || ba.Txn == nil || ms.IntentCount <= 0 {
+		return nil
+	}
+	if _, ending := ba.GetArg(roachpb.EndTransaction); ending {
+		return nil
+	}
+
+	r.txnIntentCounts.Lock()
+	defer r.txnIntentCounts.Unlock()
+	if count := r.txnIntentCounts.m[ba.Txn.ID] + ms.IntentCount; count > limit {
+		return roachpb.NewErrorWithTxn(roachpb.NewTooManyIntentsError(count, limit), ba.Txn)
+	}
+	return nil
+}
+
+// recordTxnIntentCount accounts for the intents written by a locally proposed
+// batch once it has applied. Since it is only called for the application of a
+// command, a command which is reproposed is only counted once.
+func (r *Replica) recordTxnIntentCount(ba *roachpb.BatchRequest, intentCount int64) {
+	limit := maxIntentsPerRange.Get(&r.store.cfg.Settings.SV)
+
+	r.txnIntentCounts.Lock()
+	defer r.txnIntentCounts.Unlock()
+	if limit == 0 {
+		r.txnIntentCounts.m = nil
+		return
+	}
+
+	// Forget about transactions that are finalizing. Their intents are about to
+	// be resolved and must not count against them in the meantime.
+	var ending bool
+	for _, union := range ba.Requests {
+		switch t := union.GetInner().(type) {
+		case *roachpb.ResolveIntentRequest:
+			if t.Status != roachpb.PENDING {
+				delete(r.txnIntentCounts.m, t.IntentTxn.ID)
+			}
+		case *roachpb.ResolveIntentRangeRequest:
+			if t.Status != roachpb.PENDING {
+				delete(r.txnIntentCounts.m, t.IntentTxn.ID)
+			}
+		case *roachpb.EndTransactionRequest:
+			ending = true
+		}
+	}
+	if ba.Txn == nil {
+		return
+	}
+	if ending {
+		delete(r.txnIntentCounts.m, ba.Txn.ID)
+		return
+	}
+	if intentCount <= 0 {
+		return
+	}
+	if r.txnIntentCounts.m == nil {
+		r.txnIntentCounts.m = make(map[uuid.UUID]int64)
+	}
+	r.txnIntentCounts.m[ba.Txn.ID] += intentCount
+}
+
+// clearTxnIntentCounts forgets the intent counts of all transactions. It is
+// called when the lease changes hands, since the counts are only maintained
+// by the leaseholder.
+func (r *Replica) clearTxnIntentCounts() {
+	r.txnIntentCounts.Lock()
+	defer r.txnIntentCounts.Unlock()
+	r.txnIntentCounts.m = nil
+}
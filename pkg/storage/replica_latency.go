@@ -0,0 +1,137 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package storage
+
+import (
+	"math"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+const (
+	// latencyHistogramMin is the upper bound of the smallest bucket of a
+	// latencyHistogram. Smaller latencies are recorded in that bucket.
+	latencyHistogramMin = time.Microsecond
+	// latencyHistogramBucketsPerDoubling determines the precision of a
+	// latencyHistogram: each bucket is 2^(1/latencyHistogramBucketsPerDoubling)
+	// times wider than the previous one.
+	latencyHistogramBucketsPerDoubling = 2
+	// latencyHistogramBuckets is the number of buckets of a latencyHistogram.
+	// Together with the above, it places the upper bound of the largest bucket
+	// at 2^24µs (~16.8s). Larger latencies are recorded in that bucket.
+	latencyHistogramBuckets = 24*latencyHistogramBucketsPerDoubling + 1
+	// latencyHistogramMaxCount is the number of samples after which the counts
+	// of a latencyHistogram are halved, so that old samples are gradually
+	// aged out in favor of recent ones.
+	latencyHistogramMaxCount = 1 << 16
+)
+
+// latencyHistogram is a fixed-size histogram of latencies with exponentially
+// sized buckets, in the spirit of an HDR histogram but much smaller so that
+// one can be kept for each replica. Percentiles are reported as the upper
+// bound of the bucket they fall into, so they overestimate the true value by
+// at most a factor of 2^(1/latencyHistogramBucketsPerDoubling).
+type latencyHistogram struct {
+	counts [latencyHistogramBuckets]uint32
+	total  uint32
+}
+
+func latencyHistogramBucket(d time.Duration) int {
+	if d <= latencyHistogramMin {
+		return 0
+	}
+	i := int(math.Ceil(latencyHistogramBucketsPerDoubling *
+		math.Log2(float64(d)/float64(latencyHistogramMin))))
+	if i >= latencyHistogramBuckets {
+		return latencyHistogramBuckets - 1
+	}
+	return i
+}
+
+func latencyHistogramUpperBound(i int) time.Duration {
+	return time.Duration(float64(latencyHistogramMin) *
+		math.Exp2(float64(i)/latencyHistogramBucketsPerDoubling))
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if h.total >= latencyHistogramMaxCount {
+		h.total = 0
+		for i := range h.counts {
+			h.counts[i] /= 2
+			h.total += h.counts[i]
+		}
+	}
+	h.counts[latencyHistogramBucket(d)]++
+	h.total++
+}
+
+// percentile returns the latency below which the fraction q of the recorded
+// samples fall, or zero if no samples were recorded.
+func (h *latencyHistogram) percentile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := uint32(math.Ceil(q * float64(h.total)))
+	var cum uint32
+	for i, c := range h.counts {
+		cum += c
+		if cum >= target && cum > 0 {
+			return latencyHistogramUpperBound(i)
+		}
+	}
+	return latencyHistogramUpperBound(latencyHistogramBuckets - 1)
+}
+
+// replicaLatencies tracks the latencies of the read-only and write batches
+// served by a replica.
+type replicaLatencies struct {
+	syncutil.Mutex
+	reads, writes latencyHistogram
+}
+
+// ReplicaLatencyPercentiles holds the latency percentiles of the read-only
+// and write batches served by a replica.
+type ReplicaLatencyPercentiles struct {
+	ReadP50, ReadP99   time.Duration
+	WriteP50, WriteP99 time.Duration
+}
+
+func (l *replicaLatencies) record(isReadOnly bool, d time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	if isReadOnly {
+		l.reads.record(d)
+	} else {
+		l.writes.record(d)
+	}
+}
+
+func (l *replicaLatencies) percentiles() ReplicaLatencyPercentiles {
+	l.Lock()
+	defer l.Unlock()
+	return ReplicaLatencyPercentiles{
+		ReadP50:  l.reads.percentile(0.5),
+		ReadP99:  l.reads.percentile(0.99),
+		WriteP50: l.writes.percentile(0.5),
+		WriteP99: l.writes.percentile(0.99),
+	}
+}
+
+// LatencyPercentiles returns the 50th and 99th percentile latencies of the
+// read-only and write batches served by this replica, as measured from the
+// time the replica receives a batch until it returns a response. Recent
+// batches are weighted more heavily than older ones. This makes it possible
+// to tell which specific ranges are slow, which store-wide latency metrics
+// can't. A percentile is zero if no batches of that kind have been served.
+func (r *Replica) LatencyPercentiles() ReplicaLatencyPercentiles {
+	return r.latencies.percentiles()
+}
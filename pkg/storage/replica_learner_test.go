@@ -13,7 +13,9 @@ package storage_test
 import (
 	"context"
 	"fmt"
+	"math"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,6 +27,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/server"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
@@ -32,8 +35,13 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/cockroach/pkg/util/uint128"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -158,6 +166,40 @@ func TestAddReplicaViaLearner(t *testing.T) {
 	require.Equal(t, int64(1), getFirstStoreMetric(t, tc.Server(1), `range.snapshots.learner-applied`))
 }
 
+func TestLearnerSnapshotRecordsTarget(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	knobs, _ := makeLearnerTestKnobs()
+	ctx := context.Background()
+	tc := testcluster.StartTestCluster(t, 2, base.TestClusterArgs{
+		ServerArgs:      base.TestServerArgs{Knobs: knobs},
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+	db := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+	db.Exec(t, `SET CLUSTER SETTING kv.learner_replicas.enabled = true`)
+
+	scratchStartKey := tc.ScratchRange(t)
+	_, repl := getFirstStoreReplica(t, tc.Server(0), scratchStartKey)
+	require.Empty(t, repl.RecentSnapshotTargets(10))
+
+	desc := tc.AddReplicasOrFatal(t, scratchStartKey, tc.Target(1))
+	target, ok := desc.GetReplicaDescriptor(tc.Target(1).StoreID)
+	require.True(t, ok)
+
+	// The raft snapshot queue may also have sent a snapshot to the learner, so
+	// look for the one sent by AddReplicas.
+	var found bool
+	for _, st := range repl.RecentSnapshotTargets(10) {
+		if st.To.StoreID == target.StoreID && st.Priority == storage.SnapshotRequest_REBALANCE {
+			require.True(t, st.Bytes > 0, "expected nonzero bytes: %+v", st)
+			require.False(t, st.At.IsZero())
+			found = true
+		}
+	}
+	require.True(t, found, "no snapshot to %s recorded: %+v", target, repl.RecentSnapshotTargets(10))
+	require.Len(t, repl.RecentSnapshotTargets(1), 1)
+}
+
 func TestLearnerRaftConfState(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -652,6 +694,186 @@ func TestLearnerFollowerRead(t *testing.T) {
 	})
 }
 
+func TestLearnerStatus(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	knobs, ltk := makeLearnerTestKnobs()
+	tc := testcluster.StartTestCluster(t, 2, base.TestClusterArgs{
+		ServerArgs:      base.TestServerArgs{Knobs: knobs},
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+	db := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+	db.Exec(t, `SET CLUSTER SETTING kv.learner_replicas.enabled = true`)
+
+	scratchStartKey := tc.ScratchRange(t)
+	atomic.StoreInt64(&ltk.replicaAddStopAfterLearnerAtomic, 1)
+	tc.AddReplicasOrFatal(t, scratchStartKey, tc.Target(1))
+	atomic.StoreInt64(&ltk.replicaAddStopAfterLearnerAtomic, 0)
+
+	_, voter := getFirstStoreReplica(t, tc.Server(0), scratchStartKey)
+	_, learner := getFirstStoreReplica(t, tc.Server(1), scratchStartKey)
+	isLearner, _, _ := voter.LearnerStatus()
+	require.False(t, isLearner)
+	isLearner, initialMatch, _ := learner.LearnerStatus()
+	require.True(t, isLearner)
+
+	// Writes to the range are replicated to the learner, so its match index
+	// catches up with the commit index of the leader.
+	require.NoError(t, tc.Server(0).DB().Put(ctx, scratchStartKey, "foo"))
+	commit := voter.RaftStatus().Commit
+	testutils.SucceedsSoon(t, func() error {
+		isLearner, match, leaderCommit := learner.LearnerStatus()
+		if !isLearner {
+			return errors.Errorf(`%s is no longer a learner`, learner)
+		}
+		if match <= initialMatch || match < commit {
+			return errors.Errorf(`match index %d has not caught up with commit index %d`, match, commit)
+		}
+		if leaderCommit < commit {
+			return errors.Errorf(`commit index %d has not caught up with %d`, leaderCommit, commit)
+		}
+		return nil
+	})
+}
+
+func TestLastSnapshotGeneratedIndex(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	knobs, _ := makeLearnerTestKnobs()
+	tc := testcluster.StartTestCluster(t, 2, base.TestClusterArgs{
+		ServerArgs:      base.TestServerArgs{Knobs: knobs},
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+	db := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+	db.Exec(t, `SET CLUSTER SETTING kv.learner_replicas.enabled = true`)
+
+	scratchStartKey := tc.ScratchRange(t)
+	_, repl := getFirstStoreReplica(t, tc.Server(0), scratchStartKey)
+	_, _, ok := repl.LastSnapshotGeneratedIndex()
+	require.False(t, ok)
+
+	// Adding a replica sends it a snapshot generated by the leaseholder.
+	before := timeutil.Now()
+	tc.AddReplicasOrFatal(t, scratchStartKey, tc.Target(1))
+	index, at, ok := repl.LastSnapshotGeneratedIndex()
+	require.True(t, ok)
+	require.NotZero(t, index)
+	require.True(t, index <= repl.State().ReplicaState.RaftAppliedIndex)
+	require.False(t, at.Before(before))
+	require.False(t, at.After(timeutil.Now()))
+}
+
+func TestReplicaIsApplyingSnapshot(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	// Pause the application of the first snapshot received by the second
+	// node until unblocked.
+	var store1 atomic.Value
+	var numApplied int32
+	applying, unblock := make(chan struct{}), make(chan struct{})
+	knobs, _ := makeLearnerTestKnobs()
+	pausingKnobs, _ := makeLearnerTestKnobs()
+	pausingKnobs.Store.(*storage.StoreTestingKnobs).SnapshotApplyBatchFactory = func() engine.Batch {
+		if atomic.AddInt32(&numApplied, 1) == 1 {
+			applying <- struct{}{}
+			<-unblock
+		}
+		return store1.Load().(*storage.Store).Engine().NewWriteOnlyBatch()
+	}
+	tc := testcluster.StartTestCluster(t, 2, base.TestClusterArgs{
+		ServerArgsPerNode: map[int]base.TestServerArgs{
+			0: {Knobs: knobs},
+			1: {Knobs: pausingKnobs},
+		},
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+	db := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+	db.Exec(t, `SET CLUSTER SETTING kv.learner_replicas.enabled = true`)
+
+	store, err := tc.Server(1).GetStores().(*storage.Stores).GetStore(tc.Server(1).GetFirstStoreID())
+	require.NoError(t, err)
+	store1.Store(store)
+
+	scratchStartKey := tc.ScratchRange(t)
+	rangeID := tc.LookupRangeOrFatal(t, scratchStartKey).RangeID
+	before := timeutil.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := tc.AddReplicas(scratchStartKey, tc.Target(1))
+		errCh <- err
+	}()
+
+	// While the snapshot application is paused, the replica reports it.
+	<-applying
+	repl, err := store.GetReplica(rangeID)
+	require.NoError(t, err)
+	isApplying, since := repl.IsApplyingSnapshot()
+	require.True(t, isApplying)
+	require.False(t, since.Before(before))
+	require.False(t, since.After(timeutil.Now()))
+
+	close(unblock)
+	require.NoError(t, <-errCh)
+	isApplying, since = repl.IsApplyingSnapshot()
+	require.False(t, isApplying)
+	require.True(t, since.IsZero())
+}
+
+func TestIncomingSnapshotInjectedID(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	snapID := uuid.FromUint128(uint128.FromInts(0, 1))
+	var mu syncutil.Mutex
+	var appliedIDs []uuid.UUID
+	knobs, _ := makeLearnerTestKnobs()
+	injectingKnobs, _ := makeLearnerTestKnobs()
+	storeKnobs := injectingKnobs.Store.(*storage.StoreTestingKnobs)
+	storeKnobs.IncomingSnapshotID = func(*storage.SnapshotRequest_Header) uuid.UUID {
+		return snapID
+	}
+	storeKnobs.BeforeSnapshotApply = func(inSnap *storage.IncomingSnapshot) error {
+		mu.Lock()
+		defer mu.Unlock()
+		appliedIDs = append(appliedIDs, inSnap.SnapUUID)
+		return nil
+	}
+	tc := testcluster.StartTestCluster(t, 2, base.TestClusterArgs{
+		ServerArgsPerNode: map[int]base.TestServerArgs{
+			0: {Knobs: knobs},
+			1: {Knobs: injectingKnobs},
+		},
+		ReplicationMode: base.ReplicationManual,
+	})
+	defer tc.Stopper().Stop(ctx)
+	db := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+	db.Exec(t, `SET CLUSTER SETTING kv.learner_replicas.enabled = true`)
+
+	scratchStartKey := tc.ScratchRange(t)
+	tc.AddReplicasOrFatal(t, scratchStartKey, tc.Target(1))
+
+	mu.Lock()
+	require.NotEmpty(t, appliedIDs)
+	for _, id := range appliedIDs {
+		require.Equal(t, snapID, id)
+	}
+	mu.Unlock()
+
+	// The snapshot application is logged with the injected ID.
+	log.Flush()
+	entries, err := log.FetchEntriesFromFiles(0, math.MaxInt64, 100,
+		regexp.MustCompile(`applying .* snapshot at index \d+ \(id=`+regexp.QuoteMeta(snapID.Short())))
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+}
+
 func TestLearnerAdminRelocateRange(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
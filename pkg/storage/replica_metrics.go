@@ -238,6 +238,27 @@ func (r *Replica) WritesPerSecond() float64 {
 	return wps
 }
 
+// RaftMessageRates returns the average number of Raft messages per second
+// sent and received by the replica. A rate that is high relative to the
+// range's write rate points at an unstable Raft group, for example due to
+// repeated elections or a flapping follower.
+func (r *Replica) RaftMessageRates() (sentPerSec, recvPerSec float64) {
+	sentPerSec, _ = r.raftMsgSentStats.avgQPS()
+	recvPerSec, _ = r.raftMsgRecvStats.avgQPS()
+	return sentPerSec, recvPerSec
+}
+
+// ByteThroughput returns the average number of bytes per second read from and
+// written to the range. Read bytes are measured as the size of the responses
+// to read-only batches served by this replica, so only the leaseholder (or a
+// replica serving follower reads) reports them. Write bytes are measured as
+// the size of the write batches applied by Raft.
+func (r *Replica) ByteThroughput() (readBytesPerSec, writeBytesPerSec float64) {
+	readBytesPerSec, _ = r.readBytesStats.avgQPS()
+	writeBytesPerSec, _ = r.writeBytesStats.avgQPS()
+	return readBytesPerSec, writeBytesPerSec
+}
+
 func (r *Replica) needsSplitBySizeRLocked() bool {
 	return r.exceedsMultipleOfSplitSizeRLocked(1)
 }
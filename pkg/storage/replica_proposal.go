@@ -281,6 +281,13 @@ func (r *Replica) leasePostApply(ctx context.Context, newLease roachpb.Lease, pe
 		}
 	}
 
+	if leaseChangingHands {
+		// The intent counts are only maintained by the leaseholder, and a
+		// previous leaseholder may have missed writes since it last held the
+		// lease.
+		r.clearTxnIntentCounts()
+	}
+
 	if leaseChangingHands && iAmTheLeaseHolder {
 		// When taking over the lease, we need to check whether a merge is in
 		// progress, as only the old leaseholder would have been explicitly notified
@@ -421,12 +428,19 @@ func (r *Replica) leasePostApply(ctx context.Context, newLease roachpb.Lease, pe
 	}
 }
 
+// addSSTablePreApply ingests the given SSTable, which is the payload of the
+// command at the given term and index. The suffix is appended to the path of
+// the command's sideloaded payload to derive the path under which the SSTable
+// is ingested; it is empty for the sideloaded payload itself and unique for
+// each of the command's other files, which then can't be linked in but are
+// always copied.
 func addSSTablePreApply(
 	ctx context.Context,
 	st *cluster.Settings,
 	eng engine.Engine,
 	sideloaded SideloadStorage,
 	term, index uint64,
+	suffix string,
 	sst storagepb.ReplicatedEvalResult_AddSSTable,
 	limiter *rate.Limiter,
 ) bool {
@@ -446,6 +460,7 @@ func addSSTablePreApply(
 	if err != nil {
 		log.Fatalf(ctx, "sideloaded SSTable at term %d, index %d is missing", term, index)
 	}
+	path += suffix
 
 	eng.PreIngestDelay(ctx)
 
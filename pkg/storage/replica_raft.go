@@ -19,6 +19,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage/apply"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
@@ -41,6 +42,18 @@ import (
 	"go.etcd.io/etcd/raft/tracker"
 )
 
+var largeSpanSetLogLimiter = log.Every(10 * time.Second)
+
+// largeSpanSetThreshold is the number of declared spans above which a batch
+// is considered excessively large. Such batches are counted and logged since
+// they can be expensive to latch and slow down concurrent requests. Set to 0
+// to disable.
+var largeSpanSetThreshold = settings.RegisterNonNegativeIntSetting(
+	"kv.raft.command.large_span_set_threshold",
+	"number of spans a batch may declare before it is reported as excessively large, or 0 to disable",
+	10000,
+)
+
 func makeIDKey() storagebase.CmdIDKey {
 	idKeyBuf := make([]byte, 0, raftCommandIDLen)
 	idKeyBuf = encoding.EncodeUint64Ascending(idKeyBuf, uint64(rand.Int63()))
@@ -99,6 +112,16 @@ func (r *Replica) evalAndPropose(
 		return nil, nil, 0, roachpb.NewError(err)
 	}
 
+	if threshold := largeSpanSetThreshold.Get(&r.store.cfg.Settings.SV); threshold > 0 {
+		if n := spans.Len(); int64(n) > threshold {
+			r.store.metrics.LargeSpanSetBatches.Inc(1)
+			if largeSpanSetLogLimiter.ShouldLog() {
+				log.Warningf(ctx, "batch declared %d spans, exceeding threshold of %d: %s",
+					n, threshold, ba.Summary())
+			}
+		}
+	}
+
 	// Checking the context just before proposing can help avoid ambiguous errors.
 	if err := ctx.Err(); err != nil {
 		errStr := fmt.Sprintf("%s before proposing: %s", err, ba.Summary())
@@ -110,7 +133,7 @@ func (r *Replica) evalAndPropose(
 	// application time, because the spanlatch manager will synchronize all
 	// requests (notably EndTransaction with SplitTrigger) that may cause this
 	// condition to change.
-	if err := r.requestCanProceed(rSpan, ba.Timestamp); err != nil {
+	if err := r.requestCanProceed(rSpan, ba.Timestamp, false /* allowProtectedRead */); err != nil {
 		return nil, nil, 0, roachpb.NewError(err)
 	}
 
@@ -383,6 +406,7 @@ func (r *Replica) hasPendingProposalsRLocked() bool {
 // message. Before doing so, it assures that the replica is unquiesced and ready
 // to handle the request.
 func (r *Replica) stepRaftGroup(req *RaftMessageRequest) error {
+	r.raftMsgRecvStats.record(req.FromReplica.NodeID)
 	// We're processing an incoming raft message (from a batch that may
 	// include MsgVotes), so don't campaign if we wake up our raft
 	// group.
@@ -732,6 +756,13 @@ func (r *Replica) handleRaftReadyRaftMuLocked(
 	r.mu.lastIndex = lastIndex
 	r.mu.lastTerm = lastTerm
 	r.mu.raftLogSize = raftLogSize
+	if rd.SoftState != nil ||
+		(!raft.IsEmptyHardState(rd.HardState) && rd.HardState.Term != r.mu.raftTerm) {
+		r.mu.raftStateChangedAtTicks = r.mu.ticks
+	}
+	if !raft.IsEmptyHardState(rd.HardState) {
+		r.mu.raftTerm = rd.HardState.Term
+	}
 	var becameLeader bool
 	if r.mu.leaderID != leaderID {
 		r.mu.leaderID = leaderID
@@ -773,8 +804,15 @@ func (r *Replica) handleRaftReadyRaftMuLocked(
 		if stats.numEmptyEntries > 0 {
 			// Overwrite unconditionally since this is the most aggressive
 			// reproposal mode.
-			if !r.store.TestingKnobs().DisableRefreshReasonNewLeaderOrConfigChange {
-				refreshReason = reasonNewLeaderOrConfigChange
+			knobs := r.store.TestingKnobs()
+			if !knobs.DisableRefreshReasonNewLeaderOrConfigChange {
+				if knobs.DeferRefreshReasonNewLeaderOrConfigChange {
+					r.mu.Lock()
+					r.mu.deferredRefreshOnTick = true
+					r.mu.Unlock()
+				} else {
+					refreshReason = reasonNewLeaderOrConfigChange
+				}
 			}
 		}
 	}
@@ -896,9 +934,30 @@ func (r *Replica) tick(livenessMap IsLiveMap) (bool, error) {
 		// cycles.
 		r.refreshProposalsLocked(refreshAtDelta, reasonTicks)
 	}
+	if r.mu.deferredRefreshOnTick {
+		r.mu.deferredRefreshOnTick = false
+		r.refreshProposalsLocked(0, reasonNewLeaderOrConfigChange)
+	}
 	return true, nil
 }
 
+// RaftTickState returns the current term and role of the replica's Raft group,
+// along with the number of ticks since the replica last observed a change in
+// its term, role or leader. A replica whose term keeps increasing without a
+// leader emerging points at election churn. Raft does not expose its internal
+// election and heartbeat timers, so the tick count is only an approximation
+// of them: changes are observed when the replica handles its Raft Ready, and
+// ticks are not counted while the replica is quiesced.
+func (r *Replica) RaftTickState() (ticksSinceChange int, term uint64, state raft.StateType) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if rg := r.mu.internalRaftGroup; rg != nil {
+		st := rg.BasicStatus()
+		term, state = st.Term, st.RaftState
+	}
+	return r.mu.ticks - r.mu.raftStateChangedAtTicks, term, state
+}
+
 func (r *Replica) hasRaftReadyRLocked() bool {
 	return r.mu.internalRaftGroup.HasReady()
 }
@@ -1140,6 +1199,7 @@ func (r *Replica) sendRaftMessage(ctx context.Context, msg raftpb.Message) {
 		return
 	}
 
+	r.raftMsgSentStats.record(toReplica.NodeID)
 	if r.maybeCoalesceHeartbeat(ctx, msg, toReplica, fromReplica, false) {
 		return
 	}
@@ -1272,6 +1332,35 @@ func (r *Replica) getAndGCSnapshotLogTruncationConstraintsLocked(
 	return minSnapIndex
 }
 
+// FollowerNeedsSnapshot returns whether the follower with the given replica ID
+// is so far behind that only a snapshot can catch it up, i.e. whether the next
+// log entry it needs has already been truncated away. The reason explains the
+// decision. Only the Raft leader tracks follower progress, so other replicas
+// always return false.
+func (r *Replica) FollowerNeedsSnapshot(replicaID roachpb.ReplicaID) (bool, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.raftStatusRLocked()
+	if !isRaftLeader(status) {
+		return false, "replica is not the raft leader"
+	}
+	pr, ok := status.Progress[uint64(replicaID)]
+	if !ok {
+		return false, fmt.Sprintf("no progress for replica %d", replicaID)
+	}
+	if pr.State == tracker.StateSnapshot {
+		return true, fmt.Sprintf("snapshot at index %d is pending", pr.PendingSnapshot)
+	}
+	firstIndex, err := r.raftFirstIndexLocked()
+	if err != nil {
+		return false, fmt.Sprintf("unable to determine first index: %s", err)
+	}
+	if neededIndex := pr.Match + 1; neededIndex < firstIndex {
+		return true, fmt.Sprintf("needed index %d precedes first index %d", neededIndex, firstIndex)
+	}
+	return false, fmt.Sprintf("match index %d is within the log starting at %d", pr.Match, firstIndex)
+}
+
 func isRaftLeader(raftStatus *raft.Status) bool {
 	return raftStatus != nil && raftStatus.SoftState.RaftState == raft.StateLeader
 }
@@ -163,6 +163,21 @@ func (r *Replica) maybeQuiesceLocked(ctx context.Context, livenessMap IsLiveMap)
 	return r.quiesceAndNotifyLocked(ctx, status)
 }
 
+// maybeQuiesce attempts to quiesce the replica outside of the usual tick
+// cycle, returning true if the replica was quiesced by this call. See
+// maybeQuiesceLocked for the conditions under which a replica quiesces.
+func (r *Replica) maybeQuiesce(ctx context.Context, livenessMap IsLiveMap) bool {
+	r.raftMu.Lock()
+	defer r.raftMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Mirror tick, which doesn't initialize the raft group either.
+	if r.mu.internalRaftGroup == nil || r.mu.quiescent {
+		return false
+	}
+	return r.maybeQuiesceLocked(ctx, livenessMap)
+}
+
 type quiescer interface {
 	descRLocked() *roachpb.RangeDescriptor
 	raftStatusRLocked() *raft.Status
@@ -11,8 +11,11 @@
 package storage
 
 import (
+	"bufio"
 	"context"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"sync/atomic"
 	"time"
@@ -357,6 +360,116 @@ func (r *Replica) GetLeaseAppliedIndex() uint64 {
 	return r.mu.state.LeaseAppliedIndex
 }
 
+// ExportRaftLog writes the raft log entries with indexes in the inclusive range
+// [firstIndex, lastIndex] to w, for offline analysis. The range is clamped to
+// the indexes currently available in the log. Sideloaded payloads are inlined.
+// Each entry is written as its uvarint-encoded length followed by the
+// marshaled raftpb.Entry; see ReadExportedRaftLog.
+func (r *Replica) ExportRaftLog(
+	ctx context.Context, w io.Writer, firstIndex, lastIndex uint64,
+) error {
+	r.raftMu.Lock()
+	defer r.raftMu.Unlock()
+	r.mu.Lock()
+	ents, err := func() ([]raftpb.Entry, error) {
+		lo, err := r.raftFirstIndexLocked()
+		if err != nil {
+			return nil, err
+		}
+		hi, err := r.raftLastIndexLocked()
+		if err != nil {
+			return nil, err
+		}
+		if firstIndex > lo {
+			lo = firstIndex
+		}
+		if lastIndex < hi {
+			hi = lastIndex
+		}
+		if lo > hi {
+			return nil, nil
+		}
+		return r.raftEntriesLocked(lo, hi+1, math.MaxUint64)
+	}()
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i := range ents {
+		data, err := protoutil.Marshal(&ents[i])
+		if err != nil {
+			return err
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+		if _, err := bw.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	log.VEventf(ctx, 1, "exported %d raft log entries", len(ents))
+	return bw.Flush()
+}
+
+// RaftLogComposition returns the number and size of the entries in the
+// replica's Raft log, distinguishing between entries stored inline and those
+// whose payload lives in sideloaded storage (i.e. AddSSTable commands). The
+// size of a sideloaded entry is that of its sideloaded payload; the thin entry
+// which remains in the log is not accounted for.
+func (r *Replica) RaftLogComposition(
+	ctx context.Context,
+) (inlineEntries, sideloadedEntries int, inlineBytes, sideloadedBytes int64, err error) {
+	r.raftMu.Lock()
+	defer r.raftMu.Unlock()
+	err = iterateEntries(ctx, r.store.Engine(), r.RangeID, 0, math.MaxUint64,
+		func(kv roachpb.KeyValue) (bool, error) {
+			var ent raftpb.Entry
+			if err := kv.Value.GetProto(&ent); err != nil {
+				return false, err
+			}
+			if !sniffSideloadedRaftCommand(ent.Data) {
+				inlineEntries++
+				inlineBytes += int64(len(kv.Value.RawBytes))
+				return false, nil
+			}
+			payload, err := r.raftMu.sideloaded.Get(ctx, ent.Index, ent.Term)
+			if err != nil {
+				return false, errors.Wrapf(err, "loading sideloaded payload at index %d", ent.Index)
+			}
+			sideloadedEntries++
+			sideloadedBytes += int64(len(payload))
+			return false, nil
+		})
+	return inlineEntries, sideloadedEntries, inlineBytes, sideloadedBytes, err
+}
+
+// ReadExportedRaftLog decodes the raft log entries written by ExportRaftLog.
+func ReadExportedRaftLog(r io.Reader) ([]raftpb.Entry, error) {
+	br := bufio.NewReader(r)
+	var ents []raftpb.Entry
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return ents, nil
+		} else if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, errors.Wrap(err, "reading exported raft log entry")
+		}
+		var ent raftpb.Entry
+		if err := protoutil.Unmarshal(data, &ent); err != nil {
+			return nil, err
+		}
+		ents = append(ents, ent)
+	}
+}
+
 // Snapshot implements the raft.Storage interface. Snapshot requires that
 // r.mu is held. Note that the returned snapshot is a placeholder and
 // does not contain any of the replica data. The snapshot is actually generated
@@ -805,6 +918,15 @@ func (r *Replica) applySnapshot(
 		log.Fatalf(ctx, "found empty HardState for non-empty Snapshot %+v", snap)
 	}
 
+	r.mu.Lock()
+	r.mu.applyingSnapshotSince = timeutil.Now()
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.mu.applyingSnapshotSince = time.Time{}
+		r.mu.Unlock()
+	}()
+
 	var stats struct {
 		clear   time.Time
 		batch   time.Time
@@ -836,7 +958,12 @@ func (r *Replica) applySnapshot(
 
 	// Use a more efficient write-only batch because we don't need to do any
 	// reads from the batch.
-	batch := r.store.Engine().NewWriteOnlyBatch()
+	var batch engine.Batch
+	if fn := r.store.cfg.TestingKnobs.SnapshotApplyBatchFactory; fn != nil {
+		batch = fn()
+	} else {
+		batch = r.store.Engine().NewWriteOnlyBatch()
+	}
 	defer batch.Close()
 
 	// If we're subsuming a replica below, we don't have its last NextReplicaID,
@@ -13,6 +13,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
@@ -164,7 +165,10 @@ func (r *Replica) RangeFeed(
 	if !args.Timestamp.IsEmpty() {
 		usingCatchupIter = true
 		lim := &r.store.limiters.ConcurrentRangefeedIters
-		if err := lim.Begin(ctx); err != nil {
+		r.store.metrics.RangeFeedCatchUpScansQueued.Inc(1)
+		err := lim.Begin(ctx)
+		r.store.metrics.RangeFeedCatchUpScansQueued.Dec(1)
+		if err != nil {
 			return roachpb.NewError(err)
 		}
 		// Finish the iterator limit, but only if we exit before
@@ -182,7 +186,7 @@ func (r *Replica) RangeFeed(
 	// critical-section as the registration is established. This ensures that
 	// the registration doesn't miss any events.
 	r.raftMu.Lock()
-	if err := r.requestCanProceed(rspan, checkTS); err != nil {
+	if err := r.requestCanProceed(rspan, checkTS, false /* allowProtectedRead */); err != nil {
 		r.raftMu.Unlock()
 		return roachpb.NewError(err)
 	}
@@ -209,9 +213,14 @@ func (r *Replica) RangeFeed(
 			// workable. See #35122 for details.
 			// MinTimestampHint: args.Timestamp,
 		})
+		r.beginCatchUpScan()
+		release := iterSemRelease
 		catchUpIter = iteratorWithCloser{
 			SimpleIterator: innerIter,
-			close:          iterSemRelease,
+			close: func() {
+				r.endCatchUpScan()
+				release()
+			},
 		}
 		// Responsibility for releasing the semaphore now passes to the iterator.
 		iterSemRelease = nil
@@ -229,6 +238,24 @@ func (r *Replica) RangeFeed(
 	return <-errC
 }
 
+func (r *Replica) beginCatchUpScan() {
+	atomic.AddInt32(&r.activeCatchUpScans, 1)
+	r.store.metrics.RangeFeedCatchUpScansActive.Inc(1)
+}
+
+func (r *Replica) endCatchUpScan() {
+	atomic.AddInt32(&r.activeCatchUpScans, -1)
+	r.store.metrics.RangeFeedCatchUpScansActive.Dec(1)
+}
+
+// ActiveCatchUpScans returns the number of rangefeed catch-up scans currently
+// running on the replica. Catch-up scans are performed when a rangefeed is
+// registered with a start timestamp and can be expensive, so this helps
+// correlate resource usage with rangefeed registrations.
+func (r *Replica) ActiveCatchUpScans() int {
+	return int(atomic.LoadInt32(&r.activeCatchUpScans))
+}
+
 func (r *Replica) getRangefeedProcessor() *rangefeed.Processor {
 	r.rangefeedMu.RLock()
 	defer r.rangefeedMu.RUnlock()
@@ -260,6 +260,174 @@ func TestReplicaRangefeed(t *testing.T) {
 	})
 }
 
+// blockingStream is a testStream whose Send blocks until unblock is closed.
+type blockingStream struct {
+	*testStream
+	unblock chan struct{}
+}
+
+func (s *blockingStream) Send(e *roachpb.RangeFeedEvent) error {
+	select {
+	case <-s.unblock:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	return s.testStream.Send(e)
+}
+
+// TestReplicaRangefeedActiveCatchUpScans verifies that a rangefeed registered
+// with a start timestamp in the past is reported as an active catch-up scan
+// until the scan has completed.
+func TestReplicaRangefeedActiveCatchUpScans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sc := storage.TestStoreConfig(nil)
+	storage.RangefeedEnabled.Override(&sc.Settings.SV, true)
+	mtc := &multiTestContext{storeConfig: &sc, startWithSingleRange: true}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.Store(0)
+	db := mtc.dbs[0].NonTransactionalSender()
+
+	// Split the range so that the RHS uses epoch-based leases.
+	startKey := roachpb.Key("a")
+	if _, pErr := client.SendWrapped(ctx, db, adminSplitArgs(startKey)); pErr != nil {
+		t.Fatal(pErr)
+	}
+	repl := store.LookupReplica(roachpb.RKey(startKey))
+
+	// Write a key for the catch-up scan to emit.
+	initTime := mtc.clock.Now()
+	mtc.manualClock.Increment(1)
+	if _, pErr := client.SendWrapped(ctx, db, putArgs(roachpb.Key("b"), []byte("val"))); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	expActive := func(exp int) error {
+		if act := repl.ActiveCatchUpScans(); act != exp {
+			return errors.Errorf("expected %d active catch-up scans, found %d", exp, act)
+		}
+		if act := store.Metrics().RangeFeedCatchUpScansActive.Value(); act != int64(exp) {
+			return errors.Errorf("expected catch-up scans gauge to be %d, found %d", exp, act)
+		}
+		return nil
+	}
+	if err := expActive(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The catch-up scan blocks on sending its first event.
+	stream := &blockingStream{testStream: newTestStream(), unblock: make(chan struct{})}
+	streamErrC := make(chan *roachpb.Error, 1)
+	go func() {
+		req := roachpb.RangeFeedRequest{
+			Header: roachpb.Header{Timestamp: initTime, RangeID: repl.RangeID},
+			Span:   roachpb.Span{Key: startKey, EndKey: roachpb.Key("z")},
+		}
+		streamErrC <- store.RangeFeed(&req, stream)
+	}()
+	testutils.SucceedsSoon(t, func() error { return expActive(1) })
+
+	close(stream.unblock)
+	testutils.SucceedsSoon(t, func() error { return expActive(0) })
+	if events := stream.Events(); len(events) == 0 || events[0].Val == nil ||
+		!events[0].Val.Key.Equal(roachpb.Key("b")) {
+		t.Fatalf("expected catch-up scan to emit value for key b, found %v", events)
+	}
+
+	stream.Cancel()
+	if pErr := <-streamErrC; !testutils.IsPError(pErr, "context canceled") {
+		t.Fatalf("got error for RangeFeed: %v", pErr)
+	}
+}
+
+// TestReplicaRangefeedCatchUpScanLimit verifies that catch-up scans beyond
+// the concurrency limit are queued until earlier ones complete.
+func TestReplicaRangefeedCatchUpScanLimit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sc := storage.TestStoreConfig(nil)
+	storage.RangefeedEnabled.Override(&sc.Settings.SV, true)
+	storage.ConcurrentRangefeedItersLimit.Override(&sc.Settings.SV, 1)
+	mtc := &multiTestContext{storeConfig: &sc, startWithSingleRange: true}
+	defer mtc.Stop()
+	mtc.Start(t, 1)
+	store := mtc.Store(0)
+	db := mtc.dbs[0].NonTransactionalSender()
+
+	// Split the range so that the RHS uses epoch-based leases.
+	startKey := roachpb.Key("a")
+	if _, pErr := client.SendWrapped(ctx, db, adminSplitArgs(startKey)); pErr != nil {
+		t.Fatal(pErr)
+	}
+	repl := store.LookupReplica(roachpb.RKey(startKey))
+
+	// Write a key for the catch-up scans to emit.
+	initTime := mtc.clock.Now()
+	mtc.manualClock.Increment(1)
+	if _, pErr := client.SendWrapped(ctx, db, putArgs(roachpb.Key("b"), []byte("val"))); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	expScans := func(expActive, expQueued int) error {
+		if act := repl.ActiveCatchUpScans(); act != expActive {
+			return errors.Errorf("expected %d active catch-up scans, found %d", expActive, act)
+		}
+		if act := store.Metrics().RangeFeedCatchUpScansQueued.Value(); act != int64(expQueued) {
+			return errors.Errorf("expected %d queued catch-up scans, found %d", expQueued, act)
+		}
+		return nil
+	}
+
+	// Each catch-up scan blocks on sending its first event.
+	var streams [2]*blockingStream
+	streamErrCs := make(chan *roachpb.Error, len(streams))
+	for i := range streams {
+		stream := &blockingStream{testStream: newTestStream(), unblock: make(chan struct{})}
+		streams[i] = stream
+		go func() {
+			req := roachpb.RangeFeedRequest{
+				Header: roachpb.Header{Timestamp: initTime, RangeID: repl.RangeID},
+				Span:   roachpb.Span{Key: startKey, EndKey: roachpb.Key("z")},
+			}
+			streamErrCs <- store.RangeFeed(&req, stream)
+		}()
+		// The second catch-up scan is queued behind the first.
+		testutils.SucceedsSoon(t, func() error { return expScans(1, i) })
+	}
+
+	// Once the first catch-up scan completes, the second one begins.
+	close(streams[0].unblock)
+	testutils.SucceedsSoon(t, func() error { return expScans(1, 0) })
+	testutils.SucceedsSoon(t, func() error {
+		if len(streams[0].Events()) == 0 {
+			return errors.New("expected first catch-up scan to emit events")
+		}
+		return nil
+	})
+	if events := streams[1].Events(); len(events) != 0 {
+		t.Fatalf("expected second catch-up scan to be blocked, found %v", events)
+	}
+
+	close(streams[1].unblock)
+	testutils.SucceedsSoon(t, func() error { return expScans(0, 0) })
+	if events := streams[1].Events(); len(events) == 0 || events[0].Val == nil ||
+		!events[0].Val.Key.Equal(roachpb.Key("b")) {
+		t.Fatalf("expected catch-up scan to emit value for key b, found %v", events)
+	}
+
+	for _, stream := range streams {
+		stream.Cancel()
+	}
+	for range streams {
+		if pErr := <-streamErrCs; !testutils.IsPError(pErr, "context canceled") {
+			t.Fatalf("got error for RangeFeed: %v", pErr)
+		}
+	}
+}
+
 func TestReplicaRangefeedExpiringLeaseError(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
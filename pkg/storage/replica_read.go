@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/spanset"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagepb"
@@ -29,6 +30,11 @@ import (
 func (r *Replica) executeReadOnlyBatch(
 	ctx context.Context, ba *roachpb.BatchRequest,
 ) (br *roachpb.BatchResponse, pErr *roachpb.Error) {
+	// Move the timestamp of the batch above the GC threshold if it asked for
+	// it. This needs to happen before the closed timestamp is consulted and
+	// latches are acquired, both of which depend on the timestamp.
+	clamped := r.maybeClampReadTimestamp(ba)
+
 	// If the read is not inconsistent, the read requires the range lease or
 	// permission to serve via follower reads.
 	var status storagepb.LeaseStatus
@@ -77,7 +83,7 @@ func (r *Replica) executeReadOnlyBatch(
 		return nil, roachpb.NewError(err)
 	}
 
-	if err := r.requestCanProceed(rSpan, ba.Timestamp); err != nil {
+	if err := r.requestCanProceed(rSpan, ba.Timestamp, ba.AllowReadBelowGCThreshold); err != nil {
 		return nil, roachpb.NewError(err)
 	}
 
@@ -120,7 +126,52 @@ func (r *Replica) executeReadOnlyBatch(
 	if pErr != nil {
 		log.VErrEvent(ctx, 3, pErr.String())
 	} else {
+		br.ReadTimestampClamped = clamped
+		r.readBytesStats.recordCount(float64(readBytes(ba, br)), 0 /* nodeID */)
 		log.Event(ctx, "read completed")
 	}
 	return br, pErr
 }
+
+// readBytes returns the number of key and value bytes returned by a read-only
+// batch. Span requests report the bytes they counted during evaluation in
+// their NumBytes; Gets don't, so their key and value are counted the same way
+// here.
+func readBytes(ba *roachpb.BatchRequest, br *roachpb.BatchResponse) int64 {
+	var n int64
+	for i, ru := range br.Responses {
+		switch resp := ru.GetInner().(type) {
+		case *roachpb.GetResponse:
+			if resp.Value != nil {
+				key := engine.MVCCKey{Key: ba.Requests[i].GetGet().Key, Timestamp: resp.Value.Timestamp}
+				n += int64(engine.EncodedKeySize(key) + len(resp.Value.RawBytes))
+			}
+		default:
+			n += resp.Header().NumBytes
+		}
+	}
+	return n
+}
+
+// maybeClampReadTimestamp moves the timestamp of a non-transactional batch
+// which set ClampReadTimestamp to just above the replica's GC threshold, if it
+// is at or below the threshold. Returns whether the timestamp was moved.
+//
+// Transactional batches are never clamped since that would allow a
+// transaction to observe data at different timestamps. Non-transactional
+// batches spanning multiple ranges are split by the DistSender and each range
+// clamps against its own threshold, so such a batch may read each range at a
+// different timestamp; callers opting into clamping accept that.
+func (r *Replica) maybeClampReadTimestamp(ba *roachpb.BatchRequest) bool {
+	if !ba.ClampReadTimestamp || ba.Txn != nil {
+		return false
+	}
+	r.mu.RLock()
+	threshold := *r.mu.state.GCThreshold
+	r.mu.RUnlock()
+	if threshold.Less(ba.Timestamp) {
+		return false
+	}
+	ba.Timestamp = threshold.Next()
+	return true
+}
@@ -49,11 +49,24 @@ type SideloadStorage interface {
 	// the given one. Returns the number of bytes freed, the number of bytes in
 	// files that remain, or an error.
 	TruncateTo(_ context.Context, index uint64) (freed, retained int64, _ error)
+	// Size returns the total size of the files in the storage.
+	Size(context.Context) (int64, error)
 	// Returns an absolute path to the file that Get() would return the contents
 	// of. Does not check whether the file actually exists.
 	Filename(_ context.Context, index, term uint64) (string, error)
 }
 
+// SideloadedBytes returns the total size of the replica's sideloaded SSTables,
+// i.e. of the payloads of the AddSSTable commands in its Raft log.
+func (r *Replica) SideloadedBytes(ctx context.Context) (int64, error) {
+	r.raftMu.Lock()
+	defer r.raftMu.Unlock()
+	if r.raftMu.sideloaded == nil {
+		return 0, nil
+	}
+	return r.raftMu.sideloaded.Size(ctx)
+}
+
 // maybeSideloadEntriesRaftMuLocked should be called with a slice of "fat"
 // entries before appending them to the Raft log. For those entries which are
 // sideloadable, this is where the actual sideloading happens: in come fat
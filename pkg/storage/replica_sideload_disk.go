@@ -296,6 +296,22 @@ func (ss *diskSideloadStorage) TruncateTo(
 	return bytesFreed, bytesRetained, nil
 }
 
+// Size implements SideloadStorage.
+func (ss *diskSideloadStorage) Size(ctx context.Context) (int64, error) {
+	var size int64
+	if err := ss.forEach(ctx, func(_ uint64, filename string) error {
+		fileSize, err := ss.fileSize(filename)
+		if err != nil {
+			return err
+		}
+		size += fileSize
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
 func (ss *diskSideloadStorage) forEach(
 	ctx context.Context, visit func(index uint64, filename string) error,
 ) error {
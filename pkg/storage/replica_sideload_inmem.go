@@ -110,3 +110,11 @@ func (ss *inMemSideloadStorage) TruncateTo(
 	}
 	return freed, retained, nil
 }
+
+func (ss *inMemSideloadStorage) Size(_ context.Context) (int64, error) {
+	var size int64
+	for _, v := range ss.m {
+		size += int64(len(v))
+	}
+	return size, nil
+}
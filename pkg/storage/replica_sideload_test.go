@@ -788,6 +788,69 @@ func testRaftSSTableSideloadingProposal(t *testing.T, engineInMem, mockSideloade
 	verifyLogSizeInSync(t, tc.repl)
 }
 
+func TestReplicaRaftLogComposition(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer SetMockAddSSTable()()
+
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	cfg := TestStoreConfig(nil)
+	// Prevent the log from being truncated underneath the test.
+	cfg.TestingKnobs.DisableRaftLogQueue = true
+	tc.StartWithStoreConfig(t, stopper, cfg)
+	makeInMemSideloaded(tc.repl)
+	ctx := context.Background()
+
+	type composition struct {
+		inlineEntries, sideloadedEntries int
+		inlineBytes, sideloadedBytes     int64
+	}
+	getComposition := func() composition {
+		t.Helper()
+		var c composition
+		var err error
+		c.inlineEntries, c.sideloadedEntries, c.inlineBytes, c.sideloadedBytes, err =
+			tc.repl.RaftLogComposition(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	initial := getComposition()
+	if initial.sideloadedEntries != 0 || initial.sideloadedBytes != 0 {
+		t.Fatalf("expected no sideloaded entries initially, got %+v", initial)
+	}
+
+	// Pure KV writes only affect the inline entries.
+	for i := 0; i < 5; i++ {
+		args := putArgs(roachpb.Key(fmt.Sprintf("key%d", i)), []byte("value"))
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	afterPuts := getComposition()
+	if afterPuts.inlineEntries < initial.inlineEntries+5 || afterPuts.inlineBytes <= initial.inlineBytes {
+		t.Fatalf("expected inline entries to grow from %+v, got %+v", initial, afterPuts)
+	}
+	if afterPuts.sideloadedEntries != 0 || afterPuts.sideloadedBytes != 0 {
+		t.Fatalf("expected no sideloaded entries after puts, got %+v", afterPuts)
+	}
+
+	// An AddSSTable is sideloaded.
+	if err := ProposeAddSSTable(ctx, "foo", strings.Repeat("x", 128), hlc.Timestamp{Logical: 1}, tc.store); err != nil {
+		t.Fatal(err)
+	}
+	afterSST := getComposition()
+	if afterSST.sideloadedEntries != 1 || afterSST.sideloadedBytes <= 0 {
+		t.Fatalf("expected one sideloaded entry, got %+v", afterSST)
+	}
+	if afterSST.inlineBytes < afterPuts.inlineBytes {
+		t.Fatalf("expected inline bytes not to shrink from %+v, got %+v", afterPuts, afterSST)
+	}
+}
+
 type mockSender struct {
 	logEntries [][]byte
 	done       bool
@@ -890,7 +953,7 @@ func TestRaftSSTableSideloadingSnapshot(t *testing.T) {
 			SnapshotRequest_Header{State: os.State, Priority: SnapshotRequest_RECOVERY},
 			os,
 			tc.repl.store.Engine().NewBatch,
-			func() {},
+			func(int64) {},
 		); err != nil {
 			t.Fatal(err)
 		}
@@ -1012,7 +1075,7 @@ func TestRaftSSTableSideloadingSnapshot(t *testing.T) {
 			SnapshotRequest_Header{State: failingOS.State, Priority: SnapshotRequest_RECOVERY},
 			failingOS,
 			tc.repl.store.Engine().NewBatch,
-			func() {},
+			func(int64) {},
 		)
 		if _, ok := errors.Cause(err).(*errMustRetrySnapshotDueToTruncation); !ok {
 			t.Fatal(err)
@@ -1090,3 +1153,53 @@ func TestRaftSSTableSideloadingTruncation(t *testing.T) {
 	}
 
 }
+
+// TestStoreTotalSideloadedBytes verifies that Store.TotalSideloadedBytes
+// accounts for the payloads of AddSSTable commands until the Raft log is
+// truncated.
+func TestStoreTotalSideloadedBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer SetMockAddSSTable()()
+
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	makeInMemSideloaded(tc.repl)
+	ctx := context.Background()
+
+	checkTotal := func(exp int64) {
+		t.Helper()
+		if total, err := tc.store.TotalSideloadedBytes(); err != nil {
+			t.Fatal(err)
+		} else if total != exp {
+			t.Fatalf("expected %d sideloaded bytes, got %d", exp, total)
+		}
+	}
+	checkTotal(0)
+
+	var expTotal int64
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		val := strings.Repeat("x", 100*(i+1))
+		ts := tc.Clock().Now()
+		data, _ := MakeSSTable(key, val, ts)
+		if err := ProposeAddSSTable(ctx, key, val, ts, tc.store); err != nil {
+			t.Fatalf("%d: %+v", i, err)
+		}
+		expTotal += int64(len(data))
+		checkTotal(expTotal)
+	}
+
+	lastIndex, err := tc.repl.GetLastIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncateArgs := truncateLogArgs(lastIndex+1, tc.repl.RangeID)
+	if _, pErr := client.SendWrappedWith(
+		ctx, tc.Sender(), roachpb.Header{RangeID: tc.repl.RangeID}, &truncateArgs,
+	); pErr != nil {
+		t.Fatal(pErr)
+	}
+	checkTotal(0)
+}
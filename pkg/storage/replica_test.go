@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
@@ -409,6 +410,15 @@ func assignSeqNumsForReqs(txn *roachpb.Transaction, reqs ...roachpb.Request) {
 	}
 }
 
+// assignSeqNumsForBatch sets sequence numbers for each of the requests in the
+// provided batch, in order, given a transaction proto. It also updates the
+// proto to reflect the incremented sequence number.
+func assignSeqNumsForBatch(txn *roachpb.Transaction, ba *roachpb.BatchRequest) {
+	for _, ru := range ba.Requests {
+		assignSeqNumsForReqs(txn, ru.GetInner())
+	}
+}
+
 // createReplicaSets creates new roachpb.ReplicaDescriptor protos based on an array of
 // StoreIDs to aid in testing. Note that this does not actually produce any
 // replicas, it just creates the descriptors.
@@ -422,6 +432,35 @@ func createReplicaSets(replicaNumbers []roachpb.StoreID) []roachpb.ReplicaDescri
 	return result
 }
 
+// TestAssignSeqNumsForBatch verifies that assignSeqNumsForBatch assigns
+// monotonically increasing sequence numbers to every request in a batch, in
+// order, and advances the transaction's sequence number accordingly.
+func TestAssignSeqNumsForBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	txn := newTransaction("test", roachpb.Key("a"), 1, nil)
+	put1 := putArgs(roachpb.Key("a"), []byte("value"))
+	inc1 := incrementArgs(roachpb.Key("b"), 1)
+	put2 := putArgs(roachpb.Key("c"), []byte("value"))
+	inc2 := incrementArgs(roachpb.Key("b"), 2)
+
+	var ba roachpb.BatchRequest
+	ba.Add(&put1, &inc1, &put2, &inc2)
+	assignSeqNumsForBatch(txn, &ba)
+
+	var prev enginepb.TxnSeq
+	for i, ru := range ba.Requests {
+		seq := ru.GetInner().Header().Sequence
+		if seq <= prev {
+			t.Errorf("%d: expected sequence number > %d, got %d", i, prev, seq)
+		}
+		prev = seq
+	}
+	if txn.Sequence != prev {
+		t.Errorf("expected txn sequence %d, got %d", prev, txn.Sequence)
+	}
+}
+
 // TestMaybeStripInFlightWrites verifies that in-flight writes declared
 // on an EndTransaction request are stripped if the corresponding write
 // or query intent is in the same batch as the EndTransaction.
@@ -2661,6 +2700,65 @@ func TestReplicaLatchingTimestampNonInterference(t *testing.T) {
 	}
 }
 
+// TestReplicaHeldLatches verifies that the latches of a write which is
+// blocked during evaluation are reported by HeldLatches, and that they are no
+// longer reported once the write completes.
+func TestReplicaHeldLatches(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	key := roachpb.Key("a")
+	blockedCh := make(chan struct{}, 1)
+	blockCh := make(chan struct{})
+
+	tc := testContext{}
+	tsc := TestStoreConfig(nil)
+	tsc.TestingKnobs.EvalKnobs.TestingEvalFilter =
+		func(filterArgs storagebase.FilterArgs) *roachpb.Error {
+			if filterArgs.Req.Method() == roachpb.Put && filterArgs.Req.Header().Key.Equal(key) {
+				select {
+				case blockedCh <- struct{}{}:
+					<-blockCh
+				default:
+				}
+			}
+			return nil
+		}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.StartWithStoreConfig(t, stopper, tsc)
+
+	errCh := make(chan *roachpb.Error, 1)
+	go func() {
+		pArgs := putArgs(key, []byte("value"))
+		_, pErr := tc.SendWrapped(&pArgs)
+		errCh <- pErr
+	}()
+	<-blockedCh
+
+	findLatch := func() bool {
+		for _, la := range tc.repl.HeldLatches() {
+			if la.Span.Key.Equal(key) && la.Access == spanset.SpanReadWrite.String() {
+				if la.HeldFor < 0 {
+					t.Errorf("expected non-negative hold duration, got %s", la.HeldFor)
+				}
+				return true
+			}
+		}
+		return false
+	}
+	if !findLatch() {
+		t.Fatalf("expected write latch on %s in %v", key, tc.repl.HeldLatches())
+	}
+
+	close(blockCh)
+	if pErr := <-errCh; pErr != nil {
+		t.Fatal(pErr)
+	}
+	if findLatch() {
+		t.Fatalf("expected write latch on %s to be released", key)
+	}
+}
+
 // TestReplicaLatchingSplitDeclaresWrites verifies that split
 // operations declare write access to their entire span. This is
 // necessary to avoid conflicting changes to the range's stats, even
@@ -5188,6 +5286,338 @@ func TestPushTxnHeartbeatTimeout(t *testing.T) {
 	}
 }
 
+// TestReplicaHostedTransactions verifies that the transaction records stored
+// on a range are reported along with their statuses.
+func TestReplicaHostedTransactions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+
+	// Create a PENDING and a STAGING transaction record.
+	pending := newTransaction("pending", roachpb.Key("a"), 1, tc.Clock())
+	hb, hbH := heartbeatArgs(pending, tc.Clock().Now())
+	if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), hbH, &hb); pErr != nil {
+		t.Fatal(pErr)
+	}
+	staging := newTransaction("staging", roachpb.Key("b"), 1, tc.Clock())
+	et, etH := endTxnArgs(staging, true)
+	et.InFlightWrites = []roachpb.SequencedWrite{{Key: roachpb.Key("b"), Sequence: 1}}
+	if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), etH, &et); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	txns, err := tc.repl.HostedTransactions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[uuid.UUID]roachpb.TransactionStatus)
+	for _, txn := range txns {
+		statuses[txn.ID] = txn.Status
+	}
+	if status, ok := statuses[pending.ID]; !ok || status != roachpb.PENDING {
+		t.Errorf("expected PENDING record for %s, got %v (found: %t)", pending.ID.Short(), status, ok)
+	}
+	if status, ok := statuses[staging.ID]; !ok || status != roachpb.STAGING {
+		t.Errorf("expected STAGING record for %s, got %v (found: %t)", staging.ID.Short(), status, ok)
+	}
+}
+
+// TestReplicaKeyIntentStatus verifies that KeyIntentStatus reports the
+// transaction holding an intent on a key, and nothing for keys without one.
+func TestReplicaKeyIntentStatus(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+
+	// Write an intent on "a" and a committed value on "b".
+	txn := newTransaction("test", roachpb.Key("a"), 1, tc.Clock())
+	txn.Sequence = 1
+	put := putArgs(roachpb.Key("a"), []byte("value"))
+	if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), roachpb.Header{Txn: txn}, &put); pErr != nil {
+		t.Fatal(pErr)
+	}
+	put = putArgs(roachpb.Key("b"), []byte("value"))
+	if _, pErr := client.SendWrapped(ctx, tc.Sender(), &put); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	hasIntent, txnID, ts, err := tc.repl.KeyIntentStatus(ctx, roachpb.Key("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasIntent || txnID != txn.ID || ts != txn.Timestamp {
+		t.Fatalf("expected intent of txn %s at %s, got hasIntent=%t txn=%s ts=%s",
+			txn.ID, txn.Timestamp, hasIntent, txnID, ts)
+	}
+	for _, key := range []string{"b", "c"} {
+		hasIntent, txnID, _, err := tc.repl.KeyIntentStatus(ctx, roachpb.Key(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hasIntent || txnID != (uuid.UUID{}) {
+			t.Fatalf("expected no intent on %s, got intent of txn %s", key, txnID)
+		}
+	}
+}
+
+// TestStoreRecoverIndeterminateCommit verifies that a transaction abandoned
+// in the STAGING state can be recovered through the store once it has
+// expired, and that requests to recover transactions which are not in an
+// indeterminate state are handled appropriately.
+func TestStoreRecoverIndeterminateCommit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+
+	// Write the transaction's only intent and then stage its record with the
+	// intent as an in-flight write. All of the transaction's writes succeeded,
+	// so recovery should find it implicitly committed.
+	key := roachpb.Key("a")
+	txn := newTransaction("staging", key, 1, tc.Clock())
+	txn.Sequence = 1
+	put := putArgs(key, []byte("value"))
+	if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), roachpb.Header{Txn: txn}, &put); pErr != nil {
+		t.Fatal(pErr)
+	}
+	et, etH := endTxnArgs(txn, true)
+	et.InFlightWrites = []roachpb.SequencedWrite{{Key: key, Sequence: 1}}
+	etH.Txn.Sequence = 2
+	if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), etH, &et); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// The transaction's coordinator may still be about to commit it.
+	if _, err := tc.store.RecoverIndeterminateCommit(ctx, txn.Key, txn.ID); !testutils.IsError(
+		err, "may still be live",
+	) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tc.manualClock.Increment(txnwait.TxnLivenessThreshold.Nanoseconds() + 1)
+	status, err := tc.store.RecoverIndeterminateCommit(ctx, txn.Key, txn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != roachpb.COMMITTED {
+		t.Fatalf("expected COMMITTED, got %s", status)
+	}
+	// Recovering a finalized transaction is a no-op.
+	if status, err := tc.store.RecoverIndeterminateCommit(ctx, txn.Key, txn.ID); err != nil {
+		t.Fatal(err)
+	} else if status != roachpb.COMMITTED {
+		t.Fatalf("expected COMMITTED, got %s", status)
+	}
+
+	// PENDING transactions can't be recovered.
+	pending := newTransaction("pending", roachpb.Key("b"), 1, tc.Clock())
+	hb, hbH := heartbeatArgs(pending, tc.Clock().Now())
+	if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), hbH, &hb); pErr != nil {
+		t.Fatal(pErr)
+	}
+	if _, err := tc.store.RecoverIndeterminateCommit(ctx, pending.Key, pending.ID); !testutils.IsError(
+		err, "not in an indeterminate commit state",
+	) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A transaction without a record which can no longer create one is
+	// reported as aborted.
+	if status, err := tc.store.RecoverIndeterminateCommit(ctx, key, uuid.MakeV4()); err != nil {
+		t.Fatal(err)
+	} else if status != roachpb.ABORTED {
+		t.Fatalf("expected ABORTED, got %s", status)
+	}
+}
+
+// TestReplicaApplyCommittedEntriesMetrics verifies that the stats collected
+// while applying committed Raft entries are reflected in the store's metrics.
+func TestReplicaApplyCommittedEntriesMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+	m := tc.store.metrics
+
+	// The leader election on startup committed an empty entry.
+	if n := m.RaftApplyCommittedEmptyEntries.Count(); n == 0 {
+		t.Fatalf("expected empty entries to have been applied")
+	}
+
+	batches, entries := m.RaftApplyCommittedBatches.Count(), m.RaftApplyCommittedEntries.Count()
+	args := putArgs(roachpb.Key("a"), []byte("value"))
+	if _, pErr := client.SendWrapped(ctx, tc.Sender(), &args); pErr != nil {
+		t.Fatal(pErr)
+	}
+	// The write may be acknowledged before its application is complete.
+	testutils.SucceedsSoon(t, func() error {
+		if n := m.RaftApplyCommittedBatches.Count(); n <= batches {
+			return errors.Errorf("expected more than %d applied batches, got %d", batches, n)
+		}
+		if n := m.RaftApplyCommittedEntries.Count(); n <= entries {
+			return errors.Errorf("expected more than %d applied entries, got %d", entries, n)
+		}
+		return nil
+	})
+}
+
+// TestReplicaMaxIntentsPerRange verifies that a transaction is prevented from
+// leaving more intents on a range than permitted by
+// kv.transaction.max_intents_per_range.
+func TestReplicaMaxIntentsPerRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+	maxIntentsPerRange.Override(&tc.store.cfg.Settings.SV, 2)
+
+	put := func(txn *roachpb.Transaction, key string) *roachpb.Error {
+		txn.Sequence++
+		args := putArgs(roachpb.Key(key), []byte("value"))
+		_, pErr := client.SendWrappedWith(ctx, tc.Sender(), roachpb.Header{Txn: txn}, &args)
+		return pErr
+	}
+
+	// A transaction can write up to the limit, and rewriting one of its own
+	// intents doesn't count against it.
+	txn := newTransaction("big", roachpb.Key("a"), 1, tc.Clock())
+	for _, key := range []string{"a", "b", "a"} {
+		if pErr := put(txn, key); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	// Exceeding the limit is rejected.
+	pErr := put(txn, "c")
+	tmiErr, ok := pErr.GetDetail().(*roachpb.TooManyIntentsError)
+	if !ok {
+		t.Fatalf("expected TooManyIntentsError, got %v", pErr)
+	}
+	if tmiErr.IntentCount != 3 || tmiErr.MaxIntents != 2 {
+		t.Fatalf("unexpected error: %+v", tmiErr)
+	}
+
+	// Other transactions are unaffected.
+	small := newTransaction("small", roachpb.Key("d"), 1, tc.Clock())
+	if pErr := put(small, "d"); pErr != nil {
+		t.Fatal(pErr)
+	}
+}
+
+// TestReplicaMaxIntentsPerRangeReproposal verifies that the intents of a
+// command which is reproposed only count once against its transaction.
+func TestReplicaMaxIntentsPerRangeReproposal(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+	maxIntentsPerRange.Override(&tc.store.cfg.Settings.SV, 2)
+
+	txn := newTransaction("test", roachpb.Key("a"), 1, tc.Clock())
+	put := func(key string) *roachpb.Error {
+		txn.Sequence++
+		args := putArgs(roachpb.Key(key), []byte("value"))
+		_, pErr := client.SendWrappedWith(ctx, tc.Sender(), roachpb.Header{Txn: txn}, &args)
+		return pErr
+	}
+
+	// Reject the application of the first write of the transaction so that it
+	// is reproposed.
+	var rejected int64 // accessed atomically
+	r := tc.repl
+	r.mu.Lock()
+	r.mu.proposalBuf.testing.leaseIndexFilter = func(p *ProposalData) (indexOverride uint64, _ error) {
+		if p.Request.Txn != nil && p.Request.Txn.ID == txn.ID &&
+			atomic.CompareAndSwapInt64(&rejected, 0, 1) {
+			return p.command.MaxLeaseIndex - 1, nil
+		}
+		return 0, nil
+	}
+	r.mu.Unlock()
+
+	if pErr := put("a"); pErr != nil {
+		t.Fatal(pErr)
+	}
+	if atomic.LoadInt64(&rejected) == 0 {
+		t.Fatal("expected the write to be reproposed")
+	}
+	r.txnIntentCounts.Lock()
+	count := r.txnIntentCounts.m[txn.ID]
+	r.txnIntentCounts.Unlock()
+	if count != 1 {
+		t.Fatalf("expected an intent count of 1, got %d", count)
+	}
+	if pErr := put("b"); pErr != nil {
+		t.Fatal(pErr)
+	}
+	if _, ok := put("c").GetDetail().(*roachpb.TooManyIntentsError); !ok {
+		t.Fatal("expected TooManyIntentsError")
+	}
+}
+
+// TestReplicaMaxIntentsPerRangeLeaseTransfer verifies that the intent counts
+// are cleared when the lease changes hands.
+func TestReplicaMaxIntentsPerRangeLeaseTransfer(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+
+	tc := testContext{manualClock: hlc.NewManualClock(123)}
+	cfg := TestStoreConfig(hlc.NewClock(tc.manualClock.UnixNano, time.Nanosecond))
+	cfg.TestingKnobs.DisableAutomaticLeaseRenewal = true
+	tc.StartWithStoreConfig(t, stopper, cfg)
+	ctx := context.Background()
+	maxIntentsPerRange.Override(&tc.store.cfg.Settings.SV, 2)
+
+	secondReplica, err := tc.addBogusReplicaToRangeDesc(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := newTransaction("test", roachpb.Key("a"), 1, tc.Clock())
+	for _, key := range []string{"a", "b"} {
+		txn.Sequence++
+		args := putArgs(roachpb.Key(key), []byte("value"))
+		if _, pErr := client.SendWrappedWith(ctx, tc.Sender(), roachpb.Header{Txn: txn}, &args); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	intentCounts := func() int {
+		tc.repl.txnIntentCounts.Lock()
+		defer tc.repl.txnIntentCounts.Unlock()
+		return len(tc.repl.txnIntentCounts.m)
+	}
+	if n := intentCounts(); n != 1 {
+		t.Fatalf("expected the intents of 1 transaction to be counted, got %d", n)
+	}
+
+	tc.manualClock.Set(leaseExpiry(tc.repl))
+	now := tc.Clock().Now()
+	if err := sendLeaseRequest(tc.repl, &roachpb.Lease{
+		Start:      now,
+		Expiration: now.Add(10, 0).Clone(),
+		Replica:    secondReplica,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := intentCounts(); n != 0 {
+		t.Fatalf("expected the intent counts to be cleared, got %d", n)
+	}
+}
+
 // TestResolveIntentPushTxnReplyTxn makes sure that no Txn is returned from
 // PushTxn and that it and ResolveIntent{,Range} can not be carried out in a
 // transaction.
@@ -6782,7 +7212,10 @@ func TestEntries(t *testing.T) {
 	repl.mu.Unlock()
 }
 
-func TestTerm(t *testing.T) {
+// TestReplicaExportRaftLog verifies that raft log entries exported by
+// ExportRaftLog can be read back with matching indexes and terms, and that
+// the exported range is clamped to the available log.
+func TestReplicaExportRaftLog(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	tc := testContext{}
 	tsc := TestStoreConfig(nil)
@@ -6791,18 +7224,16 @@ func TestTerm(t *testing.T) {
 	defer stopper.Stop(context.TODO())
 	tc.StartWithStoreConfig(t, stopper, tsc)
 
+	ctx := context.Background()
 	repl := tc.repl
-	rangeID := repl.RangeID
 
-	// Populate the log with 10 entries. Save the LastIndex after each write.
 	var indexes []uint64
 	for i := 0; i < 10; i++ {
 		args := incrementArgs([]byte("a"), int64(i))
-
 		if _, pErr := tc.SendWrapped(&args); pErr != nil {
 			t.Fatal(pErr)
 		}
-		idx, err := tc.repl.GetLastIndex()
+		idx, err := repl.GetLastIndex()
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -6810,21 +7241,101 @@ func TestTerm(t *testing.T) {
 	}
 
 	// Discard the first half of the log.
-	truncateArgs := truncateLogArgs(indexes[5], rangeID)
+	truncateArgs := truncateLogArgs(indexes[5], repl.RangeID)
 	if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &truncateArgs); pErr != nil {
 		t.Fatal(pErr)
 	}
 
-	repl.mu.Lock()
-	defer repl.mu.Unlock()
-
-	firstIndex, err := repl.raftFirstIndexLocked()
-	if err != nil {
-		t.Fatal(err)
-	}
-	if firstIndex != indexes[5] {
-		t.Fatalf("expected firstIndex %d to be %d", firstIndex, indexes[4])
-	}
+	for _, test := range []struct {
+		lo, hi       uint64
+		expLo, expHi uint64
+	}{
+		{lo: indexes[6], hi: indexes[8], expLo: indexes[6], expHi: indexes[8]},
+		{lo: indexes[2], hi: indexes[7], expLo: indexes[5], expHi: indexes[7]},
+		{lo: indexes[8], hi: math.MaxUint64, expLo: indexes[8], expHi: indexes[9]},
+		{lo: indexes[9] + 1, hi: math.MaxUint64},
+	} {
+		t.Run(fmt.Sprintf("%d-%d", test.lo, test.hi), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := repl.ExportRaftLog(ctx, &buf, test.lo, test.hi); err != nil {
+				t.Fatal(err)
+			}
+			ents, err := ReadExportedRaftLog(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.expHi == 0 {
+				if len(ents) != 0 {
+					t.Fatalf("expected no entries, got %d", len(ents))
+				}
+				return
+			}
+
+			repl.mu.Lock()
+			expEnts, err := repl.raftEntriesLocked(test.expLo, test.expHi+1, math.MaxUint64)
+			repl.mu.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ents) != len(expEnts) {
+				t.Fatalf("expected %d entries, got %d", len(expEnts), len(ents))
+			}
+			for i := range ents {
+				if ents[i].Index != expEnts[i].Index || ents[i].Term != expEnts[i].Term {
+					t.Errorf("%d: expected index %d term %d, got index %d term %d", i,
+						expEnts[i].Index, expEnts[i].Term, ents[i].Index, ents[i].Term)
+				}
+				if !bytes.Equal(ents[i].Data, expEnts[i].Data) {
+					t.Errorf("%d: entry data mismatch", i)
+				}
+			}
+		})
+	}
+}
+
+func TestTerm(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tsc := TestStoreConfig(nil)
+	tsc.TestingKnobs.DisableRaftLogQueue = true
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.StartWithStoreConfig(t, stopper, tsc)
+
+	repl := tc.repl
+	rangeID := repl.RangeID
+
+	// Populate the log with 10 entries. Save the LastIndex after each write.
+	var indexes []uint64
+	for i := 0; i < 10; i++ {
+		args := incrementArgs([]byte("a"), int64(i))
+
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+		idx, err := tc.repl.GetLastIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	// Discard the first half of the log.
+	truncateArgs := truncateLogArgs(indexes[5], rangeID)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &truncateArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	repl.mu.Lock()
+	defer repl.mu.Unlock()
+
+	firstIndex, err := repl.raftFirstIndexLocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstIndex != indexes[5] {
+		t.Fatalf("expected firstIndex %d to be %d", firstIndex, indexes[4])
+	}
 
 	// Truncated logs should return an ErrCompacted error.
 	if _, err := tc.repl.raftTermRLocked(indexes[1]); err != raft.ErrCompacted {
@@ -7428,6 +7939,210 @@ func TestReplicaRetryRaftProposal(t *testing.T) {
 
 }
 
+// TestReplicaReproposalReasonCounts verifies that a local proposal which is
+// rejected below Raft for applying at an illegal lease index is tallied by
+// ReproposalReasonCounts.
+func TestReplicaReproposalReasonCounts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.TODO()
+	var tc testContext
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc.Start(t, stopper)
+
+	type magicKey struct{}
+
+	var c int32                // updated atomically
+	var wrongLeaseIndex uint64 // populated below
+
+	tc.repl.mu.Lock()
+	tc.repl.mu.proposalBuf.testing.leaseIndexFilter = func(p *ProposalData) (indexOverride uint64, _ error) {
+		if v := p.ctx.Value(magicKey{}); v != nil {
+			if curAttempt := atomic.AddInt32(&c, 1); curAttempt == 1 {
+				return wrongLeaseIndex, nil
+			}
+		}
+		return 0, nil
+	}
+	tc.repl.mu.Unlock()
+
+	pArg := putArgs(roachpb.Key("a"), []byte("asd"))
+	if _, pErr := tc.SendWrapped(&pArg); pErr != nil {
+		t.Fatal(pErr)
+	}
+	if counts := tc.repl.ReproposalReasonCounts(); len(counts) != 0 {
+		t.Fatalf("expected no reproposals, found %v", counts)
+	}
+
+	tc.repl.mu.RLock()
+	wrongLeaseIndex = tc.repl.mu.state.LeaseAppliedIndex
+	tc.repl.mu.RUnlock()
+
+	var ba roachpb.BatchRequest
+	ba.RangeID = 1
+	ba.Timestamp = tc.Clock().Now()
+	iArg := incrementArgs(roachpb.Key("b"), 1)
+	ba.Add(&iArg)
+	if _, pErr := tc.repl.executeWriteBatch(context.WithValue(ctx, magicKey{}, "foo"), &ba); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	exp := map[string]int64{proposalIllegalLeaseIndex.String(): 1}
+	if counts := tc.repl.ReproposalReasonCounts(); !reflect.DeepEqual(counts, exp) {
+		t.Fatalf("expected reproposal counts %v, found %v", exp, counts)
+	}
+}
+
+// TestReplicaLeaseIndexStatus verifies that the gap between the assigned and
+// the applied lease index widens while proposals are blocked from applying
+// and closes once they apply.
+func TestReplicaLeaseIndexStatus(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	var tc testContext
+	cfg := TestStoreConfig(nil)
+	var blockApply int32 // updated atomically
+	unblockApply := make(chan struct{})
+	cfg.TestingKnobs.TestingApplyFilter = func(filterArgs storagebase.ApplyFilterArgs) (int, *roachpb.Error) {
+		if atomic.LoadInt32(&blockApply) == 1 && filterArgs.RangeID == tc.repl.RangeID &&
+			atomic.CompareAndSwapInt32(&blockApply, 1, 0) {
+			<-unblockApply
+		}
+		return 0, nil
+	}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	gap := func() uint64 {
+		applied, assigned := tc.repl.LeaseIndexStatus()
+		if assigned < applied {
+			return 0
+		}
+		return assigned - applied
+	}
+	if g := gap(); g != 0 {
+		t.Fatalf("expected no gap between assigned and applied lease index, found %d", g)
+	}
+
+	const num = 10
+	atomic.StoreInt32(&blockApply, 1)
+	var unblockOnce sync.Once
+	unblock := func() { unblockOnce.Do(func() { close(unblockApply) }) }
+	defer unblock()
+	errCh := make(chan *roachpb.Error, num)
+	for i := 0; i < num; i++ {
+		pArgs := putArgs(roachpb.Key(fmt.Sprintf("k%d", i)), []byte("value"))
+		go func() {
+			_, pErr := tc.SendWrapped(&pArgs)
+			errCh <- pErr
+		}()
+	}
+
+	testutils.SucceedsSoon(t, func() error {
+		if g := gap(); g < num {
+			return errors.Errorf("expected a gap of at least %d, found %d", num, g)
+		}
+		return nil
+	})
+
+	unblock()
+	for i := 0; i < num; i++ {
+		if pErr := <-errCh; pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if g := gap(); g != 0 {
+			return errors.Errorf("expected the gap to close, found %d", g)
+		}
+		return nil
+	})
+}
+
+// TestReplicaApplyLag verifies that the apply lag grows while the application
+// of committed entries is blocked and closes once they apply.
+func TestReplicaApplyLag(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	var tc testContext
+	cfg := TestStoreConfig(nil)
+	var blockApply int32 // updated atomically
+	blockedCh := make(chan struct{})
+	unblockApply := make(chan struct{})
+	cfg.TestingKnobs.TestingApplyFilter = func(filterArgs storagebase.ApplyFilterArgs) (int, *roachpb.Error) {
+		if atomic.LoadInt32(&blockApply) == 1 && filterArgs.RangeID == tc.repl.RangeID &&
+			atomic.CompareAndSwapInt32(&blockApply, 1, 0) {
+			close(blockedCh)
+			<-unblockApply
+		}
+		return 0, nil
+	}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	lag := func() uint64 {
+		t.Helper()
+		l, err := tc.repl.ApplyLag()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if l := lag(); l != 0 {
+			return errors.Errorf("expected no apply lag, found %d", l)
+		}
+		return nil
+	})
+
+	atomic.StoreInt32(&blockApply, 1)
+	var unblockOnce sync.Once
+	unblock := func() { unblockOnce.Do(func() { close(unblockApply) }) }
+	defer unblock()
+	errCh := make(chan *roachpb.Error, 1)
+	go func() {
+		pArgs := putArgs(roachpb.Key("a"), []byte("value"))
+		_, pErr := tc.SendWrapped(&pArgs)
+		errCh <- pErr
+	}()
+	<-blockedCh
+
+	initial := lag()
+	if initial == 0 {
+		t.Fatal("expected apply lag while application is blocked")
+	}
+	// In a single replica Raft group, proposals are committed as soon as they
+	// are appended to the leader's log, so the committed index advances even
+	// though the entries can't be applied.
+	const num = 3
+	for i := 0; i < num; i++ {
+		if err := tc.repl.withRaftGroup(false, func(raftGroup *raft.RawNode) (bool, error) {
+			return true, raftGroup.Propose(nil)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l := lag(); l != initial+num {
+		t.Fatalf("expected apply lag of %d, found %d", initial+num, l)
+	}
+
+	unblock()
+	if pErr := <-errCh; pErr != nil {
+		t.Fatal(pErr)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if l := lag(); l != 0 {
+			return errors.Errorf("expected the apply lag to close, found %d", l)
+		}
+		return nil
+	})
+}
+
 // TestReplicaCancelRaftCommandProgress creates a number of Raft commands and
 // immediately abandons some of them, while proposing the remaining ones. It
 // then verifies that all the non-abandoned commands get applied (which would
@@ -7589,6 +8304,96 @@ func TestReplicaBurstPendingCommandsAndRepropose(t *testing.T) {
 	}
 }
 
+// TestReplicaRaftTickState verifies that a quiesced replica's Raft tick state
+// doesn't progress, that a change of term resets the tick count, and that the
+// tick count of a leaderless replica advances with every tick.
+func TestReplicaRaftTickState(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	var tc testContext
+	cfg := TestStoreConfig(nil)
+	// Disable ticks which would interfere with the manual ticking in this test.
+	cfg.RaftTickInterval = math.MaxInt32
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	// Flush a write through Raft so that leadership settles down.
+	args := incrementArgs([]byte("a"), 1)
+	if _, pErr := tc.SendWrapped(&args); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	const numTicks = 3
+	tick := func(r *Replica) {
+		for i := 0; i < numTicks; i++ {
+			if _, err := r.tick(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if !tc.repl.quiesce() {
+		t.Fatal("unable to quiesce replica")
+	}
+	ticks, term, state := tc.repl.RaftTickState()
+	tick(tc.repl)
+	if k, tm, s := tc.repl.RaftTickState(); k != ticks || tm != term || s != state {
+		t.Fatalf("expected quiesced tick state (%d, %d, %s), got (%d, %d, %s)",
+			ticks, term, state, k, tm, s)
+	}
+
+	// Once unquiesced, the replica counts its ticks until it campaigns again.
+	tc.repl.mu.Lock()
+	tc.repl.unquiesceWithOptionsLocked(false /* campaignOnWake */)
+	tc.repl.mu.Unlock()
+	tick(tc.repl)
+	if k, _, _ := tc.repl.RaftTickState(); k != ticks+numTicks {
+		t.Fatalf("expected %d ticks, got %d", ticks+numTicks, k)
+	}
+	if err := tc.repl.withRaftGroup(false, func(raftGroup *raft.RawNode) (bool, error) {
+		return false, raftGroup.Campaign()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tc.store.processReady(context.Background(), tc.repl.RangeID)
+	if k, tm, s := tc.repl.RaftTickState(); k != 0 || tm <= term || s != raft.StateLeader {
+		t.Fatalf("expected a leader with a new term and no ticks, got (%d, %d, %s)", k, tm, s)
+	}
+
+	// An uninitialized replica has no peers to elect, so it remains leaderless
+	// and its election timer keeps advancing.
+	const rangeID = 999
+	repl, _, err := tc.store.getOrCreateReplica(context.Background(), rangeID, 2, &roachpb.ReplicaDescriptor{
+		NodeID:    2,
+		StoreID:   2,
+		ReplicaID: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repl.raftMu.Unlock()
+	if err := repl.withRaftGroup(false, func(*raft.RawNode) (bool, error) {
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	repl.mu.Lock()
+	repl.unquiesceWithOptionsLocked(false /* campaignOnWake */)
+	repl.mu.Unlock()
+
+	for i := 1; i <= 2; i++ {
+		tick(repl)
+		ticks, term, state := repl.RaftTickState()
+		if state != raft.StateFollower || term != 0 {
+			t.Fatalf("expected leaderless replica to be a follower at term 0, got %s at term %d",
+				state, term)
+		}
+		if exp := i * numTicks; ticks != exp {
+			t.Fatalf("expected %d ticks, got %d", exp, ticks)
+		}
+	}
+}
+
 func TestReplicaRefreshPendingCommandsTicks(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	var tc testContext
@@ -7712,6 +8517,134 @@ func TestReplicaRefreshPendingCommandsTicks(t *testing.T) {
 	}
 }
 
+// TestReplicaRefreshPendingCommandsEmptyEntry verifies that pending commands
+// are reproposed as soon as an empty Raft entry is applied, unless the
+// DeferRefreshReasonNewLeaderOrConfigChange knob defers the reproposal to the
+// next tick.
+func TestReplicaRefreshPendingCommandsEmptyEntry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testutils.RunTrueAndFalse(t, "defer", func(t *testing.T, deferRefresh bool) {
+		var tc testContext
+		cfg := TestStoreConfig(nil)
+		// Disable ticks which would interfere with the manual ticking in this
+		// test, along with all other reasons for reproposing commands.
+		cfg.RaftTickInterval = math.MaxInt32
+		cfg.TestingKnobs.DisableRefreshReasonNewLeader = true
+		cfg.TestingKnobs.DisableRefreshReasonSnapshotApplied = true
+		cfg.TestingKnobs.DisableRefreshReasonTicks = true
+		cfg.TestingKnobs.DeferRefreshReasonNewLeaderOrConfigChange = deferRefresh
+		stopper := stop.NewStopper()
+		defer stopper.Stop(context.TODO())
+		tc.StartWithStoreConfig(t, stopper, cfg)
+
+		r := tc.repl
+		repDesc, err := r.GetReplicaDescriptor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+
+		// Flush a write all the way through the Raft proposal pipeline so that
+		// leadership has settled before we start submitting proposals manually.
+		args := incrementArgs([]byte("a"), 1)
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+
+		// Propose a command which is silently dropped every time it is
+		// submitted to Raft, counting the submissions.
+		var ba roachpb.BatchRequest
+		ba.Timestamp = tc.Clock().Now()
+		ba.Add(&roachpb.PutRequest{RequestHeader: roachpb.RequestHeader{Key: roachpb.Key("b")}})
+		cmd, pErr := r.requestToProposal(ctx, makeIDKey(), &ba, &allSpans)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		var submissions int32
+		r.mu.Lock()
+		r.mu.proposalBuf.testing.submitProposalFilter = func(p *ProposalData) (drop bool, _ error) {
+			if p == cmd {
+				atomic.AddInt32(&submissions, 1)
+				return true, nil
+			}
+			return false, nil
+		}
+		r.mu.Unlock()
+
+		lease, _ := r.GetLease()
+		cmd.command.ProposerReplica = repDesc
+		cmd.command.ProposerLeaseSequence = lease.Sequence
+		if _, pErr := r.propose(ctx, cmd); pErr != nil {
+			t.Fatal(pErr)
+		}
+		checkSubmissions := func(exp int32) {
+			t.Helper()
+			r.mu.Lock()
+			if err := r.mu.proposalBuf.flushLocked(); err != nil {
+				t.Fatal(err)
+			}
+			r.mu.Unlock()
+			if n := atomic.LoadInt32(&submissions); n != exp {
+				t.Fatalf("expected %d submissions of the pending command, found %d", exp, n)
+			}
+		}
+		checkSubmissions(1)
+
+		// Force the replica to step down and win a new election. The new leader
+		// appends an empty entry to its log.
+		var emptyEntryIndex uint64
+		if err := r.withRaftGroup(true, func(raftGroup *raft.RawNode) (bool, error) {
+			term := raftGroup.Status().Term
+			if err := raftGroup.Step(raftpb.Message{
+				Type: raftpb.MsgHeartbeat,
+				From: uint64(repDesc.ReplicaID + 1),
+				To:   uint64(repDesc.ReplicaID),
+				Term: term + 1,
+			}); err != nil {
+				return false, err
+			}
+			if err := raftGroup.Campaign(); err != nil {
+				return false, err
+			}
+			status := raftGroup.Status()
+			if status.RaftState != raft.StateLeader {
+				return false, errors.Errorf("expected to be leader, found %s", status.RaftState)
+			}
+			emptyEntryIndex = status.Commit
+			return true, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		testutils.SucceedsSoon(t, func() error {
+			if applied := r.State().RaftAppliedIndex; applied < emptyEntryIndex {
+				return errors.Errorf("applied index %d below %d", applied, emptyEntryIndex)
+			}
+			return nil
+		})
+		// Wait for the Raft ready iteration which applied the empty entry to
+		// complete.
+		if _, _, err := r.handleRaftReady(ctx, noSnap); err != nil {
+			t.Fatal(err)
+		}
+
+		if !deferRefresh {
+			checkSubmissions(2)
+			return
+		}
+		checkSubmissions(1)
+		if _, err := r.tick(nil); err != nil {
+			t.Fatal(err)
+		}
+		checkSubmissions(2)
+		// The deferred refresh only fires once.
+		if _, err := r.tick(nil); err != nil {
+			t.Fatal(err)
+		}
+		checkSubmissions(2)
+	})
+}
+
 // TestReplicaRefreshMultiple tests an interaction between refreshing
 // proposals after a new leader or ticks (which results in multiple
 // copies in the log with the same lease index) and refreshing after
@@ -8097,49 +9030,211 @@ func TestCommandTimeThreshold(t *testing.T) {
 		t.Fatalf("could not get data: %+v", err)
 	}
 
-	// Put some data for use with CP later on.
-	pArgs := putArgs(keycp, va)
-	if _, err := tc.SendWrappedWith(roachpb.Header{
-		Timestamp: ts1,
-	}, &pArgs); err != nil {
-		t.Fatalf("could not put data: %+v", err)
+	// Put some data for use with CP later on.
+	pArgs := putArgs(keycp, va)
+	if _, err := tc.SendWrappedWith(roachpb.Header{
+		Timestamp: ts1,
+	}, &pArgs); err != nil {
+		t.Fatalf("could not put data: %+v", err)
+	}
+
+	// Do a GC.
+	gcr := roachpb.GCRequest{
+		Threshold: ts2,
+	}
+	if _, err := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); err != nil {
+		t.Fatal(err)
+	}
+
+	// Do the same Get, which should now fail.
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{
+		Timestamp: ts1,
+	}, &gArgs); !testutils.IsPError(pErr, `batch timestamp 0.\d+,\d+ must be after replica GC threshold 0.\d+,\d+`) {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+
+	// Verify a later Get works.
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{
+		Timestamp: ts3,
+	}, &gArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// Verify an early CPut fails.
+	cpArgs := cPutArgs(keycp, vb, va)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{
+		Timestamp: ts2,
+	}, &cpArgs); !testutils.IsPError(pErr, `batch timestamp 0.\d+,\d+ must be after replica GC threshold 0.\d+,\d+`) {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+	// Verify a later CPut works.
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{
+		Timestamp: ts3,
+	}, &cpArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+}
+
+// TestCommandTimeThresholdClampReadTimestamp verifies that non-transactional
+// reads below the replica GC threshold which opt into it are evaluated just
+// above the threshold instead of failing.
+func TestCommandTimeThresholdClampReadTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+	ctx := context.Background()
+
+	now := tc.Clock().Now()
+	ts1 := now.Add(1, 0)
+	ts2 := now.Add(2, 0)
+	ts3 := now.Add(3, 0)
+
+	key := roachpb.Key("a")
+	for _, kv := range []struct {
+		ts    hlc.Timestamp
+		value string
+	}{{ts1, "a"}, {ts3, "b"}} {
+		pArgs := putArgs(key, []byte(kv.value))
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: kv.ts}, &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	gcr := roachpb.GCRequest{
+		Threshold: ts2,
+	}
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	get := func(h roachpb.Header) (*roachpb.BatchResponse, *roachpb.Error) {
+		var ba roachpb.BatchRequest
+		ba.Header = h
+		gArgs := getArgs(key)
+		ba.Add(&gArgs)
+		return tc.Sender().Send(ctx, ba)
+	}
+	const gcErr = `batch timestamp 0.\d+,\d+ must be after replica GC threshold 0.\d+,\d+`
+
+	// Without the option, the read fails.
+	if _, pErr := get(roachpb.Header{Timestamp: ts1}); !testutils.IsPError(pErr, gcErr) {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+
+	// With it, the read observes the data as of just above the threshold.
+	br, pErr := get(roachpb.Header{Timestamp: ts1, ClampReadTimestamp: true})
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if !br.ReadTimestampClamped {
+		t.Error("expected the read timestamp to be reported as clamped")
+	}
+	if exp := ts2.Next(); br.Timestamp != exp {
+		t.Errorf("expected read at %s, got %s", exp, br.Timestamp)
+	}
+	if v := br.Responses[0].GetGet().Value; v == nil {
+		t.Error("expected a value")
+	} else if b, err := v.GetBytes(); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "a" {
+		t.Errorf("expected value %q, got %q", "a", b)
+	}
+
+	// Reads above the threshold are unaffected.
+	br, pErr = get(roachpb.Header{Timestamp: ts3, ClampReadTimestamp: true})
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if br.ReadTimestampClamped || br.Timestamp != ts3 {
+		t.Errorf("expected unclamped read at %s, got clamped=%t at %s",
+			ts3, br.ReadTimestampClamped, br.Timestamp)
+	}
+
+	// Transactional reads are never clamped.
+	txn := newTransaction("test", key, 1, tc.Clock())
+	txn.Timestamp, txn.OrigTimestamp = ts1, ts1
+	if _, pErr := get(roachpb.Header{Txn: txn, ClampReadTimestamp: true}); !testutils.IsPError(pErr, gcErr) {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+}
+
+// protectedTimestampProviderFunc adapts a function to the
+// ProtectedTimestampProvider interface.
+type protectedTimestampProviderFunc func(roachpb.Span, hlc.Timestamp) bool
+
+func (f protectedTimestampProviderFunc) IsProtected(span roachpb.Span, ts hlc.Timestamp) bool {
+	return f(span, ts)
+}
+
+// TestCommandTimeThresholdAllowProtectedRead verifies that reads below the
+// replica GC threshold which set AllowReadBelowGCThreshold succeed if the data
+// they read is protected, and fail otherwise.
+func TestCommandTimeThresholdAllowProtectedRead(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	protectedKey, unprotectedKey := roachpb.Key("a"), roachpb.Key("b")
+	cfg := TestStoreConfig(nil)
+	cfg.ProtectedTimestampProvider = protectedTimestampProviderFunc(
+		func(span roachpb.Span, _ hlc.Timestamp) bool {
+			return span.Key.Equal(protectedKey)
+		})
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	now := tc.Clock().Now()
+	ts1 := now.Add(1, 0)
+	ts2 := now.Add(2, 0)
+
+	for _, key := range []roachpb.Key{protectedKey, unprotectedKey} {
+		pArgs := putArgs(key, []byte("value"))
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts1}, &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
 	}
-
-	// Do a GC.
 	gcr := roachpb.GCRequest{
 		Threshold: ts2,
 	}
-	if _, err := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); err != nil {
-		t.Fatal(err)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); pErr != nil {
+		t.Fatal(pErr)
 	}
+	const gcErr = `batch timestamp 0.\d+,\d+ must be after replica GC threshold 0.\d+,\d+`
 
-	// Do the same Get, which should now fail.
+	// A protected read which opts in succeeds.
+	gArgs := getArgs(protectedKey)
+	reply, pErr := tc.SendWrappedWith(roachpb.Header{
+		Timestamp: ts1, AllowReadBelowGCThreshold: true,
+	}, &gArgs)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if v := reply.(*roachpb.GetResponse).Value; v == nil {
+		t.Error("expected a value")
+	}
+
+	// The same read fails without the option.
 	if _, pErr := tc.SendWrappedWith(roachpb.Header{
 		Timestamp: ts1,
-	}, &gArgs); !testutils.IsPError(pErr, `batch timestamp 0.\d+,\d+ must be after replica GC threshold 0.\d+,\d+`) {
+	}, &gArgs); !testutils.IsPError(pErr, gcErr) {
 		t.Fatalf("unexpected error: %v", pErr)
 	}
 
-	// Verify a later Get works.
+	// An unprotected read fails even with the option.
+	gArgs = getArgs(unprotectedKey)
 	if _, pErr := tc.SendWrappedWith(roachpb.Header{
-		Timestamp: ts3,
-	}, &gArgs); pErr != nil {
-		t.Fatal(pErr)
+		Timestamp: ts1, AllowReadBelowGCThreshold: true,
+	}, &gArgs); !testutils.IsPError(pErr, gcErr) {
+		t.Fatalf("unexpected error: %v", pErr)
 	}
 
-	// Verify an early CPut fails.
-	cpArgs := cPutArgs(keycp, vb, va)
+	// Writes are never allowed below the threshold.
+	pArgs := putArgs(protectedKey, []byte("value"))
 	if _, pErr := tc.SendWrappedWith(roachpb.Header{
-		Timestamp: ts2,
-	}, &cpArgs); !testutils.IsPError(pErr, `batch timestamp 0.\d+,\d+ must be after replica GC threshold 0.\d+,\d+`) {
+		Timestamp: ts1, AllowReadBelowGCThreshold: true,
+	}, &pArgs); !testutils.IsPError(pErr, gcErr) {
 		t.Fatalf("unexpected error: %v", pErr)
 	}
-	// Verify a later CPut works.
-	if _, pErr := tc.SendWrappedWith(roachpb.Header{
-		Timestamp: ts3,
-	}, &cpArgs); pErr != nil {
-		t.Fatal(pErr)
-	}
 }
 
 func TestReplicaTimestampCacheBumpNotLost(t *testing.T) {
@@ -8793,6 +9888,101 @@ func TestCommandTooLarge(t *testing.T) {
 	}
 }
 
+// TestLargeSpanSetBatches verifies that a batch which declares more spans than
+// kv.raft.command.large_span_set_threshold is counted, while one that stays
+// within the threshold is not.
+func TestLargeSpanSetBatches(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	st := tc.store.cfg.Settings
+	st.Manual.Store(true)
+	largeSpanSetThreshold.Override(&st.SV, 5)
+
+	sendPuts := func(n int) {
+		t.Helper()
+		var ba roachpb.BatchRequest
+		for i := 0; i < n; i++ {
+			put := putArgs(roachpb.Key(fmt.Sprintf("k%03d", i)), []byte("v"))
+			ba.Add(&put)
+		}
+		if _, pErr := tc.Sender().Send(context.Background(), ba); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	sendPuts(1)
+	if c := tc.store.metrics.LargeSpanSetBatches.Count(); c != 0 {
+		t.Fatalf("expected no large span set batches, found %d", c)
+	}
+
+	sendPuts(10)
+	if c := tc.store.metrics.LargeSpanSetBatches.Count(); c != 1 {
+		t.Fatalf("expected 1 large span set batch, found %d", c)
+	}
+
+	// Disabling the threshold stops batches from being counted.
+	largeSpanSetThreshold.Override(&st.SV, 0)
+	sendPuts(10)
+	if c := tc.store.metrics.LargeSpanSetBatches.Count(); c != 1 {
+		t.Fatalf("expected 1 large span set batch, found %d", c)
+	}
+}
+
+// TestReplicaReturnMutationCounts verifies that when a batch sets
+// ReturnMutationCounts, each response reports the number of engine mutations
+// its request produced.
+func TestReplicaReturnMutationCounts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	const numKeys = 50
+	for i := 0; i < numKeys; i++ {
+		args := putArgs(roachpb.Key(fmt.Sprintf("k%03d", i)), []byte("v"))
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	put := putArgs(roachpb.Key("a"), []byte("v"))
+	delRange := &roachpb.DeleteRangeRequest{
+		RequestHeader: roachpb.RequestHeader{Key: roachpb.Key("k"), EndKey: roachpb.Key("l")},
+	}
+	var ba roachpb.BatchRequest
+	ba.ReturnMutationCounts = true
+	ba.Add(&put, delRange)
+	br, pErr := tc.Sender().Send(context.Background(), ba)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	if n := br.Responses[0].GetInner().Header().NumMutations; n < 1 || n > 2 {
+		t.Errorf("expected Put to report a small number of mutations, got %d", n)
+	}
+	if n := br.Responses[1].GetInner().Header().NumMutations; n < numKeys {
+		t.Errorf("expected DeleteRange to report at least %d mutations, got %d", numKeys, n)
+	}
+
+	// Without the flag, no mutation counts are reported.
+	ba.ReturnMutationCounts = false
+	if br, pErr = tc.Sender().Send(context.Background(), ba); pErr != nil {
+		t.Fatal(pErr)
+	}
+	for i, resp := range br.Responses {
+		if n := resp.GetInner().Header().NumMutations; n != 0 {
+			t.Errorf("%d: expected no mutation count, got %d", i, n)
+		}
+	}
+}
+
 // Test that, if the application of a Raft command fails, intents are not
 // resolved. This is because we don't want intent resolution to take place if an
 // EndTransaction fails.
@@ -9278,105 +10468,427 @@ func TestShouldReplicaQuiesce(t *testing.T) {
 			return q
 		})
 	}
-	// Pass a nil liveness map.
-	test(true, func(q *testQuiescer) *testQuiescer {
-		q.livenessMap = nil
-		return q
-	})
-	// Verify quiesce even when replica progress doesn't match, if
-	// the replica is on a non-live node.
-	for _, i := range []uint64{1, 2, 3} {
-		test(true, func(q *testQuiescer) *testQuiescer {
-			q.livenessMap[roachpb.NodeID(i)] = IsLiveMapEntry{IsLive: false}
-			q.status.Progress[i] = tracker.Progress{Match: invalidIndex}
-			return q
-		})
+	// Pass a nil liveness map.
+	test(true, func(q *testQuiescer) *testQuiescer {
+		q.livenessMap = nil
+		return q
+	})
+	// Verify quiesce even when replica progress doesn't match, if
+	// the replica is on a non-live node.
+	for _, i := range []uint64{1, 2, 3} {
+		test(true, func(q *testQuiescer) *testQuiescer {
+			q.livenessMap[roachpb.NodeID(i)] = IsLiveMapEntry{IsLive: false}
+			q.status.Progress[i] = tracker.Progress{Match: invalidIndex}
+			return q
+		})
+	}
+}
+
+func TestReplicaRecomputeStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	key := roachpb.RKey("a")
+	repl := tc.store.LookupReplica(key)
+	desc := repl.Desc()
+	sKey := desc.StartKey.AsRawKey()
+
+	const errMismatch = "descriptor mismatch; range likely merged"
+
+	type testCase struct {
+		name     string
+		key      roachpb.Key
+		expDelta enginepb.MVCCStats
+		expErr   string
+	}
+
+	runTest := func(test testCase) {
+		t.Run(test.name, func(t *testing.T) {
+			args := &roachpb.RecomputeStatsRequest{
+				RequestHeader: roachpb.RequestHeader{
+					Key: test.key,
+				},
+			}
+
+			resp, pErr := tc.SendWrapped(args)
+			if !testutils.IsPError(pErr, test.expErr) {
+				t.Fatalf("got:\n%s\nexpected: %s", pErr, test.expErr)
+			}
+			if test.expErr != "" {
+				return
+			}
+
+			delta := enginepb.MVCCStats(resp.(*roachpb.RecomputeStatsResponse).AddedDelta)
+			delta.AgeTo(test.expDelta.LastUpdateNanos)
+
+			if delta != test.expDelta {
+				t.Fatal("diff(wanted, actual) = ", strings.Join(pretty.Diff(test.expDelta, delta), "\n"))
+			}
+		})
+	}
+
+	for _, test := range []testCase{
+		// Non-matching endpoints.
+		{"leftmismatch", roachpb.Key("a"), enginepb.MVCCStats{}, errMismatch},
+		// Recomputation that shouldn't find anything.
+		{"noop", sKey, enginepb.MVCCStats{}, ""},
+	} {
+		runTest(test)
+	}
+
+	ctx := context.Background()
+	seed := randutil.NewPseudoSeed()
+	t.Logf("seed is %d", seed)
+	rnd := rand.New(rand.NewSource(seed))
+
+	repl.raftMu.Lock()
+	repl.mu.Lock()
+	ms := repl.mu.state.Stats // intentionally mutated below
+	disturbMS := enginepb.NewPopulatedMVCCStats(rnd, false)
+	disturbMS.ContainsEstimates = false
+	ms.Add(*disturbMS)
+	err := repl.raftMu.stateLoader.SetMVCCStats(ctx, tc.engine, ms)
+	repl.assertStateLocked(ctx, tc.engine)
+	repl.mu.Unlock()
+	repl.raftMu.Unlock()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// We have `stored ms = recomputable ms + disturbMS`, and so the returned delta
+	// should be `recomputable ms - stored ms = -disturbMS`.
+	var expDelta enginepb.MVCCStats
+	expDelta.Subtract(*disturbMS)
+
+	runTest(testCase{"randdelta", sKey, expDelta, ""})
+	if !t.Failed() {
+		runTest(testCase{"noopagain", sKey, enginepb.MVCCStats{}, ""})
+	}
+}
+
+// TestReplicaRecomputeAndPersistStats verifies that stats which contain
+// estimates, as left behind by AddSSTable, are replaced with exact ones and
+// that the ContainsEstimates flag is cleared once all replicas understand how
+// to do so.
+func TestReplicaRecomputeAndPersistStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	testutils.RunTrueAndFalse(t, "versionActive", testReplicaRecomputeAndPersistStats)
+}
+
+func testReplicaRecomputeAndPersistStats(t *testing.T, versionActive bool) {
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	cfg := TestStoreConfig(nil)
+	if !versionActive {
+		v := cluster.VersionByKey(cluster.VersionClearStatsEstimates - 1)
+		cfg.Settings = cluster.MakeTestingClusterSettingsWithVersion(v, v)
+	}
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	ctx := context.Background()
+	repl := tc.store.LookupReplica(roachpb.RKey("a"))
+	pArgs := putArgs(roachpb.Key("a"), []byte("value"))
+	if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// Disturb the stats the way an AddSSTable with overlapping data would.
+	repl.raftMu.Lock()
+	repl.mu.Lock()
+	ms := repl.mu.state.Stats // intentionally mutated below
+	ms.Add(enginepb.MVCCStats{
+		ContainsEstimates: true,
+		KeyBytes:          100,
+		ValBytes:          1000,
+		KeyCount:          10,
+		ValCount:          10,
+		LiveBytes:         1100,
+		LiveCount:         10,
+	})
+	err := repl.raftMu.stateLoader.SetMVCCStats(ctx, tc.engine, ms)
+	repl.assertStateLocked(ctx, tc.engine)
+	repl.mu.Unlock()
+	repl.raftMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := repl.RecomputeAndPersistStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ContainsEstimates != !versionActive {
+		t.Fatalf("expected ContainsEstimates=%t: %+v", !versionActive, stats)
+	}
+	expMS, err := rditer.ComputeStatsForRange(repl.Desc(), tc.engine, stats.LastUpdateNanos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expMS.ContainsEstimates = !versionActive
+	if stats != expMS {
+		t.Fatal("diff(wanted, actual) = ", strings.Join(pretty.Diff(expMS, stats), "\n"))
+	}
+	persistedMS, err := repl.raftMu.stateLoader.LoadMVCCStats(ctx, tc.engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persistedMS != stats {
+		t.Fatal("diff(persisted, in-memory) = ", strings.Join(pretty.Diff(persistedMS, stats), "\n"))
+	}
+}
+
+// TestReplicaAddSSTableProgressMarker verifies that the progress marker of an
+// AddSSTable request is rejected until the cluster version allows it, and that
+// it is never written below an earlier read of the marker key.
+func TestReplicaAddSSTableProgressMarker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	testutils.RunTrueAndFalse(t, "versionActive", testReplicaAddSSTableProgressMarker)
+}
+
+func testReplicaAddSSTableProgressMarker(t *testing.T, versionActive bool) {
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	cfg := TestStoreConfig(nil)
+	if !versionActive {
+		v := cluster.VersionByKey(cluster.VersionAddSSTableProgressMarker - 1)
+		cfg.Settings = cluster.MakeTestingClusterSettingsWithVersion(v, v)
+	}
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	markerKey := roachpb.Key("progress")
+	readTS := tc.Clock().Now()
+	gArgs := getArgs(markerKey)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: readTS}, &gArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	data, _ := MakeSSTable("b", "1", hlc.Timestamp{WallTime: 1})
+	marker := roachpb.MakeValueFromString("done")
+	addArgs := &roachpb.AddSSTableRequest{
+		RequestHeader:       roachpb.RequestHeader{Key: roachpb.Key("b"), EndKey: roachpb.Key("c")},
+		Data:                data,
+		ProgressMarkerKey:   markerKey,
+		ProgressMarkerValue: &marker,
+	}
+	// Attempt to write the marker below the read.
+	writeTS := readTS.Add(-1, 0)
+	_, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: writeTS}, addArgs)
+	if !versionActive {
+		if !testutils.IsPError(pErr, "progress markers require all nodes to be upgraded") {
+			t.Fatalf("expected progress marker to be rejected, got %v", pErr)
+		}
+		return
+	}
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	reply, pErr := tc.SendWrapped(&gArgs)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	val := reply.(*roachpb.GetResponse).Value
+	if val == nil {
+		t.Fatal("expected progress marker to be written")
+	}
+	if !readTS.Less(val.Timestamp) {
+		t.Errorf("expected progress marker to be written above read at %s, got %s",
+			readTS, val.Timestamp)
 	}
 }
 
-func TestReplicaRecomputeStats(t *testing.T) {
+// TestReplicaAddSSTableWriteAtRequestTimestamp verifies that an AddSSTable
+// request which writes its keys at the request timestamp is pushed above an
+// earlier read of its span, just like any other write.
+func TestReplicaAddSSTableWriteAtRequestTimestamp(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	tc := testContext{}
 	stopper := stop.NewStopper()
 	defer stopper.Stop(context.TODO())
 	tc.Start(t, stopper)
 
-	key := roachpb.RKey("a")
-	repl := tc.store.LookupReplica(key)
-	desc := repl.Desc()
-	sKey := desc.StartKey.AsRawKey()
-
-	const errMismatch = "descriptor mismatch; range likely merged"
+	key := roachpb.Key("b")
+	readTS := tc.Clock().Now()
+	gArgs := getArgs(key)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: readTS}, &gArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
 
-	type testCase struct {
-		name     string
-		key      roachpb.Key
-		expDelta enginepb.MVCCStats
-		expErr   string
+	data, _ := MakeSSTable("b", "1", hlc.Timestamp{WallTime: 1})
+	addArgs := &roachpb.AddSSTableRequest{
+		RequestHeader:           roachpb.RequestHeader{Key: key, EndKey: roachpb.Key("c")},
+		Data:                    data,
+		WriteAtRequestTimestamp: true,
+	}
+	// Attempt to write the SSTable at, and below, the read.
+	for _, writeTS := range []hlc.Timestamp{readTS, readTS.Add(-1, 0)} {
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: writeTS}, addArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+		reply, pErr := tc.SendWrapped(&gArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		val := reply.(*roachpb.GetResponse).Value
+		if val == nil {
+			t.Fatal("expected SSTable to be ingested")
+		}
+		if !readTS.Less(val.Timestamp) {
+			t.Errorf("expected SSTable written at %s to land above read at %s, got %s",
+				writeTS, readTS, val.Timestamp)
+		}
 	}
+}
 
-	runTest := func(test testCase) {
-		t.Run(test.name, func(t *testing.T) {
-			args := &roachpb.RecomputeStatsRequest{
-				RequestHeader: roachpb.RequestHeader{
-					Key: test.key,
-				},
-			}
+func TestReplicaGCBacklogEstimate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
 
-			resp, pErr := tc.SendWrapped(args)
-			if !testutils.IsPError(pErr, test.expErr) {
-				t.Fatalf("got:\n%s\nexpected: %s", pErr, test.expErr)
+	ctx := context.Background()
+	tc.manualClock.Set(10 * time.Second.Nanoseconds())
+
+	// Write two versions of a number of keys and then delete them, so that all
+	// of their versions are garbage as of 3s.
+	for i := 0; i < 100; i++ {
+		key := roachpb.Key(fmt.Sprintf("a%03d", i))
+		for j, val := range []string{"value1", "value2"} {
+			pArgs := putArgs(key, []byte(val))
+			ts := hlc.Timestamp{WallTime: int64(j+1) * time.Second.Nanoseconds()}
+			if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &pArgs); pErr != nil {
+				t.Fatal(pErr)
 			}
-			if test.expErr != "" {
-				return
+		}
+		dArgs := deleteArgs(key)
+		ts := hlc.Timestamp{WallTime: 3 * time.Second.Nanoseconds()}
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &dArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	tc.manualClock.Set(100 * time.Second.Nanoseconds())
+
+	bumpThreshold := func(threshold time.Duration) {
+		gcr := roachpb.GCRequest{
+			Threshold: hlc.Timestamp{WallTime: threshold.Nanoseconds()},
+		}
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	expBacklog := func(positive bool) {
+		t.Helper()
+		garbageKeys, garbageBytes, err := tc.repl.GCBacklogEstimate(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !positive {
+			if garbageKeys != 0 || garbageBytes != 0 {
+				t.Fatalf("expected no GC backlog, got %d keys and %d bytes", garbageKeys, garbageBytes)
 			}
+			return
+		}
+		ms := tc.repl.GetMVCCStats()
+		if garbageKeys <= 0 || garbageKeys > ms.ValCount-ms.LiveCount {
+			t.Fatalf("expected positive number of garbage keys bounded by %d, got %d",
+				ms.ValCount-ms.LiveCount, garbageKeys)
+		}
+		if garbageBytes <= 0 || garbageBytes > ms.GCBytes() {
+			t.Fatalf("expected positive number of garbage bytes bounded by %d, got %d",
+				ms.GCBytes(), garbageBytes)
+		}
+	}
 
-			delta := enginepb.MVCCStats(resp.(*roachpb.RecomputeStatsResponse).AddedDelta)
-			delta.AgeTo(test.expDelta.LastUpdateNanos)
+	// Without a GC threshold, nothing is eligible for collection.
+	expBacklog(false)
+	// The same holds for a threshold which predates (most of) the garbage.
+	bumpThreshold(2 * time.Second)
+	expBacklog(false)
+	// Once the threshold passes the deletions, there is a backlog.
+	bumpThreshold(50 * time.Second)
+	expBacklog(true)
+}
 
-			if delta != test.expDelta {
-				t.Fatal("diff(wanted, actual) = ", strings.Join(pretty.Diff(test.expDelta, delta), "\n"))
+func TestReplicaEstimatedTimeToGC(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	ctx := context.Background()
+	// writeGarbage writes two versions of n keys and deletes them, starting at
+	// the given time.
+	writeGarbage := func(prefix string, n int, start time.Duration) {
+		for i := 0; i < n; i++ {
+			key := roachpb.Key(fmt.Sprintf("%s%03d", prefix, i))
+			for j, val := range []string{"value1", "value2"} {
+				pArgs := putArgs(key, []byte(val))
+				ts := hlc.Timestamp{WallTime: (start + time.Duration(j)*time.Second).Nanoseconds()}
+				if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &pArgs); pErr != nil {
+					t.Fatal(pErr)
+				}
 			}
-		})
+			dArgs := deleteArgs(key)
+			ts := hlc.Timestamp{WallTime: (start + 2*time.Second).Nanoseconds()}
+			if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &dArgs); pErr != nil {
+				t.Fatal(pErr)
+			}
+		}
+	}
+	bumpThreshold := func(threshold time.Duration) {
+		gcr := roachpb.GCRequest{
+			Threshold: hlc.Timestamp{WallTime: threshold.Nanoseconds()},
+		}
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); pErr != nil {
+			t.Fatal(pErr)
+		}
 	}
 
-	for _, test := range []testCase{
-		// Non-matching endpoints.
-		{"leftmismatch", roachpb.Key("a"), enginepb.MVCCStats{}, errMismatch},
-		// Recomputation that shouldn't find anything.
-		{"noop", sKey, enginepb.MVCCStats{}, ""},
-	} {
-		runTest(test)
+	// Without a backlog, there is nothing to wait for.
+	if ttg, err := tc.repl.EstimatedTimeToGC(ctx); err != nil || ttg != 0 {
+		t.Fatalf("expected no time to GC, got %s (err: %v)", ttg, err)
 	}
 
-	ctx := context.Background()
-	seed := randutil.NewPseudoSeed()
-	t.Logf("seed is %d", seed)
-	rnd := rand.New(rand.NewSource(seed))
+	// Create a small backlog.
+	tc.manualClock.Set(10 * time.Second.Nanoseconds())
+	writeGarbage("a", 5, time.Second)
+	tc.manualClock.Set(100 * time.Second.Nanoseconds())
+	bumpThreshold(50 * time.Second)
 
-	repl.raftMu.Lock()
-	repl.mu.Lock()
-	ms := repl.mu.state.Stats // intentionally mutated below
-	disturbMS := enginepb.NewPopulatedMVCCStats(rnd, false)
-	disturbMS.ContainsEstimates = false
-	ms.Add(*disturbMS)
-	err := repl.raftMu.stateLoader.SetMVCCStats(ctx, tc.engine, ms)
-	repl.assertStateLocked(ctx, tc.engine)
-	repl.mu.Unlock()
-	repl.raftMu.Unlock()
+	// The GC queue hasn't processed anything, so there is no rate to go by.
+	if _, err := tc.repl.EstimatedTimeToGC(ctx); !testutils.IsError(err, "no GC queue processing rate") {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
+	// Simulate a slow GC queue which removed a key per second.
+	tc.store.metrics.GCNumKeysAffected.Inc(10)
+	tc.store.metrics.GCQueueProcessingNanos.Inc((10 * time.Second).Nanoseconds())
+	small, err := tc.repl.EstimatedTimeToGC(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if small <= 0 {
+		t.Fatalf("expected positive time to GC, got %s", small)
+	}
 
-	// We have `stored ms = recomputable ms + disturbMS`, and so the returned delta
-	// should be `recomputable ms - stored ms = -disturbMS`.
-	var expDelta enginepb.MVCCStats
-	expDelta.Subtract(*disturbMS)
-
-	runTest(testCase{"randdelta", sKey, expDelta, ""})
-	if !t.Failed() {
-		runTest(testCase{"noopagain", sKey, enginepb.MVCCStats{}, ""})
+	// Create a much larger backlog, which takes longer to collect.
+	writeGarbage("b", 100, 51*time.Second)
+	tc.manualClock.Set(200 * time.Second.Nanoseconds())
+	bumpThreshold(150 * time.Second)
+	large, err := tc.repl.EstimatedTimeToGC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if large <= small {
+		t.Fatalf("expected time to GC to exceed %s, got %s", small, large)
 	}
 }
 
@@ -11835,6 +13347,228 @@ func TestLaterReproposalsDoNotReuseContext(t *testing.T) {
 	}
 }
 
+func TestReplicaSelfCheckStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc.Start(t, stopper)
+
+	for _, k := range []string{"a", "b", "c"} {
+		args := putArgs(roachpb.Key(k), []byte("value"))
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	if divergence, ok, err := tc.repl.SelfCheckStats(ctx); err != nil {
+		t.Fatal(err)
+	} else if !ok || divergence != (enginepb.MVCCStats{}) {
+		t.Fatalf("expected consistent stats, got divergence %+v", divergence)
+	}
+
+	// Corrupt the in-memory stats.
+	tc.repl.mu.Lock()
+	tc.repl.mu.state.Stats.LiveBytes += 7
+	tc.repl.mu.state.Stats.KeyCount -= 2
+	tc.repl.mu.Unlock()
+
+	divergence, ok, err := tc.repl.SelfCheckStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected corrupted stats to be detected")
+	}
+	if exp := (enginepb.MVCCStats{LiveBytes: 7, KeyCount: -2}); divergence != exp {
+		t.Fatalf("expected divergence %+v, got %+v", exp, divergence)
+	}
+}
+
+// TestReplicaLogsSlowCommandApplication verifies that applying a Raft command
+// which takes longer than kv.apply.slow_command_threshold logs a warning
+// identifying the range and the slow command itself.
+func TestReplicaLogsSlowCommandApplication(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Set logging up to a test specific directory.
+	scope := log.Scope(t)
+	defer scope.Close(t)
+
+	tc := testContext{}
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+
+	const delay = 50 * time.Millisecond
+	var injectDelay int32
+	var mu struct {
+		syncutil.Mutex
+		delayed map[string]bool
+	}
+	mu.delayed = map[string]bool{}
+	cfg := TestStoreConfig(nil)
+	slowCommandApplyThreshold.Override(&cfg.Settings.SV, delay/2)
+	cfg.TestingKnobs.TestingApplyFilter = func(args storagebase.ApplyFilterArgs) (int, *roachpb.Error) {
+		if atomic.LoadInt32(&injectDelay) == 1 {
+			mu.Lock()
+			mu.delayed[fmt.Sprintf("%x", args.CmdID)] = true
+			mu.Unlock()
+			time.Sleep(delay)
+		}
+		return 0, nil
+	}
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	atomic.StoreInt32(&injectDelay, 1)
+	args := putArgs(roachpb.Key("a"), []byte("value"))
+	if _, pErr := tc.SendWrapped(&args); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	re := regexp.MustCompile(fmt.Sprintf(`slow raft command application: r%d cmd=([0-9a-f]+)`, tc.repl.RangeID))
+	testutils.SucceedsSoon(t, func() error {
+		log.Flush()
+		entries, err := log.FetchEntriesFromFiles(0, math.MaxInt64, 100, re)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var found bool
+		for _, e := range entries {
+			m := re.FindStringSubmatch(e.Message)
+			if m == nil {
+				continue
+			}
+			// Commands are timed individually, so a command which was not
+			// delayed must not be blamed for the delay of another one in the
+			// same batch.
+			if !mu.delayed[m[1]] {
+				t.Fatalf("slow application warning for command which was not delayed: %s", e.Message)
+			}
+			found = true
+		}
+		if !found {
+			return errors.Errorf("no slow application warning for delayed commands %v in %v", mu.delayed, entries)
+		}
+		return nil
+	})
+	atomic.StoreInt32(&injectDelay, 0)
+}
+
+// TestReplicaLogsLargeStatsDelta verifies that applying a Raft command whose
+// MVCC stats delta exceeds kv.apply.large_stats_delta_threshold logs a
+// warning, while commands with small deltas don't.
+func TestReplicaLogsLargeStatsDelta(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Set logging up to a test specific directory.
+	scope := log.Scope(t)
+	defer scope.Close(t)
+
+	tc := testContext{}
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+
+	cfg := TestStoreConfig(nil)
+	largeStatsDeltaThreshold.Override(&cfg.Settings.SV, 10<<10)
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	re := regexp.MustCompile(fmt.Sprintf(`large MVCC stats delta: r%d cmd=[0-9a-f]+`, tc.repl.RangeID))
+	fetchWarnings := func() []log.Entry {
+		t.Helper()
+		log.Flush()
+		entries, err := log.FetchEntriesFromFiles(0, math.MaxInt64, 100, re)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	// Write a number of keys, each of which is well below the threshold.
+	value := bytes.Repeat([]byte("v"), 1<<10)
+	for i := 0; i < 100; i++ {
+		args := putArgs(roachpb.Key(fmt.Sprintf("a%03d", i)), value)
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	if entries := fetchWarnings(); len(entries) > 0 {
+		t.Fatalf("unexpected warnings for small writes: %v", entries)
+	}
+
+	// Deleting all of them at once removes ~100KiB of live bytes.
+	dArgs := &roachpb.DeleteRangeRequest{
+		RequestHeader: roachpb.RequestHeader{Key: roachpb.Key("a"), EndKey: roachpb.Key("b")},
+	}
+	if _, pErr := tc.SendWrapped(dArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if entries := fetchWarnings(); len(entries) == 0 {
+			return errors.New("no warning for large delete")
+		}
+		return nil
+	})
+}
+
+// TestReplicaSimulatedEngineWriteStall verifies that a write stall injected
+// through the SimulateEngineWriteStall testing knob is reflected in the
+// command commit latency and triggers the slow command application warning.
+func TestReplicaSimulatedEngineWriteStall(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Set logging up to a test specific directory.
+	scope := log.Scope(t)
+	defer scope.Close(t)
+
+	tc := testContext{}
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+
+	const stall = 50 * time.Millisecond
+	var injectStall int32
+	cfg := TestStoreConfig(nil)
+	slowCommandApplyThreshold.Override(&cfg.Settings.SV, stall/2)
+	cfg.TestingKnobs.SimulateEngineWriteStall = func() time.Duration {
+		if atomic.LoadInt32(&injectStall) == 1 {
+			return stall
+		}
+		return 0
+	}
+	tc.StartWithStoreConfig(t, stopper, cfg)
+
+	atomic.StoreInt32(&injectStall, 1)
+	args := putArgs(roachpb.Key("a"), []byte("value"))
+	if _, pErr := tc.SendWrapped(&args); pErr != nil {
+		t.Fatal(pErr)
+	}
+	atomic.StoreInt32(&injectStall, 0)
+
+	// The client may be acknowledged before the command is applied, so the
+	// stall isn't necessarily reflected yet. The stall happens while committing
+	// the application batch, so it is the batch which is reported as slow.
+	re := regexp.MustCompile(fmt.Sprintf(`slow raft command application: r%d batch of \d+ commands`, tc.repl.RangeID))
+	testutils.SucceedsSoon(t, func() error {
+		if max := time.Duration(tc.store.metrics.RaftCommandCommitLatency.Snapshot().Max()); max < stall {
+			return errors.Errorf("expected command commit latency of at least %s, got %s", stall, max)
+		}
+		log.Flush()
+		entries, err := log.FetchEntriesFromFiles(0, math.MaxInt64, 100, re)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 {
+			return errors.New("no slow application warning for the stalled command")
+		}
+		return nil
+	})
+}
+
 func enableTraceDebugUseAfterFree() (restore func()) {
 	prev := trace.DebugUseAfterFinish
 	trace.DebugUseAfterFinish = true
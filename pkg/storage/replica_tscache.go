@@ -250,11 +250,9 @@ func (r *Replica) applyTimestampCache(
 	var bumped bool
 	for _, union := range ba.Requests {
 		args := union.GetInner()
-		if roachpb.ConsultsTimestampCache(args) {
-			header := args.Header()
-
+		if span, ok := timestampCacheSpan(args); ok {
 			// Forward the timestamp if there's been a more recent read (by someone else).
-			rTS, rTxnID := r.store.tsCache.GetMaxRead(header.Key, header.EndKey)
+			rTS, rTxnID := r.store.tsCache.GetMaxRead(span.Key, span.EndKey)
 			if rTS.Forward(minReadTS) {
 				rTxnID = uuid.Nil
 			}
@@ -275,7 +273,7 @@ func (r *Replica) applyTimestampCache(
 			// write too old boolean for transactions. Note that currently
 			// only EndTransaction and DeleteRange requests update the
 			// write timestamp cache.
-			wTS, wTxnID := r.store.tsCache.GetMaxWrite(header.Key, header.EndKey)
+			wTS, wTxnID := r.store.tsCache.GetMaxWrite(span.Key, span.EndKey)
 			if ba.Txn != nil {
 				if ba.Txn.ID != wTxnID {
 					if !wTS.Less(ba.Txn.Timestamp) {
@@ -293,6 +291,39 @@ func (r *Replica) applyTimestampCache(
 	return bumped, nil
 }
 
+// timestampCacheSpan returns the span that the request must consult the
+// timestamp cache for, if any. An AddSSTable request usually doesn't consult
+// the timestamp cache for the SSTable, which is ingested at the timestamps of
+// its keys, but its progress marker is a regular write at the batch timestamp
+// and must not be written below a read or the closed timestamp. If the SSTable
+// is written at the request timestamp, the same holds for its whole span, so
+// the returned span covers both it and the progress marker.
+func timestampCacheSpan(args roachpb.Request) (roachpb.Span, bool) {
+	if roachpb.ConsultsTimestampCache(args) {
+		return args.Header().Span(), true
+	}
+	req, ok := args.(*roachpb.AddSSTableRequest)
+	if !ok {
+		return roachpb.Span{}, false
+	}
+	if !req.WriteAtRequestTimestamp {
+		if req.ProgressMarkerKey != nil {
+			return roachpb.Span{Key: req.ProgressMarkerKey}, true
+		}
+		return roachpb.Span{}, false
+	}
+	span := req.Span()
+	if req.ProgressMarkerKey != nil {
+		if req.ProgressMarkerKey.Compare(span.Key) < 0 {
+			span.Key = req.ProgressMarkerKey
+		}
+		if markerEnd := req.ProgressMarkerKey.Next(); markerEnd.Compare(span.EndKey) > 0 {
+			span.EndKey = markerEnd
+		}
+	}
+	return span, true
+}
+
 // CanCreateTxnRecord determines whether a transaction record can be created for
 // the provided transaction information. Callers must provide the transaction's
 // minimum timestamp across all epochs, along with its ID and its key.
@@ -349,6 +349,9 @@ func (r *Replica) evaluateWriteBatch(
 	// We can retry locally if this is a non-transactional request.
 	canRetry := ba.Txn == nil
 	batch, br, res, pErr := r.evaluateWriteBatchWithLocalRetries(ctx, idKey, rec, &ms, ba, spans, canRetry)
+	if pErr == nil {
+		pErr = r.checkTxnIntentLimit(ba, ms)
+	}
 	return batch, ms, br, res, pErr
 }
 
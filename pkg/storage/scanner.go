@@ -12,9 +12,11 @@ package storage
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
@@ -22,6 +24,15 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
+// scannerMaxReplicasPerPass bounds the number of replicas visited by a single
+// pass of the replica scanner.
+var scannerMaxReplicasPerPass = settings.RegisterNonNegativeIntSetting(
+	"kv.scanner.max_replicas_per_pass",
+	"maximum number of replicas the replica scanner visits per pass; "+
+		"subsequent passes resume where the previous one left off (0 = unlimited)",
+	0,
+)
+
 // A replicaQueue is a prioritized queue of replicas for which work is
 // scheduled. For example, there's a GC queue for replicas which are due
 // for garbage collection, a rebalance queue to move replicas from full
@@ -71,6 +82,16 @@ type replicaScanner struct {
 	replicas       replicaSet     // Replicas to be scanned
 	queues         []replicaQueue // Replica queues managed by this scanner
 	removed        chan *Replica  // Replicas to remove from queues
+	// sv, if set, provides the cluster settings which may bound the number of
+	// replicas visited per pass. It is nil in tests which construct a scanner
+	// directly, in which case passes are unbounded.
+	sv *settings.Values
+	// resumeAfter is the ID of the last replica visited by a bounded pass; the
+	// next bounded pass resumes with the following replica. passRemaining is
+	// the number of replicas left to visit in the current bounded pass, or
+	// zero if the pass is unbounded. Both are only accessed by the scan loop.
+	resumeAfter   roachpb.RangeID
+	passRemaining int
 	// Count of times and total duration through the scanning loop.
 	mu struct {
 		syncutil.Mutex
@@ -188,6 +209,9 @@ func (rs *replicaScanner) paceInterval(start, now time.Time) time.Duration {
 		remainingNanos = 0
 	}
 	count := rs.replicas.EstimatedCount()
+	if rs.passRemaining > 0 && rs.passRemaining < count {
+		count = rs.passRemaining
+	}
 	if count < 1 {
 		count = 1
 	}
@@ -274,7 +298,7 @@ func (rs *replicaScanner) scanLoop(stopper *stop.Stopper) {
 			}
 			var shouldStop bool
 			count := 0
-			rs.replicas.Visit(func(repl *Replica) bool {
+			rs.visitReplicas(func(repl *Replica) bool {
 				count++
 				shouldStop = rs.waitAndProcess(ctx, stopper, start, repl)
 				return !shouldStop
@@ -307,6 +331,43 @@ func (rs *replicaScanner) scanLoop(stopper *stop.Stopper) {
 	})
 }
 
+// visitReplicas calls the visitor with each replica to be visited in the
+// current pass until false is returned. If kv.scanner.max_replicas_per_pass
+// is set, the replicas are visited in RangeID order, starting after the
+// replica visited last by the previous pass and wrapping around, and at most
+// that many replicas are visited.
+func (rs *replicaScanner) visitReplicas(visitor func(*Replica) bool) {
+	var limit int
+	if rs.sv != nil {
+		limit = int(scannerMaxReplicasPerPass.Get(rs.sv))
+	}
+	if limit == 0 {
+		rs.replicas.Visit(visitor)
+		return
+	}
+	var repls []*Replica
+	rs.replicas.Visit(func(repl *Replica) bool {
+		repls = append(repls, repl)
+		return true
+	})
+	sort.Slice(repls, func(i, j int) bool { return repls[i].RangeID < repls[j].RangeID })
+	if limit > len(repls) {
+		limit = len(repls)
+	}
+	start := sort.Search(len(repls), func(i int) bool {
+		return repls[i].RangeID > rs.resumeAfter
+	})
+	defer func() { rs.passRemaining = 0 }()
+	for i := 0; i < limit; i++ {
+		repl := repls[(start+i)%len(repls)]
+		rs.resumeAfter = repl.RangeID
+		rs.passRemaining = limit - i
+		if !visitor(repl) {
+			return
+		}
+	}
+}
+
 // waitEnabled loops, removing replicas from the scanner's queues,
 // until scanning is enabled or the stopper signals shutdown,
 func (rs *replicaScanner) waitEnabled(stopper *stop.Stopper) bool {
@@ -13,10 +13,12 @@ package storage
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -406,3 +408,52 @@ func TestScannerEmptyRangeSet(t *testing.T) {
 		t.Errorf("expected at most one loop, but got %d", count)
 	}
 }
+
+// TestScannerMaxReplicasPerPass verifies that kv.scanner.max_replicas_per_pass
+// bounds the number of replicas visited by a pass, that the next pass resumes
+// where the previous one left off, and that a bounded pass is paced according
+// to the number of replicas it visits.
+func TestScannerMaxReplicasPerPass(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	const count = 8
+	const maxPerPass = 3
+	const targetInterval = 30 * time.Millisecond
+	ranges := newTestRangeSet(count, t)
+	s := newReplicaScanner(makeAmbCtx(), nil, targetInterval, 0, 0, ranges)
+	st := cluster.MakeTestingClusterSettings()
+	scannerMaxReplicasPerPass.Override(&st.SV, maxPerPass)
+	s.sv = &st.SV
+
+	bounded := true
+	pass := func() []roachpb.RangeID {
+		var visited []roachpb.RangeID
+		s.visitReplicas(func(repl *Replica) bool {
+			if bounded && len(visited) == 0 {
+				startTime := timeutil.Now()
+				if interval, exp := s.paceInterval(startTime, startTime), targetInterval/maxPerPass; interval != exp {
+					t.Errorf("expected pace interval %s, got %s", exp, interval)
+				}
+			}
+			visited = append(visited, repl.RangeID)
+			return true
+		})
+		return visited
+	}
+	for i, exp := range [][]roachpb.RangeID{
+		{1, 2, 3},
+		{4, 5, 6},
+		{7, 0, 1},
+		{2, 3, 4},
+	} {
+		if visited := pass(); !reflect.DeepEqual(visited, exp) {
+			t.Errorf("%d: expected pass to visit %v, got %v", i, exp, visited)
+		}
+	}
+
+	// Without a bound, a pass visits all replicas.
+	scannerMaxReplicasPerPass.Override(&st.SV, 0)
+	bounded = false
+	if visited := pass(); len(visited) != count {
+		t.Errorf("expected unbounded pass to visit %d replicas, got %v", count, visited)
+	}
+}
@@ -13,6 +13,8 @@ package spanlatch
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
@@ -88,6 +90,10 @@ type latch struct {
 	ts         hlc.Timestamp
 	done       *signal
 	next, prev *latch // readSet linked-list.
+	// acquired is the time, in unix nanos, at which the latch acquisition
+	// completed. It is zero while the acquisition is still waiting on
+	// prerequisite latches. Accessed atomically.
+	acquired int64
 }
 
 func (la *latch) String() string {
@@ -209,9 +215,23 @@ func (m *Manager) Acquire(
 		m.Release(lg)
 		return nil, err
 	}
+	lg.markAcquired(timeutil.Now())
 	return lg, nil
 }
 
+// markAcquired records the time at which the Guard's latches were acquired.
+func (lg *Guard) markAcquired(now time.Time) {
+	nanos := now.UnixNano()
+	for s := spanset.SpanScope(0); s < spanset.NumSpanScope; s++ {
+		for a := spanset.SpanAccess(0); a < spanset.NumSpanAccess; a++ {
+			latches := lg.latches(s, a)
+			for i := range latches {
+				atomic.StoreInt64(&latches[i].acquired, nanos)
+			}
+		}
+	}
+}
+
 // sequence locks the manager, captures an immutable snapshot, inserts latches
 // for each of the specified spans into the manager's interval trees, and
 // unlocks the manager. The role of the method is to sequence latch acquisition
@@ -467,3 +487,46 @@ func (sm *scopedManager) infoLocked() storagepb.LatchManagerInfo {
 	info.WriteCount = int64(sm.trees[spanset.SpanReadWrite].Len())
 	return info
 }
+
+// LatchInfo describes a single latch held in the Manager.
+type LatchInfo struct {
+	Span      roachpb.Span
+	Access    spanset.SpanAccess
+	Timestamp hlc.Timestamp
+	// AcquiredAt is the time at which the latch was acquired.
+	AcquiredAt time.Time
+}
+
+// HeldLatches returns a snapshot of the latches currently held in the
+// Manager. Latches belonging to acquisition attempts that are still waiting on
+// prerequisite latches are not included.
+func (m *Manager) HeldLatches() []LatchInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var infos []LatchInfo
+	addLatch := func(la *latch, a spanset.SpanAccess) {
+		acquired := atomic.LoadInt64(&la.acquired)
+		if acquired == 0 || la.done.signaled() {
+			return
+		}
+		infos = append(infos, LatchInfo{
+			Span:       la.span,
+			Access:     a,
+			Timestamp:  la.ts,
+			AcquiredAt: timeutil.Unix(0, acquired),
+		})
+	}
+	for s := spanset.SpanScope(0); s < spanset.NumSpanScope; s++ {
+		sm := &m.scopes[s]
+		for la := sm.readSet.front(); la != nil && la != &sm.readSet.root; la = la.next {
+			addLatch(la, spanset.SpanReadOnly)
+		}
+		for a := spanset.SpanAccess(0); a < spanset.NumSpanAccess; a++ {
+			it := sm.trees[a].MakeIter()
+			for it.First(); it.Valid(); it.Next() {
+				addLatch(it.Cur(), a)
+			}
+		}
+	}
+	return infos
+}
@@ -15,6 +15,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -25,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -108,6 +110,8 @@ func (m *Manager) MustAcquireChCtx(
 		if err != nil {
 			m.Release(lg)
 			lg = nil
+		} else {
+			lg.markAcquired(timeutil.Now())
 		}
 		ch <- lg
 	}()
@@ -137,6 +141,41 @@ func TestLatchManager(t *testing.T) {
 	testLatchSucceeds(t, lg4C)
 }
 
+func TestLatchManagerHeldLatches(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	var m Manager
+
+	require.Len(t, m.HeldLatches(), 0)
+
+	// Acquire a read latch and a write latch.
+	lg1 := m.MustAcquire(spans("a", "", read), zeroTS)
+	lg2 := m.MustAcquire(spans("b", "c", write), zeroTS)
+	// A latch acquisition that is still waiting is not reported.
+	lg3C := m.MustAcquireCh(spans("b", "", write), zeroTS)
+	testLatchBlocks(t, lg3C)
+
+	held := m.HeldLatches()
+	require.Len(t, held, 2)
+	sort.Slice(held, func(i, j int) bool { return held[i].Span.Key.Compare(held[j].Span.Key) < 0 })
+	require.Equal(t, roachpb.Span{Key: roachpb.Key("a")}, held[0].Span)
+	require.Equal(t, spanset.SpanReadOnly, held[0].Access)
+	require.Equal(t, roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("c")}, held[1].Span)
+	require.Equal(t, spanset.SpanReadWrite, held[1].Access)
+	for _, la := range held {
+		require.False(t, la.AcquiredAt.IsZero())
+	}
+
+	// Releasing the blocking write allows the waiting one to acquire its latch.
+	m.Release(lg2)
+	lg3 := testLatchSucceeds(t, lg3C)
+	held = m.HeldLatches()
+	require.Len(t, held, 2)
+
+	m.Release(lg1)
+	m.Release(lg3)
+	require.Len(t, m.HeldLatches(), 0)
+}
+
 func TestLatchManagerNoWaitOnReadOnly(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	var m Manager
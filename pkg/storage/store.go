@@ -44,7 +44,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/idalloc"
 	"github.com/cockroachdb/cockroach/pkg/storage/intentresolver"
 	"github.com/cockroachdb/cockroach/pkg/storage/raftentry"
+	"github.com/cockroachdb/cockroach/pkg/storage/rditer"
 	"github.com/cockroachdb/cockroach/pkg/storage/stateloader"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/tscache"
 	"github.com/cockroachdb/cockroach/pkg/storage/txnrecovery"
 	"github.com/cockroachdb/cockroach/pkg/storage/txnwait"
@@ -132,8 +134,9 @@ var addSSTableRequestLimit = settings.RegisterPositiveIntSetting(
 	1,
 )
 
-// concurrentRangefeedItersLimit limits concurrent rangefeed catchup iterators.
-var concurrentRangefeedItersLimit = settings.RegisterPositiveIntSetting(
+// ConcurrentRangefeedItersLimit limits concurrent rangefeed catchup iterators.
+// Catch-up scans beyond the limit are queued.
+var ConcurrentRangefeedItersLimit = settings.RegisterPositiveIntSetting(
 	"kv.rangefeed.concurrent_catchup_iterators",
 	"number of rangefeeds catchup iterators a store will allow concurrently before queueing",
 	64,
@@ -152,6 +155,24 @@ var ExportRequestsLimit = settings.RegisterPositiveIntSetting(
 	3,
 )
 
+// retryPushTxnFailures controls whether a request whose transaction push
+// fails waits in the txn wait queue and retries, or whether the failure is
+// immediately surfaced to the client. Deployments sensitive to latency may
+// prefer to fail fast on contention, but disabling the retries turns every
+// contended push into a client-visible retry and can starve transactions, so
+// the setting is hidden and not meant for production use.
+var retryPushTxnFailures = func() *settings.BoolSetting {
+	s := settings.RegisterBoolSetting(
+		"kv.transaction.retry_push_failures_enabled",
+		"if set, requests whose transaction pushes fail wait for the pushee and retry "+
+			"instead of returning an error; not for production use",
+		true,
+	)
+	s.SetConfidential()
+	s.SetSensitive()
+	return s
+}()
+
 // TestStoreConfig has some fields initialized with values relevant in tests.
 func TestStoreConfig(clock *hlc.Clock) StoreConfig {
 	if clock == nil {
@@ -279,13 +300,15 @@ func (e *NotBootstrappedError) Error() string {
 }
 
 // A storeReplicaVisitor calls a visitor function for each of a store's
-// initialized Replicas (in unspecified order). It provides an option
-// to visit replicas in increasing RangeID order.
+// initialized Replicas (in unspecified order). It provides options to visit
+// replicas in increasing RangeID order and to visit only the replicas matching
+// a filter.
 type storeReplicaVisitor struct {
 	store   *Store
-	repls   []*Replica // Replicas to be visited
-	ordered bool       // Option to visit replicas in sorted order
-	visited int        // Number of visited ranges, -1 before first call to Visit()
+	repls   []*Replica          // Replicas to be visited
+	ordered bool                // Option to visit replicas in sorted order
+	filter  func(*Replica) bool // Option to visit only matching replicas
+	visited int                 // Number of visited ranges, -1 before first call to Visit()
 }
 
 // Len implements sort.Interface.
@@ -311,6 +334,14 @@ func (rs *storeReplicaVisitor) InOrder() *storeReplicaVisitor {
 	return rs
 }
 
+// Filter tells the visitor to visit only the replicas for which the filter
+// returns true. The filter is invoked without holding any locks, before the
+// replicas are handed to the visitor function, and should be cheap.
+func (rs *storeReplicaVisitor) Filter(filter func(*Replica) bool) *storeReplicaVisitor {
+	rs.filter = filter
+	return rs
+}
+
 // Visit calls the visitor with each Replica until false is returned.
 func (rs *storeReplicaVisitor) Visit(visitor func(*Replica) bool) {
 	// Copy the range IDs to a slice so that we iterate over some (possibly
@@ -318,7 +349,9 @@ func (rs *storeReplicaVisitor) Visit(visitor func(*Replica) bool) {
 	// no locks are acquired during the copy process.
 	rs.repls = nil
 	rs.store.mu.replicas.Range(func(k int64, v unsafe.Pointer) bool {
-		rs.repls = append(rs.repls, (*Replica)(v))
+		if repl := (*Replica)(v); rs.filter == nil || rs.filter(repl) {
+			rs.repls = append(rs.repls, repl)
+		}
 		return true
 	})
 
@@ -355,12 +388,24 @@ func (rs *storeReplicaVisitor) Visit(visitor func(*Replica) bool) {
 }
 
 // EstimatedCount returns an estimated count of the underlying store's
-// replicas.
+// replicas, or of those matching the filter if one is set. Uninitialized
+// replicas are counted whether or not there is a filter, even though Visit
+// skips them.
 //
 // TODO(tschottdorf): this method has highly doubtful semantics.
 func (rs *storeReplicaVisitor) EstimatedCount() int {
 	if rs.visited <= 0 {
-		return rs.store.ReplicaCount()
+		if rs.filter == nil {
+			return rs.store.ReplicaCount()
+		}
+		var count int
+		rs.store.mu.replicas.Range(func(k int64, v unsafe.Pointer) bool {
+			if rs.filter((*Replica)(v)) {
+				count++
+			}
+			return true
+		})
+		return count
 	}
 	return len(rs.repls) - rs.visited
 }
@@ -434,6 +479,9 @@ type Store struct {
 	startedAt    int64
 	nodeDesc     *roachpb.NodeDescriptor
 	initComplete sync.WaitGroup // Signaled by async init tasks
+	// The store descriptor isn't gossiped before this time, see
+	// StoreTestingKnobs.DelayStoreCapacityGossip.
+	gossipStoreNotBefore time.Time
 
 	// Semaphore to limit concurrent non-empty snapshot application.
 	snapshotApplySem chan struct{}
@@ -609,6 +657,25 @@ type Store struct {
 
 var _ client.Sender = &Store{}
 
+// ProtectedTimestampProvider determines whether the data needed to read at a
+// timestamp is protected from garbage collection.
+type ProtectedTimestampProvider interface {
+	// IsProtected returns whether the MVCC versions needed to read the given
+	// span at the given timestamp are retained, even if the timestamp is at or
+	// below the range's GC threshold. Implementations must coordinate with
+	// garbage collection so that such versions are never collected.
+	IsProtected(span roachpb.Span, ts hlc.Timestamp) bool
+}
+
+// unprotectedTimestampProvider is a ProtectedTimestampProvider which protects
+// nothing.
+type unprotectedTimestampProvider struct{}
+
+// IsProtected implements the ProtectedTimestampProvider interface.
+func (unprotectedTimestampProvider) IsProtected(roachpb.Span, hlc.Timestamp) bool {
+	return false
+}
+
 // A StoreConfig encompasses the auxiliary objects and configuration
 // required to create a store.
 // All fields holding a pointer or an interface are required to create
@@ -639,6 +706,11 @@ type StoreConfig struct {
 	// maintenance queue to dispatch individual maintenance tasks.
 	TimeSeriesDataStore TimeSeriesDataStore
 
+	// ProtectedTimestampProvider determines whether read-only batches which
+	// set AllowReadBelowGCThreshold may be evaluated at or below the GC
+	// threshold. If not set, nothing is protected.
+	ProtectedTimestampProvider ProtectedTimestampProvider
+
 	// CoalescedHeartbeatsInterval is the interval for which heartbeat messages
 	// are queued and then sent as a single coalesced heartbeat; it is a
 	// fraction of the RaftTickInterval so that heartbeats don't get delayed by
@@ -758,6 +830,9 @@ func (sc *StoreConfig) SetDefaults() {
 	if sc.GossipWhenCapacityDeltaExceedsFraction == 0 {
 		sc.GossipWhenCapacityDeltaExceedsFraction = defaultGossipWhenCapacityDeltaExceedsFraction
 	}
+	if sc.ProtectedTimestampProvider == nil {
+		sc.ProtectedTimestampProvider = unprotectedTimestampProvider{}
+	}
 }
 
 // LeaseExpiration returns an int64 to increment a manual clock with to
@@ -890,11 +965,11 @@ func NewStore(
 		s.limiters.ConcurrentAddSSTableRequests.SetLimit(int(addSSTableRequestLimit.Get(&cfg.Settings.SV)))
 	})
 	s.limiters.ConcurrentRangefeedIters = limit.MakeConcurrentRequestLimiter(
-		"rangefeedIterLimiter", int(concurrentRangefeedItersLimit.Get(&cfg.Settings.SV)),
+		"rangefeedIterLimiter", int(ConcurrentRangefeedItersLimit.Get(&cfg.Settings.SV)),
 	)
-	concurrentRangefeedItersLimit.SetOnChange(&cfg.Settings.SV, func() {
+	ConcurrentRangefeedItersLimit.SetOnChange(&cfg.Settings.SV, func() {
 		s.limiters.ConcurrentRangefeedIters.SetLimit(
-			int(concurrentRangefeedItersLimit.Get(&cfg.Settings.SV)))
+			int(ConcurrentRangefeedItersLimit.Get(&cfg.Settings.SV)))
 	})
 
 	if s.cfg.Gossip != nil {
@@ -903,6 +978,7 @@ func NewStore(
 			s.cfg.AmbientCtx, s.cfg.Clock, cfg.ScanInterval,
 			cfg.ScanMinIdleTime, cfg.ScanMaxIdleTime, newStoreReplicaVisitor(s),
 		)
+		s.scanner.sv = &cfg.Settings.SV
 		s.gcQueue = newGCQueue(s, s.cfg.Gossip)
 		s.mergeQueue = newMergeQueue(s, s.db, s.cfg.Gossip)
 		s.splitQueue = newSplitQueue(s, s.db, s.cfg.Gossip)
@@ -970,6 +1046,21 @@ func (s *Store) ClusterSettings() *cluster.Settings {
 	return s.cfg.Settings
 }
 
+// NonDefaultSettings returns the cluster settings whose values, as seen by
+// this store, differ from their defaults, keyed by setting name. Values of
+// settings that might contain sensitive information are redacted.
+func (s *Store) NonDefaultSettings() map[string]string {
+	sv := &s.cfg.Settings.SV
+	res := make(map[string]string)
+	for _, name := range settings.Keys() {
+		setting, _ := settings.Lookup(name)
+		if setting.Encoded(sv) != setting.EncodedDefault() {
+			res[name] = settings.SanitizedValue(name, sv)
+		}
+	}
+	return res
+}
+
 // AnnotateCtx is a convenience wrapper; see AmbientContext.
 func (s *Store) AnnotateCtx(ctx context.Context) context.Context {
 	return s.cfg.AmbientCtx.AnnotateCtx(ctx)
@@ -1208,6 +1299,75 @@ func IterateIDPrefixKeys(
 	}
 }
 
+// IterateIDPrefixKeysReverse is like IterateIDPrefixKeys, but visits the keys
+// in descending order of RangeID.
+func IterateIDPrefixKeysReverse(
+	ctx context.Context,
+	eng engine.Reader,
+	keyFn func(roachpb.RangeID) roachpb.Key,
+	msg protoutil.Message,
+	f func(_ roachpb.RangeID) (more bool, _ error),
+) error {
+	iter := eng.NewIterator(engine.IterOptions{
+		LowerBound: keys.LocalRangeIDPrefix.AsRawKey(),
+		UpperBound: keys.LocalRangeIDPrefix.PrefixEnd().AsRawKey(),
+	})
+	defer iter.Close()
+
+	// Start at the last RangeID-prefixed key. Every time the iterator is
+	// positioned on a key of some RangeID, seek either to the desired key of
+	// that RangeID (if the iterator is past it) or to that of the previous
+	// RangeID. Either way, the iterator moves backwards.
+	iter.SeekReverse(engine.MakeMVCCMetadataKey(keys.LocalRangeIDPrefix.PrefixEnd().AsRawKey()))
+	for {
+		if ok, err := iter.Valid(); !ok {
+			return err
+		}
+
+		unsafeKey := iter.UnsafeKey()
+
+		if !bytes.HasPrefix(unsafeKey.Key, keys.LocalRangeIDPrefix) {
+			// Left the local keyspace, so we're done.
+			return nil
+		}
+
+		rangeID, _, _, _, err := keys.DecodeRangeIDKey(unsafeKey.Key)
+		if err != nil {
+			return err
+		}
+		if rangeID < 1 {
+			return nil
+		}
+
+		key := keyFn(rangeID)
+		if c := unsafeKey.Key.Compare(key); c > 0 {
+			// Positioned after the desired key of this RangeID, for instance on
+			// a RaftHardStateKey when looking for tombstones. Back up to it.
+			iter.SeekReverse(engine.MakeMVCCMetadataKey(key))
+			continue
+		} else if c < 0 {
+			// This RangeID doesn't have the desired key.
+			iter.SeekReverse(engine.MakeMVCCMetadataKey(keyFn(rangeID - 1)))
+			continue
+		}
+
+		ok, err := engine.MVCCGetProto(
+			ctx, eng, key, hlc.Timestamp{}, msg, engine.MVCCGetOptions{})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.Errorf("unable to unmarshal %s into %T", key, msg)
+		}
+
+		more, err := f(rangeID)
+		if !more || err != nil {
+			return err
+		}
+		iter.SeekReverse(engine.MakeMVCCMetadataKey(keyFn(rangeID - 1)))
+	}
+}
+
 // IterateRangeDescriptors calls the provided function with each descriptor
 // from the provided Engine. The return values of this method and fn have
 // semantics similar to engine.MVCCIterate.
@@ -1328,6 +1488,9 @@ func (s *Store) Start(ctx context.Context, stopper *stop.Stopper) error {
 
 	now := s.cfg.Clock.Now()
 	s.startedAt = now.WallTime
+	if delay := s.cfg.TestingKnobs.DelayStoreCapacityGossip; delay > 0 {
+		s.gossipStoreNotBefore = timeutil.Now().Add(delay)
+	}
 
 	// Iterate over all range descriptors, ignoring uncommitted versions
 	// (consistent=false). Uncommitted intents which have been abandoned
@@ -1450,6 +1613,17 @@ func (s *Store) Start(ctx context.Context, stopper *stop.Stopper) error {
 		s.compactor.Start(s.AnnotateCtx(context.Background()), s.stopper)
 	}
 
+	if delay := s.cfg.TestingKnobs.DelayStoreCapacityGossip; delay > 0 && s.cfg.Gossip != nil {
+		// Gossip the store descriptor as soon as the delay has elapsed.
+		s.stopper.RunWorker(ctx, func(ctx context.Context) {
+			select {
+			case <-time.After(delay):
+				s.asyncGossipStore(ctx, "delayed capacity gossip", false /* useCached */)
+			case <-s.stopper.ShouldStop():
+			}
+		})
+	}
+
 	// Set the started flag (for unittests).
 	atomic.StoreInt32(&s.started, 1)
 
@@ -1733,6 +1907,10 @@ func (s *Store) GossipStore(ctx context.Context, useCached bool) error {
 		// Nothing to do if gossip is not connected.
 		return nil
 	}
+	if timeutil.Now().Before(s.gossipStoreNotBefore) {
+		// Gossiping the store descriptor is delayed for testing.
+		return nil
+	}
 
 	// Temporarily indicate that we're gossiping the store capacity to avoid
 	// recursively triggering a gossip of the store capacity.
@@ -1761,6 +1939,76 @@ func (s *Store) GossipStore(ctx context.Context, useCached bool) error {
 	return s.cfg.Gossip.AddInfoProto(gossipStoreKey, storeDesc, gossip.StoreTTL)
 }
 
+// GossipPropagationLag estimates how stale this store's view of its peers
+// is, as the time elapsed since the oldest of the other stores' descriptors
+// known to this node's gossip instance was originated. Stores re-gossip their
+// descriptors every gossip.StoresInterval, so a lag well beyond that points
+// at a problem with the gossip network, which in turn leads the allocator to
+// act on outdated information. It returns zero if no peer store descriptors
+// are known.
+func (s *Store) GossipPropagationLag() time.Duration {
+	if s.cfg.Gossip == nil {
+		return 0
+	}
+	ownKey := gossip.MakeStoreKey(s.StoreID())
+	var oldest int64
+	_ = s.cfg.Gossip.IterateInfos(gossip.KeyStorePrefix, func(key string, info gossip.Info) error {
+		if key != ownKey && (oldest == 0 || info.OrigStamp < oldest) {
+			oldest = info.OrigStamp
+		}
+		return nil
+	})
+	if oldest == 0 {
+		return 0
+	}
+	return timeutil.Since(timeutil.Unix(0, oldest))
+}
+
+// ReGossipAll re-gossips the store descriptor along with the first range
+// descriptor, system config and node liveness records for which this store
+// is responsible, i.e. for which it holds the range lease. Unlike the periodic
+// gossip, which skips system config and node liveness records that are
+// unchanged, all of this information is gossiped anew, which refreshes entries
+// that went stale (for example after a network partition).
+func (s *Store) ReGossipAll(ctx context.Context) error {
+	if err := s.GossipStore(ctx, false /* useCached */); err != nil {
+		return err
+	}
+	if repl := s.LookupReplica(roachpb.RKeyMin); repl != nil {
+		hasLease, pErr := repl.getLeaseForGossip(ctx)
+		if pErr != nil {
+			return errors.Wrap(pErr.GoError(), "could not gossip first range descriptor")
+		}
+		if hasLease {
+			repl.gossipFirstRange(ctx)
+		}
+	}
+	for _, span := range []roachpb.Span{keys.SystemConfigSpan, keys.NodeLivenessSpan} {
+		repl := s.LookupReplica(roachpb.RKey(span.Key))
+		if repl == nil {
+			continue
+		}
+		if hasLease, pErr := repl.getLeaseForGossip(ctx); pErr != nil {
+			return errors.Wrapf(pErr.GoError(), "could not gossip %s", span)
+		} else if !hasLease {
+			continue
+		}
+		// Hold raftMu to serialize with the application of Raft commands, which
+		// is where system data is normally gossiped from.
+		if err := func() error {
+			repl.raftMu.Lock()
+			defer repl.raftMu.Unlock()
+			if span.Equal(keys.SystemConfigSpan) {
+				return repl.maybeGossipSystemConfig(ctx, true /* force */)
+			}
+			return repl.maybeGossipNodeLiveness(ctx, span, true /* force */)
+		}(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type capacityChangeEvent int
 
 const (
@@ -1985,6 +2233,53 @@ func (s *Store) LookupReplica(key roachpb.RKey) *Replica {
 	return repl
 }
 
+// LookupReplicas is a batched version of LookupReplica. It returns, for each
+// of the specified keys, the replica that contains it, or nil if no such
+// replica exists. Rather than searching replicasByKey once per key, the keys
+// are sorted and replicasByKey is walked once.
+func (s *Store) LookupReplicas(keys []roachpb.RKey) []*Replica {
+	repls := make([]*Replica, len(keys))
+	if len(keys) == 0 {
+		return repls
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return keys[order[i]].Less(keys[order[j]])
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Begin the walk at the only item that can possibly contain the smallest
+	// key, if there is one.
+	var start btree.Item = rangeBTreeKey(keys[order[0]])
+	s.mu.replicasByKey.DescendLessOrEqual(start, func(item btree.Item) bool {
+		start = item
+		return false
+	})
+	i := 0
+	s.mu.replicasByKey.AscendGreaterOrEqual(start, func(item btree.Item) bool {
+		// Placeholders don't contain any keys, as in LookupReplica.
+		repl, _ := item.(*Replica)
+		desc := item.(KeyRange).Desc()
+		for ; i < len(order); i++ {
+			key := keys[order[i]]
+			if key.Less(desc.StartKey) {
+				// Not covered by any item.
+				continue
+			}
+			if !key.Less(desc.EndKey) {
+				break
+			}
+			repls[order[i]] = repl
+		}
+		return i < len(order)
+	})
+	return repls
+}
+
 // lookupPrecedingReplica finds the replica in this store that immediately
 // precedes the specified key without containing it. It returns nil if no such
 // replica exists. It ignores replica placeholders.
@@ -2006,6 +2301,35 @@ func (s *Store) lookupPrecedingReplica(key roachpb.RKey) *Replica {
 	return repl
 }
 
+// ReplicasByKeyEntry describes a single item of a store's replicasByKey
+// btree, as returned by DumpReplicasByKey.
+type ReplicasByKeyEntry struct {
+	StartKey, EndKey roachpb.RKey
+	RangeID          roachpb.RangeID
+	IsPlaceholder    bool
+}
+
+// DumpReplicasByKey returns a snapshot of the contents of the replicasByKey
+// btree, including replica placeholders, in key order. It is intended for
+// debugging gaps or overlaps in the keyspace covered by the store.
+func (s *Store) DumpReplicasByKey() []ReplicasByKeyEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := make([]ReplicasByKeyEntry, 0, s.mu.replicasByKey.Len())
+	s.mu.replicasByKey.Ascend(func(item btree.Item) bool {
+		_, isPlaceholder := item.(*ReplicaPlaceholder)
+		desc := item.(KeyRange).Desc()
+		entries = append(entries, ReplicasByKeyEntry{
+			StartKey:      desc.StartKey,
+			EndKey:        desc.EndKey,
+			RangeID:       desc.RangeID,
+			IsPlaceholder: isPlaceholder,
+		})
+		return true // keep iterating
+	})
+	return entries
+}
+
 // getOverlappingKeyRangeLocked returns a KeyRange from the Store overlapping the given
 // descriptor (or nil if no such KeyRange exists).
 func (s *Store) getOverlappingKeyRangeLocked(rngDesc *roachpb.RangeDescriptor) KeyRange {
@@ -2033,6 +2357,13 @@ func (s *Store) RaftStatus(rangeID roachpb.RangeID) *raft.Status {
 	return nil
 }
 
+// TxnObservedTimestamp returns the timestamp the given transaction has
+// observed from this store's node clock, if any. Reads by the transaction on
+// this node use it to shrink their uncertainty interval.
+func (s *Store) TxnObservedTimestamp(txn *roachpb.Transaction) (hlc.Timestamp, bool) {
+	return txn.GetObservedTimestamp(s.Ident.NodeID)
+}
+
 // ClusterID accessor.
 func (s *Store) ClusterID() uuid.UUID { return s.Ident.ClusterID }
 
@@ -2051,9 +2382,42 @@ func (s *Store) DB() *client.DB { return s.cfg.DB }
 // Gossip accessor.
 func (s *Store) Gossip() *gossip.Gossip { return s.cfg.Gossip }
 
+// ThrottledStores returns the stores which this store's store pool currently
+// considers throttled because a snapshot sent to them failed or was declined.
+// Throttled stores are not considered as targets for new replicas until the
+// throttle expires.
+func (s *Store) ThrottledStores() []ThrottledStore {
+	if s.cfg.StorePool == nil {
+		return nil
+	}
+	return s.cfg.StorePool.throttledStores()
+}
+
 // Compactor accessor.
 func (s *Store) Compactor() *compactor.Compactor { return s.compactor }
 
+// CompactionBacklog returns the number of suggested compactions which the
+// store's compactor has yet to process, along with the number of bytes they
+// are estimated to reclaim. A store which is falling behind on reclaiming
+// space, for instance after large deletions, will report a growing backlog.
+func (s *Store) CompactionBacklog() (pendingCompactions int, estimatedBytes int64) {
+	ctx := s.AnnotateCtx(context.TODO())
+	pendingCompactions, estimatedBytes, err := s.compactor.Backlog(ctx)
+	if err != nil {
+		log.Warningf(ctx, "unable to examine suggested compactions: %+v", err)
+		return 0, 0
+	}
+	return pendingCompactions, estimatedBytes
+}
+
+// ProcessSuggestedCompactions synchronously processes the store's suggested
+// compactions, which allows reclaiming space right after a large deletion
+// instead of waiting for the compactor to get to it. Returns the number of
+// suggested compactions which were compacted.
+func (s *Store) ProcessSuggestedCompactions(ctx context.Context) (processed int, err error) {
+	return s.compactor.ProcessSuggestions(ctx)
+}
+
 // Stopper accessor.
 func (s *Store) Stopper() *stop.Stopper { return s.stopper }
 
@@ -2306,6 +2670,8 @@ func (s *Store) SplitRange(
 	// spans that are now owned by the new range.
 	leftRepl.leaseholderStats.resetRequestCounts()
 	leftRepl.writeStats.splitRequestCounts(rightRepl.writeStats)
+	leftRepl.readBytesStats.splitRequestCounts(rightRepl.readBytesStats)
+	leftRepl.writeBytesStats.splitRequestCounts(rightRepl.writeBytesStats)
 
 	if err := s.addReplicaInternalLocked(rightRepl); err != nil {
 		return errors.Errorf("unable to add replica %v: %s", rightRepl, err)
@@ -2390,6 +2756,10 @@ func (s *Store) MergeRange(
 		// logic that depends on them.
 		leftRepl.writeStats.resetRequestCounts()
 	}
+	if leftRepl.readBytesStats != nil {
+		leftRepl.readBytesStats.resetRequestCounts()
+		leftRepl.writeBytesStats.resetRequestCounts()
+	}
 
 	// Clear the wait queue to redirect the queued transactions to the
 	// left-hand replica, if necessary.
@@ -2539,33 +2909,121 @@ func (s *Store) RemoveReplica(
 	return s.removeReplicaImpl(ctx, rep, nextReplicaID, opts)
 }
 
+// ReplicaRemoval describes a replica to be removed by RemoveReplicas, along
+// with the arguments that would otherwise be passed to RemoveReplica.
+type ReplicaRemoval struct {
+	Replica       *Replica
+	NextReplicaID roachpb.ReplicaID
+	Options       RemoveOptions
+}
+
+// RemoveReplicas removes the given replicas from the store, with the same
+// semantics as calling RemoveReplica for each of them, but performs the
+// bookkeeping under Store.mu for all of them at once.
+//
+// Removals are independent of each other: a removal which fails does not
+// prevent the others from proceeding. The returned slice holds the error for
+// each removal, in the order in which they were passed in, or is nil if all
+// removals succeeded. A replica whose removal failed may or may not have been
+// marked as destroyed, exactly as if RemoveReplica had failed for it. A
+// replica which is passed in more than once, or which is concurrently removed
+// by RemoveReplica, is only removed once, and the other removals of it fail.
+//
+// The caller must not hold the raftMu of any of the replicas. The raftMu of
+// each replica is only held while it is verified and destroyed, so the
+// replicas are never locked at the same time.
+func (s *Store) RemoveReplicas(ctx context.Context, removals []ReplicaRemoval) []error {
+	errs := make([]error, len(removals))
+	descs := make([]*roachpb.RangeDescriptor, len(removals))
+	for i, rm := range removals {
+		// Marking the replica as destroyed prevents it from being used, and
+		// prevents any other removal of it from proceeding, so the store's
+		// references to it can be removed afterwards without holding its
+		// raftMu.
+		rm.Replica.raftMu.Lock()
+		descs[i], errs[i] = s.prepareRemoveReplica(ctx, rm.Replica, rm.NextReplicaID)
+		if errs[i] == nil {
+			errs[i] = s.destroyRemovedReplica(ctx, rm.Replica, rm.NextReplicaID, rm.Options)
+		}
+		rm.Replica.raftMu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var failed bool
+	for i, rm := range removals {
+		if errs[i] != nil {
+			failed = true
+			continue
+		}
+		s.beginRemoveReplicaLocked(ctx, rm.Replica, descs[i])
+		s.finishRemoveReplicaLocked(ctx, rm.Replica, descs[i])
+	}
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
 // removeReplicaImpl is the implementation of RemoveReplica, which is sometimes
 // called directly when the necessary lock is already held. It requires that
 // Replica.raftMu is held and that s.mu is not held.
 func (s *Store) removeReplicaImpl(
 	ctx context.Context, rep *Replica, nextReplicaID roachpb.ReplicaID, opts RemoveOptions,
 ) error {
+	desc, err := s.prepareRemoveReplica(ctx, rep, nextReplicaID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.beginRemoveReplicaLocked(ctx, rep, desc)
+	s.mu.Unlock()
+
+	if err := s.destroyRemovedReplica(ctx, rep, nextReplicaID, opts); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finishRemoveReplicaLocked(ctx, rep, desc)
+	return nil
+}
+
+// prepareRemoveReplica verifies that the replica can be removed and returns
+// its descriptor. It requires that Replica.raftMu is held and that s.mu is not
+// held.
+func (s *Store) prepareRemoveReplica(
+	ctx context.Context, rep *Replica, nextReplicaID roachpb.ReplicaID,
+) (*roachpb.RangeDescriptor, error) {
 	rep.raftMu.AssertHeld()
+	rep.mu.Lock()
+	// A replica which has been marked as removed may not have been unlinked
+	// from the store yet if RemoveReplicas is removing it, which it does
+	// without holding its raftMu.
+	if rep.mu.destroyStatus.Removed() {
+		rep.mu.Unlock()
+		return nil, errors.Errorf("cannot remove replica %s; it has already been removed", rep)
+	}
 	// We check both rep.mu.ReplicaID and rep.mu.state.Desc's replica ID because
 	// they can differ in cases when a replica's ID is increased due to an
 	// incoming raft message (see #14231 for background).
-	rep.mu.Lock()
 	replicaID := rep.mu.replicaID
 	if rep.mu.replicaID >= nextReplicaID {
 		rep.mu.Unlock()
-		return errors.Errorf("cannot remove replica %s; replica ID has changed (%s >= %s)",
+		return nil, errors.Errorf("cannot remove replica %s; replica ID has changed (%s >= %s)",
 			rep, rep.mu.replicaID, nextReplicaID)
 	}
 	desc := rep.mu.state.Desc
 	if repDesc, ok := desc.GetReplicaDescriptor(s.StoreID()); ok && repDesc.ReplicaID >= nextReplicaID {
 		rep.mu.Unlock()
-		return errors.Errorf("cannot remove replica %s; replica descriptor's ID has changed (%s >= %s)",
+		return nil, errors.Errorf("cannot remove replica %s; replica descriptor's ID has changed (%s >= %s)",
 			rep, repDesc.ReplicaID, nextReplicaID)
 	}
 	rep.mu.Unlock()
 
 	if _, err := s.GetReplica(rep.RangeID); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !rep.IsInitialized() {
@@ -2580,8 +3038,15 @@ func (s *Store) removeReplicaImpl(
 	// During merges, the context might have the subsuming range, so we explicitly
 	// log the replica to be removed.
 	log.Infof(ctx, "removing replica r%d/%d", rep.RangeID, replicaID)
+	return desc, nil
+}
 
-	s.mu.Lock()
+// beginRemoveReplicaLocked removes the replica from the store's metrics. It
+// requires that s.mu is held, and that Replica.raftMu is held unless the
+// replica has already been marked as destroyed.
+func (s *Store) beginRemoveReplicaLocked(
+	ctx context.Context, rep *Replica, desc *roachpb.RangeDescriptor,
+) {
 	if placeholder := s.getOverlappingKeyRangeLocked(desc); placeholder != rep {
 		// This is a fatal error because uninitialized replicas shouldn't make it
 		// this far. This method will need some changes when we introduce GC of
@@ -2594,8 +3059,14 @@ func (s *Store) removeReplicaImpl(
 	// tests.
 	s.metrics.subtractMVCCStats(rep.GetMVCCStats())
 	s.metrics.ReplicaCount.Dec(1)
-	s.mu.Unlock()
+}
 
+// destroyRemovedReplica marks the replica as destroyed and, if requested,
+// destroys its on-disk data. It requires that Replica.raftMu is held and that
+// s.mu is not held.
+func (s *Store) destroyRemovedReplica(
+	ctx context.Context, rep *Replica, nextReplicaID roachpb.ReplicaID, opts RemoveOptions,
+) error {
 	// The replica will no longer exist, so cancel any rangefeed registrations.
 	rep.disconnectRangefeedWithReason(
 		roachpb.RangeFeedRetryError_REASON_REPLICA_REMOVED,
@@ -2619,9 +3090,15 @@ func (s *Store) removeReplicaImpl(
 			return err
 		}
 	}
+	return nil
+}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// finishRemoveReplicaLocked removes all of the store's references to the
+// replica. It requires that s.mu is held, and that Replica.raftMu is held
+// unless the replica has already been marked as destroyed.
+func (s *Store) finishRemoveReplicaLocked(
+	ctx context.Context, rep *Replica, desc *roachpb.RangeDescriptor,
+) {
 	s.unlinkReplicaByRangeIDLocked(rep.RangeID)
 	if placeholder := s.mu.replicasByKey.Delete(rep); placeholder != rep {
 		// We already checked that our replica was present in replicasByKey
@@ -2635,8 +3112,6 @@ func (s *Store) removeReplicaImpl(
 	// TODO(peter): Could release s.mu.Lock() here.
 	s.maybeGossipOnCapacityChange(ctx, rangeRemoveEvent)
 	s.scanner.RemoveReplica(rep)
-
-	return nil
 }
 
 // unlinkReplicaByRangeIDLocked removes all of the store's references to the
@@ -2778,6 +3253,367 @@ func (s *Store) ReplicaCount() int {
 	return count
 }
 
+// TotalSideloadedBytes returns the total size of the sideloaded SSTables of
+// all replicas on the store, i.e. the disk space held by AddSSTable payloads
+// whose Raft log entries haven't been truncated yet.
+func (s *Store) TotalSideloadedBytes() (int64, error) {
+	ctx := s.AnnotateCtx(context.Background())
+	var total int64
+	var err error
+	newStoreReplicaVisitor(s).Visit(func(repl *Replica) bool {
+		var size int64
+		size, err = repl.SideloadedBytes(ctx)
+		total += size
+		return err == nil
+	})
+	return total, err
+}
+
+// MergeCandidate describes a range which is below the minimum size threshold
+// of its zone config, as returned by Store.MergeCandidates.
+type MergeCandidate struct {
+	RangeID roachpb.RangeID
+	// Size is the total size of the range, per its MVCC stats.
+	Size int64
+	// MinBytes is the minimum size threshold of the range's zone config.
+	MinBytes int64
+	// BlockedReason is the reason for which the merge queue won't merge the
+	// range into its right-hand neighbor. It is empty if the range is eligible,
+	// in which case the merge may still be prevented by the right-hand neighbor
+	// (for example if it is too large).
+	BlockedReason string
+}
+
+// MergeCandidates returns the ranges for which this store holds a valid lease
+// and which are smaller than their zone config's minimum size, along with the
+// reason, if any, for which the merge queue isn't merging them. This helps to
+// explain why small ranges persist. The result is sorted by RangeID, and is
+// nil if the system config isn't available.
+func (s *Store) MergeCandidates() []MergeCandidate {
+	if s.mergeQueue == nil {
+		return nil
+	}
+	sysCfg := s.cfg.Gossip.GetSystemConfig()
+	if sysCfg == nil {
+		return nil
+	}
+	now := s.Clock().Now()
+	var candidates []MergeCandidate
+	newStoreReplicaVisitor(s).Visit(func(repl *Replica) bool {
+		if !repl.IsInitialized() || !repl.OwnsValidLease(now) {
+			return true // more
+		}
+		repl.mu.RLock()
+		needsMerge := repl.needsMergeBySizeRLocked()
+		size, minBytes := repl.mu.state.Stats.Total(), *repl.mu.zone.RangeMinBytes
+		repl.mu.RUnlock()
+		if !needsMerge {
+			return true // more
+		}
+		candidates = append(candidates, MergeCandidate{
+			RangeID:       repl.RangeID,
+			Size:          size,
+			MinBytes:      minBytes,
+			BlockedReason: s.mergeQueue.mergeBlockedReason(repl.Desc(), sysCfg),
+		})
+		return true // more
+	})
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].RangeID < candidates[j].RangeID })
+	return candidates
+}
+
+// RecoverIndeterminateCommit runs the transaction recovery protocol for the
+// transaction with the given key and ID and returns the transaction's
+// resulting status. This is meant for operators to unwedge ranges that are
+// blocked on a transaction which was abandoned in the STAGING state. The
+// transaction record is queried on the leaseholder of the transaction's key,
+// and recovery is refused while the transaction might still be live.
+// Transactions which are already finalized are left alone and their status is
+// returned.
+func (s *Store) RecoverIndeterminateCommit(
+	ctx context.Context, txnKey roachpb.Key, txnID uuid.UUID,
+) (roachpb.TransactionStatus, error) {
+	now := s.Clock().Now()
+	b := &client.Batch{}
+	b.Header.Timestamp = now
+	b.AddRawRequest(&roachpb.QueryTxnRequest{
+		RequestHeader: roachpb.RequestHeader{Key: txnKey},
+		Txn:           enginepb.TxnMeta{ID: txnID, Key: txnKey},
+	})
+	if err := s.db.Run(ctx, b); err != nil {
+		return 0, errors.Wrapf(err, "unable to query txn %s", txnID.Short())
+	}
+	record := b.RawResponse().Responses[0].GetQueryTxn().QueriedTxn
+	switch record.Status {
+	case roachpb.STAGING:
+	case roachpb.PENDING:
+		return 0, errors.Errorf("transaction %s is not in an indeterminate commit state: %s",
+			txnID.Short(), record.Status)
+	default:
+		return record.Status, nil
+	}
+	if !txnwait.IsExpired(now, &record) {
+		return 0, errors.Errorf("transaction %s may still be live (last active %s); refusing to recover it",
+			txnID.Short(), record.LastActive())
+	}
+	txn, err := s.recoveryMgr.ResolveIndeterminateCommit(ctx, roachpb.NewIndeterminateCommitError(record))
+	if err != nil {
+		return 0, err
+	}
+	return txn.Status, nil
+}
+
+// ClosedTimestampLaggards returns the IDs of the ranges on this store whose
+// closed timestamp trails the current time by more than threshold. Follower
+// reads on these ranges are unlikely to be servable. The result is sorted by
+// RangeID.
+func (s *Store) ClosedTimestampLaggards(threshold time.Duration) []roachpb.RangeID {
+	ctx := s.AnnotateCtx(context.TODO())
+	now := s.Clock().PhysicalNow()
+	var laggards []roachpb.RangeID
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		if !r.IsInitialized() {
+			return true // more
+		}
+		if behind := now - r.maxClosed(ctx).WallTime; behind > threshold.Nanoseconds() {
+			laggards = append(laggards, r.RangeID)
+		}
+		return true // more
+	})
+	sort.Slice(laggards, func(i, j int) bool { return laggards[i] < laggards[j] })
+	return laggards
+}
+
+// LeaseLeaderMismatches returns the IDs of the ranges for which this store
+// holds a valid lease but not the Raft leadership, or vice versa. Writes to
+// such ranges incur an additional hop between the leaseholder and the leader.
+func (s *Store) LeaseLeaderMismatches() []roachpb.RangeID {
+	now := s.Clock().Now()
+	var mismatches []roachpb.RangeID
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		r.mu.RLock()
+		leader := isRaftLeader(r.raftStatusRLocked())
+		status := r.leaseStatus(*r.mu.state.Lease, now, r.mu.minLeaseProposedTS)
+		r.mu.RUnlock()
+		if status.State != storagepb.LeaseState_VALID {
+			return true // more
+		}
+		if leaseholder := status.Lease.OwnedBy(s.StoreID()); leaseholder != leader {
+			mismatches = append(mismatches, r.RangeID)
+		}
+		return true // more
+	})
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i] < mismatches[j] })
+	return mismatches
+}
+
+// LeaselessRanges returns the IDs of the initialized ranges on this store for
+// which, as far as this store can tell, no replica holds a usable lease. These
+// are candidates for lease acquisition; a growing number of them points at a
+// problem with Raft leadership or node liveness. The result is sorted by
+// RangeID.
+func (s *Store) LeaselessRanges() []roachpb.RangeID {
+	now := s.Clock().Now()
+	var leaseless []roachpb.RangeID
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		if !r.IsInitialized() {
+			return true // more
+		}
+		r.mu.RLock()
+		status := r.leaseStatus(*r.mu.state.Lease, now, r.mu.minLeaseProposedTS)
+		r.mu.RUnlock()
+		switch status.State {
+		case storagepb.LeaseState_VALID, storagepb.LeaseState_STASIS:
+		default:
+			leaseless = append(leaseless, r.RangeID)
+		}
+		return true // more
+	})
+	sort.Slice(leaseless, func(i, j int) bool { return leaseless[i] < leaseless[j] })
+	return leaseless
+}
+
+// DescriptorMismatch describes a replica whose range descriptor disagrees
+// with the authoritative copy in the meta ranges.
+type DescriptorMismatch struct {
+	RangeID   roachpb.RangeID
+	LocalDesc *roachpb.RangeDescriptor
+	// MetaDesc is the descriptor found in the meta ranges at the addressing
+	// key of LocalDesc, or nil if there is none. A MetaDesc for a different
+	// range indicates that the local replica is orphaned.
+	MetaDesc *roachpb.RangeDescriptor
+}
+
+// DescriptorConsistencyCheck compares the descriptor of each initialized
+// replica on this store with the one stored in the meta ranges and returns
+// the replicas for which the two differ, sorted by RangeID. This finds
+// replicas that hold a stale descriptor as well as replicas that the range
+// no longer knows about. Note that replicas which simply haven't caught up
+// on a recent split, merge or replication change are reported as well, so
+// only mismatches that persist across checks warrant attention.
+//
+// The descriptors are looked up in batches of descriptorLookupBatchSize.
+// Failing to look up a batch of descriptors, for instance because the meta
+// ranges are unavailable, does not abort the check. The mismatches found among
+// the remaining replicas are returned along with an error.
+func (s *Store) DescriptorConsistencyCheck(ctx context.Context) ([]DescriptorMismatch, error) {
+	var repls []*Replica
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		if r.IsInitialized() {
+			repls = append(repls, r)
+		}
+		return true // more
+	})
+	sort.Slice(repls, func(i, j int) bool { return repls[i].RangeID < repls[j].RangeID })
+
+	total := len(repls)
+	var mismatches []DescriptorMismatch
+	var failed int
+	var lastErr error
+	for len(repls) > 0 {
+		chunk := repls
+		if len(chunk) > descriptorLookupBatchSize {
+			chunk = chunk[:descriptorLookupBatchSize]
+		}
+		repls = repls[len(chunk):]
+
+		localDescs := make([]*roachpb.RangeDescriptor, len(chunk))
+		for i, r := range chunk {
+			localDescs[i] = r.Desc()
+		}
+		metaDescs, err := s.lookupMetaDescriptors(ctx, localDescs)
+		if err != nil {
+			log.VEventf(ctx, 2, "unable to look up meta descriptors for r%d-r%d: %s",
+				chunk[0].RangeID, chunk[len(chunk)-1].RangeID, err)
+			failed += len(chunk)
+			lastErr = err
+			continue
+		}
+		for i, r := range chunk {
+			if metaDescs[i] != nil && localDescs[i].Equal(metaDescs[i]) {
+				continue
+			}
+			mismatches = append(mismatches, DescriptorMismatch{
+				RangeID:   r.RangeID,
+				LocalDesc: localDescs[i],
+				MetaDesc:  metaDescs[i],
+			})
+		}
+	}
+	if failed > 0 {
+		return mismatches, errors.Wrapf(lastErr,
+			"unable to look up %d of %d descriptors in the meta ranges", failed, total)
+	}
+	return mismatches, nil
+}
+
+// descriptorLookupBatchSize is the number of descriptors which
+// DescriptorConsistencyCheck looks up in the meta ranges in a single batch.
+const descriptorLookupBatchSize = 100
+
+// lookupMetaDescriptors looks up the descriptors stored in the meta ranges at
+// the addressing keys of the given descriptors in a single batch. The returned
+// slice holds a nil descriptor for every addressing key at which none is
+// found.
+func (s *Store) lookupMetaDescriptors(
+	ctx context.Context, descs []*roachpb.RangeDescriptor,
+) ([]*roachpb.RangeDescriptor, error) {
+	metaDescs := make([]*roachpb.RangeDescriptor, len(descs))
+	err := contextutil.RunWithTimeout(ctx, "look up meta descriptors", 5*time.Second,
+		func(ctx context.Context) error {
+			var b client.Batch
+			for _, desc := range descs {
+				b.Get(keys.RangeMetaKey(desc.EndKey).AsRawKey())
+			}
+			if err := s.db.Run(ctx, &b); err != nil {
+				return err
+			}
+			for i, res := range b.Results {
+				if kv := res.Rows[0]; kv.Value != nil {
+					metaDescs[i] = &roachpb.RangeDescriptor{}
+					if err := kv.Value.GetProto(metaDescs[i]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	return metaDescs, err
+}
+
+// ZoneThresholds are the size and replication thresholds that a replica
+// derives from its zone config.
+type ZoneThresholds struct {
+	MaxBytes, MinBytes int64
+	NumReplicas        int32
+}
+
+// ReplicaZoneThresholds returns the zone config derived thresholds currently
+// in effect for each of the store's replicas. This allows verifying that a
+// zone config change has been applied to the replicas.
+func (s *Store) ReplicaZoneThresholds() map[roachpb.RangeID]ZoneThresholds {
+	thresholds := make(map[roachpb.RangeID]ZoneThresholds)
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		r.mu.RLock()
+		zone := r.mu.zone
+		r.mu.RUnlock()
+		thresholds[r.RangeID] = ZoneThresholds{
+			MaxBytes:    *zone.RangeMaxBytes,
+			MinBytes:    *zone.RangeMinBytes,
+			NumReplicas: *zone.NumReplicas,
+		}
+		return true // more
+	})
+	return thresholds
+}
+
+// IntentAgeSummary returns the number of intents held by the store's
+// replicas, along with the age of the oldest intent and the 99th percentile
+// intent age. A store accumulating many old intents points at abandoned
+// transactions or a stuck intent resolver. The summary is approximate:
+// replicas whose MVCC stats report no intents are not scanned.
+func (s *Store) IntentAgeSummary() (count int64, oldest time.Duration, p99 time.Duration) {
+	ctx := s.AnnotateCtx(context.TODO())
+	now := s.Clock().PhysicalNow()
+	var ages []time.Duration
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		if !r.IsInitialized() || r.GetMVCCStats().IntentCount == 0 {
+			return true // more
+		}
+		for _, kr := range rditer.MakeReplicatedKeyRanges(r.Desc()) {
+			if err := s.engine.Iterate(kr.Start, kr.End, func(kv engine.MVCCKeyValue) (bool, error) {
+				if kv.Key.IsValue() {
+					return false, nil
+				}
+				var meta enginepb.MVCCMetadata
+				if err := protoutil.Unmarshal(kv.Value, &meta); err != nil {
+					return false, err
+				}
+				if meta.Txn != nil {
+					ages = append(ages, time.Duration(now-meta.Timestamp.WallTime))
+				}
+				return false, nil
+			}); err != nil {
+				log.Warningf(ctx, "%s: unable to scan for intents: %s", r, err)
+			}
+		}
+		return true // more
+	})
+	if len(ages) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+	p99Idx := (len(ages)*99+99)/100 - 1
+	return int64(len(ages)), ages[len(ages)-1], ages[p99Idx]
+}
+
+// RecentReplicaGCDecisions returns up to n of the replica GC queue's most
+// recent decisions, newest first. This helps explain after the fact why a
+// replica was (or was not) removed from this store.
+func (s *Store) RecentReplicaGCDecisions(n int) []ReplicaGCDecision {
+	return s.replicaGCQueue.recentDecisions(n)
+}
+
 // Registry returns the store registry.
 func (s *Store) Registry() *metric.Registry {
 	return s.metrics.registry
@@ -3018,7 +3854,8 @@ func (s *Store) Send(
 			// enqueue into the txnWaitQueue in order to await further updates to
 			// the unpushed txn's status. We check ShouldPushImmediately to avoid
 			// retrying non-queueable PushTxnRequests (see #18191).
-			dontRetry := s.cfg.TestingKnobs.DontRetryPushTxnFailures
+			dontRetry := s.cfg.TestingKnobs.DontRetryPushTxnFailures ||
+				!retryPushTxnFailures.Get(&s.cfg.Settings.SV)
 			if !dontRetry && ba.IsSinglePushTxnRequest() {
 				pushReq := ba.Requests[0].GetInner().(*roachpb.PushTxnRequest)
 				dontRetry = txnwait.ShouldPushImmediately(pushReq)
@@ -3028,7 +3865,7 @@ func (s *Store) Send(
 				// after the first failure to guarantee a retry.
 				if ba.Txn != nil {
 					err := roachpb.NewTransactionRetryError(
-						roachpb.RETRY_REASON_UNKNOWN, "DontRetryPushTxnFailures testing knob")
+						roachpb.RETRY_REASON_UNKNOWN, "push txn failure retries disabled")
 					return nil, roachpb.NewErrorWithTxn(err, ba.Txn)
 				}
 				return nil, pErr
@@ -3472,6 +4309,12 @@ func (s *Store) processRaftSnapshotRequest(
 			}()
 		}
 
+		if fn := s.cfg.TestingKnobs.BeforeSnapshotApply; fn != nil {
+			if err := fn(&inSnap); err != nil {
+				return roachpb.NewError(err)
+			}
+		}
+
 		if err := r.stepRaftGroup(&snapHeader.RaftMessageRequest); err != nil {
 			return roachpb.NewError(err)
 		}
@@ -3709,6 +4552,26 @@ func (s *Store) processTick(ctx context.Context, rangeID roachpb.RangeID) bool {
 	return exists // ready
 }
 
+// QuiesceIdleRanges attempts to immediately quiesce every range on the store
+// which would otherwise quiesce on its next tick, i.e. ranges led by this
+// store with no pending proposals and with all live followers caught up. It
+// returns the number of ranges quiesced by this call. Quiescing idle ranges
+// proactively reduces the raft tick overhead of a store, for example ahead of
+// a maintenance window.
+func (s *Store) QuiesceIdleRanges(ctx context.Context) (quiesced int, err error) {
+	livenessMap, _ := s.livenessMap.Load().(IsLiveMap)
+	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		if r.maybeQuiesce(r.AnnotateCtx(ctx), livenessMap) {
+			quiesced++
+		}
+		return true // more
+	})
+	return quiesced, err
+}
+
 // nodeIsLiveCallback is invoked when a node transitions from non-live
 // to live. Iterate through all replicas and find any which belong to
 // ranges containing the implicated node. Unquiesce if currently
@@ -4327,6 +5190,117 @@ func (s *Store) ManuallyEnqueue(
 	return collect(), "", nil
 }
 
+// EnforceLeasePreferences checks whether the leaseholder of the given range
+// violates the lease preferences of the range's zone config and, if so,
+// transfers the lease to a preferred replica. This allows a change to the
+// lease preferences to take effect immediately instead of waiting for the
+// replicate queue to get around to the range. The lease is acquired first if
+// no replica holds it; an error is returned if a different store holds it.
+func (s *Store) EnforceLeasePreferences(ctx context.Context, rangeID roachpb.RangeID) error {
+	repl, err := s.GetReplica(rangeID)
+	if err != nil {
+		return err
+	}
+	ctx = repl.AnnotateCtx(ctx)
+	if _, pErr := repl.redirectOnOrAcquireLease(ctx); pErr != nil {
+		return pErr.GoError()
+	}
+
+	desc, zone := repl.DescAndZone()
+	preferred := s.allocator.preferredLeaseholders(zone, desc.Replicas().Voters())
+	if len(preferred) == 0 || storeHasReplica(s.StoreID(), preferred) {
+		return nil
+	}
+	if s.replicateQueue == nil {
+		return errors.Errorf("%s: cannot enforce lease preferences without a replicate queue", repl)
+	}
+	transferred, err := s.replicateQueue.findTargetAndTransferLease(
+		ctx, repl, desc, zone, transferLeaseOptions{checkTransferLeaseSource: true},
+	)
+	if err != nil {
+		return err
+	}
+	if !transferred {
+		return errors.Errorf("%s: no preferred replica is eligible to receive the lease", repl)
+	}
+	return nil
+}
+
+// AbortPendingDescriptorChange aborts the split, merge or replication change
+// transaction holding an intent on the given range's descriptor (see
+// Replica.PendingDescriptorChange) and resolves that intent, which rolls the
+// descriptor back to its last committed value. It refuses to do so unless the
+// transaction has stopped heartbeating, since aborting a live transaction
+// would needlessly fail the operation it belongs to. This is a last resort for
+// unwedging ranges whose descriptor is stuck behind an abandoned transaction;
+// any other intents of the transaction are cleaned up lazily as usual.
+func (s *Store) AbortPendingDescriptorChange(ctx context.Context, rangeID roachpb.RangeID) error {
+	repl, err := s.GetReplica(rangeID)
+	if err != nil {
+		return err
+	}
+	ctx = repl.AnnotateCtx(ctx)
+	kind, intent, err := repl.pendingDescriptorChange(ctx)
+	if err != nil {
+		return err
+	}
+	if intent == nil {
+		return errors.Errorf("%s: no pending descriptor change", repl)
+	}
+
+	// Verify that the transaction is abandoned before touching it.
+	now := s.Clock().Now()
+	b := &client.Batch{}
+	b.Header.Timestamp = now
+	b.AddRawRequest(&roachpb.QueryTxnRequest{
+		RequestHeader: roachpb.RequestHeader{Key: intent.Txn.Key},
+		Txn:           intent.Txn,
+	})
+	if err := s.db.Run(ctx, b); err != nil {
+		return errors.Wrapf(err, "%s: unable to query %s txn %s", repl, kind, intent.Txn.ID.Short())
+	}
+	txn := b.RawResponse().Responses[0].GetQueryTxn().QueriedTxn
+	if txn.Status == roachpb.COMMITTED {
+		return errors.Errorf("%s: %s txn %s has already committed", repl, kind, txn.ID.Short())
+	}
+	if txn.Status != roachpb.ABORTED && !txnwait.IsExpired(now, &txn) {
+		return errors.Errorf("%s: %s txn %s may still be live (last active %s); refusing to abort it",
+			repl, kind, txn.ID.Short(), txn.LastActive())
+	}
+
+	// Abort the transaction. The push is sent with the lowest possible
+	// priority so that it only succeeds because the pushee is expired.
+	b = &client.Batch{}
+	b.Header.Timestamp = now
+	b.AddRawRequest(&roachpb.PushTxnRequest{
+		RequestHeader: roachpb.RequestHeader{Key: intent.Txn.Key},
+		PusherTxn: roachpb.Transaction{
+			TxnMeta: enginepb.TxnMeta{Priority: enginepb.MinTxnPriority},
+		},
+		PusheeTxn:       intent.Txn,
+		PushTo:          now,
+		InclusivePushTo: true,
+		PushType:        roachpb.PUSH_ABORT,
+	})
+	if err := s.db.Run(ctx, b); err != nil {
+		return errors.Wrapf(err, "%s: unable to abort %s txn %s", repl, kind, intent.Txn.ID.Short())
+	}
+	pushee := b.RawResponse().Responses[0].GetPushTxn().PusheeTxn
+	if pushee.Status != roachpb.ABORTED {
+		return errors.Errorf("%s: %s txn %s is %s after push; refusing to resolve its intent",
+			repl, kind, pushee.ID.Short(), pushee.Status)
+	}
+
+	// Resolving the now aborted intent restores the committed descriptor.
+	if err := s.intentResolver.ResolveIntents(ctx, roachpb.AsIntents(
+		[]roachpb.Span{intent.Span}, &pushee,
+	), intentresolver.ResolveOptions{Wait: true, Poison: true}); err != nil {
+		return err
+	}
+	log.Infof(ctx, "aborted abandoned %s txn %s", kind, pushee.ID.Short())
+	return nil
+}
+
 // GetClusterVersion reads the the cluster version from the store-local version
 // key. Returns an empty version if the key is not found.
 func (s *Store) GetClusterVersion(ctx context.Context) (cluster.ClusterVersion, error) {
@@ -73,6 +73,12 @@ func InitEngine(
 // WriteInitialClusterData writes bootstrapping data to an engine. It creates
 // system ranges (filling in meta1 and meta2) and the default zone config.
 //
+// When called without initialValues and splits, it performs a minimal
+// bootstrap: a single range spanning the entire keyspace is created along
+// with its addressing records and the ID generators, but none of the SQL
+// system tables (i.e. the system config span) are written. This is useful for
+// low-level storage tests that want to start from the barest possible state.
+//
 // Args:
 // eng: the engine to which data is to be written.
 // initialValues: an optional list of k/v to be written as well after each
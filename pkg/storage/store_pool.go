@@ -110,6 +110,9 @@ type storeDetail struct {
 	// throttledBecause is set to the most recent reason for which a store was
 	// marked as throttled.
 	throttledBecause string
+	// throttledReason is the kind of snapshot outcome which most recently
+	// caused the store to be throttled.
+	throttledReason throttleReason
 	// lastUpdatedTime is set when a store is first consulted and every time
 	// gossip arrives for a store.
 	lastUpdatedTime time.Time
@@ -653,6 +656,17 @@ const (
 	throttleFailed
 )
 
+func (r throttleReason) String() string {
+	switch r {
+	case throttleDeclined:
+		return "declined"
+	case throttleFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("throttleReason(%d)", int(r))
+	}
+}
+
 // throttle informs the store pool that the given remote store declined a
 // snapshot or failed to apply one, ensuring that it will not be considered
 // for up-replication or rebalancing until after the configured timeout period
@@ -663,6 +677,7 @@ func (sp *StorePool) throttle(reason throttleReason, why string, storeID roachpb
 	defer sp.detailsMu.Unlock()
 	detail := sp.getStoreDetailLocked(storeID)
 	detail.throttledBecause = why
+	detail.throttledReason = reason
 
 	// If a snapshot is declined, be it due to an error or because it was
 	// rejected, we mark the store detail as having been declined so it won't
@@ -688,6 +703,44 @@ func (sp *StorePool) throttle(reason throttleReason, why string, storeID roachpb
 	}
 }
 
+// ThrottledStore describes a store which the store pool is currently
+// refusing to consider as a target for new replicas.
+type ThrottledStore struct {
+	StoreID roachpb.StoreID
+	// Reason is the kind of snapshot outcome that throttled the store, either
+	// "declined" or "failed".
+	Reason string
+	// Why is a description of the most recent throttling cause.
+	Why string
+	// Until is when the store will be considered available again.
+	Until time.Time
+}
+
+// throttledStores returns the stores which are currently throttled, sorted
+// by store ID.
+func (sp *StorePool) throttledStores() []ThrottledStore {
+	now := sp.clock.PhysicalTime()
+
+	sp.detailsMu.RLock()
+	defer sp.detailsMu.RUnlock()
+	var throttled []ThrottledStore
+	for storeID, detail := range sp.detailsMu.storeDetails {
+		if !detail.isThrottled(now) {
+			continue
+		}
+		throttled = append(throttled, ThrottledStore{
+			StoreID: storeID,
+			Reason:  detail.throttledReason.String(),
+			Why:     detail.throttledBecause,
+			Until:   detail.throttledUntil,
+		})
+	}
+	sort.Slice(throttled, func(i, j int) bool {
+		return throttled[i].StoreID < throttled[j].StoreID
+	})
+	return throttled
+}
+
 // getLocalities returns the localities for the provided replicas.
 // TODO(bram): consider storing a full list of all node to node diversity
 // scores for faster lookups.
@@ -78,6 +78,10 @@ type snapshotStrategy interface {
 	// Status provides a status report on the work performed during the
 	// snapshot. Only valid if the strategy succeeded.
 	Status() string
+
+	// BytesSent returns the number of KV batch and log entry bytes streamed by
+	// Send. Only valid if the strategy succeeded.
+	BytesSent() int64
 }
 
 func assertStrategy(
@@ -96,6 +100,7 @@ type kvBatchSnapshotStrategy struct {
 
 	// Fields used when sending snapshots.
 	batchSize int64
+	bytesSent int64
 	limiter   *rate.Limiter
 	newBatch  func() engine.Batch
 }
@@ -326,6 +331,9 @@ func (kvSS *kvBatchSnapshotStrategy) Send(
 		}
 	}
 	kvSS.status = fmt.Sprintf("kv pairs: %d, log entries: %d", n, len(logEntries))
+	for _, ent := range logEntries {
+		kvSS.bytesSent += int64(len(ent))
+	}
 	return stream.Send(&SnapshotRequest{LogEntries: logEntries})
 }
 
@@ -334,12 +342,16 @@ func (kvSS *kvBatchSnapshotStrategy) sendBatch(
 ) error {
 	repr := batch.Repr()
 	batch.Close()
+	kvSS.bytesSent += int64(len(repr))
 	return stream.Send(&SnapshotRequest{KVBatch: repr})
 }
 
 // Status implements the snapshotStrategy interface.
 func (kvSS *kvBatchSnapshotStrategy) Status() string { return kvSS.status }
 
+// BytesSent implements the snapshotStrategy interface.
+func (kvSS *kvBatchSnapshotStrategy) BytesSent() int64 { return kvSS.bytesSent }
+
 // reserveSnapshot throttles incoming snapshots. The returned closure is used
 // to cleanup the reservation and release its resources. A nil cleanup function
 // and a non-empty rejectionMessage indicates the reservation was declined.
@@ -354,7 +366,9 @@ func (s *Store) reserveSnapshot(
 		// getting stuck behind large snapshots managed by the replicate queue.
 	} else if header.CanDecline {
 		storeDesc, ok := s.cfg.StorePool.getStoreDescriptor(s.StoreID())
-		if ok && (!maxCapacityCheck(storeDesc) || header.RangeSize > storeDesc.Capacity.Available) {
+		maxFractionUsed := 1 - snapshotMinAvailableFraction.Get(&s.ClusterSettings().SV)
+		if ok && (storeDesc.Capacity.FractionUsed() >= maxFractionUsed ||
+			header.RangeSize > storeDesc.Capacity.Available) {
 			return nil, snapshotStoreTooFullMsg, nil
 		}
 		select {
@@ -593,6 +607,13 @@ func (s *Store) receiveSnapshot(
 			return sendSnapshotError(stream, err)
 		}
 	}
+	if fn := s.cfg.TestingKnobs.IncomingSnapshotID; fn != nil {
+		if id := fn(header); id != (uuid.UUID{}) {
+			// The snapshot ID is carried as the data of the Raft snapshot, which
+			// must match the IncomingSnapshot's ID when it is applied.
+			header.RaftMessageRequest.Message.Snapshot.Data = id.GetBytes()
+		}
+	}
 
 	// Defensive check that any non-preemptive snapshot contains this store in the
 	// descriptor.
@@ -617,6 +638,16 @@ func (s *Store) receiveSnapshot(
 	}
 	defer cleanup()
 
+	if delay := s.cfg.TestingKnobs.SnapshotReservationHoldDelay; delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stopper.ShouldStop():
+			return errors.Errorf("stopped")
+		}
+	}
+
 	// Check to see if the snapshot can be applied but don't attempt to add
 	// a placeholder here, because we're not holding the replica's raftMu.
 	// We'll perform this check again later after receiving the rest of the
@@ -688,6 +719,22 @@ type SnapshotStorePool interface {
 	throttle(reason throttleReason, why string, toStoreID roachpb.StoreID)
 }
 
+// snapshotMinAvailableFraction is the fraction of a store's capacity which must
+// remain available for the store to accept a snapshot it is allowed to decline.
+// Snapshots which can't be declined are accepted regardless, and a snapshot
+// larger than the available space is always declined.
+var snapshotMinAvailableFraction = settings.RegisterValidatedFloatSetting(
+	"kv.snapshot_receiver.min_available_fraction",
+	"minimum fraction of a store's capacity that must be available for it to accept a snapshot it is allowed to decline",
+	1-maxFractionUsedThreshold,
+	func(v float64) error {
+		if v < 0 || v > 1 {
+			return errors.Errorf("cannot set to a value outside of [0, 1]: %f", v)
+		}
+		return nil
+	},
+)
+
 // rebalanceSnapshotRate is the rate at which preemptive snapshots can be sent.
 // This includes snapshots generated for upreplication or for rebalancing.
 var rebalanceSnapshotRate = settings.RegisterByteSizeSetting(
@@ -742,7 +789,7 @@ func sendSnapshot(
 	header SnapshotRequest_Header,
 	snap *OutgoingSnapshot,
 	newBatch func() engine.Batch,
-	sent func(),
+	sent func(bytesSent int64),
 ) error {
 	start := timeutil.Now()
 	to := header.RaftMessageRequest.ToReplica
@@ -822,7 +869,7 @@ func sendSnapshot(
 	// Notify the sent callback before the final snapshot request is sent so that
 	// the snapshots generated metric gets incremented before the snapshot is
 	// applied.
-	sent()
+	sent(ss.BytesSent())
 	if err := stream.Send(&SnapshotRequest{Final: true}); err != nil {
 		return err
 	}
@@ -225,6 +225,12 @@ func (s *Store) processPreemptiveSnapshotRequest(
 			}()
 		}
 
+		if fn := s.cfg.TestingKnobs.BeforeSnapshotApply; fn != nil {
+			if err := fn(&inSnap); err != nil {
+				return roachpb.NewError(err)
+			}
+		}
+
 		// Requiring that the Term is set in a message makes sure that we
 		// get all of Raft's internal safety checks (it confuses messages
 		// at term zero for internal messages). The sending side uses the
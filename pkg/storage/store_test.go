@@ -18,6 +18,7 @@ import (
 	"math/rand"
 	"reflect"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -48,6 +49,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/gogo/protobuf/proto"
 	"github.com/kr/pretty"
@@ -180,6 +183,10 @@ type testStoreOpts struct {
 	// set, the store will have all the system ranges that are generally created
 	// for a cluster at boostrap.
 	createSystemRanges bool
+	// If minimalBootstrap is set, the store is bootstrapped without writing the
+	// SQL system tables, leaving the system config span empty. It is
+	// incompatible with createSystemRanges.
+	minimalBootstrap bool
 }
 
 // createTestStoreWithoutStart creates a test store using an in-memory
@@ -222,8 +229,14 @@ func createTestStoreWithoutStart(
 	); err != nil {
 		t.Fatal(err)
 	}
+	if opts.createSystemRanges && opts.minimalBootstrap {
+		t.Fatal("createSystemRanges and minimalBootstrap are mutually exclusive")
+	}
 	var splits []roachpb.RKey
 	kvs, tableSplits := sqlbase.MakeMetadataSchema(cfg.DefaultZoneConfig, cfg.DefaultSystemZoneConfig).GetInitialValues()
+	if opts.minimalBootstrap {
+		kvs = nil
+	}
 	if opts.createSystemRanges {
 		splits = config.StaticSplits()
 		splits = append(splits, tableSplits...)
@@ -375,28 +388,58 @@ func TestIterateIDPrefixKeys(t *testing.T) {
 		return true, nil
 	}
 
-	if err := IterateIDPrefixKeys(ctx, eng, keys.RaftTombstoneKey, &tombstone, handleTombstone); err != nil {
-		t.Fatal(err)
-	}
-	placeholder := seenT{
-		rangeID: roachpb.RangeID(9999),
+	check := func(wanted []seenT) {
+		t.Helper()
+		placeholder := seenT{
+			rangeID: roachpb.RangeID(9999),
+		}
+
+		if len(wanted) != len(seen) {
+			t.Errorf("wanted %d results, got %d", len(wanted), len(seen))
+		}
+
+		wanted = append([]seenT(nil), wanted...)
+		for len(wanted) < len(seen) {
+			wanted = append(wanted, placeholder)
+		}
+		for len(seen) < len(wanted) {
+			seen = append(seen, placeholder)
+		}
+
+		if diff := pretty.Diff(wanted, seen); len(diff) > 0 {
+			pretty.Ldiff(t, wanted, seen)
+			t.Fatal("diff(wanted, seen) is nonempty")
+		}
 	}
 
-	if len(wanted) != len(seen) {
-		t.Errorf("wanted %d results, got %d", len(wanted), len(seen))
+	if err := IterateIDPrefixKeys(ctx, eng, keys.RaftTombstoneKey, &tombstone, handleTombstone); err != nil {
+		t.Fatal(err)
 	}
+	check(wanted)
 
-	for len(wanted) < len(seen) {
-		wanted = append(wanted, placeholder)
+	// Iterate in reverse, which should visit the same keys in reverse order.
+	reversed := make([]seenT, len(wanted))
+	for i := range wanted {
+		reversed[len(wanted)-1-i] = wanted[i]
 	}
-	for len(seen) < len(wanted) {
-		seen = append(seen, placeholder)
+	seen = nil
+	if err := IterateIDPrefixKeysReverse(ctx, eng, keys.RaftTombstoneKey, &tombstone, handleTombstone); err != nil {
+		t.Fatal(err)
 	}
+	check(reversed)
 
-	if diff := pretty.Diff(wanted, seen); len(diff) > 0 {
-		pretty.Ldiff(t, wanted, seen)
-		t.Fatal("diff(wanted, seen) is nonempty")
+	// Stopping early returns only the highest RangeIDs.
+	const limit = 3
+	seen = nil
+	if err := IterateIDPrefixKeysReverse(ctx, eng, keys.RaftTombstoneKey, &tombstone,
+		func(rangeID roachpb.RangeID) (more bool, _ error) {
+			seen = append(seen, seenT{rangeID: rangeID, tombstone: tombstone})
+			return len(seen) < limit, nil
+		},
+	); err != nil {
+		t.Fatal(err)
 	}
+	check(reversed[:limit])
 }
 
 // TestStoreInitAndBootstrap verifies store initialization and bootstrap.
@@ -473,6 +516,36 @@ func TestStoreInitAndBootstrap(t *testing.T) {
 	}
 }
 
+// TestStoreMinimalBootstrap verifies that a store bootstrapped without the SQL
+// system tables starts with a single range and an empty system config span.
+func TestStoreMinimalBootstrap(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	ctx := context.TODO()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t, testStoreOpts{minimalBootstrap: true}, stopper)
+
+	if count := store.ReplicaCount(); count != 1 {
+		t.Fatalf("expected a single range, found %d", count)
+	}
+	repl := store.LookupReplica(roachpb.RKeyMin)
+	if repl == nil {
+		t.Fatal("expected a replica containing KeyMin")
+	}
+	if desc := repl.Desc(); !desc.StartKey.Equal(roachpb.RKeyMin) || !desc.EndKey.Equal(roachpb.RKeyMax) {
+		t.Fatalf("expected the first range to span the entire keyspace, found %s", desc)
+	}
+
+	kvs, _, _, err := engine.MVCCScan(ctx, store.Engine(), keys.SystemConfigSpan.Key,
+		keys.SystemConfigSpan.EndKey, math.MaxInt64, store.Clock().Now(), engine.MVCCScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 0 {
+		t.Fatalf("expected an empty system config span, found %d keys", len(kvs))
+	}
+}
+
 // TestBootstrapOfNonEmptyStore verifies bootstrap failure if engine
 // is not empty.
 func TestBootstrapOfNonEmptyStore(t *testing.T) {
@@ -602,6 +675,208 @@ func TestStoreAddRemoveRanges(t *testing.T) {
 	}
 }
 
+// TestStoreRemoveReplicas verifies that RemoveReplicas removes all replicas
+// it can and reports an error for those it can't, including replicas which
+// are passed in more than once.
+func TestStoreRemoveReplicas(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t,
+		testStoreOpts{
+			createSystemRanges: false,
+		},
+		stopper)
+
+	var repls []*Replica
+	for i, span := range [][2]string{{"a", "b"}, {"c", "d"}, {"e", "f"}} {
+		repl := createReplica(store, roachpb.RangeID(i+2), roachpb.RKey(span[0]), roachpb.RKey(span[1]))
+		if err := store.AddReplica(repl); err != nil {
+			t.Fatal(err)
+		}
+		repls = append(repls, repl)
+	}
+
+	// Pass the replicas out of key order, with a stale NextReplicaID for the
+	// second one and the last one a duplicate of the first one.
+	removals := []ReplicaRemoval{
+		{Replica: repls[2], NextReplicaID: repls[2].Desc().NextReplicaID},
+		{Replica: repls[1], NextReplicaID: 1},
+		{Replica: repls[0], NextReplicaID: repls[0].Desc().NextReplicaID},
+		{Replica: repls[2], NextReplicaID: repls[2].Desc().NextReplicaID},
+	}
+	for i := range removals {
+		removals[i].Options.DestroyData = true
+	}
+	errs := store.RemoveReplicas(ctx, removals)
+	if len(errs) != len(removals) {
+		t.Fatalf("expected %d errors, got %v", len(removals), errs)
+	}
+	for i, err := range errs {
+		if i == 1 {
+			if !testutils.IsError(err, "replica descriptor's ID has changed") {
+				t.Errorf("%d: unexpected error %v", i, err)
+			}
+		} else if i == 3 {
+			if !testutils.IsError(err, "already been removed") {
+				t.Errorf("%d: unexpected error %v", i, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d: unexpected error %v", i, err)
+		}
+	}
+	for _, repl := range []*Replica{repls[0], repls[2]} {
+		if _, err := store.GetReplica(repl.RangeID); err == nil {
+			t.Errorf("expected %s to be removed", repl)
+		}
+	}
+	if _, err := store.GetReplica(repls[1].RangeID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Retrying the failed removal with a current NextReplicaID succeeds.
+	if errs := store.RemoveReplicas(ctx, []ReplicaRemoval{{
+		Replica:       repls[1],
+		NextReplicaID: repls[1].Desc().NextReplicaID,
+		Options:       RemoveOptions{DestroyData: true},
+	}}); errs != nil {
+		t.Fatal(errs)
+	}
+	if _, err := store.GetReplica(repls[1].RangeID); err == nil {
+		t.Errorf("expected %s to be removed", repls[1])
+	}
+}
+
+// TestStoreRemoveReplicasConcurrently verifies that a replica which is removed
+// by RemoveReplicas and RemoveReplica at the same time is only removed once.
+func TestStoreRemoveReplicasConcurrently(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t,
+		testStoreOpts{
+			createSystemRanges: false,
+		},
+		stopper)
+
+	const numReplicas = 10
+	var removals []ReplicaRemoval
+	for i := 0; i < numReplicas; i++ {
+		key := roachpb.RKey(fmt.Sprintf("%02d", i))
+		repl := createReplica(store, roachpb.RangeID(i+2), key, key.PrefixEnd())
+		if err := store.AddReplica(repl); err != nil {
+			t.Fatal(err)
+		}
+		removals = append(removals, ReplicaRemoval{
+			Replica:       repl,
+			NextReplicaID: repl.Desc().NextReplicaID,
+			Options:       RemoveOptions{DestroyData: true},
+		})
+	}
+	replicaCount := store.metrics.ReplicaCount.Value()
+
+	var wg sync.WaitGroup
+	var batchErrs []error
+	singleErrs := make([]error, numReplicas)
+	wg.Add(1 + numReplicas)
+	go func() {
+		defer wg.Done()
+		batchErrs = store.RemoveReplicas(ctx, removals)
+	}()
+	for i := range removals {
+		go func(i int) {
+			defer wg.Done()
+			rm := removals[i]
+			singleErrs[i] = store.RemoveReplica(ctx, rm.Replica, rm.NextReplicaID, rm.Options)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, rm := range removals {
+		var batchErr error
+		if batchErrs != nil {
+			batchErr = batchErrs[i]
+		}
+		if (batchErr == nil) == (singleErrs[i] == nil) {
+			t.Errorf("expected exactly one removal of %s to succeed, got %v and %v",
+				rm.Replica, batchErr, singleErrs[i])
+		}
+		if _, err := store.GetReplica(rm.Replica.RangeID); err == nil {
+			t.Errorf("expected %s to be removed", rm.Replica)
+		}
+	}
+	if count := store.metrics.ReplicaCount.Value(); count != replicaCount-numReplicas {
+		t.Errorf("expected replica count %d, got %d", replicaCount-numReplicas, count)
+	}
+}
+
+// TestStoreLookupReplicas verifies that LookupReplicas maps unsorted keys
+// spanning several replicas to the replicas containing them, and maps
+// uncovered keys to nil.
+func TestStoreLookupReplicas(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store, _ := createTestStore(t, testStoreOpts{}, stopper)
+
+	// Replace range 1 with replicas covering [a,b), [c,d) and [d,f).
+	repl1, err := store.GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveReplica(context.Background(), repl1, repl1.Desc().NextReplicaID, RemoveOptions{
+		DestroyData: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	replAB := createReplica(store, 2, roachpb.RKey("a"), roachpb.RKey("b"))
+	replCD := createReplica(store, 3, roachpb.RKey("c"), roachpb.RKey("d"))
+	replDF := createReplica(store, 4, roachpb.RKey("d"), roachpb.RKey("f"))
+	for _, repl := range []*Replica{replAB, replCD, replDF} {
+		if err := store.AddReplica(repl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		key    roachpb.RKey
+		expRng *Replica
+	}{
+		{roachpb.RKey("e"), replDF},
+		{roachpb.RKey("a"), replAB},
+		{roachpb.RKey("x"), nil},
+		{roachpb.RKey("c\xff\xff"), replCD},
+		{roachpb.RKey("b"), nil},
+		{roachpb.RKey("a\xff\xff"), replAB},
+		{roachpb.RKey("d"), replDF},
+		{roachpb.RKey("0"), nil},
+		{roachpb.RKey("a"), replAB},
+		{roachpb.RKey("f"), nil},
+	}
+	keys := make([]roachpb.RKey, len(testCases))
+	for i, test := range testCases {
+		keys[i] = test.key
+	}
+	repls := store.LookupReplicas(keys)
+	if len(repls) != len(keys) {
+		t.Fatalf("expected %d replicas, got %d", len(keys), len(repls))
+	}
+	for i, test := range testCases {
+		if repls[i] != test.expRng {
+			t.Errorf("%d: expected range %v for key %s; got %v", i, test.expRng, test.key, repls[i])
+		}
+		if r := store.LookupReplica(test.key); r != repls[i] {
+			t.Errorf("%d: LookupReplica returned %v, LookupReplicas returned %v", i, r, repls[i])
+		}
+	}
+
+	if repls := store.LookupReplicas(nil); len(repls) != 0 {
+		t.Errorf("expected no replicas, got %v", repls)
+	}
+}
+
 // TestReplicasByKey tests that operations that depend on the
 // store.replicasByKey map function correctly when the underlying replicas'
 // start and end keys are manipulated in place. This mutation happens when a
@@ -765,54 +1040,79 @@ func TestStoreReplicaVisitor(t *testing.T) {
 		}
 	}
 
-	// Verify two passes of the visit, the second one in-order.
-	visitor := newStoreReplicaVisitor(store)
-	exp := make(map[roachpb.RangeID]struct{})
-	for i := 0; i < newCount; i++ {
-		exp[roachpb.RangeID(i+1)] = struct{}{}
-	}
-
-	for pass := 0; pass < 2; pass++ {
-		if ec := visitor.EstimatedCount(); ec != 10 {
-			t.Fatalf("expected 10 remaining; got %d", ec)
+	t.Run("unfiltered", func(t *testing.T) {
+		// Verify two passes of the visit, the second one in-order.
+		visitor := newStoreReplicaVisitor(store)
+		exp := make(map[roachpb.RangeID]struct{})
+		for i := 0; i < newCount; i++ {
+			exp[roachpb.RangeID(i+1)] = struct{}{}
 		}
-		i := 1
-		seen := make(map[roachpb.RangeID]struct{})
 
-		// Ensure that our next pass is done in-order.
-		if pass == 1 {
-			_ = visitor.InOrder()
-		}
-		var lastRangeID roachpb.RangeID
-		visitor.Visit(func(repl *Replica) bool {
+		for pass := 0; pass < 2; pass++ {
+			if ec := visitor.EstimatedCount(); ec != 10 {
+				t.Fatalf("expected 10 remaining; got %d", ec)
+			}
+			i := 1
+			seen := make(map[roachpb.RangeID]struct{})
+
+			// Ensure that our next pass is done in-order.
 			if pass == 1 {
-				if repl.RangeID <= lastRangeID {
-					t.Fatalf("on second pass, expect ranges to be visited in ascending range ID order; %d !> %d", repl.RangeID, lastRangeID)
+				_ = visitor.InOrder()
+			}
+			var lastRangeID roachpb.RangeID
+			visitor.Visit(func(repl *Replica) bool {
+				if pass == 1 {
+					if repl.RangeID <= lastRangeID {
+						t.Fatalf("on second pass, expect ranges to be visited in ascending range ID order; %d !> %d", repl.RangeID, lastRangeID)
+					}
+					lastRangeID = repl.RangeID
+				}
+				_, ok := seen[repl.RangeID]
+				if ok {
+					t.Fatalf("already saw %d", repl.RangeID)
+				}
+
+				seen[repl.RangeID] = struct{}{}
+				if ec := visitor.EstimatedCount(); ec != 10-i {
+					t.Fatalf(
+						"expected %d remaining; got %d after seeing %+v",
+						10-i, ec, seen,
+					)
 				}
-				lastRangeID = repl.RangeID
+				i++
+				return true
+			})
+			if ec := visitor.EstimatedCount(); ec != 10 {
+				t.Fatalf("expected 10 remaining; got %d", ec)
 			}
-			_, ok := seen[repl.RangeID]
-			if ok {
-				t.Fatalf("already saw %d", repl.RangeID)
+			if !reflect.DeepEqual(exp, seen) {
+				t.Fatalf("got %v, expected %v", seen, exp)
 			}
+		}
+	})
 
-			seen[repl.RangeID] = struct{}{}
-			if ec := visitor.EstimatedCount(); ec != 10-i {
-				t.Fatalf(
-					"expected %d remaining; got %d after seeing %+v",
-					10-i, ec, seen,
-				)
+	// A filtered visitor visits only the matching replicas and estimates their
+	// count accordingly.
+	t.Run("filtered", func(t *testing.T) {
+		visitor := newStoreReplicaVisitor(store).InOrder().Filter(func(repl *Replica) bool {
+			return repl.RangeID%2 == 0
+		})
+		const expCount = newCount / 2
+		if ec := visitor.EstimatedCount(); ec != expCount {
+			t.Fatalf("expected %d remaining; got %d", expCount, ec)
+		}
+		var seen []roachpb.RangeID
+		visitor.Visit(func(repl *Replica) bool {
+			seen = append(seen, repl.RangeID)
+			if ec, exp := visitor.EstimatedCount(), expCount-len(seen); ec != exp {
+				t.Fatalf("expected %d remaining; got %d", exp, ec)
 			}
-			i++
 			return true
 		})
-		if ec := visitor.EstimatedCount(); ec != 10 {
-			t.Fatalf("expected 10 remaining; got %d", ec)
+		if exp := []roachpb.RangeID{2, 4, 6, 8, 10}; !reflect.DeepEqual(exp, seen) {
+			t.Fatalf("expected to visit %v, got %v", exp, seen)
 		}
-		if !reflect.DeepEqual(exp, seen) {
-			t.Fatalf("got %v, expected %v", seen, exp)
-		}
-	}
+	})
 }
 
 func TestHasOverlappingReplica(t *testing.T) {
@@ -1124,6 +1424,135 @@ func TestStoreObservedTimestamp(t *testing.T) {
 	}
 }
 
+// TestStoreTxnObservedTimestamp verifies that Store.TxnObservedTimestamp
+// returns the timestamp a transaction observed from the store's node.
+func TestStoreTxnObservedTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	key := roachpb.Key("b")
+	manual := hlc.NewManualClock(123)
+	cfg := TestStoreConfig(hlc.NewClock(manual.UnixNano, time.Nanosecond))
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+
+	txn := newTransaction("test", key, 1, store.cfg.Clock)
+	txn.MaxTimestamp = hlc.MaxTimestamp
+	if _, ok := store.TxnObservedTimestamp(txn); ok {
+		t.Fatalf("unexpected observed timestamp before contacting the store: %+v", txn.ObservedTimestamps)
+	}
+
+	pArgs := putArgs(key, []byte("value"))
+	h := roachpb.Header{
+		Txn: txn,
+		Replica: roachpb.ReplicaDescriptor{
+			NodeID:    store.Ident.NodeID,
+			StoreID:   store.StoreID(),
+			ReplicaID: 1,
+		},
+	}
+	assignSeqNumsForReqs(txn, &pArgs)
+	pReply, pErr := client.SendWrappedWith(context.Background(), store.TestSender(), h, &pArgs)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	replyTxn := pReply.Header().Txn
+	if replyTxn == nil || replyTxn.ID == (uuid.UUID{}) {
+		t.Fatal("expected transactional response")
+	}
+	obs, ok := store.TxnObservedTimestamp(replyTxn)
+	if !ok {
+		t.Fatalf("expected observed timestamp for n%d in %+v", store.Ident.NodeID, replyTxn.ObservedTimestamps)
+	}
+	if act, exp := obs.WallTime, manual.UnixNano(); exp != act {
+		t.Fatalf("unexpected observed wall time: %d, wanted %d", act, exp)
+	}
+}
+
+// TestStoreDescriptorConsistencyCheck verifies that a replica whose
+// descriptor has diverged from the one in the meta ranges is reported by
+// Store.DescriptorConsistencyCheck, and that other replicas are not.
+func TestStoreDescriptorConsistencyCheck(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	cfg := TestStoreConfig(nil)
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+
+	if mismatches, err := store.DescriptorConsistencyCheck(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(mismatches) != 0 {
+		t.Fatalf("unexpected mismatches: %+v", mismatches)
+	}
+
+	// Stale the local descriptor of the range containing user keys.
+	repl := store.LookupReplica(roachpb.RKey("a"))
+	metaDesc := repl.Desc()
+	staleDesc := *metaDesc
+	staleDesc.IncrementGeneration()
+	repl.raftMu.Lock()
+	repl.setDesc(ctx, &staleDesc)
+	repl.raftMu.Unlock()
+
+	mismatches, err := store.DescriptorConsistencyCheck(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 {
+		t.Fatalf("expected a single mismatch, got %+v", mismatches)
+	}
+	m := mismatches[0]
+	if m.RangeID != repl.RangeID {
+		t.Fatalf("expected r%d to be flagged, got r%d", repl.RangeID, m.RangeID)
+	}
+	if !m.LocalDesc.Equal(&staleDesc) {
+		t.Fatalf("expected local descriptor %+v, got %+v", &staleDesc, m.LocalDesc)
+	}
+	if m.MetaDesc == nil || !m.MetaDesc.Equal(metaDesc) {
+		t.Fatalf("expected meta descriptor %+v, got %+v", metaDesc, m.MetaDesc)
+	}
+}
+
+// TestStoreDescriptorConsistencyCheckLookupError verifies that
+// Store.DescriptorConsistencyCheck reports a failure to look up descriptors
+// in the meta ranges as an error rather than as mismatches.
+func TestStoreDescriptorConsistencyCheckLookupError(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	cfg := TestStoreConfig(nil)
+	var failLookups int32
+	cfg.TestingKnobs.TestingRequestFilter = func(ba roachpb.BatchRequest) *roachpb.Error {
+		if atomic.LoadInt32(&failLookups) == 0 {
+			return nil
+		}
+		for _, ru := range ba.Requests {
+			if get, ok := ru.GetInner().(*roachpb.GetRequest); ok && bytes.HasPrefix(get.Key, keys.Meta2Prefix) {
+				return roachpb.NewErrorf("injected meta lookup error")
+			}
+		}
+		return nil
+	}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+
+	atomic.StoreInt32(&failLookups, 1)
+	mismatches, err := store.DescriptorConsistencyCheck(ctx)
+	if !testutils.IsError(err, `unable to look up \d+ of \d+ descriptors in the meta ranges: .*injected meta lookup error`) {
+		t.Fatalf("expected lookup error, got %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("expected no mismatches for replicas whose lookup failed, got %+v", mismatches)
+	}
+
+	atomic.StoreInt32(&failLookups, 0)
+	if mismatches, err := store.DescriptorConsistencyCheck(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(mismatches) != 0 {
+		t.Fatalf("unexpected mismatches: %+v", mismatches)
+	}
+}
+
 // TestStoreAnnotateNow verifies that the Store sets Now on the batch responses.
 func TestStoreAnnotateNow(t *testing.T) {
 	defer leaktest.AfterTest(t)()
@@ -1393,6 +1822,80 @@ func TestStoreSendOutOfRange(t *testing.T) {
 	}
 }
 
+// TestStoreClosedTimestampLaggards verifies that ranges whose closed timestamp
+// trails the current time by more than the threshold are reported, while
+// ranges with a recent closed timestamp are not.
+func TestStoreClosedTimestampLaggards(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store, manual := createTestStore(t, testStoreOpts{}, stopper)
+
+	repl1 := store.LookupReplica(roachpb.RKeyMin)
+	repl2 := splitTestRange(store, roachpb.RKeyMin, roachpb.RKey("b"), t)
+
+	manual.Increment((10 * time.Second).Nanoseconds())
+
+	// Give the right-hand range a closed timestamp of the current time.
+	repl2.mu.Lock()
+	repl2.mu.initialMaxClosed = store.Clock().Now()
+	repl2.mu.Unlock()
+
+	if laggards := store.ClosedTimestampLaggards(time.Hour); len(laggards) != 0 {
+		t.Fatalf("expected no laggards, found %v", laggards)
+	}
+	exp := []roachpb.RangeID{repl1.RangeID}
+	if laggards := store.ClosedTimestampLaggards(5 * time.Second); !reflect.DeepEqual(laggards, exp) {
+		t.Fatalf("expected laggards %v, found %v", exp, laggards)
+	}
+}
+
+// TestStoreIntentAgeSummary verifies that intents laid down at different
+// times are reflected in the store's intent age summary, and that committed
+// values are not counted.
+func TestStoreIntentAgeSummary(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store, manual := createTestStore(t, testStoreOpts{}, stopper)
+
+	if count, _, _ := store.IntentAgeSummary(); count != 0 {
+		t.Fatalf("expected no intents, found %d", count)
+	}
+
+	// Write a committed value, which must not be counted.
+	pArgs := putArgs(roachpb.Key("committed"), []byte("value"))
+	if _, pErr := client.SendWrapped(context.Background(), store.TestSender(), &pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// Lay down intents, advancing the clock by a second between each.
+	const numIntents = 10
+	for i := 0; i < numIntents; i++ {
+		key := roachpb.Key(fmt.Sprintf("key-%d", i))
+		txn := newTransaction("test", key, 1, store.cfg.Clock)
+		pArgs := putArgs(key, []byte("value"))
+		assignSeqNumsForReqs(txn, &pArgs)
+		if _, pErr := client.SendWrappedWith(
+			context.Background(), store.TestSender(), roachpb.Header{Txn: txn}, &pArgs,
+		); pErr != nil {
+			t.Fatal(pErr)
+		}
+		manual.Increment(time.Second.Nanoseconds())
+	}
+
+	count, oldest, p99 := store.IntentAgeSummary()
+	if count != numIntents {
+		t.Errorf("expected %d intents, found %d", numIntents, count)
+	}
+	if exp := numIntents * time.Second; oldest < exp-time.Second || oldest > exp+time.Second {
+		t.Errorf("expected oldest intent age of about %s, found %s", exp, oldest)
+	}
+	if p99 > oldest || p99 < time.Second {
+		t.Errorf("expected p99 intent age between 1s and %s, found %s", oldest, p99)
+	}
+}
+
 // TestStoreRangeIDAllocation verifies that  range IDs are
 // allocated in successive blocks.
 func TestStoreRangeIDAllocation(t *testing.T) {
@@ -1472,25 +1975,106 @@ func TestStoreReplicasByKey(t *testing.T) {
 	}
 }
 
-// TestStoreSetRangesMaxBytes creates a set of ranges via splitting
-// and then sets the config zone to a custom max bytes value to
-// verify the ranges' max bytes are updated appropriately.
-func TestStoreSetRangesMaxBytes(t *testing.T) {
+// TestStoreDumpReplicasByKey verifies that DumpReplicasByKey returns the
+// replicas and placeholders of a store in key order.
+func TestStoreDumpReplicasByKey(t *testing.T) {
 	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
 	stopper := stop.NewStopper()
-	defer stopper.Stop(context.TODO())
-	cfg := TestStoreConfig(nil)
-	cfg.TestingKnobs.DisableMergeQueue = true
-	store := createTestStoreWithConfig(t, stopper,
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t,
 		testStoreOpts{
-			// This test was written before test stores could start with more than one
-			// range and was not adapted.
 			createSystemRanges: false,
 		},
-		&cfg)
+		stopper)
 
-	baseID := uint32(keys.MinUserDescID)
-	testData := []struct {
+	// Clobber the existing range so that the remaining ones, plus the
+	// placeholder, cover a contiguous span of our choosing.
+	repl1, err := store.GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveReplica(ctx, repl1, repl1.Desc().NextReplicaID, RemoveOptions{
+		DestroyData: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, repl := range []*Replica{
+		createReplica(store, 2, roachpb.RKey("a"), roachpb.RKey("b")),
+		createReplica(store, 5, roachpb.RKey("d"), roachpb.RKey("e")),
+		createReplica(store, 3, roachpb.RKey("b"), roachpb.RKey("c")),
+	} {
+		if err := store.AddReplica(repl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.addPlaceholder(&ReplicaPlaceholder{rangeDesc: roachpb.RangeDescriptor{
+		RangeID: 4, StartKey: roachpb.RKey("c"), EndKey: roachpb.RKey("d"),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []ReplicasByKeyEntry{
+		{StartKey: roachpb.RKey("a"), EndKey: roachpb.RKey("b"), RangeID: 2},
+		{StartKey: roachpb.RKey("b"), EndKey: roachpb.RKey("c"), RangeID: 3},
+		{StartKey: roachpb.RKey("c"), EndKey: roachpb.RKey("d"), RangeID: 4, IsPlaceholder: true},
+		{StartKey: roachpb.RKey("d"), EndKey: roachpb.RKey("e"), RangeID: 5},
+	}
+	dump := store.DumpReplicasByKey()
+	if !reflect.DeepEqual(exp, dump) {
+		t.Fatalf("expected %+v, got %+v", exp, dump)
+	}
+	for i := 1; i < len(dump); i++ {
+		if !dump[i-1].EndKey.Equal(dump[i].StartKey) {
+			t.Errorf("gap or overlap between %+v and %+v", dump[i-1], dump[i])
+		}
+	}
+}
+
+// TestStoreNonDefaultSettings verifies that NonDefaultSettings reports
+// overridden cluster settings but not unchanged ones.
+func TestStoreNonDefaultSettings(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store, _ := createTestStore(t, testStoreOpts{createSystemRanges: true}, stopper)
+
+	const overridden = "kv.replica_gc.new_replica_grace_period"
+	const unchanged = "kv.scanner.max_replicas_per_pass"
+	if v, ok := store.NonDefaultSettings()[overridden]; ok {
+		t.Fatalf("expected %s not to be reported before overriding it, got %s", overridden, v)
+	}
+
+	ReplicaGCNewReplicaGracePeriod.Override(&store.ClusterSettings().SV, time.Hour)
+	nonDefault := store.NonDefaultSettings()
+	if v, ok := nonDefault[overridden]; !ok || v != "1h0m0s" {
+		t.Errorf("expected %s to be reported as 1h0m0s, got %q (present: %t)", overridden, v, ok)
+	}
+	if v, ok := nonDefault[unchanged]; ok {
+		t.Errorf("expected %s not to be reported, got %s", unchanged, v)
+	}
+}
+
+// TestStoreSetRangesMaxBytes creates a set of ranges via splitting
+// and then sets the config zone to a custom max bytes value to
+// verify the ranges' max bytes are updated appropriately.
+func TestStoreSetRangesMaxBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	cfg := TestStoreConfig(nil)
+	cfg.TestingKnobs.DisableMergeQueue = true
+	store := createTestStoreWithConfig(t, stopper,
+		testStoreOpts{
+			// This test was written before test stores could start with more than one
+			// range and was not adapted.
+			createSystemRanges: false,
+		},
+		&cfg)
+
+	baseID := uint32(keys.MinUserDescID)
+	testData := []struct {
 		repl        *Replica
 		expMaxBytes int64
 	}{
@@ -1528,6 +2112,53 @@ func TestStoreSetRangesMaxBytes(t *testing.T) {
 	})
 }
 
+// TestStoreReplicaZoneThresholds verifies that a zone config change is
+// reflected in the thresholds reported for the affected replicas.
+func TestStoreReplicaZoneThresholds(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	cfg := TestStoreConfig(nil)
+	cfg.TestingKnobs.DisableMergeQueue = true
+	store := createTestStoreWithConfig(t, stopper,
+		testStoreOpts{
+			createSystemRanges: false,
+		},
+		&cfg)
+
+	baseID := uint32(keys.MinUserDescID)
+	repl := splitTestRange(store, keys.MakeTablePrefix(baseID), keys.MakeTablePrefix(baseID+1), t)
+	defZone := store.cfg.DefaultZoneConfig
+	if th := store.ReplicaZoneThresholds()[repl.RangeID]; th.MaxBytes != *defZone.RangeMaxBytes {
+		t.Fatalf("expected default max bytes %d, got %+v", *defZone.RangeMaxBytes, th)
+	}
+
+	zone := *defZone
+	zone.RangeMinBytes = proto.Int64(1 << 10)
+	zone.RangeMaxBytes = proto.Int64(1 << 20)
+	zone.NumReplicas = proto.Int32(5)
+	config.TestingSetZoneConfig(baseID, zone)
+
+	// Gossip a new system config so that the store picks up the zone config.
+	sysCfg := &config.SystemConfigEntries{}
+	sysCfg.Values = []roachpb.KeyValue{{Key: roachpb.Key("a")}}
+	if err := store.Gossip().AddInfoProto(gossip.KeySystemConfig, sysCfg, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := ZoneThresholds{MaxBytes: 1 << 20, MinBytes: 1 << 10, NumReplicas: 5}
+	testutils.SucceedsSoon(t, func() error {
+		thresholds := store.ReplicaZoneThresholds()
+		if th := thresholds[repl.RangeID]; th != exp {
+			return errors.Errorf("expected thresholds %+v, got %+v", exp, th)
+		}
+		if th := thresholds[store.LookupReplica(roachpb.RKeyMin).RangeID]; th.MaxBytes != *defZone.RangeMaxBytes {
+			return errors.Errorf("expected unaffected range to keep default max bytes, got %+v", th)
+		}
+		return nil
+	})
+}
+
 // TestStoreResolveWriteIntent adds a write intent and then verifies
 // that a put returns success and aborts intent's txn in the event the
 // pushee has lower priority. Otherwise, verifies that the put blocks
@@ -1838,6 +2469,115 @@ func TestStoreResolveWriteIntentPushOnRead(t *testing.T) {
 	}
 }
 
+// TestStoreRetryPushTxnFailuresSetting verifies that when the
+// kv.transaction.retry_push_failures_enabled setting is disabled, a failed
+// push is surfaced to the client immediately instead of waiting on the pushee,
+// and that STAGING pushees are still recovered.
+func TestStoreRetryPushTxnFailuresSetting(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	storeCfg := TestStoreConfig(nil)
+	retryPushTxnFailures.Override(&storeCfg.Settings.SV, false)
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &storeCfg)
+
+	for _, staging := range []bool{false, true} {
+		t.Run(fmt.Sprintf("staging=%t", staging), func(t *testing.T) {
+			ctx := context.Background()
+			key := roachpb.Key(fmt.Sprintf("key-staging=%t", staging))
+			pusher := newTransaction("pusher", key, 1, store.cfg.Clock)
+			pushee := newTransaction("pushee", key, 1, store.cfg.Clock)
+			if staging {
+				// A winning pusher must recover STAGING pushees.
+				pushee.Priority = enginepb.MinTxnPriority
+				pusher.Priority = enginepb.MaxTxnPriority
+			} else {
+				pushee.Priority = enginepb.MaxTxnPriority
+				pusher.Priority = enginepb.MinTxnPriority // Pusher will lose.
+			}
+
+			args := putArgs(key, []byte("value"))
+			assignSeqNumsForReqs(pushee, &args)
+			if _, pErr := client.SendWrappedWith(
+				ctx, store.TestSender(), roachpb.Header{Txn: pushee}, &args,
+			); pErr != nil {
+				t.Fatal(pErr)
+			}
+			if staging {
+				// Stage the pushee's record with an in-flight write that was never
+				// performed, so recovery will find the pushee aborted.
+				et, etH := endTxnArgs(pushee, true)
+				et.InFlightWrites = []roachpb.SequencedWrite{{Key: roachpb.Key("missing"), Sequence: 1}}
+				if _, pErr := client.SendWrappedWith(ctx, store.TestSender(), etH, &et); pErr != nil {
+					t.Fatal(pErr)
+				}
+			}
+
+			readTs := store.cfg.Clock.Now()
+			pusher.UpdateObservedTimestamp(store.Ident.NodeID, readTs)
+			pusher.OrigTimestamp.Forward(readTs)
+			pusher.Timestamp.Forward(readTs)
+			gArgs := getArgs(key)
+			assignSeqNumsForReqs(pusher, &gArgs)
+			_, pErr := client.SendWrappedWith(ctx, store.TestSender(), roachpb.Header{Txn: pusher}, &gArgs)
+			if staging {
+				if pErr != nil {
+					t.Fatalf("expected read to succeed after recovering the pushee: %s", pErr)
+				}
+			} else if !testutils.IsPError(pErr, "failed to push") {
+				t.Fatalf("expected push failure, found %v", pErr)
+			}
+		})
+	}
+}
+
+// TestStoreQuiesceIdleRanges verifies that QuiesceIdleRanges quiesces the
+// idle ranges of a store without waiting for them to tick.
+func TestStoreQuiesceIdleRanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	storeCfg := TestStoreConfig(nil)
+	// Disable ticks, which would otherwise quiesce the ranges on their own.
+	storeCfg.RaftTickInterval = math.MaxInt32
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &storeCfg)
+	ctx := context.Background()
+
+	// Quiescence requires the leaseholder, so make sure each range has one.
+	var repls []*Replica
+	store.VisitReplicas(func(repl *Replica) bool {
+		repls = append(repls, repl)
+		return true
+	})
+	for _, repl := range repls {
+		if _, pErr := repl.redirectOnOrAcquireLease(ctx); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	var total int
+	testutils.SucceedsSoon(t, func() error {
+		n, err := store.QuiesceIdleRanges(ctx)
+		if err != nil {
+			return err
+		}
+		total += n
+		var quiescent int
+		for _, repl := range repls {
+			if repl.IsQuiescent() {
+				quiescent++
+			}
+		}
+		if 2*quiescent <= len(repls) {
+			return errors.Errorf("only %d of %d ranges quiescent", quiescent, len(repls))
+		}
+		return nil
+	})
+	if total == 0 {
+		t.Fatal("expected QuiesceIdleRanges to quiesce ranges")
+	}
+}
+
 // TestStoreResolveWriteIntentNoTxn verifies that reads and writes
 // which are not part of a transaction can push intents.
 func TestStoreResolveWriteIntentNoTxn(t *testing.T) {
@@ -1926,6 +2666,65 @@ func TestStoreResolveWriteIntentNoTxn(t *testing.T) {
 	}
 }
 
+// TestStoreInspectLogicalOpLog verifies that the InspectLogicalOpLog testing
+// knob observes the logical operations of applied commands, and in particular
+// that committing a transaction resolves its intent with an
+// MVCCCommitIntentOp.
+func TestStoreInspectLogicalOpLog(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	key := roachpb.Key("a")
+	cfg := TestStoreConfig(nil)
+	RangefeedEnabled.Override(&cfg.Settings.SV, true)
+	var mu syncutil.Mutex
+	var writeIntentTxns, commitIntentTxns []uuid.UUID
+	cfg.TestingKnobs.InspectLogicalOpLog = func(_ storagebase.CmdIDKey, ops []enginepb.MVCCLogicalOp) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, op := range ops {
+			switch t := op.GetValue().(type) {
+			case *enginepb.MVCCWriteIntentOp:
+				if key.Equal(t.TxnKey) {
+					writeIntentTxns = append(writeIntentTxns, t.TxnID)
+				}
+			case *enginepb.MVCCCommitIntentOp:
+				if key.Equal(t.Key) {
+					commitIntentTxns = append(commitIntentTxns, t.TxnID)
+				}
+			}
+		}
+	}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+
+	// Write the intent and commit in separate batches so that the transaction
+	// does not commit in one phase.
+	txn := client.NewTxn(ctx, store.DB(), 0 /* gatewayNodeID */, client.RootTxn)
+	if err := txn.Put(ctx, key, "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.SucceedsSoon(t, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(writeIntentTxns) != 1 {
+			return errors.Errorf("expected one MVCCWriteIntentOp, got %v", writeIntentTxns)
+		}
+		if len(commitIntentTxns) != 1 {
+			return errors.Errorf("expected one MVCCCommitIntentOp, got %v", commitIntentTxns)
+		}
+		if txnID := txn.ID(); writeIntentTxns[0] != txnID || commitIntentTxns[0] != txnID {
+			return errors.Errorf("expected ops for txn %s, got %v and %v",
+				txnID, writeIntentTxns, commitIntentTxns)
+		}
+		return nil
+	})
+}
+
 func setTxnAutoGC(to bool) func() {
 	orig := batcheval.TxnAutoGC
 	f := func() {
@@ -2221,79 +3020,478 @@ func TestStoreScanResumeTSCache(t *testing.T) {
 	}
 }
 
-// TestStoreScanIntents verifies that a scan across 10 intents resolves
-// them in one fell swoop using both consistent and inconsistent reads.
-func TestStoreScanIntents(t *testing.T) {
+// TestStoreScanByteLimit verifies that scans stop at a key boundary once
+// their results reach the batch's MaxSpanRequestBytes, and that the resume
+// spans pick up where they left off.
+func TestStoreScanByteLimit(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
-	cfg := TestStoreConfig(nil)
-	var count int32
-	countPtr := &count
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t, testStoreOpts{createSystemRanges: true}, stopper)
 
-	cfg.TestingKnobs.EvalKnobs.TestingEvalFilter =
-		func(filterArgs storagebase.FilterArgs) *roachpb.Error {
-			if req, ok := filterArgs.Req.(*roachpb.ScanRequest); ok {
-				// Avoid counting scan requests not generated by this test, e.g. those
-				// generated by periodic gossips.
-				if bytes.HasPrefix(req.Key, []byte(t.Name())) {
-					atomic.AddInt32(countPtr, 1)
-				}
-			}
-			return nil
+	// Write five keys with large values.
+	value := bytes.Repeat([]byte("v"), 1000)
+	for _, keyStr := range []string{"a", "b", "c", "d", "e"} {
+		putArgs := putArgs(roachpb.Key(keyStr), value)
+		if _, pErr := client.SendWrapped(ctx, store.TestSender(), &putArgs); pErr != nil {
+			t.Fatal(pErr)
 		}
-	stopper := stop.NewStopper()
-	defer stopper.Stop(context.TODO())
-	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+	}
 
+	span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("f")}
 	testCases := []struct {
-		consistent bool
-		canPush    bool  // can the txn be pushed?
-		expFinish  bool  // do we expect the scan to finish?
-		expCount   int32 // how many times do we expect to scan?
+		targetBytes   int64
+		reverse       bool
+		expRows       int
+		expResumeSpan *roachpb.Span
 	}{
-		// Consistent which can push will make two loops.
-		{true, true, true, 2},
-		// Consistent but can't push will backoff and retry and not finish.
-		{true, false, false, -1},
-		// Inconsistent and can push will make one loop, with async resolves.
-		{false, true, true, 1},
-		// Inconsistent and can't push will just read inconsistent (will read nils).
-		{false, false, true, 1},
+		// The key/value pair crossing the limit is included.
+		{2500, false, 3, &roachpb.Span{Key: roachpb.Key("d"), EndKey: roachpb.Key("f")}},
+		{2500, true, 3, &roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("b").Next()}},
+		// A limit below the size of a single key/value pair still returns one.
+		{1, false, 1, &roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("f")}},
+		// A sufficiently large limit returns everything.
+		{1 << 20, false, 5, nil},
 	}
-	for i, test := range testCases {
-		// The command filter just counts the number of scan requests which are
-		// submitted to the range.
-		atomic.StoreInt32(countPtr, 0)
-
-		// Lay down 10 intents to scan over.
-		var txn *roachpb.Transaction
-		keys := []roachpb.Key{}
-		for j := 0; j < 10; j++ {
-			key := roachpb.Key(fmt.Sprintf("%s%d-%02d", t.Name(), i, j))
-			keys = append(keys, key)
-			if txn == nil {
-				priority := roachpb.UserPriority(1)
-				if test.canPush {
-					priority = roachpb.MinUserPriority
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("targetBytes=%d,reverse=%t", tc.targetBytes, tc.reverse), func(t *testing.T) {
+			h := roachpb.Header{MaxSpanRequestBytes: tc.targetBytes}
+			var args roachpb.Request
+			if tc.reverse {
+				rsArgs := reverseScanArgs(span.Key, span.EndKey)
+				args = &rsArgs
+			} else {
+				sArgs := scanArgs(span.Key, span.EndKey)
+				args = &sArgs
+			}
+			reply, pErr := client.SendWrappedWith(ctx, store.TestSender(), h, args)
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+			var rows []roachpb.KeyValue
+			if tc.reverse {
+				rows = reply.(*roachpb.ReverseScanResponse).Rows
+			} else {
+				rows = reply.(*roachpb.ScanResponse).Rows
+			}
+			if a, e := len(rows), tc.expRows; a != e {
+				t.Fatalf("expected %d rows; got %d", e, a)
+			}
+			for _, row := range rows {
+				if v, err := row.Value.GetBytes(); err != nil {
+					t.Fatal(err)
+				} else if !bytes.Equal(v, value) {
+					t.Fatalf("unexpected value for key %s", row.Key)
 				}
-				txn = newTransaction(fmt.Sprintf("test-%d", i), key, priority, store.cfg.Clock)
 			}
-			args := putArgs(key, []byte(fmt.Sprintf("value%02d", j)))
-			assignSeqNumsForReqs(txn, &args)
-			if _, pErr := client.SendWrappedWith(context.Background(), store.TestSender(), roachpb.Header{Txn: txn}, &args); pErr != nil {
+			rh := reply.Header()
+			if a, e := rh.ResumeSpan, tc.expResumeSpan; !reflect.DeepEqual(a, e) {
+				t.Fatalf("expected resume span %s; got %s", e, a)
+			}
+			var expBytes int64
+			for _, row := range rows {
+				key := engine.MVCCKey{Key: row.Key, Timestamp: row.Value.Timestamp}
+				expBytes += int64(len(engine.EncodeKey(key)) + len(row.Value.RawBytes))
+			}
+			if rh.NumBytes != expBytes {
+				t.Errorf("expected %d bytes; got %d", expBytes, rh.NumBytes)
+			}
+			// The BATCH_RESPONSE format counts the same bytes.
+			if tc.reverse {
+				args.(*roachpb.ReverseScanRequest).ScanFormat = roachpb.BATCH_RESPONSE
+			} else {
+				args.(*roachpb.ScanRequest).ScanFormat = roachpb.BATCH_RESPONSE
+			}
+			batchReply, pErr := client.SendWrappedWith(ctx, store.TestSender(), h, args)
+			if pErr != nil {
 				t.Fatal(pErr)
 			}
-		}
+			if a := batchReply.Header().NumBytes; a != expBytes {
+				t.Errorf("expected %d bytes in BATCH_RESPONSE format; got %d", expBytes, a)
+			}
+			if tc.expResumeSpan != nil {
+				if rh.ResumeReason != roachpb.RESUME_BYTE_LIMIT {
+					t.Errorf("expected resume reason %s; got %s", roachpb.RESUME_BYTE_LIMIT, rh.ResumeReason)
+				}
+				if rh.NumBytes < tc.targetBytes {
+					t.Errorf("expected at least %d bytes; got %d", tc.targetBytes, rh.NumBytes)
+				}
+			}
+		})
+	}
 
-		// Scan the range and verify count. Do this in a goroutine in case
-		// it isn't expected to finish.
-		sArgs := scanArgs(keys[0], keys[9].Next())
-		var sReply *roachpb.ScanResponse
-		ts := store.Clock().Now()
-		consistency := roachpb.CONSISTENT
-		if !test.consistent {
-			consistency = roachpb.INCONSISTENT
-		}
+	// A scan which exhausts the byte limit prevents later scans in the same
+	// batch from returning results.
+	var ba roachpb.BatchRequest
+	ba.MaxSpanRequestBytes = 1500
+	sArgs1 := scanArgs(roachpb.Key("a"), roachpb.Key("c"))
+	sArgs2 := scanArgs(roachpb.Key("c"), roachpb.Key("f"))
+	ba.Add(&sArgs1, &sArgs2)
+	br, pErr := store.TestSender().Send(ctx, ba)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if a, e := len(br.Responses[0].GetScan().Rows), 2; a != e {
+		t.Errorf("expected %d rows from first scan; got %d", e, a)
+	}
+	if rh := br.Responses[0].GetInner().Header(); rh.ResumeSpan != nil {
+		t.Errorf("expected first scan to complete; got resume span %s", rh.ResumeSpan)
+	}
+	if a, e := len(br.Responses[1].GetScan().Rows), 0; a != e {
+		t.Errorf("expected %d rows from second scan; got %d", e, a)
+	}
+	rh := br.Responses[1].GetInner().Header()
+	expResumeSpan := &roachpb.Span{Key: roachpb.Key("c"), EndKey: roachpb.Key("f")}
+	if a, e := rh.ResumeSpan, expResumeSpan; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected resume span %s; got %s", e, a)
+	}
+	if rh.ResumeReason != roachpb.RESUME_BYTE_LIMIT {
+		t.Errorf("expected resume reason %s; got %s", roachpb.RESUME_BYTE_LIMIT, rh.ResumeReason)
+	}
+}
+
+// TestStoreScanIntentRowLimit verifies that READ_UNCOMMITTED scans stop at
+// the key of the first intent row exceeding the batch's MaxIntentRows, and
+// that they report the truncation with a resume span.
+func TestStoreScanIntentRowLimit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	cfg := TestStoreConfig(nil)
+	// Keep the intents in place across scans.
+	cfg.TestingKnobs.IntentResolverKnobs.DisableAsyncIntentResolution = true
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+
+	// Write committed values at "a" through "j" and intents over every other
+	// one of them, starting at "b".
+	var intentKeys []roachpb.Key
+	for c := 'a'; c <= 'j'; c++ {
+		key := roachpb.Key(string(c))
+		pArgs := putArgs(key, []byte("committed"))
+		if _, pErr := client.SendWrapped(ctx, store.TestSender(), &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+		if (c-'a')%2 == 1 {
+			intentKeys = append(intentKeys, key)
+		}
+	}
+	txn := newTransaction("test", intentKeys[0], 1, store.cfg.Clock)
+	for _, key := range intentKeys {
+		pArgs := putArgs(key, []byte("intent"))
+		assignSeqNumsForReqs(txn, &pArgs)
+		if _, pErr := client.SendWrappedWith(ctx, store.TestSender(), roachpb.Header{Txn: txn}, &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("k")}
+	testCases := []struct {
+		maxIntentRows int64
+		reverse       bool
+		format        roachpb.ScanFormat
+		expRows       int
+		expIntentRows int
+		expResumeSpan *roachpb.Span
+	}{
+		{2, false, roachpb.KEY_VALUES, 5, 2, &roachpb.Span{Key: roachpb.Key("f"), EndKey: roachpb.Key("k")}},
+		{2, false, roachpb.BATCH_RESPONSE, 5, 2, &roachpb.Span{Key: roachpb.Key("f"), EndKey: roachpb.Key("k")}},
+		{2, true, roachpb.KEY_VALUES, 4, 2, &roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("f").Next()}},
+		{2, true, roachpb.BATCH_RESPONSE, 4, 2, &roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("f").Next()}},
+		// A limit that isn't exceeded doesn't truncate the scan.
+		{5, false, roachpb.KEY_VALUES, 10, 5, nil},
+		// Without a limit, all intent rows are returned.
+		{0, false, roachpb.KEY_VALUES, 10, 5, nil},
+	}
+	for _, tc := range testCases {
+		name := fmt.Sprintf("maxIntentRows=%d,reverse=%t,format=%s", tc.maxIntentRows, tc.reverse, tc.format)
+		t.Run(name, func(t *testing.T) {
+			h := roachpb.Header{
+				ReadConsistency: roachpb.READ_UNCOMMITTED,
+				MaxIntentRows:   tc.maxIntentRows,
+			}
+			var args roachpb.Request
+			if tc.reverse {
+				rsArgs := reverseScanArgs(span.Key, span.EndKey)
+				rsArgs.ScanFormat = tc.format
+				args = &rsArgs
+			} else {
+				sArgs := scanArgs(span.Key, span.EndKey)
+				sArgs.ScanFormat = tc.format
+				args = &sArgs
+			}
+			reply, pErr := client.SendWrappedWith(ctx, store.TestSender(), h, args)
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+			var rows, intentRows []roachpb.KeyValue
+			var batchResponses [][]byte
+			if tc.reverse {
+				rsReply := reply.(*roachpb.ReverseScanResponse)
+				rows, intentRows, batchResponses = rsReply.Rows, rsReply.IntentRows, rsReply.BatchResponses
+			} else {
+				sReply := reply.(*roachpb.ScanResponse)
+				rows, intentRows, batchResponses = sReply.Rows, sReply.IntentRows, sReply.BatchResponses
+			}
+			numRows := len(rows)
+			for _, data := range batchResponses {
+				for len(data) > 0 {
+					var err error
+					if _, _, data, err = engine.MVCCScanDecodeKeyValue(data); err != nil {
+						t.Fatal(err)
+					}
+					numRows++
+				}
+			}
+			rh := reply.Header()
+			if a, e := numRows, tc.expRows; a != e {
+				t.Errorf("expected %d rows; got %d", e, a)
+			}
+			if a, e := rh.NumKeys, int64(tc.expRows); a != e {
+				t.Errorf("expected NumKeys %d; got %d", e, a)
+			}
+			if a, e := len(intentRows), tc.expIntentRows; a != e {
+				t.Errorf("expected %d intent rows; got %d", e, a)
+			}
+			if a, e := rh.ResumeSpan, tc.expResumeSpan; !reflect.DeepEqual(a, e) {
+				t.Fatalf("expected resume span %s; got %s", e, a)
+			}
+			if tc.expResumeSpan != nil && rh.ResumeReason != roachpb.RESUME_INTENT_LIMIT {
+				t.Errorf("expected resume reason %s; got %s", roachpb.RESUME_INTENT_LIMIT, rh.ResumeReason)
+			}
+		})
+	}
+
+	// A scan which exhausts the limit prevents later scans in the same batch
+	// from returning results.
+	var ba roachpb.BatchRequest
+	ba.ReadConsistency = roachpb.READ_UNCOMMITTED
+	ba.MaxIntentRows = 2
+	sArgs1 := scanArgs(roachpb.Key("a"), roachpb.Key("f"))
+	sArgs2 := scanArgs(roachpb.Key("f"), roachpb.Key("k"))
+	ba.Add(&sArgs1, &sArgs2)
+	br, pErr := store.TestSender().Send(ctx, ba)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if a, e := len(br.Responses[0].GetScan().IntentRows), 2; a != e {
+		t.Errorf("expected %d intent rows from first scan; got %d", e, a)
+	}
+	if rh := br.Responses[0].GetInner().Header(); rh.ResumeSpan != nil {
+		t.Errorf("expected first scan to complete; got resume span %s", rh.ResumeSpan)
+	}
+	if a, e := len(br.Responses[1].GetScan().Rows), 0; a != e {
+		t.Errorf("expected %d rows from second scan; got %d", e, a)
+	}
+	rh := br.Responses[1].GetInner().Header()
+	expResumeSpan := &roachpb.Span{Key: roachpb.Key("f"), EndKey: roachpb.Key("k")}
+	if a, e := rh.ResumeSpan, expResumeSpan; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected resume span %s; got %s", e, a)
+	}
+	if rh.ResumeReason != roachpb.RESUME_INTENT_LIMIT {
+		t.Errorf("expected resume reason %s; got %s", roachpb.RESUME_INTENT_LIMIT, rh.ResumeReason)
+	}
+}
+
+// TestStoreScanKeysOnly verifies that a KeysOnly scan returns the scanned keys
+// without their values, which makes for a much smaller response.
+func TestStoreScanKeysOnly(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t, testStoreOpts{createSystemRanges: true}, stopper)
+
+	value := bytes.Repeat([]byte("v"), 1000)
+	var expKeys []roachpb.Key
+	for c := 'a'; c <= 'j'; c++ {
+		key := roachpb.Key(string(c))
+		pArgs := putArgs(key, value)
+		if _, pErr := client.SendWrapped(ctx, store.TestSender(), &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+		expKeys = append(expKeys, key)
+	}
+
+	scan := func(keysOnly bool) *roachpb.ScanResponse {
+		t.Helper()
+		sArgs := scanArgs(roachpb.Key("a"), roachpb.Key("k"))
+		sArgs.KeysOnly = keysOnly
+		reply, pErr := client.SendWrapped(ctx, store.TestSender(), &sArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		return reply.(*roachpb.ScanResponse)
+	}
+	full, keysOnly := scan(false), scan(true)
+	if len(keysOnly.Rows) != len(expKeys) {
+		t.Fatalf("expected %d rows, got %d", len(expKeys), len(keysOnly.Rows))
+	}
+	for i, row := range keysOnly.Rows {
+		if !row.Key.Equal(expKeys[i]) {
+			t.Errorf("%d: expected key %s, got %s", i, expKeys[i], row.Key)
+		}
+		if len(row.Value.RawBytes) != 0 {
+			t.Errorf("%d: expected empty value, got %q", i, row.Value.RawBytes)
+		}
+	}
+	if fullSize, keysOnlySize := full.Size(), keysOnly.Size(); keysOnlySize*10 > fullSize {
+		t.Errorf("expected keys-only response of %d bytes to be far smaller than %d bytes",
+			keysOnlySize, fullSize)
+	}
+
+	// The batch response format doesn't support KeysOnly.
+	sArgs := scanArgs(roachpb.Key("a"), roachpb.Key("k"))
+	sArgs.ScanFormat = roachpb.BATCH_RESPONSE
+	sArgs.KeysOnly = true
+	if _, pErr := client.SendWrapped(ctx, store.TestSender(), &sArgs); !testutils.IsPError(pErr, "keys_only is not supported") {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+}
+
+// TestStoreDeleteRangeDryRun verifies that a dry-run DeleteRange reports the
+// number of live keys and bytes in the span without deleting them.
+func TestStoreDeleteRangeDryRun(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t, testStoreOpts{createSystemRanges: true}, stopper)
+
+	for c := 'a'; c <= 'j'; c++ {
+		pArgs := putArgs(roachpb.Key(string(c)), bytes.Repeat([]byte("v"), int(c)))
+		if _, pErr := client.SendWrapped(ctx, store.TestSender(), &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	// Deleted keys aren't counted.
+	dArgs := deleteArgs(roachpb.Key("c"))
+	if _, pErr := client.SendWrapped(ctx, store.TestSender(), &dArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	scan := func() []roachpb.KeyValue {
+		t.Helper()
+		sArgs := scanArgs(roachpb.Key("a"), roachpb.Key("k"))
+		reply, pErr := client.SendWrapped(ctx, store.TestSender(), &sArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		return reply.(*roachpb.ScanResponse).Rows
+	}
+	before := scan()
+	var expBytes int64
+	for _, kv := range before {
+		key := engine.MVCCKey{Key: kv.Key, Timestamp: kv.Value.Timestamp}
+		expBytes += int64(len(engine.EncodeKey(key)) + len(kv.Value.RawBytes))
+	}
+
+	drArgs := &roachpb.DeleteRangeRequest{
+		RequestHeader: roachpb.RequestHeader{Key: roachpb.Key("a"), EndKey: roachpb.Key("k")},
+		ReturnKeys:    true,
+		DryRun:        true,
+	}
+	if !roachpb.IsReadOnly(drArgs) {
+		t.Fatal("expected a dry run to be a read-only request")
+	}
+	repl := store.LookupReplica(roachpb.RKey("a"))
+	appliedBefore := repl.State().LeaseAppliedIndex
+	reply, pErr := client.SendWrapped(ctx, store.TestSender(), drArgs)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	drReply := reply.(*roachpb.DeleteRangeResponse)
+	if drReply.NumKeys != 9 || len(drReply.Keys) != 9 {
+		t.Errorf("expected 9 keys, got %d (%d returned)", drReply.NumKeys, len(drReply.Keys))
+	}
+	if drReply.NumBytes != expBytes {
+		t.Errorf("expected %d bytes, got %d", expBytes, drReply.NumBytes)
+	}
+	if appliedAfter := repl.State().LeaseAppliedIndex; appliedAfter != appliedBefore {
+		t.Errorf("expected dry run not to be proposed; lease applied index went from %d to %d",
+			appliedBefore, appliedAfter)
+	}
+	if after := scan(); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected data to be left intact; before: %v, after: %v", before, after)
+	}
+}
+
+// TestStoreScanIntents verifies that a scan across 10 intents resolves
+// them in one fell swoop using both consistent and inconsistent reads.
+func TestStoreScanIntents(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	cfg := TestStoreConfig(nil)
+	var count int32
+	countPtr := &count
+
+	cfg.TestingKnobs.EvalKnobs.TestingEvalFilter =
+		func(filterArgs storagebase.FilterArgs) *roachpb.Error {
+			if req, ok := filterArgs.Req.(*roachpb.ScanRequest); ok {
+				// Avoid counting scan requests not generated by this test, e.g. those
+				// generated by periodic gossips.
+				if bytes.HasPrefix(req.Key, []byte(t.Name())) {
+					atomic.AddInt32(countPtr, 1)
+				}
+			}
+			return nil
+		}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, testStoreOpts{createSystemRanges: true}, &cfg)
+
+	testCases := []struct {
+		consistent bool
+		canPush    bool  // can the txn be pushed?
+		expFinish  bool  // do we expect the scan to finish?
+		expCount   int32 // how many times do we expect to scan?
+	}{
+		// Consistent which can push will make two loops.
+		{true, true, true, 2},
+		// Consistent but can't push will backoff and retry and not finish.
+		{true, false, false, -1},
+		// Inconsistent and can push will make one loop, with async resolves.
+		{false, true, true, 1},
+		// Inconsistent and can't push will just read inconsistent (will read nils).
+		{false, false, true, 1},
+	}
+	for i, test := range testCases {
+		// The command filter just counts the number of scan requests which are
+		// submitted to the range.
+		atomic.StoreInt32(countPtr, 0)
+
+		// Lay down 10 intents to scan over.
+		var txn *roachpb.Transaction
+		keys := []roachpb.Key{}
+		for j := 0; j < 10; j++ {
+			key := roachpb.Key(fmt.Sprintf("%s%d-%02d", t.Name(), i, j))
+			keys = append(keys, key)
+			if txn == nil {
+				priority := roachpb.UserPriority(1)
+				if test.canPush {
+					priority = roachpb.MinUserPriority
+				}
+				txn = newTransaction(fmt.Sprintf("test-%d", i), key, priority, store.cfg.Clock)
+			}
+			args := putArgs(key, []byte(fmt.Sprintf("value%02d", j)))
+			assignSeqNumsForReqs(txn, &args)
+			if _, pErr := client.SendWrappedWith(context.Background(), store.TestSender(), roachpb.Header{Txn: txn}, &args); pErr != nil {
+				t.Fatal(pErr)
+			}
+		}
+
+		// Scan the range and verify count. Do this in a goroutine in case
+		// it isn't expected to finish.
+		sArgs := scanArgs(keys[0], keys[9].Next())
+		var sReply *roachpb.ScanResponse
+		ts := store.Clock().Now()
+		consistency := roachpb.CONSISTENT
+		if !test.consistent {
+			consistency = roachpb.INCONSISTENT
+		}
 		errChan := make(chan *roachpb.Error, 1)
 		go func() {
 			reply, pErr := client.SendWrappedWith(context.Background(), store.TestSender(), roachpb.Header{
@@ -2686,6 +3884,59 @@ func TestStoreGCThreshold(t *testing.T) {
 	assertThreshold(threshold)
 }
 
+func TestStoreGCThresholdHistory(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	repl, err := tc.store.GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur, advanced := repl.GCThresholdHistory(); !cur.IsEmpty() || !advanced.IsZero() {
+		t.Fatalf("expected no threshold history, got %s at %s", cur, advanced)
+	}
+
+	sendGC := func(threshold hlc.Timestamp) {
+		t.Helper()
+		gcr := roachpb.GCRequest{
+			// Bogus span to make it a valid request.
+			RequestHeader: roachpb.RequestHeader{
+				Key:    roachpb.Key("a"),
+				EndKey: roachpb.Key("b"),
+			},
+			Threshold: threshold,
+		}
+		if _, pErr := tc.SendWrappedWith(roachpb.Header{RangeID: 1}, &gcr); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	threshold := hlc.Timestamp{WallTime: 2e9}
+	before := timeutil.Now()
+	sendGC(threshold)
+	cur, advanced := repl.GCThresholdHistory()
+	if cur != threshold {
+		t.Fatalf("expected threshold %s, got %s", threshold, cur)
+	}
+	if advanced.Before(before) {
+		t.Fatalf("expected threshold to have advanced after %s, got %s", before, advanced)
+	}
+
+	// Bumping the threshold again moves the advance time forward.
+	threshold = hlc.Timestamp{WallTime: 3e9}
+	sendGC(threshold)
+	cur, advancedAgain := repl.GCThresholdHistory()
+	if cur != threshold {
+		t.Fatalf("expected threshold %s, got %s", threshold, cur)
+	}
+	if advancedAgain.Before(advanced) {
+		t.Fatalf("expected advance time to move forward from %s, got %s", advanced, advancedAgain)
+	}
+}
+
 func TestStoreRangePlaceholders(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	tc := testContext{}
@@ -2954,6 +4205,214 @@ func TestStoreRemovePlaceholderOnRaftIgnored(t *testing.T) {
 	})
 }
 
+// Test that a snapshot rejected by the BeforeSnapshotApply testing knob is not
+// applied and that its placeholder is removed.
+func TestStoreRemovePlaceholderOnSnapshotRejected(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	var rejected int32
+	tsc := TestStoreConfig(nil)
+	tsc.TestingKnobs.BeforeSnapshotApply = func(inSnap *IncomingSnapshot) error {
+		atomic.AddInt32(&rejected, 1)
+		// Corrupt the snapshot; it must never reach Raft.
+		inSnap.State = nil
+		return errors.New("rejecting corrupt snapshot")
+	}
+	tc.StartWithStoreConfig(t, stopper, tsc)
+	s := tc.store
+	ctx := context.Background()
+
+	// Clobber the existing range so that the snapshot requires a placeholder.
+	repl1, err := s.GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveReplica(ctx, repl1, repl1.Desc().NextReplicaID, RemoveOptions{
+		DestroyData: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cv := s.ClusterSettings().Version.Version().Version
+	if _, err := stateloader.WriteInitialState(
+		ctx, s.Engine(), enginepb.MVCCStats{}, *repl1.Desc(), roachpb.Lease{},
+		hlc.Timestamp{}, cv, stateloader.TruncatedStateUnreplicated,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := protoutil.Marshal(&roachpb.RaftSnapshotData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &SnapshotRequest_Header{
+		State: storagepb.ReplicaState{Desc: repl1.Desc()},
+		RaftMessageRequest: RaftMessageRequest{
+			RangeID: 1,
+			ToReplica: roachpb.ReplicaDescriptor{
+				NodeID:    1,
+				StoreID:   1,
+				ReplicaID: 2,
+			},
+			FromReplica: roachpb.ReplicaDescriptor{
+				NodeID:    2,
+				StoreID:   2,
+				ReplicaID: 2,
+			},
+			Message: raftpb.Message{
+				Type: raftpb.MsgSnap,
+				Snapshot: raftpb.Snapshot{
+					Data: data,
+					Metadata: raftpb.SnapshotMetadata{
+						Index: 1,
+						Term:  1,
+					},
+				},
+			},
+		},
+	}
+	pErr := s.processRaftSnapshotRequest(ctx, req,
+		IncomingSnapshot{
+			SnapUUID: uuid.MakeV4(),
+			State:    &storagepb.ReplicaState{Desc: repl1.Desc()},
+		})
+	if !testutils.IsPError(pErr, "rejecting corrupt snapshot") {
+		t.Fatalf("expected snapshot to be rejected, got %v", pErr)
+	}
+	if n := atomic.LoadInt32(&rejected); n != 1 {
+		t.Fatalf("expected the hook to run once, ran %d times", n)
+	}
+
+	s.mu.Lock()
+	numPlaceholders := len(s.mu.replicaPlaceholders)
+	s.mu.Unlock()
+	if numPlaceholders != 0 {
+		t.Fatalf("expected 0 placeholders, but found %d", numPlaceholders)
+	}
+	if n := atomic.LoadInt32(&s.counts.removedPlaceholders); n != 1 {
+		t.Fatalf("expected 1 removed placeholder, but found %d", n)
+	}
+}
+
+// commitFailingBatch is an engine.Batch whose Commit always fails.
+type commitFailingBatch struct {
+	engine.Batch
+}
+
+func (b commitFailingBatch) Commit(sync bool) error {
+	return errors.New("injected commit failure")
+}
+
+// Test that a preemptive snapshot whose batch fails to commit leaves no
+// placeholder behind and does not persist or initialize the replica.
+func TestStoreRemovePlaceholderOnSnapshotCommitFailure(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	var commits int32
+	tsc := TestStoreConfig(nil)
+	tsc.TestingKnobs.SnapshotApplyBatchFactory = func() engine.Batch {
+		atomic.AddInt32(&commits, 1)
+		return commitFailingBatch{Batch: tc.store.Engine().NewWriteOnlyBatch()}
+	}
+	tc.StartWithStoreConfig(t, stopper, tsc)
+	s := tc.store
+	ctx := context.Background()
+
+	// Clobber the existing range so that the snapshot requires a placeholder.
+	repl1, err := s.GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveReplica(ctx, repl1, repl1.Desc().NextReplicaID, RemoveOptions{
+		DestroyData: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := protoutil.Marshal(&roachpb.RaftSnapshotData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const snapIndex = 10
+	snapHeader := &SnapshotRequest_Header{
+		State: storagepb.ReplicaState{Desc: repl1.Desc()},
+		RaftMessageRequest: RaftMessageRequest{
+			RangeID: 1,
+			ToReplica: roachpb.ReplicaDescriptor{
+				NodeID:    1,
+				StoreID:   1,
+				ReplicaID: 0,
+			},
+			FromReplica: roachpb.ReplicaDescriptor{
+				NodeID:    2,
+				StoreID:   2,
+				ReplicaID: 2,
+			},
+			Message: raftpb.Message{
+				Type: raftpb.MsgSnap,
+				From: 2,
+				Term: 1,
+				Snapshot: raftpb.Snapshot{
+					Data: data,
+					Metadata: raftpb.SnapshotMetadata{
+						ConfState: raftpb.ConfState{Voters: []uint64{2}},
+						Index:     snapIndex,
+						Term:      1,
+					},
+				},
+			},
+		},
+	}
+	if pErr := s.processPreemptiveSnapshotRequest(ctx, snapHeader,
+		IncomingSnapshot{
+			SnapUUID: uuid.MakeV4(),
+			State: &storagepb.ReplicaState{
+				Desc:             repl1.Desc(),
+				RaftAppliedIndex: snapIndex,
+			},
+			snapType: SnapshotRequest_PREEMPTIVE,
+		}); !testutils.IsPError(pErr, "injected commit failure") {
+		t.Fatalf("expected injected commit failure, got %v", pErr)
+	}
+	if n := atomic.LoadInt32(&commits); n != 1 {
+		t.Fatalf("expected the batch factory to be used once, used %d times", n)
+	}
+
+	s.mu.Lock()
+	numPlaceholders := len(s.mu.replicaPlaceholders)
+	s.mu.Unlock()
+	if numPlaceholders != 0 {
+		t.Fatalf("expected 0 placeholders, but found %d", numPlaceholders)
+	}
+	if n := atomic.LoadInt32(&s.counts.removedPlaceholders); n != 1 {
+		t.Fatalf("expected 1 removed placeholder, but found %d", n)
+	}
+	if n := atomic.LoadInt32(&s.counts.filledPlaceholders); n != 0 {
+		t.Fatalf("expected 0 filled placeholders, but found %d", n)
+	}
+
+	// Nothing from the failed snapshot may have been persisted or applied in
+	// memory.
+	repl, err := s.GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repl.IsInitialized() {
+		t.Fatalf("expected replica to remain uninitialized after failed snapshot")
+	}
+	hs, err := stateloader.Make(1).LoadHardState(ctx, s.Engine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hs.Commit == snapIndex {
+		t.Fatalf("HardState %+v from failed snapshot was persisted", hs)
+	}
+}
+
 // Test that we set proper tombstones for removed replicas and use the
 // tombstone to reject attempts to create a replica with a lesser ID.
 func TestRemovedReplicaTombstone(t *testing.T) {
@@ -3164,6 +4623,60 @@ func TestSendSnapshotThrottling(t *testing.T) {
 	}
 }
 
+// TestStoreThrottledStores verifies that a store which failed to receive a
+// snapshot is reported by Store.ThrottledStores until its throttle expires.
+func TestStoreThrottledStores(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc := testContext{}
+	tc.Start(t, stopper)
+	s := tc.store
+
+	if throttled := s.ThrottledStores(); len(throttled) != 0 {
+		t.Fatalf("expected no throttled stores, found %+v", throttled)
+	}
+
+	ctx := context.Background()
+	header := SnapshotRequest_Header{
+		State: storagepb.ReplicaState{
+			Desc: &roachpb.RangeDescriptor{RangeID: 1},
+		},
+		RaftMessageRequest: RaftMessageRequest{
+			ToReplica: roachpb.ReplicaDescriptor{NodeID: 2, StoreID: 2},
+		},
+	}
+	expectedErr := errors.New("snapshot stream broken")
+	c := fakeSnapshotStream{nil, expectedErr}
+	if err := sendSnapshot(
+		ctx, &s.cfg.RaftConfig, s.ClusterSettings(), c, s.cfg.StorePool, header, nil, s.Engine().NewBatch, nil,
+	); err != expectedErr {
+		t.Fatalf("expected error %s, but found %v", expectedErr, err)
+	}
+
+	throttled := s.ThrottledStores()
+	if len(throttled) != 1 {
+		t.Fatalf("expected 1 throttled store, found %+v", throttled)
+	}
+	timeout := FailedReservationsTimeout.Get(&s.ClusterSettings().SV)
+	expectedUntil := s.Clock().PhysicalTime().Add(timeout)
+	if ts := throttled[0]; ts.StoreID != 2 || ts.Reason != "failed" ||
+		ts.Why != expectedErr.Error() || !ts.Until.Equal(expectedUntil) {
+		t.Fatalf("unexpected throttled store %+v, expected s2 throttled until %s", ts, expectedUntil)
+	}
+
+	// The store is still throttled right up until the timeout elapses.
+	tc.manualClock.Increment(timeout.Nanoseconds() - 1)
+	if throttled := s.ThrottledStores(); len(throttled) != 1 {
+		t.Fatalf("expected 1 throttled store, found %+v", throttled)
+	}
+	tc.manualClock.Increment(1)
+	if throttled := s.ThrottledStores(); len(throttled) != 0 {
+		t.Fatalf("expected no throttled stores, found %+v", throttled)
+	}
+}
+
 func TestReserveSnapshotThrottling(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -3250,6 +4763,74 @@ func TestReserveSnapshotThrottling(t *testing.T) {
 	}
 }
 
+type fakeIncomingSnapshotStream struct {
+	responses chan *SnapshotResponse
+}
+
+func (c fakeIncomingSnapshotStream) Send(resp *SnapshotResponse) error {
+	c.responses <- resp
+	return nil
+}
+
+func (c fakeIncomingSnapshotStream) Recv() (*SnapshotRequest, error) {
+	return nil, errors.New("no snapshot data")
+}
+
+// TestReserveSnapshotHoldDelay verifies that an incoming snapshot which holds
+// on to its reservation causes a concurrent declinable snapshot to be
+// declined.
+func TestReserveSnapshotHoldDelay(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	cfg := TestStoreConfig(nil)
+	// Hold the reservation until the test cancels the first snapshot.
+	cfg.TestingKnobs.SnapshotReservationHoldDelay = time.Hour
+	tc := testContext{}
+	tc.StartWithStoreConfig(t, stopper, cfg)
+	s := tc.store
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	header := SnapshotRequest_Header{
+		RangeSize: 1,
+		State: storagepb.ReplicaState{
+			Desc: &roachpb.RangeDescriptor{RangeID: 100},
+		},
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		stream := fakeIncomingSnapshotStream{responses: make(chan *SnapshotResponse, 1)}
+		errCh <- s.receiveSnapshot(ctx, &header, stream)
+	}()
+	testutils.SucceedsSoon(t, func() error {
+		if n := s.ReservationCount(); n != 1 {
+			return errors.Errorf("expected 1 reservation, but found %d", n)
+		}
+		return nil
+	})
+
+	declinable := header
+	declinable.CanDecline = true
+	stream := fakeIncomingSnapshotStream{responses: make(chan *SnapshotResponse, 1)}
+	if err := s.receiveSnapshot(context.Background(), &declinable, stream); err != nil {
+		t.Fatal(err)
+	}
+	resp := <-stream.responses
+	if resp.Status != SnapshotResponse_DECLINED || resp.Message != snapshotApplySemBusyMsg {
+		t.Fatalf("expected snapshot to be declined with %q, got %+v", snapshotApplySemBusyMsg, resp)
+	}
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if n := s.ReservationCount(); n != 0 {
+		t.Fatalf("expected 0 reservations, but found %d", n)
+	}
+}
+
 // TestReserveSnapshotFullnessLimit verifies that snapshots are rejected when
 // the recipient store's disk is near full.
 func TestReserveSnapshotFullnessLimit(t *testing.T) {
@@ -3335,21 +4916,101 @@ func TestReserveSnapshotFullnessLimit(t *testing.T) {
 	}
 }
 
+// TestReserveSnapshotFullnessLimitSetting verifies that the fullness above
+// which declinable snapshots are rejected follows the cluster setting.
+func TestReserveSnapshotFullnessLimitSetting(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc := testContext{}
+	tc.Start(t, stopper)
+	s := tc.store
+
+	ctx := context.Background()
+
+	desc, err := s.Descriptor(false /* useCached */)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave 3% of the store's capacity available.
+	desc.Capacity.Available = desc.Capacity.Capacity * 3 / 100
+	desc.Capacity.Used = desc.Capacity.Capacity - desc.Capacity.Available
+	s.cfg.StorePool.detailsMu.Lock()
+	s.cfg.StorePool.getStoreDetailLocked(desc.StoreID).desc = desc
+	s.cfg.StorePool.detailsMu.Unlock()
+
+	reserve := func(rangeSize int64, canDecline bool) string {
+		t.Helper()
+		cleanup, rejectionMsg, err := s.reserveSnapshot(ctx, &SnapshotRequest_Header{
+			RangeSize:  rangeSize,
+			CanDecline: canDecline,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (cleanup == nil) == (rejectionMsg == "") {
+			t.Fatalf("unexpected cleanup %v for rejection message %q", cleanup, rejectionMsg)
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+		return rejectionMsg
+	}
+
+	// By default, a declinable snapshot is rejected.
+	if msg := reserve(1, true /* canDecline */); msg != snapshotStoreTooFullMsg {
+		t.Fatalf("expected rejection message %q, got %q", snapshotStoreTooFullMsg, msg)
+	}
+
+	// Under a more permissive setting, it is accepted.
+	snapshotMinAvailableFraction.Override(&s.ClusterSettings().SV, 0.01)
+	if msg := reserve(1, true /* canDecline */); msg != "" {
+		t.Fatalf("expected no rejection message, got %q", msg)
+	}
+	// But a range larger than the available space is still rejected.
+	snapshotMinAvailableFraction.Override(&s.ClusterSettings().SV, 0)
+	if msg := reserve(desc.Capacity.Available+1, true /* canDecline */); msg != snapshotStoreTooFullMsg {
+		t.Fatalf("expected rejection message %q, got %q", snapshotStoreTooFullMsg, msg)
+	}
+
+	// Under a stricter setting, non-declinable snapshots are still accepted.
+	snapshotMinAvailableFraction.Override(&s.ClusterSettings().SV, 0.5)
+	if msg := reserve(1, true /* canDecline */); msg != snapshotStoreTooFullMsg {
+		t.Fatalf("expected rejection message %q, got %q", snapshotStoreTooFullMsg, msg)
+	}
+	if msg := reserve(1, false /* canDecline */); msg != "" {
+		t.Fatalf("expected no rejection message, got %q", msg)
+	}
+}
+
 func TestSnapshotRateLimit(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
 	testCases := []struct {
 		priority      SnapshotRequest_Priority
+		recoveryRate  int64 // overrides the setting if non-zero
+		rebalanceRate int64 // overrides the setting if non-zero
 		expectedLimit rate.Limit
 		expectedErr   string
 	}{
-		{SnapshotRequest_UNKNOWN, 0, "unknown snapshot priority"},
-		{SnapshotRequest_RECOVERY, 8 << 20, ""},
-		{SnapshotRequest_REBALANCE, 8 << 20, ""},
+		{SnapshotRequest_UNKNOWN, 0, 0, 0, "unknown snapshot priority"},
+		{SnapshotRequest_RECOVERY, 0, 0, 8 << 20, ""},
+		{SnapshotRequest_REBALANCE, 0, 0, 8 << 20, ""},
+		{SnapshotRequest_RECOVERY, 32 << 20, 2 << 20, 32 << 20, ""},
+		{SnapshotRequest_REBALANCE, 32 << 20, 2 << 20, 2 << 20, ""},
 	}
 	for _, c := range testCases {
-		t.Run(c.priority.String(), func(t *testing.T) {
-			limit, err := snapshotRateLimit(cluster.MakeTestingClusterSettings(), c.priority)
+		name := fmt.Sprintf("%s/recovery=%d/rebalance=%d", c.priority, c.recoveryRate, c.rebalanceRate)
+		t.Run(name, func(t *testing.T) {
+			st := cluster.MakeTestingClusterSettings()
+			if c.recoveryRate != 0 {
+				recoverySnapshotRate.Override(&st.SV, c.recoveryRate)
+			}
+			if c.rebalanceRate != 0 {
+				rebalanceSnapshotRate.Override(&st.SV, c.rebalanceRate)
+			}
+			limit, err := snapshotRateLimit(st, c.priority)
 			if !testutils.IsError(err, c.expectedErr) {
 				t.Fatalf("expected \"%s\", but found %v", c.expectedErr, err)
 			}
@@ -11,6 +11,7 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"reflect"
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
@@ -176,6 +178,55 @@ func TestStoresGetReplicaForRangeID(t *testing.T) {
 	}
 }
 
+// TestStoresLocalConsistentScan verifies that a span fully covered by local
+// leaseholder replicas is scanned directly against the local stores, and that
+// a span without a local leaseholder returns an error.
+func TestStoresLocalConsistentScan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	store, _ := createTestStore(t, testStoreOpts{createSystemRanges: false}, stopper)
+	splitTestRange(store, roachpb.RKeyMin, roachpb.RKey("b"), t)
+
+	expKeys := []roachpb.Key{roachpb.Key("a"), roachpb.Key("a1"), roachpb.Key("b"), roachpb.Key("c")}
+	for _, key := range append(expKeys, roachpb.Key("d")) {
+		pArgs := putArgs(key, []byte(key))
+		if _, pErr := client.SendWrapped(ctx, store.TestSender(), &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	ls := newStores(log.AmbientContext{Tracer: tracing.NewTracer()}, store.Clock())
+	ls.AddStore(store)
+	span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("d")}
+	kvs, err := ls.LocalConsistentScan(ctx, span, store.Clock().Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != len(expKeys) {
+		t.Fatalf("expected %d rows, got %d: %v", len(expKeys), len(kvs), kvs)
+	}
+	for i, kv := range kvs {
+		if !kv.Key.Equal(expKeys[i]) {
+			t.Errorf("%d: expected key %s, got %s", i, expKeys[i], kv.Key)
+		}
+		if b, err := kv.Value.GetBytes(); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(b, expKeys[i]) {
+			t.Errorf("%d: expected value %q, got %q", i, expKeys[i], b)
+		}
+	}
+
+	// Without any local stores, there is no local leaseholder to serve the
+	// scan and the caller must fall back to normal routing.
+	empty := newStores(log.AmbientContext{Tracer: tracing.NewTracer()}, store.Clock())
+	_, err = empty.LocalConsistentScan(ctx, span, store.Clock().Now())
+	if !testutils.IsError(err, "no local leaseholder") {
+		t.Fatalf("expected no local leaseholder error, got %v", err)
+	}
+}
+
 func TestStoresGetStore(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	ls := newStores(log.AmbientContext{Tracer: tracing.NewTracer()}, hlc.NewClock(hlc.UnixNano, time.Nanosecond))
@@ -15,9 +15,12 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/storage/txnwait"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
 // StoreTestingKnobs is a part of the context used to control parts of
@@ -59,6 +62,17 @@ type StoreTestingKnobs struct {
 	// It is only called on the replica the proposed the command.
 	TestingPostApplyFilter storagebase.ReplicaApplyFilter
 
+	// InspectLogicalOpLog, if set, is called on each replica before a command
+	// is applied with the logical operations recorded during its evaluation.
+	// Logical operations are only recorded while kv.rangefeed.enabled is set.
+	// The ops must not be modified.
+	InspectLogicalOpLog func(storagebase.CmdIDKey, []enginepb.MVCCLogicalOp)
+
+	// SimulateEngineWriteStall, if set, is called before each batch of applied
+	// Raft commands is committed to the engine. The batch commit is delayed by
+	// the returned duration, as if the engine had stalled writes.
+	SimulateEngineWriteStall func() time.Duration
+
 	// TestingResponseFilter is called after the replica processes a
 	// command in order for unittests to modify the batch response,
 	// error returned to the client, or to simulate network failures.
@@ -128,6 +142,12 @@ type StoreTestingKnobs struct {
 	DisableScanner bool
 	// DisablePeriodicGossips disables periodic gossiping.
 	DisablePeriodicGossips bool
+	// DelayStoreCapacityGossip, if positive, suppresses gossiping the store
+	// descriptor (and with it, the store's capacity) for the given duration
+	// after the store is started. The descriptor is gossiped once the delay has
+	// elapsed. This allows tests to set up a topology before the allocator
+	// reacts to the store.
+	DelayStoreCapacityGossip time.Duration
 	// DisableLeaderFollowsLeaseholder disables attempts to transfer raft
 	// leadership when it diverges from the range's leaseholder.
 	DisableLeaderFollowsLeaseholder bool
@@ -138,6 +158,11 @@ type StoreTestingKnobs struct {
 	// commands when a new leader is discovered or when a config change is
 	// dropped.
 	DisableRefreshReasonNewLeaderOrConfigChange bool
+	// DeferRefreshReasonNewLeaderOrConfigChange causes empty Raft entries to
+	// defer the refresh of pending commands to the replica's next tick
+	// instead of refreshing them eagerly once the entries have been applied.
+	// Has no effect if DisableRefreshReasonNewLeaderOrConfigChange is set.
+	DeferRefreshReasonNewLeaderOrConfigChange bool
 	// DisableRefreshReasonTicks disables refreshing pending commands when a
 	// snapshot is applied.
 	DisableRefreshReasonSnapshotApplied bool
@@ -175,7 +200,9 @@ type StoreTestingKnobs struct {
 	SystemLogsGCGCDone chan<- struct{}
 	// DontRetryPushTxnFailures will propagate a push txn failure immediately
 	// instead of utilizing the txn wait queue to wait for the transaction to
-	// finish or be pushed by a higher priority contender.
+	// finish or be pushed by a higher priority contender. This has the same
+	// effect as disabling the kv.transaction.retry_push_failures_enabled
+	// cluster setting.
 	DontRetryPushTxnFailures bool
 	// DontRecoverIndeterminateCommits will propagate indeterminate commit
 	// errors from failed txn pushes immediately instead of utilizing the txn
@@ -189,6 +216,26 @@ type StoreTestingKnobs struct {
 	// acquiring snapshot quota or doing shouldAcceptSnapshotData checks. If an
 	// error is returned from the hook, it's sent as an ERROR SnapshotResponse.
 	ReceiveSnapshot func(*SnapshotRequest_Header) error
+	// IncomingSnapshotID, if set, is run on the header of every snapshot
+	// received by the store. A non-empty returned ID replaces the one the
+	// sender assigned to the snapshot, so that snapshot IDs, and the logs
+	// referring to them, are reproducible across test runs.
+	IncomingSnapshotID func(*SnapshotRequest_Header) uuid.UUID
+	// SnapshotReservationHoldDelay, if positive, makes an incoming snapshot
+	// hold on to its reservation for the given duration before proceeding
+	// with the snapshot. This allows tests to deterministically exercise
+	// concurrent snapshots being declined.
+	SnapshotReservationHoldDelay time.Duration
+	// BeforeSnapshotApply is run after an incoming Raft or preemptive snapshot
+	// has been received and its placeholder (if any) added, but before the
+	// snapshot is handed to Raft for application. The hook may mutate the
+	// snapshot. If an error is returned, the snapshot is rejected.
+	BeforeSnapshotApply func(*IncomingSnapshot) error
+	// SnapshotApplyBatchFactory, if set, is used in place of the engine's
+	// NewWriteOnlyBatch to create the batch into which an incoming snapshot is
+	// written. Tests can use it to wrap the batch, e.g. to inject a failure
+	// when the batch is committed.
+	SnapshotApplyBatchFactory func() engine.Batch
 	// ReplicaAddStopAfterLearnerSnapshot causes replica addition to return early
 	// if the func returns true. Specifically, after the learner txn is successful
 	// and after the LEARNER type snapshot, but before promoting it to a voter.
@@ -383,6 +383,18 @@ var charts = []sectionDescription{
 					"kv.rangefeed.catchup_scan_nanos",
 				},
 			},
+			{
+				Title: "Rangefeed Active Catch-Up Scans",
+				Metrics: []string{
+					"kv.rangefeed.catchup_scans_active",
+				},
+			},
+			{
+				Title: "Rangefeed Queued Catch-Up Scans",
+				Metrics: []string{
+					"kv.rangefeed.catchup_scans_queued",
+				},
+			},
 			{
 				Title: "Snapshots",
 				Metrics: []string{
@@ -592,6 +604,10 @@ var charts = []sectionDescription{
 				Percentiles: false,
 				Metrics:     []string{"requests.backpressure.split"},
 			},
+			{
+				Title:   "Batches Declaring Excessive Spans",
+				Metrics: []string{"requests.large_span_set"},
+			},
 		},
 	},
 	{
@@ -1081,6 +1097,17 @@ var charts = []sectionDescription{
 	{
 		Organization: [][]string{{ReplicationLayer, "Raft", "Overview"}},
 		Charts: []chartDescription{
+			{
+				Title:   "Application Batches",
+				Metrics: []string{"raft.process.applycommitted.batches"},
+			},
+			{
+				Title: "Applied Entries",
+				Metrics: []string{
+					"raft.process.applycommitted.emptyentries",
+					"raft.process.applycommitted.entries",
+				},
+			},
 			{
 				Title:   "Commands Count",
 				Metrics: []string{"raft.commandsapplied"},
@@ -1101,6 +1128,10 @@ var charts = []sectionDescription{
 				Title:   "Leaders",
 				Metrics: []string{"replicas.leaders"},
 			},
+			{
+				Title:   "State Assertions",
+				Metrics: []string{"raft.process.applycommitted.stateassertions"},
+			},
 			{
 				Title:   "Stuck Request Count",
 				Metrics: []string{"requests.slow.raft"},